@@ -0,0 +1,113 @@
+package aac
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ghodss/yaml"
+)
+
+// ADTSHeaderSize is size of the ADTS header without CRC.
+const ADTSHeaderSize = 7
+
+// maxADTSFrameLength is the max frame length in ADTS header(13 bits), including the header itself.
+const maxADTSFrameLength = 0x1FFF
+
+// ADTSHeader creates the fixed and variable ADTS header(without CRC) for a raw frame,
+// which were defined in ISO/IEC-13818-7 6.2.
+func (a *AudioSpecificConfig) ADTSHeader(rawFrameSize int) ([]byte, error) {
+	if a.AudioObjectType < AudioObjectTypeAACMain || a.AudioObjectType > AudioObjectTypeAACLTP {
+		return nil, fmt.Errorf("audioObjectType %d can not be represented by adts", a.AudioObjectType)
+	}
+	if a.ChannelConfiguration == 0 || a.ChannelConfiguration > 7 {
+		return nil, fmt.Errorf("channelConfiguration %d can not be represented by adts", a.ChannelConfiguration)
+	}
+
+	index := a.SamplingFrequencyIndex
+	if index == samplingFrequencyIndexEscape { // adts only supports indexed frequency
+		for i, f := range samplingFrequencies {
+			if f == a.SamplingFrequency {
+				index = uint8(i)
+				break
+			}
+		}
+		if index == samplingFrequencyIndexEscape {
+			return nil, fmt.Errorf("samplingFrequency %d can not be represented by adts", a.SamplingFrequency)
+		}
+	}
+
+	frameLength := rawFrameSize + ADTSHeaderSize
+	if frameLength > maxADTSFrameLength {
+		return nil, fmt.Errorf("frame length %d exceeds adts max %d", frameLength, maxADTSFrameLength)
+	}
+
+	profile := a.AudioObjectType - 1
+	return []byte{
+		0xFF,       // syncword 12 bits
+		0xF0 | 0x1, // syncword, ID 0(MPEG-4), layer 00, protection_absent 1
+		profile<<6 | index<<2 | (a.ChannelConfiguration>>2)&0x1,      // profile 2 bits, sampling_frequency_index 4 bits, private_bit 0, channel_configuration 1st bit
+		(a.ChannelConfiguration&0x3)<<6 | uint8(frameLength>>11)&0x3, // channel_configuration 2 bits, original_copy 0, home 0, copyright_identification_bit 0, copyright_identification_start 0, aac_frame_length 2 bits
+		uint8(frameLength >> 3),          // aac_frame_length 8 bits
+		uint8(frameLength&0x7)<<5 | 0x1F, // aac_frame_length 3 bits, adts_buffer_fullness 5 bits
+		0xFC,                             // adts_buffer_fullness 6 bits(0x7FF for VBR), number_of_raw_data_blocks_in_frame 0
+	}, nil
+}
+
+// ADTS represents AAC stream in ADTS format, i.e., raw frames that every one has an ADTS header.
+type ADTS struct {
+	AudioSpecificConfig AudioSpecificConfig `json:"audio_specific_config"`
+	RawFrames           [][]byte            `json:"-"`
+}
+
+// JSON marshals ADTS to JSON representation
+func (a *ADTS) JSON() ([]byte, error) {
+	return json.Marshal(a)
+}
+
+// JSONIndent marshals ADTS to JSON representation with customized indent.
+func (a *ADTS) JSONIndent(prefix, indent string) ([]byte, error) {
+	return json.MarshalIndent(a, prefix, indent)
+}
+
+// YAML formats ADTS to YAML representation.
+func (a *ADTS) YAML() ([]byte, error) {
+	j, err := json.Marshal(a)
+	if err != nil {
+		return j, err
+	}
+	return yaml.JSONToYAML(j)
+}
+
+// CSV formats ADTS to CSV representation, which isn't supported at the moment.
+func (a *ADTS) CSV() ([]byte, error) {
+	return nil, fmt.Errorf("csv representation does not support yet")
+}
+
+// Dump dumps raw frames with ADTS header into io.Writer.
+func (a *ADTS) Dump(w io.Writer) (int, error) {
+	if len(a.RawFrames) == 0 {
+		return 0, fmt.Errorf("empty adts stream")
+	}
+
+	var writedBytes int
+
+	for i := range a.RawFrames {
+		header, err := a.AudioSpecificConfig.ADTSHeader(len(a.RawFrames[i]))
+		if err != nil {
+			return writedBytes, err
+		}
+
+		for _, data := range [][]byte{header, a.RawFrames[i]} {
+			if n, err := w.Write(data); err != nil {
+				return writedBytes, err
+			} else if n != len(data) {
+				return writedBytes, fmt.Errorf("write bytes unmatch, expect(%d) != actual(%d)", len(data), n)
+			} else {
+				writedBytes += n
+			}
+		}
+	}
+
+	return writedBytes, nil
+}
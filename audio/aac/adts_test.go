@@ -0,0 +1,44 @@
+package aac
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestADTSHeader(t *testing.T) {
+	cases := []struct {
+		config       []byte
+		rawFrameSize int
+		expect       []byte
+		expectErr    bool
+	}{
+		{config: []byte{0x11, 0x90}, rawFrameSize: 136, expect: []byte{0xFF, 0xF1, 0x4C, 0x80, 0x11, 0xFF, 0xFC}},                 // AAC-LC 48000Hz stereo
+		{config: []byte{0x12, 0x10}, rawFrameSize: 0x1FF9, expectErr: true},                                                       // frame too large
+		{config: []byte{0x2b, 0x92, 0x08, 0x00}, rawFrameSize: 100, expect: []byte{0xFF, 0xF1, 0x5C, 0x80, 0x0D, 0x7F, 0xFC}},     // HE-AAC explicit signalling, 22050Hz core stereo
+		{config: []byte{0x17, 0x80, 0x2E, 0xE0, 0x08}, rawFrameSize: 1, expect: []byte{0xFF, 0xF1, 0x58, 0x40, 0x01, 0x1F, 0xFC}}, // explicit 24000Hz mono
+		{config: []byte{0x17, 0x80, 0x2E, 0xE0, 0x88}, rawFrameSize: 1, expectErr: true},                                          // explicit 24001Hz
+		{config: []byte{0x29, 0x90}, rawFrameSize: 1, expectErr: true},                                                            // SBR without underlying object type
+	}
+
+	for i, c := range cases {
+		a := AudioSpecificConfig{}
+		_, err := a.Parse(bytes.NewReader(c.config), len(c.config))
+		var header []byte
+		if err == nil {
+			header, err = a.ADTSHeader(c.rawFrameSize)
+		}
+		if c.expectErr {
+			if err == nil {
+				t.Errorf("case %d expect error but got nil, config %+v", i, a)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d expect nil but got %v", i, err)
+			continue
+		}
+		if !bytes.Equal(header, c.expect) {
+			t.Errorf("case %d expect header % x but got % x, config %+v", i, c.expect, header, a)
+		}
+	}
+}
@@ -0,0 +1,494 @@
+// Package aac represents MPEG-4 AAC audio, which was defined in ISO/IEC-14496-3.
+package aac
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/wangyoucao577/medialib/util"
+	"github.com/wangyoucao577/medialib/util/bitreader"
+)
+
+// Audio Object Types, defined in ISO/IEC-14496-3 1.5.1.1 Table 1.1.
+const (
+	AudioObjectTypeAACMain      = 1
+	AudioObjectTypeAACLC        = 2
+	AudioObjectTypeAACSSR       = 3
+	AudioObjectTypeAACLTP       = 4
+	AudioObjectTypeSBR          = 5
+	AudioObjectTypeAACScalable  = 6
+	AudioObjectTypeTwinVQ       = 7
+	AudioObjectTypeERAACLC      = 17
+	AudioObjectTypeERAACLTP     = 19
+	AudioObjectTypeERAACScal    = 20
+	AudioObjectTypeERTwinVQ     = 21
+	AudioObjectTypeERBSAC       = 22
+	AudioObjectTypeERAACLD      = 23
+	AudioObjectTypeERParametric = 27
+	AudioObjectTypePS           = 29
+
+	audioObjectTypeEscape = 31
+)
+
+// Sync extension types for backward-compatible SBR/PS signalling, defined in ISO/IEC-14496-3 1.6.2.1.
+const (
+	syncExtensionTypeSBR = 0x2b7
+	syncExtensionTypePS  = 0x548
+)
+
+// samplingFrequencyIndexEscape indicates that sampling frequency is explicitly stored.
+const samplingFrequencyIndexEscape = 0xF
+
+// samplingFrequencies indexed by samplingFrequencyIndex, defined in ISO/IEC-14496-3 1.6.3.4 Table 1.18.
+var samplingFrequencies = []uint32{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+// channelsOfConfiguration indexed by channelConfiguration, defined in ISO/IEC-14496-3 1.6.3.5 Table 1.19 and
+// ISO/IEC 23001-8, 0 means defined by program_config_element.
+var channelsOfConfiguration = []int{0, 1, 2, 3, 4, 5, 6, 8, 0, 0, 0, 7, 8, 24, 8}
+
+// AudioSpecificConfig represents AudioSpecificConfig that defined in ISO/IEC-14496-3 1.6.2.1.
+// GASpecificConfig is parsed for General Audio object types, while other specific configs are not parsed yet.
+type AudioSpecificConfig struct {
+	AudioObjectType        uint8  `json:"audioObjectType"`
+	SamplingFrequencyIndex uint8  `json:"samplingFrequencyIndex"`
+	SamplingFrequency      uint32 `json:"samplingFrequency,omitempty"` // only if samplingFrequencyIndex == 0xF
+	ChannelConfiguration   uint8  `json:"channelConfiguration"`
+
+	// SBR/PS signalling, audioObjectType will be the underlying one in case of explicit signalling
+	ExtensionAudioObjectType        uint8  `json:"extensionAudioObjectType,omitempty"`
+	ExtensionSamplingFrequencyIndex uint8  `json:"extensionSamplingFrequencyIndex,omitempty"`
+	ExtensionSamplingFrequency      uint32 `json:"extensionSamplingFrequency,omitempty"`    // only if extensionSamplingFrequencyIndex == 0xF
+	ExtensionChannelConfiguration   uint8  `json:"extensionChannelConfiguration,omitempty"` // only for ER BSAC
+	SBRPresentFlag                  bool   `json:"sbrPresentFlag,omitempty"`
+	PSPresentFlag                   bool   `json:"psPresentFlag,omitempty"`
+
+	GASpecificConfig *GASpecificConfig `json:"GASpecificConfig,omitempty"`
+	EPConfig         uint8             `json:"epConfig,omitempty"` // only for error resilient object types
+}
+
+// GASpecificConfig represents GASpecificConfig that defined in ISO/IEC-14496-3 4.4.1.
+type GASpecificConfig struct {
+	FrameLengthFlag      uint8                 `json:"frameLengthFlag"` // 0: 1024 or 960(1) samples per frame
+	DependsOnCoreCoder   uint8                 `json:"dependsOnCoreCoder"`
+	CoreCoderDelay       uint16                `json:"coreCoderDelay,omitempty"` // 14 bits, only if dependsOnCoreCoder
+	ExtensionFlag        uint8                 `json:"extensionFlag"`
+	ProgramConfigElement *ProgramConfigElement `json:"program_config_element,omitempty"` // only if channelConfiguration == 0
+	LayerNr              uint8                 `json:"layerNr,omitempty"`                // 3 bits, only for AAC scalable
+
+	// only if extensionFlag
+	NumOfSubFrame                    uint8  `json:"numOfSubFrame,omitempty"` // 5 bits, only for ER BSAC
+	LayerLength                      uint16 `json:"layer_length,omitempty"`  // 11 bits, only for ER BSAC
+	AACSectionDataResilienceFlag     uint8  `json:"aacSectionDataResilienceFlag,omitempty"`
+	AACScalefactorDataResilienceFlag uint8  `json:"aacScalefactorDataResilienceFlag,omitempty"`
+	AACSpectralDataResilienceFlag    uint8  `json:"aacSpectralDataResilienceFlag,omitempty"`
+	ExtensionFlag3                   uint8  `json:"extensionFlag3,omitempty"`
+}
+
+// ProgramConfigElement represents program_config_element that defined in ISO/IEC-14496-3 4.4.1.1.
+type ProgramConfigElement struct {
+	ElementInstanceTag     uint8 `json:"element_instance_tag"`
+	ObjectType             uint8 `json:"object_type"`
+	SamplingFrequencyIndex uint8 `json:"sampling_frequency_index"`
+
+	MonoMixdownPresent         uint8 `json:"mono_mixdown_present"`
+	MonoMixdownElementNumber   uint8 `json:"mono_mixdown_element_number,omitempty"`
+	StereoMixdownPresent       uint8 `json:"stereo_mixdown_present"`
+	StereoMixdownElementNumber uint8 `json:"stereo_mixdown_element_number,omitempty"`
+	MatrixMixdownIdxPresent    uint8 `json:"matrix_mixdown_idx_present"`
+	MatrixMixdownIdx           uint8 `json:"matrix_mixdown_idx,omitempty"`
+	PseudoSurroundEnable       uint8 `json:"pseudo_surround_enable,omitempty"`
+
+	FrontElements             []ChannelElement `json:"front_elements,omitempty"`
+	SideElements              []ChannelElement `json:"side_elements,omitempty"`
+	BackElements              []ChannelElement `json:"back_elements,omitempty"`
+	LFEElementTagSelect       []uint8          `json:"lfe_element_tag_select,omitempty"`
+	AssocDataElementTagSelect []uint8          `json:"assoc_data_element_tag_select,omitempty"`
+	CCElements                []ChannelElement `json:"cc_elements,omitempty"` // IsCPE means cc_element_is_ind_sw here
+
+	Comment string `json:"comment,omitempty"`
+}
+
+// ChannelElement represents a front/side/back or coupling channel element in program_config_element.
+type ChannelElement struct {
+	IsCPE     uint8 `json:"is_cpe"`
+	TagSelect uint8 `json:"tag_select"`
+}
+
+// Parse parses AudioSpecificConfig, return parsed bytes or error.
+// All size bytes will be read, and the remain bits after parsed fields are ignored.
+func (a *AudioSpecificConfig) Parse(r io.Reader, size int) (uint64, error) {
+	data := make([]byte, size)
+	if err := util.ReadOrError(r, data); err != nil {
+		return 0, err
+	}
+	rd := bytes.NewReader(data)
+	br := bitreader.New(rd) // start bit-level parsing here
+	remainBits := func() int { return rd.Len()*8 + br.CachedBitsCount() }
+
+	var err error
+	if a.AudioObjectType, err = readAudioObjectType(br); err != nil {
+		return 0, err
+	}
+	if a.SamplingFrequencyIndex, a.SamplingFrequency, err = readSamplingFrequency(br); err != nil {
+		return 0, err
+	}
+	if a.ChannelConfiguration, err = readBits8(br, 4); err != nil {
+		return 0, err
+	}
+
+	// explicit SBR/PS signalling
+	if a.AudioObjectType == AudioObjectTypeSBR || a.AudioObjectType == AudioObjectTypePS {
+		a.ExtensionAudioObjectType = AudioObjectTypeSBR
+		a.SBRPresentFlag = true
+		a.PSPresentFlag = a.AudioObjectType == AudioObjectTypePS
+		if a.ExtensionSamplingFrequencyIndex, a.ExtensionSamplingFrequency, err = readSamplingFrequency(br); err != nil {
+			return 0, err
+		}
+		if a.AudioObjectType, err = readAudioObjectType(br); err != nil {
+			return 0, err
+		}
+		if a.AudioObjectType == AudioObjectTypeERBSAC {
+			if a.ExtensionChannelConfiguration, err = readBits8(br, 4); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	switch a.AudioObjectType {
+	case AudioObjectTypeAACMain, AudioObjectTypeAACLC, AudioObjectTypeAACSSR, AudioObjectTypeAACLTP,
+		AudioObjectTypeAACScalable, AudioObjectTypeTwinVQ, AudioObjectTypeERAACLC, AudioObjectTypeERAACLTP,
+		AudioObjectTypeERAACScal, AudioObjectTypeERTwinVQ, AudioObjectTypeERBSAC, AudioObjectTypeERAACLD:
+		a.GASpecificConfig = &GASpecificConfig{}
+		if err := a.GASpecificConfig.parse(br, a.ChannelConfiguration, a.AudioObjectType); err != nil {
+			return 0, fmt.Errorf("GASpecificConfig %v", err)
+		}
+	default:
+		return uint64(size), nil // specific config of other object types are not supported yet
+	}
+
+	if a.AudioObjectType >= AudioObjectTypeERAACLC && a.AudioObjectType <= AudioObjectTypeERParametric {
+		if a.EPConfig, err = readBits8(br, 2); err != nil {
+			return 0, err
+		}
+		if a.EPConfig == 2 || a.EPConfig == 3 {
+			return uint64(size), nil // ErrorProtectionSpecificConfig is not supported yet
+		}
+	}
+
+	// backward-compatible SBR/PS signalling
+	if a.ExtensionAudioObjectType != AudioObjectTypeSBR && remainBits() >= 16 {
+		if syncExtensionType, err := readBits(br, 11); err != nil {
+			return 0, err
+		} else if syncExtensionType != syncExtensionTypeSBR {
+			return uint64(size), nil
+		}
+		if a.ExtensionAudioObjectType, err = readAudioObjectType(br); err != nil {
+			return 0, err
+		}
+		switch a.ExtensionAudioObjectType {
+		case AudioObjectTypeSBR:
+			if a.SBRPresentFlag, err = readFlag(br); err != nil {
+				return 0, err
+			}
+			if a.SBRPresentFlag {
+				if a.ExtensionSamplingFrequencyIndex, a.ExtensionSamplingFrequency, err = readSamplingFrequency(br); err != nil {
+					return 0, err
+				}
+				if remainBits() >= 12 {
+					if syncExtensionType, err := readBits(br, 11); err != nil {
+						return 0, err
+					} else if syncExtensionType == syncExtensionTypePS {
+						if a.PSPresentFlag, err = readFlag(br); err != nil {
+							return 0, err
+						}
+					}
+				}
+			}
+		case AudioObjectTypeERBSAC:
+			if a.SBRPresentFlag, err = readFlag(br); err != nil {
+				return 0, err
+			}
+			if a.SBRPresentFlag {
+				if a.ExtensionSamplingFrequencyIndex, a.ExtensionSamplingFrequency, err = readSamplingFrequency(br); err != nil {
+					return 0, err
+				}
+			}
+			if a.ExtensionChannelConfiguration, err = readBits8(br, 4); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return uint64(size), nil
+}
+
+// Frequency returns sampling frequency in Hz.
+func (a *AudioSpecificConfig) Frequency() uint32 {
+	return frequency(a.SamplingFrequencyIndex, a.SamplingFrequency)
+}
+
+// OutputFrequency returns sampling frequency in Hz after SBR if it presents, otherwise same as Frequency.
+func (a *AudioSpecificConfig) OutputFrequency() uint32 {
+	if a.SBRPresentFlag {
+		if f := frequency(a.ExtensionSamplingFrequencyIndex, a.ExtensionSamplingFrequency); f > 0 {
+			return f
+		}
+		return a.Frequency() * 2
+	}
+	return a.Frequency()
+}
+
+// Channels returns count of output channels, which includes LFE and stereo generated by PS.
+// 0 will be returned if unknown.
+func (a *AudioSpecificConfig) Channels() int {
+	var channels int
+	if a.ChannelConfiguration == 0 {
+		if a.GASpecificConfig != nil && a.GASpecificConfig.ProgramConfigElement != nil {
+			channels = a.GASpecificConfig.ProgramConfigElement.Channels()
+		}
+	} else if int(a.ChannelConfiguration) < len(channelsOfConfiguration) {
+		channels = channelsOfConfiguration[a.ChannelConfiguration]
+	}
+	if a.PSPresentFlag && channels == 1 {
+		return 2
+	}
+	return channels
+}
+
+func frequency(index uint8, explicit uint32) uint32 {
+	if index == samplingFrequencyIndexEscape {
+		return explicit
+	}
+	if int(index) < len(samplingFrequencies) {
+		return samplingFrequencies[index]
+	}
+	return 0
+}
+
+func (g *GASpecificConfig) parse(br *bitreader.Reader, channelConfiguration uint8, audioObjectType uint8) error {
+	var err error
+	if g.FrameLengthFlag, err = readBits8(br, 1); err != nil {
+		return err
+	}
+	if g.DependsOnCoreCoder, err = readBits8(br, 1); err != nil {
+		return err
+	}
+	if g.DependsOnCoreCoder == 1 {
+		if v, err := readBits(br, 14); err != nil {
+			return err
+		} else {
+			g.CoreCoderDelay = uint16(v)
+		}
+	}
+	if g.ExtensionFlag, err = readBits8(br, 1); err != nil {
+		return err
+	}
+	if channelConfiguration == 0 {
+		g.ProgramConfigElement = &ProgramConfigElement{}
+		if err := g.ProgramConfigElement.parse(br); err != nil {
+			return fmt.Errorf("program_config_element %v", err)
+		}
+	}
+	if audioObjectType == AudioObjectTypeAACScalable || audioObjectType == AudioObjectTypeERAACScal {
+		if g.LayerNr, err = readBits8(br, 3); err != nil {
+			return err
+		}
+	}
+	if g.ExtensionFlag == 1 {
+		if audioObjectType == AudioObjectTypeERBSAC {
+			if g.NumOfSubFrame, err = readBits8(br, 5); err != nil {
+				return err
+			}
+			if v, err := readBits(br, 11); err != nil {
+				return err
+			} else {
+				g.LayerLength = uint16(v)
+			}
+		}
+		if audioObjectType == AudioObjectTypeERAACLC || audioObjectType == AudioObjectTypeERAACLTP ||
+			audioObjectType == AudioObjectTypeERAACScal || audioObjectType == AudioObjectTypeERAACLD {
+			for _, f := range []*uint8{&g.AACSectionDataResilienceFlag, &g.AACScalefactorDataResilienceFlag, &g.AACSpectralDataResilienceFlag} {
+				if *f, err = readBits8(br, 1); err != nil {
+					return err
+				}
+			}
+		}
+		if g.ExtensionFlag3, err = readBits8(br, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Channels returns count of channels that described by the program_config_element.
+func (p *ProgramConfigElement) Channels() int {
+	channels := len(p.LFEElementTagSelect)
+	for _, elements := range [][]ChannelElement{p.FrontElements, p.SideElements, p.BackElements} {
+		for _, e := range elements {
+			channels += 1 + int(e.IsCPE)
+		}
+	}
+	return channels
+}
+
+func (p *ProgramConfigElement) parse(br *bitreader.Reader) error {
+	fields := []struct {
+		v    *uint8
+		bits uint
+	}{
+		{&p.ElementInstanceTag, 4}, {&p.ObjectType, 2}, {&p.SamplingFrequencyIndex, 4},
+	}
+	for _, f := range fields {
+		var err error
+		if *f.v, err = readBits8(br, f.bits); err != nil {
+			return err
+		}
+	}
+
+	// num_front/side/back/lfe/assoc_data/valid_cc elements
+	var nums [6]uint8
+	for i, bits := range []uint{4, 4, 4, 2, 3, 4} {
+		var err error
+		if nums[i], err = readBits8(br, bits); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if p.MonoMixdownPresent, err = readBits8(br, 1); err != nil {
+		return err
+	}
+	if p.MonoMixdownPresent == 1 {
+		if p.MonoMixdownElementNumber, err = readBits8(br, 4); err != nil {
+			return err
+		}
+	}
+	if p.StereoMixdownPresent, err = readBits8(br, 1); err != nil {
+		return err
+	}
+	if p.StereoMixdownPresent == 1 {
+		if p.StereoMixdownElementNumber, err = readBits8(br, 4); err != nil {
+			return err
+		}
+	}
+	if p.MatrixMixdownIdxPresent, err = readBits8(br, 1); err != nil {
+		return err
+	}
+	if p.MatrixMixdownIdxPresent == 1 {
+		if p.MatrixMixdownIdx, err = readBits8(br, 2); err != nil {
+			return err
+		}
+		if p.PseudoSurroundEnable, err = readBits8(br, 1); err != nil {
+			return err
+		}
+	}
+
+	for i, elements := range []*[]ChannelElement{&p.FrontElements, &p.SideElements, &p.BackElements} {
+		if *elements, err = readChannelElements(br, int(nums[i])); err != nil {
+			return err
+		}
+	}
+	for i, tags := range []*[]uint8{&p.LFEElementTagSelect, &p.AssocDataElementTagSelect} {
+		for j := 0; j < int(nums[3+i]); j++ {
+			tag, err := readBits8(br, 4)
+			if err != nil {
+				return err
+			}
+			*tags = append(*tags, tag)
+		}
+	}
+	if p.CCElements, err = readChannelElements(br, int(nums[5])); err != nil {
+		return err
+	}
+
+	// byte_alignment() relative to the start of AudioSpecificConfig, which is byte aligned itself
+	if _, err := br.ReadBits(uint(br.CachedBitsCount())); err != nil {
+		return err
+	}
+	commentFieldBytes, err := br.ReadByte()
+	if err != nil {
+		return err
+	}
+	if commentFieldBytes > 0 {
+		comment, err := br.ReadBytes(uint(commentFieldBytes))
+		if err != nil {
+			return err
+		}
+		p.Comment = string(comment)
+	}
+	return nil
+}
+
+func readChannelElements(br *bitreader.Reader, count int) ([]ChannelElement, error) {
+	var elements []ChannelElement
+	for i := 0; i < count; i++ {
+		e := ChannelElement{}
+		var err error
+		if e.IsCPE, err = readBits8(br, 1); err != nil {
+			return nil, err
+		}
+		if e.TagSelect, err = readBits8(br, 4); err != nil {
+			return nil, err
+		}
+		elements = append(elements, e)
+	}
+	return elements, nil
+}
+
+func readAudioObjectType(br *bitreader.Reader) (uint8, error) {
+	aot, err := readBits8(br, 5)
+	if err != nil {
+		return 0, err
+	}
+	if aot != audioObjectTypeEscape {
+		return aot, nil
+	}
+
+	if aot, err = readBits8(br, 6); err != nil {
+		return 0, err
+	}
+	return 32 + aot, nil
+}
+
+func readSamplingFrequency(br *bitreader.Reader) (uint8, uint32, error) {
+	index, err := readBits8(br, 4)
+	if err != nil {
+		return 0, 0, err
+	}
+	if index != samplingFrequencyIndexEscape {
+		if int(index) >= len(samplingFrequencies) {
+			return 0, 0, fmt.Errorf("reserved samplingFrequencyIndex %d", index)
+		}
+		return index, 0, nil
+	}
+
+	f, err := readBits(br, 24)
+	if err != nil {
+		return 0, 0, err
+	}
+	return index, f, nil
+}
+
+// readBits reads count(<=32) bits as an unsigned integer.
+func readBits(br *bitreader.Reader, count uint) (uint32, error) {
+	var v uint32
+	for i := uint(0); i < count; i++ {
+		bit, err := br.ReadBit()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<1 | uint32(bit)
+	}
+	return v, nil
+}
+
+func readBits8(br *bitreader.Reader, count uint) (uint8, error) {
+	v, err := readBits(br, count)
+	return uint8(v), err
+}
+
+func readFlag(br *bitreader.Reader) (bool, error) {
+	bit, err := br.ReadBit()
+	return bit == 1, err
+}
@@ -0,0 +1,81 @@
+package aac
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestAudioSpecificConfigParse(t *testing.T) {
+	cases := []struct {
+		name      string
+		config    []byte
+		expect    AudioSpecificConfig
+		frequency uint32 // output frequency
+		channels  int
+	}{
+		{"AAC-LC 44100Hz stereo", []byte{0x12, 0x10},
+			AudioSpecificConfig{AudioObjectType: 2, SamplingFrequencyIndex: 4, ChannelConfiguration: 2, GASpecificConfig: &GASpecificConfig{}},
+			44100, 2},
+		{"AAC-LC 960 samples per frame with core coder delay", []byte{0x11, 0x8E, 0x00, 0x10},
+			AudioSpecificConfig{AudioObjectType: 2, SamplingFrequencyIndex: 3, ChannelConfiguration: 1,
+				GASpecificConfig: &GASpecificConfig{FrameLengthFlag: 1, DependsOnCoreCoder: 1, CoreCoderDelay: 2}},
+			48000, 1},
+		{"HE-AAC explicit", []byte{0x2B, 0x92, 0x08, 0x00},
+			AudioSpecificConfig{AudioObjectType: 2, SamplingFrequencyIndex: 7, ChannelConfiguration: 2,
+				ExtensionAudioObjectType: 5, ExtensionSamplingFrequencyIndex: 4, SBRPresentFlag: true, GASpecificConfig: &GASpecificConfig{}},
+			44100, 2},
+		{"HE-AACv2 explicit", []byte{0xEB, 0x09, 0x88, 0x00},
+			AudioSpecificConfig{AudioObjectType: 2, SamplingFrequencyIndex: 6, ChannelConfiguration: 1,
+				ExtensionAudioObjectType: 5, ExtensionSamplingFrequencyIndex: 3, SBRPresentFlag: true, PSPresentFlag: true, GASpecificConfig: &GASpecificConfig{}},
+			48000, 2},
+		{"HE-AAC backward-compatible", []byte{0x13, 0x10, 0x56, 0xE5, 0x98},
+			AudioSpecificConfig{AudioObjectType: 2, SamplingFrequencyIndex: 6, ChannelConfiguration: 2,
+				ExtensionAudioObjectType: 5, ExtensionSamplingFrequencyIndex: 3, SBRPresentFlag: true, GASpecificConfig: &GASpecificConfig{}},
+			48000, 2},
+		{"HE-AACv2 backward-compatible", []byte{0x13, 0x08, 0x56, 0xE5, 0x9D, 0x48, 0x80},
+			AudioSpecificConfig{AudioObjectType: 2, SamplingFrequencyIndex: 6, ChannelConfiguration: 1,
+				ExtensionAudioObjectType: 5, ExtensionSamplingFrequencyIndex: 3, SBRPresentFlag: true, PSPresentFlag: true, GASpecificConfig: &GASpecificConfig{}},
+			48000, 2},
+		{"escape audioObjectType", []byte{0xF8, 0x08, 0x40},
+			AudioSpecificConfig{AudioObjectType: 32, SamplingFrequencyIndex: 4, ChannelConfiguration: 2},
+			44100, 2},
+		{"program_config_element 5.1", []byte{0x12, 0x00, 0x05, 0x08, 0x05, 0x00, 0x01, 0x08, 0x80, 0x02, 'h', 'i'},
+			AudioSpecificConfig{AudioObjectType: 2, SamplingFrequencyIndex: 4, ChannelConfiguration: 0,
+				GASpecificConfig: &GASpecificConfig{ProgramConfigElement: &ProgramConfigElement{
+					ObjectType: 1, SamplingFrequencyIndex: 4,
+					FrontElements:       []ChannelElement{{IsCPE: 0, TagSelect: 0}, {IsCPE: 1, TagSelect: 0}},
+					BackElements:        []ChannelElement{{IsCPE: 1, TagSelect: 1}},
+					LFEElementTagSelect: []uint8{0},
+					Comment:             "hi",
+				}}},
+			44100, 6},
+	}
+
+	for _, c := range cases {
+		a := AudioSpecificConfig{}
+		if n, err := a.Parse(bytes.NewReader(c.config), len(c.config)); err != nil || n != uint64(len(c.config)) {
+			t.Errorf("%s expect %d bytes but got %d, err %v", c.name, len(c.config), n, err)
+			continue
+		}
+		if !reflect.DeepEqual(a, c.expect) {
+			t.Errorf("%s expect %+v but got %+v", c.name, c.expect, a)
+			if a.GASpecificConfig != nil {
+				t.Errorf("%s GASpecificConfig %+v, program_config_element %+v", c.name, a.GASpecificConfig, a.GASpecificConfig.ProgramConfigElement)
+			}
+		}
+		if f := a.OutputFrequency(); f != c.frequency {
+			t.Errorf("%s expect output frequency %d but got %d", c.name, c.frequency, f)
+		}
+		if channels := a.Channels(); channels != c.channels {
+			t.Errorf("%s expect %d channels but got %d", c.name, c.channels, channels)
+		}
+	}
+
+	for _, in := range [][]byte{{0x12}, {0x16, 0x90}, {0x12, 0x00, 0x05}} { // truncated, reserved frequency index, truncated program_config_element
+		a := AudioSpecificConfig{}
+		if _, err := a.Parse(bytes.NewReader(in), len(in)); err == nil {
+			t.Errorf("parse %x expect error but got nil, %+v", in, a)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/wangyoucao577/medialib/container/flv"
+	"github.com/wangyoucao577/medialib/util/dump"
+)
+
+func extractFLVAudio(inputFile string, output string) error {
+
+	// output
+	w, closer, err := dump.CreateOutput(output)
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+
+	// extract and write adts tag by tag
+	h := flv.New(inputFile)
+	if err := h.WriteAudio(w); err != nil {
+		return fmt.Errorf("extract audio failed, err %v", err)
+	}
+	return nil
+}
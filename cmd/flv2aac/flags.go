@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/wangyoucao577/medialib/util"
+)
+
+var flags struct {
+	inputFilePath  string
+	outputFilePath string
+}
+
+func init() {
+	flag.StringVar(&flags.inputFilePath, "i", "", fmt.Sprintf("Input flv file url, '%s' if stdin", util.InputStdin))
+	flag.StringVar(&flags.outputFilePath, "o", "stdout", "Output ADTS aac file path.")
+}
+
+func validateFlags() error {
+	if len(flags.inputFilePath) == 0 {
+		return fmt.Errorf("input file is required")
+	}
+	if len(flags.outputFilePath) == 0 {
+		return fmt.Errorf("output should not be empty")
+	}
+
+	return nil
+}
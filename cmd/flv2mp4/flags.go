@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/wangyoucao577/medialib/util"
+)
+
+var flags struct {
+	inputFilePath  string
+	outputFilePath string
+
+	fragmented       bool // fragmented mp4 instead of progressive one
+	fragmentDuration time.Duration
+	sidx             bool
+}
+
+func init() {
+	flag.StringVar(&flags.inputFilePath, "i", "", fmt.Sprintf("Input flv file url, '%s' if stdin", util.InputStdin))
+	flag.StringVar(&flags.outputFilePath, "o", "", "Output mp4 file path, 'stdout' if stdout.")
+	flag.BoolVar(&flags.fragmented, "fmp4", false, "Output fragmented mp4 instead of progressive mp4 with moov before mdat.")
+	flag.DurationVar(&flags.fragmentDuration, "frag_duration", 0, "Expected fragment duration for fragmented mp4, fragments are always cut at keyframes so they might be longer. 0 means cut at every keyframe.")
+	flag.BoolVar(&flags.sidx, "sidx", false, "Generate a sidx for each fragment of fragmented mp4.")
+}
+
+func validateFlags() error {
+	if len(flags.inputFilePath) == 0 {
+		return fmt.Errorf("input file is required")
+	}
+	if len(flags.outputFilePath) == 0 {
+		return fmt.Errorf("output file is required")
+	}
+	if flags.outputFilePath == flags.inputFilePath {
+		return fmt.Errorf("output file should be different from input file")
+	}
+
+	if flags.fragmentDuration < 0 {
+		return fmt.Errorf("invalid fragment duration %v", flags.fragmentDuration)
+	}
+	if !flags.fragmented && (flags.fragmentDuration > 0 || flags.sidx) {
+		return fmt.Errorf("-frag_duration and -sidx require -fmp4")
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/wangyoucao577/medialib/container/flv"
+	"github.com/wangyoucao577/medialib/container/mp4"
+	"github.com/wangyoucao577/medialib/util/dump"
+)
+
+func remuxFLV(inputFile string) error {
+
+	// remux tag by tag, samples will be read from input when writing
+	h := flv.New(inputFile)
+	defer h.Close()
+	b, err := h.RemuxMP4()
+	if err != nil {
+		return fmt.Errorf("remux to mp4 failed, err %v", err)
+	}
+
+	// output
+	w, closer, err := dump.CreateOutput(flags.outputFilePath)
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+
+	if flags.fragmented {
+		return b.WriteFragmented(w, mp4.FragmentOptions{FragmentDuration: flags.fragmentDuration, Sidx: flags.sidx})
+	}
+	return b.Encode(w)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/wangyoucao577/medialib/util"
+)
+
+var flags struct {
+	inputFilePath string
+	outputURL     string
+	timeout       time.Duration
+	realtime      bool
+}
+
+func init() {
+	flag.StringVar(&flags.inputFilePath, "i", "", fmt.Sprintf("Input flv file url, '%s' if stdin", util.InputStdin))
+	flag.StringVar(&flags.outputURL, "o", "", "Output RTMP url to publish, e.g., rtmp://127.0.0.1/live/stream")
+	flag.DurationVar(&flags.timeout, "timeout", 10*time.Second, "Timeout for connecting and publishing.")
+	flag.BoolVar(&flags.realtime, "realtime", true, "Send tags in realtime according to their timestamps, otherwise as fast as possible.")
+}
+
+func validateFlags() error {
+	if len(flags.inputFilePath) == 0 {
+		return fmt.Errorf("input file is required")
+	}
+	if len(flags.outputURL) == 0 {
+		return fmt.Errorf("output url is required")
+	}
+	if flags.timeout <= 0 {
+		return fmt.Errorf("invalid timeout %v", flags.timeout)
+	}
+
+	return nil
+}
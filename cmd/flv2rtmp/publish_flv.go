@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/container/flv"
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/protocol/rtmp"
+)
+
+func publishFLV(inputFile string, url string, timeout time.Duration, realtime bool) error {
+	h, err := rtmp.NewHandler(url)
+	if err != nil {
+		return err
+	}
+	if err := h.Connect(timeout); err != nil {
+		return err
+	}
+	defer h.Close()
+	if err := h.Publish(timeout); err != nil {
+		return err
+	}
+
+	// send tags one by one, paced by timestamps if realtime
+	var startTime time.Time
+	var firstTimestamp int32
+	var tags int
+	err = flv.New(inputFile).WalkData(func(t tag.Tag, data []byte) error {
+		header := t.GetTagHeader()
+		if header.Filter == 1 {
+			glog.Warningf("skip encrypted tag type %d(%s) timestamp %d", header.TagType, tag.TypeDescription(int(header.TagType)), header.TimestampCalculated)
+			return nil
+		}
+
+		if realtime {
+			if tags == 0 {
+				startTime, firstTimestamp = time.Now(), header.TimestampCalculated
+			}
+			due := time.Duration(header.TimestampCalculated-firstTimestamp) * time.Millisecond
+			if wait := due - time.Since(startTime); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+
+		if err := h.WriteTag(header, data); err != nil {
+			return err
+		}
+		tags++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("publish %s to %s failed after %d tags, err %v", inputFile, url, tags, err)
+	}
+
+	glog.Infof("published %d tags from %s to %s", tags, inputFile, url)
+	return nil
+}
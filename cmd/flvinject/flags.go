@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+var flags struct {
+	inputFilePath  string
+	outputFilePath string
+}
+
+func init() {
+	flag.StringVar(&flags.inputFilePath, "i", "", "Input flv file path, stdin is not supported since the input will be read twice.")
+	flag.StringVar(&flags.outputFilePath, "o", "", "Output flv file path with rebuilt onMetaData, 'stdout' if stdout. Same as input means rewrite the input file.")
+}
+
+func validateFlags() error {
+	if len(flags.inputFilePath) == 0 {
+		return fmt.Errorf("input file is required")
+	}
+	if len(flags.outputFilePath) == 0 {
+		return fmt.Errorf("output file is required")
+	}
+
+	return nil
+}
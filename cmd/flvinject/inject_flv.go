@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/wangyoucao577/medialib/container/flv"
+	"github.com/wangyoucao577/medialib/util/dump"
+)
+
+func injectFLV(inputFile, outputFile string) error {
+
+	f, err := os.Open(inputFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// rewrite via a temporary file in the same directory if output is the input
+	rewrite := filepath.Clean(outputFile) == filepath.Clean(inputFile)
+	if rewrite {
+		outputFile = inputFile + ".tmp"
+	}
+
+	w, closer, err := dump.CreateOutput(outputFile)
+	if err != nil {
+		return err
+	}
+	if err := flv.InjectKeyframes(f, info.Size(), w); err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		if rewrite {
+			os.Remove(outputFile)
+		}
+		return err
+	}
+	if closer != nil {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+
+	if rewrite {
+		return os.Rename(outputFile, inputFile)
+	}
+	return nil
+}
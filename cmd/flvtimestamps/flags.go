@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/wangyoucao577/medialib/container/flv"
+	"github.com/wangyoucao577/medialib/util"
+	"github.com/wangyoucao577/medialib/util/dump"
+)
+
+var flags struct {
+	inputFilePath  string
+	outputFilePath string
+	reportFilePath string
+	reportFormat   string
+
+	strategy           string
+	jumpThreshold      int
+	driftThreshold     int
+	videoFrameDuration int
+	audioFrameDuration int
+}
+
+func init() {
+	flag.StringVar(&flags.inputFilePath, "i", "", fmt.Sprintf("Input flv file path, '%s' if stdin which is only supported without repair since the input will be read twice.", util.InputStdin))
+	flag.StringVar(&flags.outputFilePath, "o", "", "Output flv file path with repaired timestamps. Same as input means rewrite the input file. Empty means analyze only.")
+	flag.StringVar(&flags.reportFilePath, "report", dump.OutputStdout, "Output file path of the discontinuities report, empty means stdout.")
+	flag.StringVar(&flags.reportFormat, "of", dump.FormatJSONFormatted, fmt.Sprintf("Report format, available values:%s", dump.FormatsHelper()))
+
+	flag.StringVar(&flags.strategy, "strategy", flv.TimestampStrategyOffset, fmt.Sprintf("Timestamp correction strategy, '%s' shifts all following tags to continue, '%s' sets the outlier tag to the previous one plus the expected frame duration.",
+		flv.TimestampStrategyOffset, flv.TimestampStrategyClamp))
+	flag.IntVar(&flags.jumpThreshold, "jump_threshold", flv.DefaultJumpThreshold, "Forward timestamp delta larger than it in milliseconds is regarded as a jump.")
+	flag.IntVar(&flags.driftThreshold, "drift_threshold", flv.DefaultDriftThreshold, "Audio and video timestamps drift larger than it in milliseconds will be reported.")
+	flag.IntVar(&flags.videoFrameDuration, "video_frame_duration", 0, "Expected video frame duration in milliseconds, 0 means estimate by the median of timestamp deltas.")
+	flag.IntVar(&flags.audioFrameDuration, "audio_frame_duration", 0, "Expected audio frame duration in milliseconds, 0 means estimate by the median of timestamp deltas.")
+}
+
+func validateFlags() error {
+	if len(flags.inputFilePath) == 0 {
+		return fmt.Errorf("input file is required")
+	}
+	if len(flags.outputFilePath) > 0 && flags.inputFilePath == util.InputStdin {
+		return fmt.Errorf("stdin input doesn't support repair")
+	}
+	if flags.strategy != flv.TimestampStrategyOffset && flags.strategy != flv.TimestampStrategyClamp {
+		return fmt.Errorf("unknown strategy %s", flags.strategy)
+	}
+	if _, err := dump.GetFormat(flags.reportFormat); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/util/appversion"
+	"github.com/wangyoucao577/medialib/util/exit"
+)
+
+func main() {
+	flag.Parse()
+	defer glog.Flush()
+	appversion.PrintExit()
+
+	// validate and get flags
+	if err := validateFlags(); err != nil {
+		glog.Error(err)
+		exit.Fail()
+	}
+
+	if err := processFLV(flags.inputFilePath, flags.outputFilePath); err != nil {
+		glog.Error(err)
+		exit.Fail()
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/wangyoucao577/medialib/container/flv"
+	"github.com/wangyoucao577/medialib/util"
+	"github.com/wangyoucao577/medialib/util/dump"
+)
+
+// processFLV analyzes timestamps of input, and repairs them to output if it's not empty.
+func processFLV(inputFile, outputFile string) error {
+	opts := flv.TimestampOptions{
+		Strategy:           flags.strategy,
+		JumpThreshold:      int32(flags.jumpThreshold),
+		DriftThreshold:     int32(flags.driftThreshold),
+		VideoFrameDuration: int32(flags.videoFrameDuration),
+		AudioFrameDuration: int32(flags.audioFrameDuration),
+	}
+
+	var report *flv.TimestampReport
+	var err error
+	if len(outputFile) == 0 {
+		report, err = analyzeFLV(inputFile, opts)
+	} else {
+		report, err = repairFLV(inputFile, outputFile, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	format, err := dump.GetFormat(flags.reportFormat)
+	if err != nil {
+		return err
+	}
+	return dump.Dump(report, format, flags.reportFilePath)
+}
+
+func analyzeFLV(inputFile string, opts flv.TimestampOptions) (*flv.TimestampReport, error) {
+	var r io.Reader = os.Stdin
+	if inputFile != util.InputStdin {
+		f, err := os.Open(inputFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return flv.AnalyzeTimestamps(r, opts)
+}
+
+func repairFLV(inputFile, outputFile string, opts flv.TimestampOptions) (*flv.TimestampReport, error) {
+	f, err := os.Open(inputFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// rewrite via a temporary file in the same directory if output is the input
+	rewrite := filepath.Clean(outputFile) == filepath.Clean(inputFile)
+	if rewrite {
+		outputFile = inputFile + ".tmp"
+	}
+
+	w, closer, err := dump.CreateOutput(outputFile)
+	if err != nil {
+		return nil, err
+	}
+	report, err := flv.RepairTimestamps(f, info.Size(), w, opts)
+	if err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		if rewrite {
+			os.Remove(outputFile)
+		}
+		return nil, err
+	}
+	if closer != nil {
+		if err := closer.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if rewrite {
+		if err := os.Rename(outputFile, inputFile); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
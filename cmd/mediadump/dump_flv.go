@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/container/flv"
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/util"
+	"github.com/wangyoucao577/medialib/util/dump"
+)
+
+// dumpFLV reads and dumps FLV tag by tag, so that large input can be dumped in time and in constant memory.
+// Only PreviousTagSize values are kept until the end since they're dumped after tags.
+// Tags with Filter=1 will be decrypted if decryptionKey is not empty.
+func dumpFLV(inputFilePath string, format dump.Format, output string, decryptionKey []byte) error {
+	enc, err := newFLVEncoder(format)
+	if err != nil {
+		return err
+	}
+
+	r := os.Stdin
+	if inputFilePath != util.InputStdin {
+		f, err := os.Open(inputFilePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	w, closer, err := dump.CreateOutput(output)
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+
+	fr := flv.NewReader(r)
+	if len(decryptionKey) > 0 {
+		fr.SetDecryptionKey(decryptionKey)
+	}
+	header, err := fr.ReadHeader()
+	if err != nil {
+		return err
+	}
+	if err := enc.header(w, header); err != nil {
+		return err
+	}
+
+	var previousTagSize []uint32
+	for {
+		t, err := fr.Next()
+		if size, ok := fr.PreviousTagSize(); ok {
+			previousTagSize = append(previousTagSize, size)
+		}
+		if err != nil {
+			if err != io.EOF {
+				glog.Warningf("Parse FLV failed but ignore to leverage the data has been dumped already, err %v", err)
+			}
+			break
+		}
+		if err := enc.tag(w, t); err != nil {
+			return err
+		}
+	}
+
+	return enc.end(w, previousTagSize)
+}
+
+// flvEncoder encodes FLV header, tags and PreviousTagSize in order.
+type flvEncoder interface {
+	header(w io.Writer, h flv.Header) error
+	tag(w io.Writer, t tag.Tag) error
+	end(w io.Writer, previousTagSize []uint32) error
+}
+
+// newFLVEncoder creates flvEncoder by format.
+func newFLVEncoder(format dump.Format) (flvEncoder, error) {
+	switch format {
+	case dump.FormatJSON:
+		return &flvJSONEncoder{}, nil
+	case dump.FormatJSONFormatted:
+		return &flvJSONEncoder{indent: "\t"}, nil
+	case dump.FormatYAML, dump.FormatYML:
+		return &flvYAMLEncoder{}, nil
+	}
+	return nil, fmt.Errorf("%s representation does not support yet", format)
+}
+
+// flvJSONEncoder encodes FLV as a JSON object, same as FLV.JSON or FLV.JSONIndent with empty prefix.
+type flvJSONEncoder struct {
+	indent string
+	tags   int
+}
+
+func (e *flvJSONEncoder) marshal(v interface{}, prefix string) ([]byte, error) {
+	if e.indent == "" {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, prefix, e.indent)
+}
+
+// prefix returns indent of level.
+func (e *flvJSONEncoder) prefix(level int) string {
+	return strings.Repeat(e.indent, level)
+}
+
+// newline returns new line with indent of level, or nothing if no indent.
+func (e *flvJSONEncoder) newline(level int) string {
+	if e.indent == "" {
+		return ""
+	}
+	return "\n" + e.prefix(level)
+}
+
+func (e *flvJSONEncoder) sep() string {
+	if e.indent == "" {
+		return ":"
+	}
+	return ": "
+}
+
+func (e *flvJSONEncoder) header(w io.Writer, h flv.Header) error {
+	d, err := e.marshal(h, e.prefix(1))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "{%s\"header\"%s%s,%s\"Tags\"%s[", e.newline(1), e.sep(), d, e.newline(1), e.sep())
+	return err
+}
+
+func (e *flvJSONEncoder) tag(w io.Writer, t tag.Tag) error {
+	d, err := e.marshal(t, e.prefix(2))
+	if err != nil {
+		return err
+	}
+	if e.tags > 0 {
+		if _, err := io.WriteString(w, ","); err != nil {
+			return err
+		}
+	}
+	e.tags++
+	_, err = fmt.Fprintf(w, "%s%s", e.newline(2), d)
+	return err
+}
+
+func (e *flvJSONEncoder) end(w io.Writer, previousTagSize []uint32) error {
+	if e.tags > 0 {
+		if _, err := io.WriteString(w, e.newline(1)); err != nil {
+			return err
+		}
+	}
+	d, err := e.marshal(previousTagSize, e.prefix(1))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "],%s\"PreviousTagSize\"%s%s%s}", e.newline(1), e.sep(), d, e.newline(0))
+	return err
+}
+
+// flvYAMLEncoder encodes FLV as YAML, same content as FLV.YAML but keys are not sorted.
+type flvYAMLEncoder struct {
+	tags int
+}
+
+func (e *flvYAMLEncoder) header(w io.Writer, h flv.Header) error {
+	d, err := yaml.Marshal(map[string]flv.Header{"header": h})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(d)
+	return err
+}
+
+func (e *flvYAMLEncoder) tag(w io.Writer, t tag.Tag) error {
+	j, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	d, err := yaml.JSONToYAML(j)
+	if err != nil {
+		return err
+	}
+
+	if e.tags == 0 {
+		if _, err := io.WriteString(w, "Tags:\n"); err != nil {
+			return err
+		}
+	}
+	e.tags++
+
+	// as an item of sequence
+	d = bytes.TrimSuffix(d, []byte("\n"))
+	d = bytes.ReplaceAll(d, []byte("\n"), []byte("\n  "))
+	_, err = fmt.Fprintf(w, "- %s\n", d)
+	return err
+}
+
+func (e *flvYAMLEncoder) end(w io.Writer, previousTagSize []uint32) error {
+	if e.tags == 0 {
+		if _, err := io.WriteString(w, "Tags: []\n"); err != nil {
+			return err
+		}
+	}
+	d, err := yaml.Marshal(map[string][]uint32{"PreviousTagSize": previousTagSize})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(d)
+	return err
+}
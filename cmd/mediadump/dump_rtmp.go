@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/container/flv"
+	"github.com/wangyoucao577/medialib/protocol"
+	"github.com/wangyoucao577/medialib/protocol/rtmp"
+	"github.com/wangyoucao577/medialib/util/dump"
+)
+
+// dumpRTMP plays RTMP stream and dumps received messages as FLV tags in time, same structures as dumpFLV.
+func dumpRTMP(url string, format dump.Format, output string, timeout time.Duration, duration time.Duration) error {
+	enc, err := newFLVEncoder(format)
+	if err != nil {
+		return err
+	}
+
+	h, err := rtmp.NewHandler(url)
+	if err != nil {
+		return err
+	}
+	if err := h.Connect(timeout); err != nil {
+		return err
+	}
+	defer h.Close()
+	if err := h.Play(timeout); err != nil {
+		return err
+	}
+	h.SetPlayDuration(duration)
+
+	w, closer, err := dump.CreateOutput(output)
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+
+	if err := enc.header(w, flv.NewHeader(true, true)); err != nil {
+		return err
+	}
+	previousTagSize := []uint32{0} // as if they're written to FLV
+	for {
+		t, err := h.ReadTag()
+		if err != nil {
+			if err != io.EOF {
+				glog.Warningf("Play RTMP failed but ignore to leverage the data has been dumped already, err %v", err)
+			}
+			break
+		}
+		if err := enc.tag(w, t); err != nil {
+			return err
+		}
+		previousTagSize = append(previousTagSize, uint32(t.Size()))
+	}
+
+	return enc.end(w, previousTagSize)
+}
+
+// isRTMP checks whether input is RTMP url by scheme.
+func isRTMP(inputFilePath string) bool {
+	return strings.HasPrefix(inputFilePath, protocol.SchemaRTMP+"://")
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"time"
 
 	"github.com/wangyoucao577/medialib/util"
 	"github.com/wangyoucao577/medialib/util/dump"
@@ -13,27 +15,42 @@ var flags struct {
 	outputFilePath string
 	outputFormat   string
 
-	parseES bool // parse and dump es layer rather than container layer
+	parseES    bool   // parse and dump es layer rather than container layer
+	track      string // track selector for mp4
+	listTracks bool   // list tracks of mp4
 
 	dumpBoxTypes      bool
 	dumpAVCNALUTypes  bool
 	dumpHEVCNALUTypes bool
 
 	printDurations bool
+
+	decryptionKey    string // hex encoded AES-128 key for encrypted FLV
+	decryptionKeyRaw []byte
+
+	rtmpTimeout  time.Duration
+	rtmpDuration time.Duration
 }
 
 func init() {
-	flag.StringVar(&flags.inputFilePath, "i", "", fmt.Sprintf("input file url, '%s' if stdin", util.InputStdin))
+	flag.StringVar(&flags.inputFilePath, "i", "", fmt.Sprintf("input file url, '%s' if stdin, or RTMP url to play, e.g. 'rtmp://127.0.0.1/live/stream'", util.InputStdin))
 	flag.StringVar(&flags.outputFilePath, "o", "", "output to file instead of stdout")
 	flag.StringVar(&flags.outputFormat, "of", dump.FormatJSONFormatted, fmt.Sprintf("output format, available values:%s", dump.FormatsHelper()))
 
 	flag.BoolVar(&flags.parseES, "parse_es", false, "parse and dump Elementry Stream layer rather than container layer")
+	flag.StringVar(&flags.track, "track", "", "mp4 track selector for Elementry Stream parsing, e.g. 'id:1', 'index:0' or 'type:vide'. Empty means the first video track")
+	flag.BoolVar(&flags.listTracks, "list_tracks", false, "list mp4 tracks with track_ID, index, handler_type and codec")
 
 	flag.BoolVar(&flags.dumpBoxTypes, "box_types", false, "dump supported mp4 box types")
 	flag.BoolVar(&flags.dumpAVCNALUTypes, "avc_nalu_types", false, "dump AVC supported NALU types")
 	flag.BoolVar(&flags.dumpHEVCNALUTypes, "hevc_nalu_types", false, "dump HEVC supported NALU types")
 
 	flag.BoolVar(&flags.printDurations, "print_durations", false, "print fragment-mp4 detailed durations")
+
+	flag.StringVar(&flags.decryptionKey, "decryption_key", "", "hex encoded AES-128 key to decrypt FLV tags with Filter=1, e.g. '2b7e151628aed2a6abf7158809cf4f3c'. Empty means dump encrypted body as it is")
+
+	flag.DurationVar(&flags.rtmpTimeout, "rtmp_timeout", 10*time.Second, "timeout for connecting and playing RTMP input")
+	flag.DurationVar(&flags.rtmpDuration, "rtmp_duration", 0, "stop playing RTMP input once timestamps of audio/video tags reach the duration, 0 means until the stream stops")
 }
 
 func validateFlags() error {
@@ -42,6 +59,22 @@ func validateFlags() error {
 		return err
 	}
 
+	if len(flags.decryptionKey) > 0 {
+		if flags.decryptionKeyRaw, err = hex.DecodeString(flags.decryptionKey); err != nil {
+			return fmt.Errorf("invalid decryption key, err %v", err)
+		}
+		if len(flags.decryptionKeyRaw) != 16 {
+			return fmt.Errorf("decryption key expect 16 bytes but got %d", len(flags.decryptionKeyRaw))
+		}
+	}
+
+	if flags.rtmpTimeout <= 0 {
+		return fmt.Errorf("invalid rtmp timeout %v", flags.rtmpTimeout)
+	}
+	if flags.rtmpDuration < 0 {
+		return fmt.Errorf("invalid rtmp duration %v", flags.rtmpDuration)
+	}
+
 	if !flags.dumpBoxTypes && !flags.dumpAVCNALUTypes && !flags.dumpHEVCNALUTypes && len(flags.inputFilePath) == 0 {
 		return fmt.Errorf("input file is mandantory")
 	}
@@ -42,6 +42,22 @@ func main() {
 		data = avcnalu.TypesMarshaler{}
 	} else if flags.dumpHEVCNALUTypes {
 		data = hevcnalu.TypesMarshaler{}
+	} else if isRTMP(flags.inputFilePath) {
+		if flags.parseES {
+			glog.Error("parse_es is not supported for RTMP input")
+			exit.Fail()
+		}
+		if err := dumpRTMP(flags.inputFilePath, outputFormat, flags.outputFilePath, flags.rtmpTimeout, flags.rtmpDuration); err != nil {
+			glog.Error(err)
+			exit.Fail()
+		}
+		return
+	} else if isFLV(flags.inputFilePath) && !flags.parseES {
+		if err := dumpFLV(flags.inputFilePath, outputFormat, flags.outputFilePath, flags.decryptionKeyRaw); err != nil {
+			glog.Error(err)
+			exit.Fail()
+		}
+		return
 	} else {
 		if m, err := parseInput(flags.inputFilePath, flags.parseES, flags.printDurations); err != nil {
 			glog.Error(err)
@@ -67,6 +83,7 @@ func parseInput(inputFilePath string, parseES bool, printDuration bool) (dump.Ma
 		strings.HasSuffix(inputFilePath, mediaformat.AsExtension(mediaformat.M4S)) ||
 		strings.HasSuffix(inputFilePath, mediaformat.AsExtension(mediaformat.MOV)) {
 		m := mp4.New(inputFilePath)
+		defer m.Close()
 		if err := m.Parse(); err != nil {
 			if err != io.EOF {
 				glog.Warningf("Parse mp4 failed but ignore to leverage the data has been parsed already, err %v", err)
@@ -78,29 +95,30 @@ func parseInput(inputFilePath string, parseES bool, printDuration bool) (dump.Ma
 			m.DumpDurations()
 		}
 
+		if flags.listTracks {
+			return m.Boxes.Tracks(), nil
+		}
 		if !parseES {
 			return m, nil
 		}
 
-		es, err := m.Boxes.ExtractES(0)
+		track, err := m.Boxes.SelectTrack(flags.track, box.TypeVide)
+		if err != nil {
+			return nil, err
+		}
+		es, err := m.Boxes.ExtractES(int(track.ID))
 		if err != nil {
 			return nil, fmt.Errorf("extract es failed, err %v", err)
 		}
 		return es, nil
 
-	} else if strings.HasSuffix(inputFilePath, mediaformat.AsExtension(mediaformat.FLV)) {
-		h := flv.New(inputFilePath)
-		if err := h.Parse(); err != nil {
-			if err != io.EOF {
-				glog.Warningf("Parse FLV failed but ignore to leverage the data has been parsed already, err %v", err)
-				// exit.Fail()	// ignore the error so that able to leverage the data has been parsed already
-			}
-		}
+	} else if isFLV(inputFilePath) {
 		if !parseES {
-			return h, nil
+			return nil, fmt.Errorf("dump flv by dumpFLV instead")
 		}
 
-		es, err := h.FLV.ExtractES()
+		// extract tag by tag rather than parse all tags into memory
+		es, err := flv.New(inputFilePath).WalkES()
 		if err != nil {
 			return nil, fmt.Errorf("extract es failed, err %v", err)
 		}
@@ -119,3 +137,8 @@ func parseInput(inputFilePath string, parseES bool, printDuration bool) (dump.Ma
 
 	return nil, fmt.Errorf("unknown format for input %s", inputFilePath)
 }
+
+// isFLV checks whether input is FLV by extension.
+func isFLV(inputFilePath string) bool {
+	return strings.HasSuffix(inputFilePath, mediaformat.AsExtension(mediaformat.FLV))
+}
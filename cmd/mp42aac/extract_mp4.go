@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/container/mp4"
+	"github.com/wangyoucao577/medialib/container/mp4/box"
+	"github.com/wangyoucao577/medialib/util/dump"
+)
+
+func extractMP4Audio(inputFile string, output string) error {
+
+	// parse
+	m := mp4.New(inputFile)
+	defer m.Close()
+	if err := m.Parse(); err != nil {
+		if err != io.EOF {
+			glog.Warningf("Parse mp4 failed but ignore to leverage the data has been parsed already, err %v", err)
+		}
+	}
+
+	track, err := m.Boxes.SelectTrack(flags.track, box.TypeSoun)
+	if err != nil {
+		return err
+	}
+
+	a, err := m.Boxes.ExtractAudio(int(track.ID))
+	if err != nil {
+		return fmt.Errorf("extract audio failed, err %v", err)
+	}
+
+	// output
+	w, closer, err := dump.CreateOutput(output)
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+
+	if _, err := a.Dump(w); err != nil {
+		return fmt.Errorf("dump adts failed, err %v", err)
+	}
+	return nil
+}
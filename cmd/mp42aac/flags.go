@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/wangyoucao577/medialib/util"
+)
+
+var flags struct {
+	inputFilePath  string
+	outputFilePath string
+	track          string // track selector
+}
+
+func init() {
+	flag.StringVar(&flags.inputFilePath, "i", "", fmt.Sprintf("Input mp4/fmp4 file url, '%s' if stdin", util.InputStdin))
+	flag.StringVar(&flags.outputFilePath, "o", "stdout", "Output ADTS aac file path.")
+	flag.StringVar(&flags.track, "track", "", "Track selector, e.g. 'id:2', 'index:1' or 'type:soun'. Empty means the first audio track.")
+}
+
+func validateFlags() error {
+	if len(flags.inputFilePath) == 0 {
+		return fmt.Errorf("input file is required")
+	}
+	if len(flags.outputFilePath) == 0 {
+		return fmt.Errorf("output should not be empty")
+	}
+
+	return nil
+}
@@ -12,6 +12,7 @@ var flags struct {
 	inputFilePath  string
 	outputFilePath string
 	content        string // content to output
+	track          string // track selector
 }
 
 var supportedContentTypes = []dump.ContentType{
@@ -50,6 +51,7 @@ func init() {
 	flag.StringVar(&flags.inputFilePath, "i", "", fmt.Sprintf("Input mp4/fmp4 file url, '%s' if stdin", util.InputStdin))
 	flag.StringVar(&flags.content, "content", dump.ContentTypeRawAnnexBES, fmt.Sprintf("Contents to parse and output, available values: %s", supportedConentTypesHelper()))
 	flag.StringVar(&flags.outputFilePath, "o", "stdout", "Output file path.")
+	flag.StringVar(&flags.track, "track", "", "Track selector, e.g. 'id:1', 'index:0' or 'type:vide'. Empty means the first video track.")
 }
 
 func validateFlags() error {
@@ -6,6 +6,7 @@ import (
 
 	"github.com/golang/glog"
 	"github.com/wangyoucao577/medialib/container/mp4"
+	"github.com/wangyoucao577/medialib/container/mp4/box"
 	"github.com/wangyoucao577/medialib/util/dump"
 )
 
@@ -13,6 +14,7 @@ func parseMP4(inputFile string, contentType dump.ContentType, output string) err
 
 	// parse
 	m := mp4.New(inputFile)
+	defer m.Close()
 	if err := m.Parse(); err != nil {
 		if err != io.EOF {
 			glog.Warningf("Parse mp4 failed but ignore to leverage the data has been parsed already, err %v", err)
@@ -20,6 +22,11 @@ func parseMP4(inputFile string, contentType dump.ContentType, output string) err
 		}
 	}
 
+	track, err := m.Boxes.SelectTrack(flags.track, box.TypeVide)
+	if err != nil {
+		return err
+	}
+
 	// output
 	w, closer, err := dump.CreateOutput(output)
 	if err != nil {
@@ -29,10 +36,11 @@ func parseMP4(inputFile string, contentType dump.ContentType, output string) err
 		defer closer.Close()
 	}
 
-	// parse avc es and print
+	// parse avc/hevc es by codec of the track and print
+	var es mp4.Dumper
 	switch contentType {
 	case dump.ContentTypeRawES:
-		es, err := m.Boxes.ExtractES(0)
+		es, err = m.Boxes.ExtractES(int(track.ID))
 		if err != nil {
 			return fmt.Errorf("extract es failed, err %v", err)
 		}
@@ -40,7 +48,7 @@ func parseMP4(inputFile string, contentType dump.ContentType, output string) err
 			return fmt.Errorf("dump es failed, err %v", err)
 		}
 	case dump.ContentTypeRawAnnexBES:
-		es, err := m.Boxes.ExtractAnnexBES(0)
+		es, err = m.Boxes.ExtractAnnexBES(int(track.ID))
 		if err != nil {
 			return fmt.Errorf("extract annexb_es failed, err %v", err)
 		}
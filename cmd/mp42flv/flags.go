@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/wangyoucao577/medialib/util"
+)
+
+var flags struct {
+	inputFilePath  string
+	outputFilePath string
+	track          string // track selector
+}
+
+func init() {
+	flag.StringVar(&flags.inputFilePath, "i", "", fmt.Sprintf("Input mp4 file url, '%s' if stdin", util.InputStdin))
+	flag.StringVar(&flags.outputFilePath, "o", "", "Output flv file path, 'stdout' if stdout.")
+	flag.StringVar(&flags.track, "track", "", "Track selector, e.g. 'id:1', 'index:0' or 'type:vide', comma separated for multiple. Empty means all tracks, the first AVC and AAC tracks will be remuxed.")
+}
+
+func validateFlags() error {
+	if len(flags.inputFilePath) == 0 {
+		return fmt.Errorf("input file is required")
+	}
+	if len(flags.outputFilePath) == 0 {
+		return fmt.Errorf("output file is required")
+	}
+	if flags.outputFilePath == flags.inputFilePath {
+		return fmt.Errorf("output file should be different from input file")
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/wangyoucao577/medialib/container/flv"
+	"github.com/wangyoucao577/medialib/container/mp4"
+	"github.com/wangyoucao577/medialib/util/dump"
+)
+
+func remuxMP4(inputFile string) error {
+
+	// parse
+	m := mp4.New(inputFile)
+	defer m.Close()
+	if err := m.Parse(); err != nil {
+		if err != io.EOF {
+			return err // the whole file is required for remuxing
+		}
+	}
+
+	tracks, err := m.Boxes.SelectTracks(flags.track)
+	if err != nil {
+		return err
+	}
+	m.Boxes.KeepTracks(tracks)
+
+	f, err := flv.FromMP4(&m.Boxes)
+	if err != nil {
+		return fmt.Errorf("remux to flv failed, err %v", err)
+	}
+
+	// output
+	w, closer, err := dump.CreateOutput(flags.outputFilePath)
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+	return f.Encode(w)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wangyoucao577/medialib/util"
+)
+
+var flags struct {
+	inputFilePath  string
+	outputFilePath string // single fragmented mp4 file
+
+	// separate initialization segment and media segments
+	initSegmentPath  string
+	mediaSegmentPath string
+
+	fragmentDuration time.Duration
+	sidx             bool
+
+	track string // track selector
+}
+
+func init() {
+	flag.StringVar(&flags.inputFilePath, "i", "", fmt.Sprintf("Input mp4 file url, '%s' if stdin", util.InputStdin))
+	flag.StringVar(&flags.outputFilePath, "o", "", "Output fragmented mp4 file path, 'stdout' if stdout.")
+	flag.StringVar(&flags.initSegmentPath, "init_segment", "", "Output initialization segment file path, e.g. 'init.mp4'. Write separate segments instead of a single file if set.")
+	flag.StringVar(&flags.mediaSegmentPath, "media_segment", "segment_%d.m4s", "Output media segment file path template, '%d' will be replaced by sequence number starts from 1.")
+	flag.DurationVar(&flags.fragmentDuration, "frag_duration", 0, "Expected fragment duration, fragments are always cut at keyframes so they might be longer. 0 means cut at every keyframe.")
+	flag.BoolVar(&flags.sidx, "sidx", false, "Generate a sidx for each fragment.")
+	flag.StringVar(&flags.track, "track", "", "Track selector, e.g. 'id:1', 'index:0' or 'type:vide', comma separated for multiple. Empty means all tracks.")
+}
+
+func validateFlags() error {
+	if len(flags.inputFilePath) == 0 {
+		return fmt.Errorf("input file is required")
+	}
+
+	if len(flags.outputFilePath) == 0 && len(flags.initSegmentPath) == 0 {
+		return fmt.Errorf("either output file or init segment is required")
+	}
+	if len(flags.outputFilePath) > 0 && len(flags.initSegmentPath) > 0 {
+		return fmt.Errorf("output file and init segment are exclusive")
+	}
+	if len(flags.initSegmentPath) > 0 && strings.Count(flags.mediaSegmentPath, "%d") != 1 {
+		return fmt.Errorf("media segment %s should contain one '%%d'", flags.mediaSegmentPath)
+	}
+
+	if flags.fragmentDuration < 0 {
+		return fmt.Errorf("invalid fragment duration %v", flags.fragmentDuration)
+	}
+
+	return nil
+}
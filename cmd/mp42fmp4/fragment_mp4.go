@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/container/mp4"
+	"github.com/wangyoucao577/medialib/util/dump"
+)
+
+func fragmentMP4(inputFile string) error {
+
+	// parse
+	m := mp4.New(inputFile)
+	defer m.Close()
+	if err := m.Parse(); err != nil {
+		if err != io.EOF {
+			glog.Warningf("Parse mp4 failed but ignore to leverage the data has been parsed already, err %v", err)
+		}
+	}
+
+	tracks, err := m.Boxes.SelectTracks(flags.track)
+	if err != nil {
+		return err
+	}
+	m.Boxes.KeepTracks(tracks)
+
+	opts := mp4.FragmentOptions{
+		FragmentDuration: flags.fragmentDuration,
+		Sidx:             flags.sidx,
+	}
+
+	// single fragmented mp4 file
+	if len(flags.outputFilePath) > 0 {
+		w, closer, err := dump.CreateOutput(flags.outputFilePath)
+		if err != nil {
+			return err
+		}
+		if closer != nil {
+			defer closer.Close()
+		}
+		return m.Boxes.WriteFragmented(w, opts)
+	}
+
+	// separate initialization segment and media segments
+	initSegment, err := m.Boxes.InitSegment()
+	if err != nil {
+		return err
+	}
+	if err := writeSegment(flags.initSegmentPath, initSegment); err != nil {
+		return err
+	}
+
+	opts.Styp = true
+	sequenceNumber := 1
+	return m.Boxes.Fragment(opts, func(segment *mp4.Boxes) error {
+		if err := writeSegment(fmt.Sprintf(flags.mediaSegmentPath, sequenceNumber), segment); err != nil {
+			return err
+		}
+		sequenceNumber++
+		return nil
+	})
+}
+
+func writeSegment(path string, segment *mp4.Boxes) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := segment.Encode(f); err != nil {
+		return fmt.Errorf("write %s failed, err %v", path, err)
+	}
+	glog.V(1).Infof("%s written", path)
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/container/mp4"
+)
+
+func demuxMP4(inputFile string) error {
+
+	// parse
+	m := mp4.New(inputFile)
+	defer m.Close()
+	if err := m.Parse(); err != nil {
+		if err != io.EOF {
+			glog.Warningf("Parse mp4 failed but ignore to leverage the data has been parsed already, err %v", err)
+		}
+	}
+
+	tracks, err := m.Boxes.SelectTracks(flags.track)
+	if err != nil {
+		return err
+	}
+
+	// create writers of supported tracks
+	var selected mp4.TrackList
+	writers := map[uint32]*mp4.TrackWriter{}
+	for _, t := range tracks {
+		if len(t.Extension()) == 0 {
+			glog.Warningf("trackID %d handler_type %s codec %s extraction doesn't support yet, ignore it", t.ID, t.HandlerType, t.Codec)
+			continue
+		}
+
+		path := fmt.Sprintf("%s_%d%s", flags.outputPrefix, t.ID, t.Extension())
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if writers[t.ID], err = m.Boxes.NewTrackWriter(t, f); err != nil {
+			return fmt.Errorf("extract trackID %d failed, err %v", t.ID, err)
+		}
+		selected = append(selected, t)
+		glog.V(1).Infof("trackID %d will be written to %s", t.ID, path)
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no track has been extracted")
+	}
+
+	// write samples of all tracks in one pass
+	return m.Boxes.WalkSamples(selected, func(t mp4.Track, s mp4.Sample, data []byte) error {
+		if err := writers[t.ID].WriteSample(data); err != nil {
+			return fmt.Errorf("write sample of trackID %d at offset %d failed, err %v", t.ID, s.Offset, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/wangyoucao577/medialib/util"
+)
+
+var flags struct {
+	inputFilePath string
+	outputPrefix  string
+	track         string // track selector
+}
+
+func init() {
+	flag.StringVar(&flags.inputFilePath, "i", "", fmt.Sprintf("Input mp4/fmp4 file url, '%s' if stdin", util.InputStdin))
+	flag.StringVar(&flags.outputPrefix, "o", "track", "Output files prefix, each track will be written to '<prefix>_<track_ID>.<ext>', e.g. 'track_1.h264'.")
+	flag.StringVar(&flags.track, "track", "", "Track selector, e.g. 'id:1', 'index:0' or 'type:vide', comma separated for multiple. Empty means all tracks.")
+}
+
+func validateFlags() error {
+	if len(flags.inputFilePath) == 0 {
+		return fmt.Errorf("input file is required")
+	}
+	if len(flags.outputPrefix) == 0 {
+		return fmt.Errorf("output prefix should not be empty")
+	}
+
+	return nil
+}
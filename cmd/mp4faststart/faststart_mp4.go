@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io"
+
+	"github.com/wangyoucao577/medialib/container/mp4"
+	"github.com/wangyoucao577/medialib/util/dump"
+)
+
+func fastStartMP4(inputFile string) error {
+
+	// parse
+	m := mp4.New(inputFile)
+	defer m.Close()
+	if err := m.Parse(); err != nil {
+		if err != io.EOF {
+			return err // the whole file is required for relocation
+		}
+	}
+
+	tracks, err := m.Boxes.SelectTracks(flags.track)
+	if err != nil {
+		return err
+	}
+	m.Boxes.KeepTracks(tracks)
+
+	if err := m.Boxes.FastStart(); err != nil {
+		return err
+	}
+
+	w, closer, err := dump.CreateOutput(flags.outputFilePath)
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+	return m.Boxes.Encode(w)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+var flags struct {
+	inputURL       string
+	outputFilePath string
+	timeout        time.Duration
+	duration       time.Duration
+}
+
+func init() {
+	flag.StringVar(&flags.inputURL, "i", "", "Input RTMP url to play, e.g., rtmp://127.0.0.1/live/stream")
+	flag.StringVar(&flags.outputFilePath, "o", "", "Output flv file path, stdout if empty.")
+	flag.DurationVar(&flags.timeout, "timeout", 10*time.Second, "Timeout for connecting and playing.")
+	flag.DurationVar(&flags.duration, "duration", 0, "Stop recording once timestamps of audio/video tags reach the duration, 0 means until the stream stops.")
+}
+
+func validateFlags() error {
+	if len(flags.inputURL) == 0 {
+		return fmt.Errorf("input url is required")
+	}
+	if flags.timeout <= 0 {
+		return fmt.Errorf("invalid timeout %v", flags.timeout)
+	}
+	if flags.duration < 0 {
+		return fmt.Errorf("invalid duration %v", flags.duration)
+	}
+
+	return nil
+}
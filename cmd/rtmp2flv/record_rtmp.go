@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/container/flv"
+	"github.com/wangyoucao577/medialib/protocol/rtmp"
+	"github.com/wangyoucao577/medialib/util/dump"
+)
+
+func recordRTMP(url string, output string, timeout time.Duration, duration time.Duration) error {
+	h, err := rtmp.NewHandler(url)
+	if err != nil {
+		return err
+	}
+	if err := h.Connect(timeout); err != nil {
+		return err
+	}
+	defer h.Close()
+	if err := h.Play(timeout); err != nil {
+		return err
+	}
+	h.SetPlayDuration(duration)
+
+	// output
+	w, closer, err := dump.CreateOutput(output)
+	if err != nil {
+		return err
+	}
+	if closer != nil {
+		defer closer.Close()
+	}
+
+	// write tags one by one in time, so that the output is always playable
+	fw := flv.NewWriter(w)
+	if err := fw.WriteHeader(flv.NewHeader(true, true)); err != nil {
+		return err
+	}
+	var tags int
+	for {
+		t, err := h.ReadTag()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("record %s failed after %d tags, err %v", url, tags, err)
+		}
+		if err := fw.WriteTag(t); err != nil {
+			return err
+		}
+		tags++
+	}
+
+	glog.Infof("recorded %d tags from %s", tags, url)
+	return nil
+}
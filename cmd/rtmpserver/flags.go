@@ -0,0 +1,22 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+var flags struct {
+	listenAddr string
+}
+
+func init() {
+	flag.StringVar(&flags.listenAddr, "listen", ":1935", "TCP address to listen on, e.g., ':1935' or '127.0.0.1:1935'.")
+}
+
+func validateFlags() error {
+	if len(flags.listenAddr) == 0 {
+		return fmt.Errorf("listen address is required")
+	}
+
+	return nil
+}
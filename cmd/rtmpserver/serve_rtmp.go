@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/protocol/rtmp"
+)
+
+// serveRTMP serves until SIGINT or SIGTERM received, then closes all connections.
+func serveRTMP(addr string) error {
+	s := rtmp.NewServer()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		glog.Infof("received signal %v, closing", sig)
+		s.Close()
+	}()
+
+	return s.ListenAndServe(addr)
+}
@@ -0,0 +1,202 @@
+package flv
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/container/flv/tag/audio"
+	"github.com/wangyoucao577/medialib/container/flv/tag/script"
+	"github.com/wangyoucao577/medialib/container/flv/tag/video"
+)
+
+func TestDecryptAES128CBC(t *testing.T) {
+	// NIST SP 800-38A F.2.2 CBC-AES128.Decrypt
+	hexBytes := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	key := hexBytes("2b7e151628aed2a6abf7158809cf4f3c")
+	iv := hexBytes("000102030405060708090a0b0c0d0e0f")
+	ciphertext := hexBytes("7649abac8119b246cee98e9b12e9197d5086cb9b507219ee95db113a917678b2" +
+		"73bed6b8e3c1743b7116e69e222295163ff1caa1681fac09120eca307586e1a7")
+	plaintext := hexBytes("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51" +
+		"30c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+
+	if got, err := tag.DecryptAES128CBC(key, iv, ciphertext); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypt expect %x but got %x, err %v", plaintext, got, err)
+	}
+	for _, c := range [][3][]byte{{key[:15], iv, ciphertext}, {key, iv[:15], ciphertext}, {key, iv, ciphertext[:15]}} {
+		if _, err := tag.DecryptAES128CBC(c[0], c[1], c[2]); err == nil {
+			t.Errorf("decrypt key %x iv %x data %d bytes expect error but got nil", c[0], c[1], len(c[2]))
+		}
+	}
+}
+
+func TestEncryptedTags(t *testing.T) {
+	flvFile := "../../assets/sintel_trailer-720p-firstgopfmp4.flv"
+
+	data, err := os.ReadFile(flvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := FLV{}
+	if err := f.Parse(bytes.NewReader(data)); err != io.EOF {
+		t.Fatalf("parse %s expect EOF but got %v", flvFile, err)
+	}
+
+	// encrypt all tags by "Encryption" filter, except every 3rd tag by "SE" filter without encryption
+	key := []byte("0123456789abcdef")
+	encrypted := FLV{Header: f.Header}
+	for i, ft := range f.Tags {
+		var buf bytes.Buffer
+		if err := ft.Encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		e := &tag.Encryption{EncryptionTagHeader: tag.EncryptionTagHeader{NumFilters: 1}}
+		iv := bytes.Repeat([]byte{byte(i)}, tag.IVSize)
+
+		var et tag.Tag
+		var mediaHeaderSize uint32 // AudioTagHeader or VideoTagHeader
+		switch tt := ft.(type) {
+		case *audio.Tag:
+			at := *tt
+			at.Header.Filter, at.Encryption, at.Body = 1, e, nil
+			et, mediaHeaderSize = &at, 2
+		case *video.Tag:
+			vt := *tt
+			vt.Header.Filter, vt.Encryption, vt.TagBody = 1, e, nil
+			et, mediaHeaderSize = &vt, 5
+		case *script.Tag:
+			st := *tt
+			st.Header.Filter, st.Encryption, st.TagBody = 1, e, nil
+			et = &st
+		}
+		body := buf.Bytes()[tag.HeaderSize+mediaHeaderSize:]
+
+		if i%3 == 2 {
+			e.EncryptionTagHeader.FilterName = tag.FilterNameSelectiveEncryption
+			e.EncryptionTagHeader.Length = 1
+			e.FilterParams.SelectiveEncryptionFilterParams = &tag.SelectiveEncryptionFilterParams{}
+			e.EncryptedBody = body
+		} else {
+			e.EncryptionTagHeader.FilterName = tag.FilterNameEncryption
+			e.EncryptionTagHeader.Length = tag.IVSize
+			e.FilterParams.EncryptionFilterParams = &tag.EncryptionFilterParams{IV: iv}
+			e.EncryptedBody = encryptAES128CBC(t, key, iv, body)
+		}
+		e.BodyOffset = mediaHeaderSize + 1 + 2 + uint32(len(e.EncryptionTagHeader.FilterName)) + 3 + e.EncryptionTagHeader.Length
+		e.BodySize = uint32(len(e.EncryptedBody))
+		encrypted.Tags = append(encrypted.Tags, et)
+	}
+	var buf bytes.Buffer
+	if err := encrypted.Encode(&buf); err != nil {
+		t.Fatalf("encode encrypted expect nil but got %v", err)
+	}
+	encryptedData := buf.Bytes()
+
+	// parse without key, bodies are kept encrypted
+	parsed := FLV{}
+	if err := parsed.Parse(bytes.NewReader(encryptedData)); err != io.EOF {
+		t.Fatalf("parse encrypted expect EOF but got %v", err)
+	}
+	if len(parsed.Tags) != len(f.Tags) {
+		t.Fatalf("parse encrypted expect %d tags but got %d", len(f.Tags), len(parsed.Tags))
+	}
+	for i, pt := range parsed.Tags {
+		if h := pt.GetTagHeader(); h.Filter != 1 {
+			t.Errorf("tag %d expect filter 1 but got %d", i, h.Filter)
+		}
+		if !reflect.DeepEqual(tagEncryption(pt), tagEncryption(encrypted.Tags[i])) {
+			t.Errorf("tag %d expect encryption %+v but got %+v", i, tagEncryption(encrypted.Tags[i]), tagEncryption(pt))
+		}
+		if tagBody(pt) != nil {
+			t.Errorf("tag %d expect nil body without decryption but got %v", i, tagBody(pt))
+		}
+	}
+	buf.Reset()
+	if err := parsed.Encode(&buf); err != nil || !bytes.Equal(buf.Bytes(), encryptedData) {
+		t.Errorf("encode parsed encrypted expect %d bytes same as before but got %d bytes, err %v", len(encryptedData), buf.Len(), err)
+	}
+	if _, err := parsed.ExtractAudio(); err == nil {
+		t.Errorf("extract audio from encrypted expect error but got nil")
+	}
+
+	// decrypt by key, bodies should be the same as original
+	fr := NewReader(bytes.NewReader(encryptedData))
+	fr.SetDecryptionKey(key)
+	for i := 0; ; i++ {
+		dt, err := fr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("read decrypted tag %d expect nil but got %v", i, err)
+		}
+		if expect, got := tagBody(f.Tags[i]), tagBody(dt); !reflect.DeepEqual(got, expect) {
+			t.Errorf("tag %d expect decrypted body %v but got %v", i, expect, got)
+		}
+	}
+
+	// wrong key
+	fr = NewReader(bytes.NewReader(encryptedData))
+	fr.SetDecryptionKey([]byte("fedcba9876543210"))
+	for {
+		if _, err := fr.Next(); err == io.EOF {
+			t.Errorf("decrypt by wrong key expect error but got EOF")
+			break
+		} else if err != nil {
+			break
+		}
+	}
+}
+
+// encryptAES128CBC encrypts data with PKCS#7 padding.
+func encryptAES128CBC(t *testing.T, key, iv, data []byte) []byte {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	padding := aes.BlockSize - len(data)%aes.BlockSize
+	out := append(append([]byte{}, data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, out)
+	return out
+}
+
+func tagEncryption(t tag.Tag) *tag.Encryption {
+	switch tt := t.(type) {
+	case *audio.Tag:
+		return tt.Encryption
+	case *video.Tag:
+		return tt.Encryption
+	case *script.Tag:
+		return tt.Encryption
+	}
+	return nil
+}
+
+func tagBody(t tag.Tag) interface{} {
+	switch tt := t.(type) {
+	case *audio.Tag:
+		if tt.Body != nil {
+			return tt.Body
+		}
+	case *video.Tag:
+		if tt.TagBody != nil {
+			return tt.TagBody
+		}
+	case *script.Tag:
+		if tt.TagBody != nil {
+			return tt.TagBody
+		}
+	}
+	return nil
+}
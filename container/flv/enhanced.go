@@ -0,0 +1,52 @@
+package flv
+
+import "github.com/wangyoucao577/medialib/container/flv/tag/video"
+
+// VideoFourCC returns FourCC of the first found video codec, legacy AVC video tags are regarded as avc1.
+// It returns empty if no video or the codec isn't recognized.
+func (f *FLV) VideoFourCC() string {
+	for _, t := range f.Tags {
+		if vt, ok := t.(*video.Tag); ok {
+			return tagFourCC(vt)
+		}
+	}
+	return ""
+}
+
+// tagFourCC returns FourCC of the video tag, the first track's one if many tracks many codecs.
+func tagFourCC(vt *video.Tag) string {
+	if !vt.VideoTagHeader.IsExHeader {
+		if vt.VideoTagHeader.CodecID == video.CodecIDAVC {
+			return video.FourCCAVC
+		}
+		return ""
+	}
+	if vt.VideoTagHeader.FourCC != "" {
+		return vt.VideoTagHeader.FourCC
+	}
+	if vt.TagBody != nil && len(vt.TagBody.ExVideoTracks) > 0 {
+		return vt.TagBody.ExVideoTracks[0].FourCC
+	}
+	return ""
+}
+
+// exVideoTrack returns the track matches fourCC and trackID in enhanced video tag, or nil if not found.
+// trackID < 0 means use the first found one, which will be stored back for later tags.
+func exVideoTrack(vt *video.Tag, fourCC string, trackID *int) *video.ExVideoTrack {
+	if vt.TagBody == nil {
+		return nil
+	}
+	for i := range vt.TagBody.ExVideoTracks {
+		track := &vt.TagBody.ExVideoTracks[i]
+		if track.FourCC != fourCC {
+			continue
+		}
+		if *trackID < 0 {
+			*trackID = int(track.TrackID)
+		}
+		if int(track.TrackID) == *trackID {
+			return track
+		}
+	}
+	return nil
+}
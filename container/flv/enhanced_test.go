@@ -0,0 +1,254 @@
+package flv
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/container/flv/tag/video"
+	hvcc "github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/hvcC"
+	"github.com/wangyoucao577/medialib/util/amf/amf0"
+	hevces "github.com/wangyoucao577/medialib/video/hevc/es"
+	"github.com/wangyoucao577/medialib/video/hevc/nalu"
+)
+
+func TestExVideoTagHeader(t *testing.T) {
+	nanoOffset := uint32(500000)
+	command := uint8(video.VideoCommandStartSeek)
+
+	seqEnd, err := video.NewExVideoTag(0, video.FrameTypeKey, video.PacketTypeSequenceEnd, video.ExVideoTrack{FourCC: video.FourCCAV1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seqEnd.VideoTagHeader.TimestampNanoOffset = &nanoOffset
+
+	oneTrack, err := video.NewExVideoTag(40, video.FrameTypeInner, video.PacketTypeCodedFrames, video.ExVideoTrack{FourCC: video.FourCCVP9, TrackID: 2, Data: []byte{0x01, 0x02, 0x03}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, value := amf0.NewString("colorInfo"), amf0.NewObject(amf0.NewObjectProperty("colorConfig", amf0.NewObject(amf0.NewObjectProperty("bitDepth", amf0.NewNumber(10)))))
+	metadata, err := video.NewExVideoTag(80, video.FrameTypeVideoInfoOrCommand, video.PacketTypeMetadata,
+		video.ExVideoTrack{FourCC: video.FourCCHEVC, MetadataName: &name, MetadataValue: &value})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seek := &video.Tag{
+		Header:         tag.NewHeader(tag.TypeVideo, 120),
+		VideoTagHeader: video.TagHeader{FrameType: video.FrameTypeVideoInfoOrCommand, IsExHeader: true, PacketType: new(uint8), VideoCommand: &command},
+	}
+
+	f := FLV{Header: NewHeader(false, true)}
+	f.Tags = []tag.Tag{seqEnd, oneTrack, metadata, seek}
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatalf("encode expect nil but got %v", err)
+	}
+
+	got := FLV{}
+	if err := got.Parse(bytes.NewReader(buf.Bytes())); err != io.EOF {
+		t.Fatalf("parse expect EOF but got %v", err)
+	}
+	if len(got.Tags) != len(f.Tags) {
+		t.Fatalf("expect %d tags but got %d", len(f.Tags), len(got.Tags))
+	}
+	tags := make([]*video.Tag, len(got.Tags))
+	for i := range got.Tags {
+		tags[i] = got.Tags[i].(*video.Tag)
+	}
+
+	if h := tags[0].VideoTagHeader; !h.IsExHeader || *h.PacketType != video.PacketTypeSequenceEnd || h.FourCC != video.FourCCAV1 ||
+		h.TimestampNanoOffset == nil || *h.TimestampNanoOffset != nanoOffset {
+		t.Errorf("expect av01 sequence end with nano offset %d but got %+v", nanoOffset, h)
+	}
+
+	if h := tags[1].VideoTagHeader; !h.IsMultitrack() || *h.MultitrackType != video.MultitrackTypeOneTrack || h.FourCC != video.FourCCVP9 {
+		t.Errorf("expect vp09 one track multitrack but got %+v", h)
+	}
+	if tracks := tags[1].TagBody.ExVideoTracks; len(tracks) != 1 || tracks[0].TrackID != 2 || !bytes.Equal(tracks[0].Data, []byte{0x01, 0x02, 0x03}) {
+		t.Errorf("expect vp09 track 2 with data but got %+v", tracks)
+	}
+
+	if tracks := tags[2].TagBody.ExVideoTracks; len(tracks) != 1 || tracks[0].MetadataName == nil || tracks[0].MetadataValue == nil {
+		t.Errorf("expect metadata track but got %+v", tracks)
+	} else if name, err := tracks[0].MetadataName.AsString(); err != nil || name != "colorInfo" {
+		t.Errorf("expect metadata colorInfo but got %s, err %v", name, err)
+	}
+
+	if h := tags[3].VideoTagHeader; h.VideoCommand == nil || *h.VideoCommand != command || tags[3].TagBody != nil {
+		t.Errorf("expect video command %d without body but got %+v", command, h)
+	}
+
+	var reencoded bytes.Buffer
+	if err := got.Encode(&reencoded); err != nil {
+		t.Fatalf("re-encode expect nil but got %v", err)
+	}
+	if !bytes.Equal(reencoded.Bytes(), buf.Bytes()) {
+		t.Errorf("re-encode expect %d bytes but got %d", buf.Len(), reencoded.Len())
+	}
+}
+
+func TestExtractHEVCES(t *testing.T) {
+	flvFile := "../../assets/sintel_trailer-720p-firstgopfmp4.flv"
+
+	h := New(flvFile)
+	if err := h.Parse(); err != io.EOF {
+		t.Fatalf("parse %s expect EOF but got %v", flvFile, err)
+	}
+	if fourCC := h.VideoFourCC(); fourCC != video.FourCCAVC {
+		t.Errorf("expect video FourCC %s but got %s", video.FourCCAVC, fourCC)
+	}
+	if _, err := h.ExtractHEVCES(-1); err == nil {
+		t.Errorf("extract hevc es from avc expect error but got nil")
+	}
+	expect, err := h.ExtractES()
+	if err != nil {
+		t.Fatalf("extract es from %s failed, err %v", flvFile, err)
+	}
+
+	// pretend the avc is hevc since both store length prefixed nal units in coded frames,
+	// and keep the avc as another track to verify multitrack
+	parameterSets := []hvcc.Array{
+		{NALUnitType: nalu.TypeVPS_NUT, NumNalus: 1, LengthNALUs: []hvcc.LengthNALU{{NALUnitLength: 3, NALUnit: []byte{0x40, 0x01, 0x0c}}}},
+		{NALUnitType: nalu.TypeSPS_NUT, NumNalus: 1, LengthNALUs: []hvcc.LengthNALU{{NALUnitLength: 3, NALUnit: []byte{0x42, 0x01, 0x01}}}},
+		{NALUnitType: nalu.TypePPS_NUT, NumNalus: 1, LengthNALUs: []hvcc.LengthNALU{{NALUnitLength: 3, NALUnit: []byte{0x44, 0x01, 0xc1}}}},
+	}
+	f := FLV{Header: h.FLV.Header}
+	var frames int
+	for _, ft := range h.Tags {
+		vt, ok := ft.(*video.Tag)
+		if !ok {
+			f.Tags = append(f.Tags, ft)
+			continue
+		}
+
+		var et *video.Tag
+		var err error
+		timestamp := uint32(vt.Header.TimestampCalculated)
+		switch *vt.VideoTagHeader.AVCPacketType {
+		case video.AVCPacketTypeSequenceHeader:
+			avcConfig := vt.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord
+			hevcConfig := &hvcc.HEVCDecoderConfigurationRecord{
+				LengthSizeMinusOne: uint8(avcConfig.LengthSize() - 1),
+				NumOfArrays:        uint8(len(parameterSets)),
+				Arrays:             parameterSets,
+			}
+			et, err = video.NewExVideoTag(timestamp, video.FrameTypeKey, video.PacketTypeSequenceStart,
+				video.ExVideoTrack{FourCC: video.FourCCHEVC, TrackID: 0, HEVCDecoderConfigurationRecord: hevcConfig},
+				video.ExVideoTrack{FourCC: video.FourCCAVC, TrackID: 1, AVCDecoderConfigurationRecord: avcConfig})
+		case video.AVCPacketTypeNALU:
+			hevcTrack := video.ExVideoTrack{FourCC: video.FourCCHEVC, TrackID: 0, CompositionTime: vt.VideoTagHeader.CompositionTime}
+			for _, ln := range vt.TagBody.AVCVideoPacket.LengthNALU {
+				raw := ln.NALU.Raw()
+				n := nalu.NALUnit{}
+				if _, err := n.Parse(bytes.NewReader(raw), len(raw)); err != nil {
+					t.Fatalf("parse avc nalu as hevc failed, err %v", err)
+				}
+				hevcTrack.HEVCLengthNALU = append(hevcTrack.HEVCLengthNALU, hevces.LengthNALU{Length: uint32(len(raw)), NALU: n})
+			}
+			avcTrack := video.ExVideoTrack{FourCC: video.FourCCAVC, TrackID: 1, CompositionTime: vt.VideoTagHeader.CompositionTime,
+				LengthNALU: vt.TagBody.AVCVideoPacket.LengthNALU}
+
+			packetType := uint8(video.PacketTypeCodedFrames)
+			if *vt.VideoTagHeader.CompositionTime == 0 {
+				packetType = video.PacketTypeCodedFramesX
+			}
+			et, err = video.NewExVideoTag(timestamp, vt.VideoTagHeader.FrameType, packetType, hevcTrack, avcTrack)
+			frames++
+		default:
+			et, err = video.NewExVideoTag(timestamp, video.FrameTypeKey, video.PacketTypeSequenceEnd, video.ExVideoTrack{FourCC: video.FourCCHEVC})
+		}
+		if err != nil {
+			t.Fatalf("create enhanced video tag expect nil but got %v", err)
+		}
+		f.Tags = append(f.Tags, et)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatalf("encode expect nil but got %v", err)
+	}
+	got := FLV{}
+	if err := got.Parse(bytes.NewReader(buf.Bytes())); err != io.EOF {
+		t.Fatalf("parse enhanced flv expect EOF but got %v", err)
+	}
+	var reencoded bytes.Buffer
+	if err := got.Encode(&reencoded); err != nil || !bytes.Equal(reencoded.Bytes(), buf.Bytes()) {
+		t.Errorf("re-encode enhanced flv expect %d bytes but got %d, err %v", buf.Len(), reencoded.Len(), err)
+	}
+
+	if fourCC := got.VideoFourCC(); fourCC != video.FourCCHEVC {
+		t.Errorf("expect video FourCC %s but got %s", video.FourCCHEVC, fourCC)
+	}
+	var gotFrames int
+	for _, ft := range got.Tags {
+		if vt, ok := ft.(*video.Tag); ok && vt.VideoTagHeader.IsMultitrack() && *vt.VideoTagHeader.PacketType != video.PacketTypeSequenceStart {
+			if *vt.VideoTagHeader.MultitrackType != video.MultitrackTypeManyTracksManyCodecs || len(vt.TagBody.ExVideoTracks) != 2 {
+				t.Fatalf("expect many tracks many codecs with 2 tracks but got %+v", vt.VideoTagHeader)
+			}
+			gotFrames++
+		}
+	}
+	if gotFrames != frames {
+		t.Errorf("expect %d multitrack frames but got %d", frames, gotFrames)
+	}
+
+	// avc track is still available
+	gotAVC, err := got.ExtractES()
+	if err != nil {
+		t.Fatalf("extract avc es from enhanced flv expect nil but got %v", err)
+	}
+	if len(gotAVC.LengthNALU) != len(expect.LengthNALU) {
+		t.Fatalf("extract avc es expect %d nalus but got %d", len(expect.LengthNALU), len(gotAVC.LengthNALU))
+	}
+
+	if _, err := got.ExtractHEVCES(1); err == nil {
+		t.Errorf("extract hevc es from avc track expect error but got nil")
+	}
+	gotHEVC, err := got.ExtractHEVCES(-1)
+	if err != nil {
+		t.Fatalf("extract hevc es expect nil but got %v", err)
+	}
+	expectNALUs := expect.LengthNALU[len(expect.LengthNALU)-len(gotHEVC.LengthNALU):] // exclude sps,pps from avc config
+	if len(gotHEVC.LengthNALU)+2 != len(expect.LengthNALU) {
+		t.Fatalf("extract hevc es expect %d nalus but got %d", len(expect.LengthNALU)-2, len(gotHEVC.LengthNALU))
+	}
+	for i := range gotHEVC.LengthNALU {
+		if !bytes.Equal(gotHEVC.LengthNALU[i].NALU.Raw(), expectNALUs[i].NALU.Raw()) {
+			t.Fatalf("extract hevc es nalu %d mismatch", i)
+		}
+	}
+
+	annexb, err := got.ExtractHEVCAnnexBES(0)
+	if err != nil {
+		t.Fatalf("extract hevc annexb es expect nil but got %v", err)
+	}
+	if len(annexb.NALU) != len(parameterSets)+len(gotHEVC.LengthNALU) || annexb.NALU[0].NALUnitType != nalu.TypeVPS_NUT {
+		t.Errorf("extract hevc annexb es expect %d nalus begin with vps but got %d", len(parameterSets)+len(gotHEVC.LengthNALU), len(annexb.NALU))
+	}
+
+	// streaming extraction should be the same
+	flvFile = filepath.Join(t.TempDir(), "hevc.flv")
+	if err := os.WriteFile(flvFile, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		annexB bool
+		d      dumper
+	}{{false, gotHEVC}, {true, annexb}} {
+		var expect, got bytes.Buffer
+		if _, err := c.d.Dump(&expect); err != nil {
+			t.Fatal(err)
+		}
+		if err := New(flvFile).WriteES(&got, c.annexB); err != nil {
+			t.Fatalf("write hevc es(annexb %v) expect nil but got %v", c.annexB, err)
+		}
+		if !bytes.Equal(got.Bytes(), expect.Bytes()) {
+			t.Errorf("write hevc es(annexb %v) expect %d bytes but got %d", c.annexB, expect.Len(), got.Len())
+		}
+	}
+}
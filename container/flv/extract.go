@@ -0,0 +1,195 @@
+package flv
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/audio/aac"
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/container/flv/tag/audio"
+	"github.com/wangyoucao577/medialib/container/flv/tag/video"
+	avcc "github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/avcC"
+	hvcc "github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/hvcC"
+	"github.com/wangyoucao577/medialib/video/avc/es"
+	hevces "github.com/wangyoucao577/medialib/video/hevc/es"
+)
+
+// skipEncrypted checks whether the tag with Filter=1 should be skipped with a warning,
+// since payload of encrypted tags are not able to be extracted or remuxed as it is.
+func skipEncrypted(t tag.Tag) bool {
+	h := t.GetTagHeader()
+	if h.Filter != 1 {
+		return false
+	}
+	glog.Warningf("tag type %d(%s) timestamp %d with filter=1 is skipped", h.TagType, tag.TypeDescription(int(h.TagType)), h.TimestampCalculated)
+	return true
+}
+
+// esExtractor extracts video Elementary Stream tag by tag, extracted NAL units are appended to avcES or hevcES.
+// It's shared by extracting from in memory tags and streaming.
+type esExtractor struct {
+	fourCC  string // avc1 or hvc1, decided by the first video tag if empty
+	trackID int    // track of enhanced video tags, < 0 means use the first found one
+
+	avcES      es.ElementaryStream
+	hevcES     hevces.ElementaryStream
+	hevcConfig *hvcc.HEVCDecoderConfigurationRecord // the first one
+}
+
+// extract appends NAL units of the tag, non-video tags are ignored.
+func (x *esExtractor) extract(t tag.Tag) error {
+	if t.GetTagHeader().TagType != tag.TypeVideo || skipEncrypted(t) {
+		return nil
+	}
+	vt, ok := t.(*video.Tag)
+	if !ok {
+		return fmt.Errorf("tag %#v should be video tag but cannot convert", t)
+	}
+
+	if x.fourCC == "" {
+		if x.fourCC = tagFourCC(vt); x.fourCC != video.FourCCAVC && x.fourCC != video.FourCCHEVC {
+			return fmt.Errorf("video codec %q doesn't support yet", x.fourCC)
+		}
+	}
+
+	if vt.VideoTagHeader.IsExHeader {
+		if x.fourCC == video.FourCCHEVC {
+			return x.extractExHEVC(vt)
+		}
+		return x.extractExAVC(vt)
+	}
+	if x.fourCC != video.FourCCAVC {
+		return nil
+	}
+
+	if vt.VideoTagHeader.AVCPacketType == nil {
+		return fmt.Errorf("tag %#v empty AVCPacketType", t)
+	}
+	if *vt.VideoTagHeader.AVCPacketType == video.AVCPacketTypeEOS {
+		return nil // nothing to extract
+	}
+	if vt.TagBody == nil || vt.TagBody.AVCVideoPacket == nil {
+		return fmt.Errorf("tag %#v empty TagBody", t)
+	}
+	if *vt.VideoTagHeader.AVCPacketType == video.AVCPacketTypeSequenceHeader {
+		if err := appendAVCConfig(&x.avcES, vt.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord); err != nil {
+			return fmt.Errorf("tag %#v %v", t, err)
+		}
+	} else if *vt.VideoTagHeader.AVCPacketType == video.AVCPacketTypeNALU {
+		if len(vt.TagBody.AVCVideoPacket.LengthNALU) == 0 {
+			return fmt.Errorf("tag %#v expect nal units but empty", t)
+		}
+		x.avcES.LengthNALU = append(x.avcES.LengthNALU, vt.TagBody.AVCVideoPacket.LengthNALU...)
+	}
+	return nil
+}
+
+// extractExAVC appends avc1 NAL units of enhanced video tag.
+func (x *esExtractor) extractExAVC(vt *video.Tag) error {
+	track := exVideoTrack(vt, video.FourCCAVC, &x.trackID)
+	if track == nil {
+		return nil
+	}
+
+	switch *vt.VideoTagHeader.PacketType {
+	case video.PacketTypeSequenceStart:
+		if err := appendAVCConfig(&x.avcES, track.AVCDecoderConfigurationRecord); err != nil {
+			return fmt.Errorf("tag type %d(%s) timestamp %d track %d %v", vt.Header.TagType, tag.TypeDescription(int(vt.Header.TagType)),
+				vt.Header.TimestampCalculated, track.TrackID, err)
+		}
+	case video.PacketTypeCodedFrames, video.PacketTypeCodedFramesX:
+		x.avcES.LengthNALU = append(x.avcES.LengthNALU, track.LengthNALU...)
+	}
+	return nil
+}
+
+// extractExHEVC appends hvc1 NAL units of enhanced video tag, parameter sets in hvcC are not included.
+func (x *esExtractor) extractExHEVC(vt *video.Tag) error {
+	track := exVideoTrack(vt, video.FourCCHEVC, &x.trackID)
+	if track == nil {
+		return nil
+	}
+
+	switch *vt.VideoTagHeader.PacketType {
+	case video.PacketTypeSequenceStart:
+		if track.HEVCDecoderConfigurationRecord == nil {
+			return fmt.Errorf("tag type %d(%s) timestamp %d track %d expect hevc config but empty",
+				vt.Header.TagType, tag.TypeDescription(int(vt.Header.TagType)), vt.Header.TimestampCalculated, track.TrackID)
+		}
+		if x.hevcConfig == nil {
+			x.hevcConfig = track.HEVCDecoderConfigurationRecord
+			x.hevcES.SetLengthSize(x.hevcConfig.LengthSize())
+		}
+	case video.PacketTypeCodedFrames, video.PacketTypeCodedFramesX:
+		if x.hevcConfig == nil {
+			return fmt.Errorf("hevc coded frames of track %d before sequence start", track.TrackID)
+		}
+		x.hevcES.LengthNALU = append(x.hevcES.LengthNALU, track.HEVCLengthNALU...)
+	}
+	return nil
+}
+
+// appendAVCConfig sets length size and sequence headers by avc config,
+// also appends sps,pps nalu since they're real NALU in flv tag.
+func appendAVCConfig(e *es.ElementaryStream, config *avcc.AVCDecoderConfigurationRecord) error {
+	if config == nil || len(config.LengthSPSNALU) == 0 || len(config.LengthPPSNALU) == 0 {
+		return fmt.Errorf("expect avc config but empty")
+	}
+	e.SetLengthSize(config.LengthSize())
+	e.SetSequenceHeaders(config.LengthSPSNALU[0].NALUnit.SequenceParameterSetData,
+		config.LengthPPSNALU[0].NALUnit.PictureParameterSet)
+
+	for _, n := range config.LengthSPSNALU {
+		e.LengthNALU = append(e.LengthNALU, es.LengthNALU{Length: uint32(n.Length), NALU: n.NALUnit})
+	}
+	for _, n := range config.LengthPPSNALU {
+		e.LengthNALU = append(e.LengthNALU, es.LengthNALU{Length: uint32(n.Length), NALU: n.NALUnit})
+	}
+	return nil
+}
+
+// audioExtractor extracts AAC raw frames tag by tag, extracted frames are appended to adts.
+// It's shared by extracting from in memory tags and streaming.
+type audioExtractor struct {
+	config []byte // AudioSpecificConfig
+	adts   aac.ADTS
+}
+
+// extract appends AAC raw frame of the tag, non-audio tags are ignored.
+func (x *audioExtractor) extract(t tag.Tag) error {
+	if t.GetTagHeader().TagType != tag.TypeAudio || skipEncrypted(t) {
+		return nil
+	}
+
+	at, ok := t.(*audio.Tag)
+	if !ok {
+		return fmt.Errorf("tag %#v should be audio tag but cannot convert", t)
+	}
+
+	if at.AudioTagHeader.SoundFormat != audio.SoundFormatAAC || at.AudioTagHeader.AACPacketType == nil {
+		return fmt.Errorf("sound format %d(%s) doesn't support yet", at.AudioTagHeader.SoundFormat, audio.SoundFormatDescription(int(at.AudioTagHeader.SoundFormat)))
+	}
+	if at.Body == nil || at.Body.AACAudioData == nil {
+		return fmt.Errorf("tag %#v empty AACAudioData", t)
+	}
+
+	if *at.AudioTagHeader.AACPacketType == audio.AACPacketTypeSequenceHeader {
+		if x.config != nil { // repeated sequence header
+			if !bytes.Equal(x.config, at.Body.AACAudioData.AudioSpecificConfig) {
+				return fmt.Errorf("AudioSpecificConfig changes doesn't support yet")
+			}
+			return nil
+		}
+		x.config = at.Body.AACAudioData.AudioSpecificConfig
+		if _, err := x.adts.AudioSpecificConfig.Parse(bytes.NewReader(x.config), len(x.config)); err != nil {
+			return fmt.Errorf("parse AudioSpecificConfig failed, err %v", err)
+		}
+	} else if *at.AudioTagHeader.AACPacketType == audio.AACPacketTypeRaw {
+		if x.config == nil {
+			return fmt.Errorf("AAC raw data before sequence header")
+		}
+		x.adts.RawFrames = append(x.adts.RawFrames, at.Body.AACAudioData.RawAACFrameData)
+	}
+	return nil
+}
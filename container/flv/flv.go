@@ -2,19 +2,14 @@
 package flv
 
 import (
-	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
 
 	"github.com/ghodss/yaml"
-	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/audio/aac"
 	"github.com/wangyoucao577/medialib/container/flv/tag"
-	"github.com/wangyoucao577/medialib/container/flv/tag/audio"
-	"github.com/wangyoucao577/medialib/container/flv/tag/script"
 	"github.com/wangyoucao577/medialib/container/flv/tag/video"
-	avcc "github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/avcC"
-	"github.com/wangyoucao577/medialib/util"
 	"github.com/wangyoucao577/medialib/video/avc/annexbes"
 	"github.com/wangyoucao577/medialib/video/avc/es"
 )
@@ -29,73 +24,40 @@ type FLV struct {
 	Tags            []tag.Tag `json:"Tags"`
 }
 
-// Parse parses FLV data.
+// Parse parses FLV data, all tags will be kept in memory.
+// Use Reader instead to process tags one by one for large input.
 func (f *FLV) Parse(r io.Reader) error {
-	if err := f.Header.Parse(r); err != nil {
-		return err
+	fr := NewReader(r)
+	fr.onPreviousTagSize = func(size uint32) {
+		f.PreviousTagSize = append(f.PreviousTagSize, size)
 	}
 
-	var avcConfig *avcc.AVCDecoderConfigurationRecord
-	tagSizeData := make([]byte, 4) // fixed 4 bytes
-	var lastParsedTagSize int64
-
+	var err error
+	if f.Header, err = fr.ReadHeader(); err != nil {
+		return err
+	}
 	for {
-		// parse previous tag size
-		if err := util.ReadOrError(r, tagSizeData); err != nil {
-			return err
-		}
-		size := binary.BigEndian.Uint32(tagSizeData)
-		f.PreviousTagSize = append(f.PreviousTagSize, size)
-		if size != uint32(lastParsedTagSize) {
-			glog.Warningf("PreviousTagSize %d != LastParsedTagSize %d", size, lastParsedTagSize)
-		}
-
-		// parse tag header
-		tagHeader := tag.Header{}
-		if err := tagHeader.Parse(r); err != nil {
+		t, err := fr.Next()
+		if err != nil {
 			return err
 		}
+		f.Tags = append(f.Tags, t)
+	}
+}
 
-		// parse tag data
-		var t tag.Tag
-		if tagHeader.TagType == tag.TypeAudio {
-			t = &audio.Tag{Header: tagHeader}
-		} else if tagHeader.TagType == tag.TypeVideo {
-			videoTag := &video.Tag{Header: tagHeader}
-			videoTag.SetAVCConfig(avcConfig)
-			t = videoTag
-		} else if tagHeader.TagType == tag.TypeSriptData {
-			t = &script.Tag{Header: tagHeader}
-		}
-		if tagHeader.Filter == 1 {
-
-			//TODO: parse payload
-			glog.Warningf("unsupported tag type %d with filter=1, ignore payload size %d", tagHeader.TagType, tagHeader.DataSize)
-			if err := util.ReadOrError(r, make([]byte, tagHeader.DataSize)); err != nil {
-				return err
-			}
-			continue
-		}
+// Encode writes FLV Header and all tags, the PreviousTagSize will be calculated by encoded tags.
+func (f *FLV) Encode(w io.Writer) error {
+	fw := NewWriter(w)
+	if err := fw.WriteHeader(f.Header); err != nil {
+		return err
+	}
 
-		if err := t.ParsePayload(r); err != nil {
+	for _, t := range f.Tags {
+		if err := fw.WriteTag(t); err != nil {
 			return err
 		}
-		f.Tags = append(f.Tags, t)
-
-		lastParsedTagSize = t.Size()                   // cache parsed tag size for checking
-		if t.GetTagHeader().TagType == tag.TypeVideo { // cache avcConfig for later slice parsing
-			videoTag, ok := t.(*video.Tag)
-			if !ok {
-				return fmt.Errorf("invalid video tag %v", videoTag)
-			}
-			if videoTag.VideoTagHeader.AVCPacketType != nil &&
-				*videoTag.VideoTagHeader.AVCPacketType == video.AVCPacketTypeSequenceHeader &&
-				videoTag.TagBody.AVCVideoPacket != nil &&
-				videoTag.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord != nil {
-				avcConfig = videoTag.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord
-			}
-		}
 	}
+	return nil
 }
 
 // JSON marshals FLV to JSON representation
@@ -122,61 +84,25 @@ func (f FLV) CSV() ([]byte, error) {
 	return nil, fmt.Errorf("csv representation does not support yet")
 }
 
-// ExtractES extracts AVC or HEVC Elementary Stream.
+// ExtractES extracts AVC Elementary Stream, from either legacy or enhanced(avc1) video tags.
+// Use ExtractHEVCES for HEVC.
 func (f *FLV) ExtractES() (*es.ElementaryStream, error) {
 
 	if len(f.Tags) == 0 {
 		return nil, fmt.Errorf("tags not found")
 	}
 
-	e := es.ElementaryStream{}
+	x := esExtractor{fourCC: video.FourCCAVC, trackID: -1}
 	for _, t := range f.Tags {
-		if t.GetTagHeader().TagType != tag.TypeVideo {
-			continue
-		}
-
-		vt, ok := t.(*video.Tag)
-		if !ok {
-			return nil, fmt.Errorf("tag %#v should be video tag but cannot convert", t)
-		}
-
-		if vt.VideoTagHeader.AVCPacketType == nil || vt.TagBody == nil {
-			return nil, fmt.Errorf("tag %#v empty AVCPacketType or TagBody", t)
+		if err := x.extract(t); err != nil {
+			return nil, err
 		}
-		if *vt.VideoTagHeader.AVCPacketType == video.AVCPacketTypeSequenceHeader {
-			if vt.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord == nil ||
-				len(vt.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord.LengthSPSNALU) == 0 ||
-				len(vt.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord.LengthPPSNALU) == 0 {
-				return nil, fmt.Errorf("tag %#v expect avc config but empty", t)
-			}
-			e.SetLengthSize(vt.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord.LengthSize())
-			e.SetSequenceHeaders(vt.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord.LengthSPSNALU[0].NALUnit.SequenceParameterSetData,
-				vt.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord.LengthPPSNALU[0].NALUnit.PictureParameterSet)
-
-			// also add sps,pps nalu since they're real NALU in flv tag
-			var spsLengthNALU, ppsLengthNALU []es.LengthNALU
-			for _, n := range vt.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord.LengthSPSNALU {
-				spsLengthNALU = append(spsLengthNALU, es.LengthNALU{Length: uint32(n.Length), NALU: n.NALUnit})
-			}
-			for _, n := range vt.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord.LengthPPSNALU {
-				ppsLengthNALU = append(ppsLengthNALU, es.LengthNALU{Length: uint32(n.Length), NALU: n.NALUnit})
-			}
-			e.LengthNALU = append(e.LengthNALU, spsLengthNALU...)
-			e.LengthNALU = append(e.LengthNALU, ppsLengthNALU...)
-
-		} else if *vt.VideoTagHeader.AVCPacketType == video.AVCPacketTypeNALU {
-			if len(vt.TagBody.AVCVideoPacket.LengthNALU) == 0 {
-				return nil, fmt.Errorf("tag %#v expect nal units but empty", t)
-			}
-			e.LengthNALU = append(e.LengthNALU, vt.TagBody.AVCVideoPacket.LengthNALU...)
-		} // video.AVCPacketTypeEOS doesn't need to handle
-
 	}
 
-	return &e, nil
+	return &x.avcES, nil
 }
 
-// ExtractAnnexBES extracts AVC or HEVC Elementary Stream with AnnexB byte format.
+// ExtractAnnexBES extracts AVC Elementary Stream with AnnexB byte format.
 func (f FLV) ExtractAnnexBES() (*annexbes.ElementaryStream, error) {
 	mp4ES, err := f.ExtractES()
 	if err != nil {
@@ -190,3 +116,23 @@ func (f FLV) ExtractAnnexBES() (*annexbes.ElementaryStream, error) {
 
 	return &annexbES, nil
 }
+
+// ExtractAudio extracts AAC raw frames, which can be dumped as ADTS stream.
+func (f *FLV) ExtractAudio() (*aac.ADTS, error) {
+
+	if len(f.Tags) == 0 {
+		return nil, fmt.Errorf("tags not found")
+	}
+
+	x := audioExtractor{}
+	for _, t := range f.Tags {
+		if err := x.extract(t); err != nil {
+			return nil, err
+		}
+	}
+
+	if x.config == nil {
+		return nil, fmt.Errorf("AAC sequence header not found")
+	}
+	return &x.adts, nil
+}
@@ -1,10 +1,22 @@
 package flv
 
 import (
+	"fmt"
+	"io"
 	"os"
 
 	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/container/flv/tag/video"
+	"github.com/wangyoucao577/medialib/container/mp4"
 	"github.com/wangyoucao577/medialib/util"
+	"github.com/wangyoucao577/medialib/util/dump"
+	avcannexbes "github.com/wangyoucao577/medialib/video/avc/annexbes"
+	"github.com/wangyoucao577/medialib/video/avc/es"
+	avcnalu "github.com/wangyoucao577/medialib/video/avc/nalu"
+	"github.com/wangyoucao577/medialib/video/hevc/annexbes"
+	hevces "github.com/wangyoucao577/medialib/video/hevc/es"
+	"github.com/wangyoucao577/medialib/video/hevc/nalu"
 )
 
 // Handler represents handler for `flv` structure.
@@ -29,11 +41,170 @@ func (h *Handler) Parse() error {
 		glog.Warningf("open %s failed, err %v", h.filePath, err)
 		return err
 	}
-	defer h.close()
+	defer h.Close()
 
 	return h.FLV.Parse(h.f)
 }
 
+// Walk reads FLV file tag by tag and calls fn for each of them, so that it runs in constant memory for large file.
+// FLV Header will be filled but tags will not be kept. It returns nil once all tags have been walked,
+// or the first error returned by fn.
+func (h *Handler) Walk(fn func(t tag.Tag) error) error {
+	if err := h.open(); err != nil {
+		glog.Warningf("open %s failed, err %v", h.filePath, err)
+		return err
+	}
+	defer h.Close()
+
+	return h.walk(NewReader(h.f), fn)
+}
+
+// WalkData is same as Walk, but raw data of each tag(DataSize bytes after tag header) will be passed to fn too.
+func (h *Handler) WalkData(fn func(t tag.Tag, data []byte) error) error {
+	if err := h.open(); err != nil {
+		glog.Warningf("open %s failed, err %v", h.filePath, err)
+		return err
+	}
+	defer h.Close()
+
+	fr := NewReader(h.f)
+	return h.walk(fr, func(t tag.Tag) error { return fn(t, fr.TagData()) })
+}
+
+func (h *Handler) walk(fr *Reader, fn func(t tag.Tag) error) error {
+	var err error
+	if h.FLV.Header, err = fr.ReadHeader(); err != nil {
+		return err
+	}
+
+	for {
+		t, err := fr.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+}
+
+// WriteES extracts video Elementary Stream tag by tag and writes it to w in time, AVC or HEVC is decided by the first video tag.
+// Parameter sets in hvcC come first for HEVC AnnexB byte format, same as ExtractHEVCAnnexBES.
+func (h *Handler) WriteES(w io.Writer, annexB bool) error {
+	x := esExtractor{trackID: -1}
+	var parameterSetsWritten bool
+	return h.Walk(func(t tag.Tag) error {
+		if err := x.extract(t); err != nil {
+			return err
+		}
+
+		var d dumper
+		switch {
+		case len(x.avcES.LengthNALU) > 0 && !annexB:
+			d = &x.avcES
+		case len(x.avcES.LengthNALU) > 0:
+			d = &avcannexbes.ElementaryStream{NALU: nalus(x.avcES.LengthNALU)}
+		case x.hevcConfig != nil && annexB && !parameterSetsWritten:
+			parameterSets, err := hevcParameterSets(x.hevcConfig)
+			if err != nil {
+				return err
+			}
+			parameterSetsWritten = true
+			d = &annexbes.ElementaryStream{NALU: append(parameterSets, hevcNALUs(x.hevcES.LengthNALU)...)}
+		case len(x.hevcES.LengthNALU) > 0 && !annexB:
+			d = &x.hevcES
+		case len(x.hevcES.LengthNALU) > 0:
+			d = &annexbes.ElementaryStream{NALU: hevcNALUs(x.hevcES.LengthNALU)}
+		default:
+			return nil
+		}
+
+		if _, err := d.Dump(w); err != nil {
+			return err
+		}
+		x.avcES.LengthNALU, x.hevcES.LengthNALU = nil, nil // written already
+		return nil
+	})
+}
+
+// WalkES extracts video Elementary Stream tag by tag without keeping tags, AVC or HEVC is decided by the first video tag.
+// Parameter sets in hvcC are not included for HEVC, same as ExtractHEVCES.
+func (h *Handler) WalkES() (dump.Marshaler, error) {
+	x := esExtractor{trackID: -1}
+	if err := h.Walk(x.extract); err != nil {
+		return nil, err
+	}
+
+	switch x.fourCC {
+	case video.FourCCAVC:
+		return &x.avcES, nil
+	case video.FourCCHEVC:
+		if x.hevcConfig == nil {
+			return nil, fmt.Errorf("hevc track not found")
+		}
+		return &x.hevcES, nil
+	}
+	return nil, fmt.Errorf("video tag not found")
+}
+
+// WriteAudio extracts AAC raw frames tag by tag and writes them to w as ADTS stream in time.
+func (h *Handler) WriteAudio(w io.Writer) error {
+	x := audioExtractor{}
+	if err := h.Walk(func(t tag.Tag) error {
+		if err := x.extract(t); err != nil {
+			return err
+		}
+		if len(x.adts.RawFrames) == 0 {
+			return nil
+		}
+
+		if _, err := x.adts.Dump(w); err != nil {
+			return err
+		}
+		x.adts.RawFrames = nil // written already
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if x.config == nil {
+		return fmt.Errorf("AAC sequence header not found")
+	}
+	return nil
+}
+
+// RemuxMP4 remuxes FLV file to progressive mp4 boxes tag by tag, see also FLV.ToMP4.
+// Only sample tables are kept in memory, samples in mdat are read from the FLV file when encoding,
+// so the file keeps opened until Close. Samples are kept in memory if input is stdin.
+func (h *Handler) RemuxMP4() (*mp4.Boxes, error) {
+	if err := h.open(); err != nil {
+		glog.Warningf("open %s failed, err %v", h.filePath, err)
+		return nil, err
+	}
+
+	m := mp4Remuxer{}
+	if h.f != os.Stdin {
+		m.source = h.f
+	}
+	fr := NewReader(h.f)
+	if err := h.walk(fr, func(t tag.Tag) error {
+		return m.addTag(t, fr.TagOffset())
+	}); err != nil {
+		h.Close()
+		return nil, err
+	}
+
+	b, err := m.mux()
+	if err != nil {
+		h.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
 // Open opens FLV file.
 func (h *Handler) open() error {
 
@@ -52,10 +223,35 @@ func (h *Handler) open() error {
 }
 
 // Close closes the FLV file handler.
-func (h *Handler) close() error {
+func (h *Handler) Close() error {
 	if h == nil || h.f == nil || h.f == os.Stdin {
 		return nil
 	}
 
-	return h.f.Close()
+	err := h.f.Close()
+	h.f = nil
+	return err
+}
+
+// dumper dumps extracted raw data.
+type dumper interface {
+	Dump(w io.Writer) (int, error)
+}
+
+// nalus returns NAL units of AVC length-prefixed NAL units.
+func nalus(lengthNALUs []es.LengthNALU) []avcnalu.NALUnit {
+	n := make([]avcnalu.NALUnit, len(lengthNALUs))
+	for i := range lengthNALUs {
+		n[i] = lengthNALUs[i].NALU
+	}
+	return n
+}
+
+// hevcNALUs returns NAL units of HEVC length-prefixed NAL units.
+func hevcNALUs(lengthNALUs []hevces.LengthNALU) []nalu.NALUnit {
+	n := make([]nalu.NALUnit, len(lengthNALUs))
+	for i := range lengthNALUs {
+		n[i] = lengthNALUs[i].NALU
+	}
+	return n
 }
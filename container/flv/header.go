@@ -47,3 +47,27 @@ func (h *Header) Parse(r io.Reader) error {
 
 	return nil
 }
+
+// NewHeader creates FLV Header of version 1.
+func NewHeader(hasAudio, hasVideo bool) Header {
+	h := Header{Signature: "FLV", Version: 1, DataOffset: HeaderSize}
+	if hasAudio {
+		h.TypeFlagsAudio = 1
+	}
+	if hasVideo {
+		h.TypeFlagsVideo = 1
+	}
+	return h
+}
+
+// Encode writes FLV Header.
+func (h Header) Encode(w io.Writer) error {
+	data := make([]byte, HeaderSize) // fixed 9 bytes
+	copy(data, "FLV")
+	data[3] = h.Version
+	data[4] = (h.TypeFlagsAudio&0x1)<<2 | h.TypeFlagsVideo&0x1
+	binary.BigEndian.PutUint32(data[5:], HeaderSize)
+
+	_, err := w.Write(data)
+	return err
+}
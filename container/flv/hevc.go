@@ -0,0 +1,78 @@
+package flv
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/wangyoucao577/medialib/container/flv/tag/video"
+	hvcc "github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/hvcC"
+	"github.com/wangyoucao577/medialib/video/hevc/annexbes"
+	"github.com/wangyoucao577/medialib/video/hevc/es"
+	"github.com/wangyoucao577/medialib/video/hevc/nalu"
+)
+
+// ExtractHEVCES extracts HEVC Elementary Stream from enhanced(hvc1) video tags, parameter sets in hvcC are not included.
+// Use trackID to select the specified one in multitrack, trackID < 0 means use the first found one.
+func (f *FLV) ExtractHEVCES(trackID int) (*es.ElementaryStream, error) {
+	e, _, err := f.extractHEVCES(trackID)
+	return e, err
+}
+
+// ExtractHEVCAnnexBES extracts HEVC Elementary Stream with AnnexB byte format.
+// Parameter sets in hvcC(VPS/SPS/PPS/SEI arrays) come first since they're not stored in coded frames in general.
+// Use trackID to select the specified one in multitrack, trackID < 0 means use the first found one.
+func (f *FLV) ExtractHEVCAnnexBES(trackID int) (*annexbes.ElementaryStream, error) {
+	flvES, config, err := f.extractHEVCES(trackID)
+	if err != nil {
+		return nil, err
+	}
+
+	parameterSets, err := hevcParameterSets(config)
+	if err != nil {
+		return nil, err
+	}
+	annexbES := annexbes.ElementaryStream{NALU: parameterSets}
+	for i := range flvES.LengthNALU {
+		annexbES.NALU = append(annexbES.NALU, flvES.LengthNALU[i].NALU)
+	}
+
+	return &annexbES, nil
+}
+
+// extractHEVCES extracts HEVC Elementary Stream and returns its first decoder configuration record.
+func (f *FLV) extractHEVCES(trackID int) (*es.ElementaryStream, *hvcc.HEVCDecoderConfigurationRecord, error) {
+
+	if len(f.Tags) == 0 {
+		return nil, nil, fmt.Errorf("tags not found")
+	}
+
+	x := esExtractor{fourCC: video.FourCCHEVC, trackID: trackID}
+	for _, t := range f.Tags {
+		if err := x.extract(t); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if x.hevcConfig == nil {
+		if trackID < 0 {
+			return nil, nil, fmt.Errorf("hevc track not found")
+		}
+		return nil, nil, fmt.Errorf("hevc track %d not found", trackID)
+	}
+	return &x.hevcES, x.hevcConfig, nil
+}
+
+// hevcParameterSets returns NAL units of VPS/SPS/PPS/SEI arrays in hvcC.
+func hevcParameterSets(config *hvcc.HEVCDecoderConfigurationRecord) ([]nalu.NALUnit, error) {
+	var nalus []nalu.NALUnit
+	for _, array := range config.Arrays {
+		for _, ln := range array.LengthNALUs {
+			n := nalu.NALUnit{}
+			if _, err := n.Parse(bytes.NewReader(ln.NALUnit), len(ln.NALUnit)); err != nil {
+				return nil, fmt.Errorf("parse hvcC nalu type %d failed, err %v", array.NALUnitType, err)
+			}
+			nalus = append(nalus, n)
+		}
+	}
+	return nalus, nil
+}
@@ -0,0 +1,236 @@
+package flv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/container/flv/tag/script"
+	"github.com/wangyoucao577/medialib/container/flv/tag/video"
+	"github.com/wangyoucao577/medialib/util/amf/amf0"
+)
+
+// Properties of onMetaData that will be rebuilt by InjectKeyframes.
+const (
+	metadataDuration      = "duration"
+	metadataFileSize      = "filesize"
+	metadataVideoDataRate = "videodatarate"
+	metadataAudioDataRate = "audiodatarate"
+	metadataKeyframes     = "keyframes"
+
+	keyframesTimes         = "times"
+	keyframesFilePositions = "filepositions"
+)
+
+// metadataIndex represents everything collected by scanning tags for rebuilding onMetaData.
+type metadataIndex struct {
+	headerSize int64 // FLV Header and the first PreviousTagSize
+
+	properties []amf0.ObjectProperty // of the first onMetaData, without object-end-marker
+	removed    [][2]int64            // [begin, end) of onMetaData tags with their PreviousTagSize
+
+	keyframeTimestamps []int32 // milliseconds
+	keyframeOffsets    []int64 // offset of keyframe tags in input
+
+	lastTimestamp int32
+	videoDataSize uint64 // payload bytes of video tags
+	audioDataSize uint64 // payload bytes of audio tags
+}
+
+// InjectKeyframes rewrites FLV from r to w with a rebuilt onMetaData as the first tag, which contains
+// keyframes.times/keyframes.filepositions for seeking, and corrected duration, filesize, videodatarate, audiodatarate.
+// Other properties of the original onMetaData will be kept, while all original onMetaData tags are removed.
+// The input will be read twice, and tags except onMetaData are copied as they are.
+func InjectKeyframes(r io.ReaderAt, size int64, w io.Writer) error {
+	index, err := scanMetadataIndex(io.NewSectionReader(r, 0, size), size)
+	if err != nil {
+		return err
+	}
+
+	// all properties have fixed size no matter of values, so decide size by a placeholder first
+	metadataTag, err := index.metadataTag(size, 0)
+	if err != nil {
+		return err
+	}
+	if metadataTag, err = index.metadataTag(size, metadataTag.Size()+4); err != nil {
+		return err
+	}
+
+	if err := copyRange(w, r, 0, index.headerSize); err != nil {
+		return err
+	}
+	if err := metadataTag.Encode(w); err != nil {
+		return err
+	}
+	previousTagSize := make([]byte, 4)
+	binary.BigEndian.PutUint32(previousTagSize, uint32(metadataTag.Size()))
+	if _, err := w.Write(previousTagSize); err != nil {
+		return err
+	}
+
+	begin := index.headerSize
+	for _, removed := range append(index.removed, [2]int64{size, size}) {
+		if err := copyRange(w, r, begin, removed[0]); err != nil {
+			return err
+		}
+		begin = removed[1]
+	}
+	return nil
+}
+
+// scanMetadataIndex reads all tags to collect keyframes, data size, etc.
+func scanMetadataIndex(r io.Reader, size int64) (*metadataIndex, error) {
+	index := metadataIndex{}
+	fr := NewReader(r)
+	header, err := fr.ReadHeader()
+	if err != nil {
+		return nil, err
+	}
+	index.headerSize = int64(header.DataOffset) + 4
+
+	var metadataFound bool
+	for {
+		t, err := fr.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		h := t.GetTagHeader()
+
+		switch h.TagType {
+		case tag.TypeSriptData:
+			st, ok := t.(*script.Tag)
+			if !ok {
+				return nil, fmt.Errorf("tag %#v should be script data tag but cannot convert", t)
+			}
+			if st.TagBody == nil {
+				continue
+			}
+			if name, err := st.TagBody.Name.AsString(); err != nil || name != script.OnMetaData {
+				continue
+			}
+
+			end := fr.TagOffset() + t.Size() + 4
+			if end > size {
+				end = size
+			}
+			index.removed = append(index.removed, [2]int64{fr.TagOffset(), end})
+			if metadataFound {
+				continue // only the first one will be kept
+			}
+			metadataFound = true
+
+			properties, err := st.TagBody.Value.AsProperties()
+			if err != nil {
+				return nil, fmt.Errorf("onMetaData %v", err)
+			}
+			for _, p := range properties {
+				if p.ValueType.TypeMarker != amf0.TypeMarkerObjectEnd {
+					index.properties = append(index.properties, p)
+				}
+			}
+			continue // onMetaData doesn't count in timestamp
+		case tag.TypeVideo:
+			index.videoDataSize += uint64(h.DataSize)
+			vt, ok := t.(*video.Tag)
+			if !ok {
+				return nil, fmt.Errorf("tag %#v should be video tag but cannot convert", t)
+			}
+			if isSeekableKeyframe(vt) {
+				index.keyframeTimestamps = append(index.keyframeTimestamps, h.TimestampCalculated)
+				index.keyframeOffsets = append(index.keyframeOffsets, fr.TagOffset())
+			}
+		case tag.TypeAudio:
+			index.audioDataSize += uint64(h.DataSize)
+		}
+
+		if h.TimestampCalculated > index.lastTimestamp {
+			index.lastTimestamp = h.TimestampCalculated
+		}
+	}
+
+	return &index, nil
+}
+
+// isSeekableKeyframe checks whether the video tag is a key frame that contains coded data,
+// rather than sequence header, end of sequence, etc.
+func isSeekableKeyframe(vt *video.Tag) bool {
+	h := vt.VideoTagHeader
+	if h.FrameType != video.FrameTypeKey {
+		return false
+	}
+	if h.IsExHeader {
+		return h.PacketType != nil && (*h.PacketType == video.PacketTypeCodedFrames || *h.PacketType == video.PacketTypeCodedFramesX)
+	}
+	return h.AVCPacketType == nil || *h.AVCPacketType == video.AVCPacketTypeNALU // non-AVC codecs have no AVCPacketType
+}
+
+// metadataTag creates onMetaData tag with collected index, metadataSize is size of the new onMetaData tag
+// with its PreviousTagSize, which is required to calculate file positions.
+func (index *metadataIndex) metadataTag(size int64, metadataSize int64) (*script.Tag, error) {
+	var removedSize int64
+	for _, removed := range index.removed {
+		removedSize += removed[1] - removed[0]
+	}
+
+	duration := float64(index.lastTimestamp) / 1000
+	var videoDataRate, audioDataRate float64 // kbps
+	if duration > 0 {
+		videoDataRate = float64(index.videoDataSize) * 8 / 1024 / duration
+		audioDataRate = float64(index.audioDataSize) * 8 / 1024 / duration
+	}
+
+	times := make([]amf0.ValueType, len(index.keyframeTimestamps))
+	filePositions := make([]amf0.ValueType, len(index.keyframeOffsets))
+	var removedBefore int64
+	var j int
+	for i, offset := range index.keyframeOffsets {
+		for ; j < len(index.removed) && index.removed[j][1] <= offset; j++ {
+			removedBefore += index.removed[j][1] - index.removed[j][0]
+		}
+		times[i] = amf0.NewNumber(float64(index.keyframeTimestamps[i]) / 1000)
+		filePositions[i] = amf0.NewNumber(float64(offset + metadataSize - removedBefore))
+	}
+
+	rebuilt := []amf0.ObjectProperty{
+		amf0.NewObjectProperty(metadataDuration, amf0.NewNumber(duration)),
+		amf0.NewObjectProperty(metadataFileSize, amf0.NewNumber(float64(size+metadataSize-removedSize))),
+		amf0.NewObjectProperty(metadataVideoDataRate, amf0.NewNumber(videoDataRate)),
+		amf0.NewObjectProperty(metadataAudioDataRate, amf0.NewNumber(audioDataRate)),
+		amf0.NewObjectProperty(metadataKeyframes, amf0.NewObject(
+			amf0.NewObjectProperty(keyframesTimes, amf0.NewStrictArray(times...)),
+			amf0.NewObjectProperty(keyframesFilePositions, amf0.NewStrictArray(filePositions...)))),
+	}
+
+	// replace in place to keep order of original properties, or append
+	properties := make([]amf0.ObjectProperty, len(index.properties))
+	copy(properties, index.properties)
+	for _, p := range rebuilt {
+		var replaced bool
+		for i := range properties {
+			if properties[i].String.Str == p.String.Str {
+				properties[i], replaced = p, true
+				break
+			}
+		}
+		if !replaced {
+			properties = append(properties, p)
+		}
+	}
+
+	return script.NewOnMetaDataTag(properties...)
+}
+
+// copyRange copies [begin, end) of r to w.
+func copyRange(w io.Writer, r io.ReaderAt, begin, end int64) error {
+	if end <= begin {
+		return nil
+	}
+	if _, err := io.Copy(w, io.NewSectionReader(r, begin, end-begin)); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package flv
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/wangyoucao577/medialib/container/flv/tag/script"
+	"github.com/wangyoucao577/medialib/container/flv/tag/video"
+	"github.com/wangyoucao577/medialib/util/amf/amf0"
+)
+
+func TestInjectKeyframes(t *testing.T) {
+	flvFile := "../../assets/sintel_trailer-720p-firstgopfmp4.flv"
+
+	data, err := os.ReadFile(flvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := InjectKeyframes(bytes.NewReader(data), int64(len(data)), &buf); err != nil {
+		t.Fatalf("inject keyframes expect nil but got %v", err)
+	}
+	injected := buf.Bytes()
+
+	// index tags by offset
+	fr := NewReader(bytes.NewReader(injected))
+	var metadata *script.Tag
+	videoTags := map[int64]*video.Tag{}
+	var tags, keyframes int
+	var lastTimestamp int32
+	for {
+		tg, err := fr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("read injected tag %d expect nil but got %v", tags, err)
+		}
+		if tags == 0 {
+			metadata, _ = tg.(*script.Tag)
+		} else if st, ok := tg.(*script.Tag); ok {
+			if name, _ := st.TagBody.Name.AsString(); name == script.OnMetaData {
+				t.Errorf("expect only one onMetaData but got another one at offset %d", fr.TagOffset())
+			}
+		}
+		if vt, ok := tg.(*video.Tag); ok {
+			videoTags[fr.TagOffset()] = vt
+			if isSeekableKeyframe(vt) {
+				keyframes++
+			}
+		}
+		if ts := tg.GetTagHeader().TimestampCalculated; ts > lastTimestamp {
+			lastTimestamp = ts
+		}
+		tags++
+	}
+	if metadata == nil {
+		t.Fatalf("expect onMetaData as the first tag")
+	}
+
+	properties, err := metadata.TagBody.Value.AsProperties()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]amf0.ValueType{}
+	for _, p := range properties {
+		values[p.String.Str] = p.ValueType
+	}
+	if width, err := values["width"].AsNumber(); err != nil || width != 1280 {
+		t.Errorf("expect original width 1280 kept but got %v, err %v", width, err)
+	}
+	if fileSize, err := values[metadataFileSize].AsNumber(); err != nil || int(fileSize) != len(injected) {
+		t.Errorf("expect filesize %d but got %v, err %v", len(injected), fileSize, err)
+	}
+	if duration, err := values[metadataDuration].AsNumber(); err != nil || math.Abs(duration-float64(lastTimestamp)/1000) > 1e-9 {
+		t.Errorf("expect duration %v but got %v, err %v", float64(lastTimestamp)/1000, duration, err)
+	}
+	for _, name := range []string{metadataVideoDataRate, metadataAudioDataRate} {
+		if rate, err := values[name].AsNumber(); err != nil || rate <= 0 {
+			t.Errorf("expect positive %s but got %v, err %v", name, rate, err)
+		}
+	}
+
+	// keyframes should point to key frame tags
+	keyframesProperties, err := values[metadataKeyframes].AsProperties()
+	if err != nil {
+		t.Fatalf("expect keyframes object but got err %v", err)
+	}
+	var times, filePositions []amf0.ValueType
+	for _, p := range keyframesProperties {
+		if a, ok := p.ValueType.Value.(*amf0.StrictArrayPayload); ok {
+			switch p.String.Str {
+			case keyframesTimes:
+				times = a.ValueType
+			case keyframesFilePositions:
+				filePositions = a.ValueType
+			}
+		}
+	}
+	if keyframes == 0 || len(times) != keyframes || len(filePositions) != keyframes {
+		t.Fatalf("expect %d keyframes but got %d times and %d filepositions", keyframes, len(times), len(filePositions))
+	}
+	for i := range filePositions {
+		position, _ := filePositions[i].AsNumber()
+		time, _ := times[i].AsNumber()
+		vt, ok := videoTags[int64(position)]
+		if !ok || !isSeekableKeyframe(vt) || float64(vt.Header.TimestampCalculated)/1000 != time {
+			t.Errorf("keyframe %d expect key frame at %v time %v", i, position, time)
+		}
+	}
+
+	// inject again should be the same
+	var again bytes.Buffer
+	if err := InjectKeyframes(bytes.NewReader(injected), int64(len(injected)), &again); err != nil {
+		t.Fatalf("inject keyframes again expect nil but got %v", err)
+	}
+	if !bytes.Equal(again.Bytes(), injected) {
+		t.Errorf("inject keyframes again expect %d bytes same as before but got %d", len(injected), again.Len())
+	}
+}
@@ -0,0 +1,441 @@
+package flv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/audio/aac"
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/container/flv/tag/audio"
+	"github.com/wangyoucao577/medialib/container/flv/tag/script"
+	"github.com/wangyoucao577/medialib/container/flv/tag/video"
+	"github.com/wangyoucao577/medialib/container/mp4"
+	"github.com/wangyoucao577/medialib/container/mp4/box"
+	avcc "github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/avcC"
+	"github.com/wangyoucao577/medialib/container/mp4/box/trak"
+	"github.com/wangyoucao577/medialib/util/amf/amf0"
+	"github.com/wangyoucao577/medialib/video/avc/es"
+)
+
+const mp4Timescale = 1000 // FLV timestamps are always in milliseconds
+
+// flvSample represents a sample collected from tag before decide duration.
+type flvSample struct {
+	dts    int64
+	cts    int32
+	isSync bool
+	data   []byte // nil if comes from source
+
+	offset int64 // offset in source
+	size   uint32
+}
+
+// mp4Remuxer collects AVC video and AAC audio samples tag by tag, then remuxes them to mp4.
+// Sample data will be referenced by offset rather than kept in memory if source is available.
+type mp4Remuxer struct {
+	source io.ReaderAt // the input FLV, optional
+
+	avcConfig           *avcc.AVCDecoderConfigurationRecord
+	audioSpecificConfig []byte
+	videoSamples        []flvSample
+	audioSamples        []flvSample
+}
+
+// ToMP4 remuxes AVC video and AAC audio tags to progressive mp4 boxes, which contain avc1 and mp4a sample entries.
+// Sample tables are filled by tag timestamps and CompositionTime, the mdat is kept in memory.
+func (f *FLV) ToMP4() (*mp4.Boxes, error) {
+	if len(f.Tags) == 0 {
+		return nil, fmt.Errorf("tags not found")
+	}
+
+	m := mp4Remuxer{}
+	for _, t := range f.Tags {
+		if err := m.addTag(t, 0); err != nil {
+			return nil, err
+		}
+	}
+	return m.mux()
+}
+
+// addTag collects sample of the tag, tagOffset is offset of the tag in source.
+func (m *mp4Remuxer) addTag(t tag.Tag, tagOffset int64) error {
+	if skipEncrypted(t) {
+		return nil
+	}
+
+	switch t.GetTagHeader().TagType {
+	case tag.TypeVideo:
+		vt, ok := t.(*video.Tag)
+		if !ok {
+			return fmt.Errorf("tag %#v should be video tag but cannot convert", t)
+		}
+		if vt.VideoTagHeader.CodecID != video.CodecIDAVC || vt.VideoTagHeader.AVCPacketType == nil {
+			return fmt.Errorf("codec %d(%s) doesn't support yet", vt.VideoTagHeader.CodecID, video.CodecIDDescription(int(vt.VideoTagHeader.CodecID)))
+		}
+
+		switch *vt.VideoTagHeader.AVCPacketType {
+		case video.AVCPacketTypeSequenceHeader:
+			if vt.TagBody == nil || vt.TagBody.AVCVideoPacket == nil || vt.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord == nil {
+				return fmt.Errorf("tag %#v expect avc config but empty", t)
+			}
+			config := vt.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord
+			if m.avcConfig != nil {
+				if !sameAVCConfig(m.avcConfig, config) {
+					return fmt.Errorf("AVCDecoderConfigurationRecord changes doesn't support yet")
+				}
+				return nil
+			}
+			m.avcConfig = config
+		case video.AVCPacketTypeNALU:
+			if m.avcConfig == nil {
+				return fmt.Errorf("AVC nal units before sequence header")
+			}
+			if vt.TagBody == nil || vt.TagBody.AVCVideoPacket == nil || len(vt.TagBody.AVCVideoPacket.LengthNALU) == 0 {
+				return fmt.Errorf("tag %#v expect nal units but empty", t)
+			}
+
+			s := flvSample{
+				dts:    int64(vt.Header.TimestampCalculated),
+				isSync: vt.VideoTagHeader.FrameType == video.FrameTypeKey,
+			}
+			if vt.VideoTagHeader.CompositionTime != nil {
+				s.cts = *vt.VideoTagHeader.CompositionTime
+			}
+			if m.source != nil { // length prefixed nal units follow the 5 bytes VideoTagHeader
+				s.offset = tagOffset + tag.HeaderSize + 5
+				s.size = vt.Header.DataSize - 5
+			} else {
+				length := make([]byte, 4)
+				lengthSize := m.avcConfig.LengthSize()
+				for _, n := range vt.TagBody.AVCVideoPacket.LengthNALU {
+					raw := n.NALU.Raw()
+					binary.BigEndian.PutUint32(length, uint32(len(raw)))
+					s.data = append(s.data, length[4-lengthSize:]...)
+					s.data = append(s.data, raw...)
+				}
+			}
+			m.videoSamples = append(m.videoSamples, s)
+		} // video.AVCPacketTypeEOS doesn't need to handle
+
+	case tag.TypeAudio:
+		at, ok := t.(*audio.Tag)
+		if !ok {
+			return fmt.Errorf("tag %#v should be audio tag but cannot convert", t)
+		}
+		if at.AudioTagHeader.SoundFormat != audio.SoundFormatAAC || at.AudioTagHeader.AACPacketType == nil {
+			return fmt.Errorf("sound format %d(%s) doesn't support yet", at.AudioTagHeader.SoundFormat, audio.SoundFormatDescription(int(at.AudioTagHeader.SoundFormat)))
+		}
+		if at.Body == nil || at.Body.AACAudioData == nil {
+			return fmt.Errorf("tag %#v empty AACAudioData", t)
+		}
+
+		if *at.AudioTagHeader.AACPacketType == audio.AACPacketTypeSequenceHeader {
+			if m.audioSpecificConfig != nil {
+				if !bytes.Equal(m.audioSpecificConfig, at.Body.AACAudioData.AudioSpecificConfig) {
+					return fmt.Errorf("AudioSpecificConfig changes doesn't support yet")
+				}
+				return nil
+			}
+			m.audioSpecificConfig = at.Body.AACAudioData.AudioSpecificConfig
+		} else if *at.AudioTagHeader.AACPacketType == audio.AACPacketTypeRaw {
+			if m.audioSpecificConfig == nil {
+				return fmt.Errorf("AAC raw data before sequence header")
+			}
+			s := flvSample{
+				dts:    int64(at.Header.TimestampCalculated),
+				isSync: true,
+			}
+			if m.source != nil { // raw frame follows the 2 bytes AudioTagHeader
+				s.offset = tagOffset + tag.HeaderSize + 2
+				s.size = uint32(len(at.Body.AACAudioData.RawAACFrameData))
+			} else {
+				s.data = at.Body.AACAudioData.RawAACFrameData
+			}
+			m.audioSamples = append(m.audioSamples, s)
+		}
+	}
+	return nil
+}
+
+// mux remuxes collected samples to progressive mp4 boxes.
+func (m *mp4Remuxer) mux() (*mp4.Boxes, error) {
+	videoSamples, audioSamples := m.videoSamples, m.audioSamples
+	if len(videoSamples) == 0 && len(audioSamples) == 0 {
+		return nil, fmt.Errorf("no AVC or AAC samples found")
+	}
+
+	// all tracks start from the earliest timestamp to keep sync
+	start := int64(math.MaxInt64)
+	if len(videoSamples) > 0 {
+		start = videoSamples[0].dts
+	}
+	if len(audioSamples) > 0 && audioSamples[0].dts < start {
+		start = audioSamples[0].dts
+	}
+
+	var tracks []mp4.MuxTrack
+	if len(videoSamples) > 0 {
+		entry, err := mp4.NewAVCSampleEntry(m.avcConfig)
+		if err != nil {
+			return nil, err
+		}
+		samples, err := m.toMuxSamples(videoSamples, start, 0)
+		if err != nil {
+			return nil, fmt.Errorf("video %v", err)
+		}
+		tracks = append(tracks, mp4.MuxTrack{
+			HandlerType: box.TypeVide,
+			Timescale:   mp4Timescale,
+			Width:       uint32(entry.Width),
+			Height:      uint32(entry.Height),
+			SampleEntry: entry,
+			Samples:     samples,
+		})
+	}
+	if len(audioSamples) > 0 {
+		entry, err := mp4.NewAACSampleEntry(m.audioSpecificConfig)
+		if err != nil {
+			return nil, err
+		}
+		config := aac.AudioSpecificConfig{}
+		if _, err := config.Parse(bytes.NewReader(m.audioSpecificConfig), len(m.audioSpecificConfig)); err != nil {
+			return nil, fmt.Errorf("parse AudioSpecificConfig failed, err %v", err)
+		}
+		var frameDuration uint32
+		if frequency := config.Frequency(); frequency > 0 {
+			frameDuration = 1024 * mp4Timescale / frequency // 1024 samples per AAC frame
+		}
+		samples, err := m.toMuxSamples(audioSamples, start, frameDuration)
+		if err != nil {
+			return nil, fmt.Errorf("audio %v", err)
+		}
+		tracks = append(tracks, mp4.MuxTrack{
+			HandlerType: box.TypeSoun,
+			Timescale:   mp4Timescale,
+			SampleEntry: entry,
+			Samples:     samples,
+		})
+	}
+
+	return mp4.Mux(tracks)
+}
+
+// toMuxSamples converts samples to be relative to start, duration is the distance to the next sample,
+// the last sample uses lastDuration or the previous one if lastDuration is 0.
+func (r *mp4Remuxer) toMuxSamples(samples []flvSample, start int64, lastDuration uint32) ([]mp4.MuxSample, error) {
+	muxSamples := make([]mp4.MuxSample, len(samples))
+	for i, s := range samples {
+		m := mp4.MuxSample{Data: s.data}
+		if s.data == nil && r.source != nil {
+			m.Source, m.SourceOffset, m.Size = r.source, s.offset, s.size
+		}
+		m.DecodeTime = uint64(s.dts - start)
+		m.CompositionTime = s.dts - start + int64(s.cts)
+		m.IsSync = s.isSync
+
+		if i+1 < len(samples) {
+			if samples[i+1].dts < s.dts {
+				return nil, fmt.Errorf("timestamp decreases from %d to %d at sample %d, try to repair timestamps first", s.dts, samples[i+1].dts, i+1)
+			}
+			m.Duration = uint32(samples[i+1].dts - s.dts)
+		} else if lastDuration > 0 {
+			m.Duration = lastDuration
+		} else if i > 0 {
+			m.Duration = muxSamples[i-1].Duration
+		}
+		muxSamples[i] = m
+	}
+	return muxSamples, nil
+}
+
+// sameAVCConfig compares AVCDecoderConfigurationRecords by encoded bytes.
+func sameAVCConfig(a, b *avcc.AVCDecoderConfigurationRecord) bool {
+	var bufA, bufB bytes.Buffer
+	if err := a.Encode(&bufA); err != nil {
+		return false
+	}
+	if err := b.Encode(&bufB); err != nil {
+		return false
+	}
+	return bytes.Equal(bufA.Bytes(), bufB.Bytes())
+}
+
+// FromMP4 remuxes the first AVC video track and the first AAC audio track of mp4 boxes to FLV with onMetaData,
+// sequence headers come from avcC and esds, CompositionTime comes from ctts or trun.
+// Timestamps are shifted by edit list if exist, see mp4TrackSamples for details.
+func FromMP4(b *mp4.Boxes) (*FLV, error) {
+	if b.Moov == nil {
+		return nil, fmt.Errorf("moov not found")
+	}
+
+	var videoTrak, audioTrak *trak.Box
+	for _, t := range b.Tracks() {
+		if t.Codec == box.TypeAvc1 && videoTrak == nil {
+			videoTrak = &b.Moov.Trak[t.Index]
+		} else if t.Codec == box.TypeMp4a && audioTrak == nil {
+			audioTrak = &b.Moov.Trak[t.Index]
+		}
+	}
+	if videoTrak == nil && audioTrak == nil {
+		return nil, fmt.Errorf("no AVC or AAC track found")
+	}
+
+	var duration int64 // milliseconds
+	var metadata []amf0.ObjectProperty
+	var videoTags, audioTags []tag.Tag
+
+	if videoTrak != nil {
+		entry := &videoTrak.Mdia.Minf.Stbl.Stsd.AVC1SampleEntries[0]
+		if entry.AVCConfig == nil {
+			return nil, fmt.Errorf("trackID %d avcC not found", videoTrak.Tkhd.TrackID)
+		}
+		avcConfig := &entry.AVCConfig.AVCConfig
+		e := es.ElementaryStream{}
+		e.SetLengthSize(avcConfig.LengthSize())
+		if len(avcConfig.LengthSPSNALU) > 0 && len(avcConfig.LengthPPSNALU) > 0 {
+			e.SetSequenceHeaders(avcConfig.LengthSPSNALU[0].NALUnit.SequenceParameterSetData, avcConfig.LengthPPSNALU[0].NALUnit.PictureParameterSet)
+		}
+
+		samples, offset, timescale, err := mp4TrackSamples(b, videoTrak)
+		if err != nil {
+			return nil, err
+		}
+		start := offset + toMilliseconds(int64(samples[0].DecodeTime), timescale)
+		videoTags = append(videoTags, video.NewAVCSequenceHeaderTag(uint32(start), avcConfig))
+		var end int64
+		for _, s := range samples {
+			data, err := b.ReadSample(s)
+			if err != nil {
+				return nil, err
+			}
+			e.LengthNALU = nil
+			if _, err := e.Parse(bytes.NewReader(data), len(data)); err != nil {
+				return nil, fmt.Errorf("trackID %d parse sample failed, err %v", videoTrak.Tkhd.TrackID, err)
+			}
+
+			dts := offset + toMilliseconds(int64(s.DecodeTime), timescale)
+			cts := toMilliseconds(s.CompositionTime, timescale) - toMilliseconds(int64(s.DecodeTime), timescale)
+			videoTags = append(videoTags, video.NewAVCNALUTag(uint32(dts), int32(cts), s.IsSync, avcConfig, e.LengthNALU))
+			end = offset + toMilliseconds(int64(s.DecodeTime)+int64(s.Duration), timescale)
+		}
+		videoTags = append(videoTags, video.NewAVCEndOfSequenceTag(uint32(videoTags[len(videoTags)-1].GetTagHeader().TimestampCalculated)))
+		if end > duration {
+			duration = end
+		}
+
+		metadata = append(metadata,
+			amf0.NewObjectProperty("width", amf0.NewNumber(float64(entry.Width))),
+			amf0.NewObjectProperty("height", amf0.NewNumber(float64(entry.Height))),
+			amf0.NewObjectProperty("videocodecid", amf0.NewNumber(float64(video.CodecIDAVC))))
+		if end > start {
+			metadata = append(metadata, amf0.NewObjectProperty("framerate", amf0.NewNumber(float64(len(samples))*1000/float64(end-start))))
+		}
+	}
+
+	if audioTrak != nil {
+		entry := &audioTrak.Mdia.Minf.Stbl.Stsd.MP4VisualSampleEntries[0]
+		if entry.Esds == nil || len(entry.Esds.DecoderConfigDescriptor.DecoderSpecificInfo.Data) == 0 {
+			return nil, fmt.Errorf("trackID %d AudioSpecificConfig not found", audioTrak.Tkhd.TrackID)
+		}
+		audioSpecificConfig := entry.Esds.DecoderConfigDescriptor.DecoderSpecificInfo.Data
+
+		samples, offset, timescale, err := mp4TrackSamples(b, audioTrak)
+		if err != nil {
+			return nil, err
+		}
+		start := offset + toMilliseconds(int64(samples[0].DecodeTime), timescale)
+		audioTags = append(audioTags, audio.NewAACSequenceHeaderTag(uint32(start), audioSpecificConfig))
+		var end int64
+		for _, s := range samples {
+			data, err := b.ReadSample(s)
+			if err != nil {
+				return nil, err
+			}
+			dts := offset + toMilliseconds(int64(s.DecodeTime), timescale)
+			audioTags = append(audioTags, audio.NewAACRawTag(uint32(dts), data))
+			end = offset + toMilliseconds(int64(s.DecodeTime)+int64(s.Duration), timescale)
+		}
+		if end > duration {
+			duration = end
+		}
+
+		metadata = append(metadata,
+			amf0.NewObjectProperty("audiocodecid", amf0.NewNumber(float64(audio.SoundFormatAAC))),
+			amf0.NewObjectProperty("audiosamplerate", amf0.NewNumber(float64(entry.SampleRate))),
+			amf0.NewObjectProperty("audiosamplesize", amf0.NewNumber(float64(entry.SampleSize))),
+			amf0.NewObjectProperty("stereo", amf0.NewBoolean(entry.ChannelCount == 2)))
+	}
+
+	metadataTag, err := script.NewOnMetaDataTag(append([]amf0.ObjectProperty{
+		amf0.NewObjectProperty("duration", amf0.NewNumber(float64(duration)/1000))}, metadata...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &FLV{Header: NewHeader(audioTrak != nil, videoTrak != nil)}
+	f.Tags = append(f.Tags, metadataTag)
+
+	// interleave by timestamp, video comes first if the same
+	for len(videoTags) > 0 || len(audioTags) > 0 {
+		if len(audioTags) == 0 || (len(videoTags) > 0 &&
+			videoTags[0].GetTagHeader().TimestampCalculated <= audioTags[0].GetTagHeader().TimestampCalculated) {
+			f.Tags = append(f.Tags, videoTags[0])
+			videoTags = videoTags[1:]
+		} else {
+			f.Tags = append(f.Tags, audioTags[0])
+			audioTags = audioTags[1:]
+		}
+	}
+
+	f.PreviousTagSize = append(f.PreviousTagSize, 0)
+	for _, t := range f.Tags {
+		f.PreviousTagSize = append(f.PreviousTagSize, uint32(t.Size()))
+	}
+	return f, nil
+}
+
+// mp4TrackSamples returns samples of the track, timestamp offset in milliseconds and timescale of the track.
+// The offset comes from edit list, i.e., the empty edit delays the track and media_time of the first edit advances it.
+func mp4TrackSamples(b *mp4.Boxes, t *trak.Box) ([]mp4.Sample, int64, uint32, error) {
+	if t.Mdia == nil || t.Mdia.Mdhd == nil || t.Mdia.Mdhd.Timescale == 0 {
+		return nil, 0, 0, fmt.Errorf("trackID %d invalid timescale", t.Tkhd.TrackID)
+	}
+	timescale := t.Mdia.Mdhd.Timescale
+	samples, err := b.Samples(t.Tkhd.TrackID)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if len(samples) == 0 {
+		return nil, 0, 0, fmt.Errorf("trackID %d samples not found", t.Tkhd.TrackID)
+	}
+
+	var offset int64
+	if len(t.Edts) > 0 && t.Edts[0].Elst != nil && b.Moov.Mvhd != nil && b.Moov.Mvhd.Timescale > 0 {
+		el := t.Edts[0].Elst
+		for i := 0; i < int(el.EntryCount) && i < len(el.MediaTime); i++ {
+			if el.MediaTime[i] == -1 {
+				offset += toMilliseconds(int64(el.SegmentDuration[i]), b.Moov.Mvhd.Timescale)
+				continue
+			}
+			offset -= toMilliseconds(el.MediaTime[i], timescale)
+			break
+		}
+	}
+	if offset+toMilliseconds(int64(samples[0].DecodeTime), timescale) < 0 {
+		glog.Warningf("trackID %d timestamp offset %d ms by edit list is ignored since it leads to negative timestamp", t.Tkhd.TrackID, offset)
+		offset = 0
+	}
+	return samples, offset, timescale, nil
+}
+
+// toMilliseconds converts time in timescale to milliseconds, rounds to nearest.
+func toMilliseconds(t int64, timescale uint32) int64 {
+	if t < 0 {
+		return -toMilliseconds(-t, timescale)
+	}
+	return (t*1000 + int64(timescale)/2) / int64(timescale)
+}
@@ -0,0 +1,209 @@
+package flv
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/container/flv/tag/audio"
+	"github.com/wangyoucao577/medialib/container/flv/tag/script"
+	"github.com/wangyoucao577/medialib/container/flv/tag/video"
+	"github.com/wangyoucao577/medialib/container/mp4"
+)
+
+func TestToMP4(t *testing.T) {
+	flvFile := "../../assets/sintel_trailer-720p-firstgopfmp4.flv"
+
+	h := New(flvFile)
+	if err := h.Parse(); err != io.EOF {
+		t.Fatalf("parse %s expect EOF but got %v", flvFile, err)
+	}
+
+	var compositionTimes []int64
+	for _, ft := range h.Tags {
+		if vt, ok := ft.(*video.Tag); ok && vt.VideoTagHeader.AVCPacketType != nil && *vt.VideoTagHeader.AVCPacketType == video.AVCPacketTypeNALU {
+			compositionTimes = append(compositionTimes, int64(vt.Header.TimestampCalculated)+int64(*vt.VideoTagHeader.CompositionTime))
+		}
+	}
+	videoFrames := len(compositionTimes)
+	expectAudio, err := h.ExtractAudio()
+	if err != nil {
+		t.Fatalf("extract audio from flv failed, err %v", err)
+	}
+
+	b, err := h.ToMP4()
+	if err != nil {
+		t.Fatalf("remux to mp4 expect nil but got %v", err)
+	}
+	mp4File := filepath.Join(t.TempDir(), "flv.mp4")
+	var buf bytes.Buffer
+	if err := b.Encode(&buf); err != nil {
+		t.Fatalf("encode mp4 expect nil but got %v", err)
+	}
+	if err := os.WriteFile(mp4File, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := mp4.New(mp4File)
+	if err := m.Parse(); err != nil {
+		t.Fatalf("parse remuxed mp4 failed, err %v", err)
+	}
+	defer m.Close()
+	tracks := m.Tracks()
+	if len(tracks) != 2 || tracks[0].Codec != "avc1" || tracks[1].Codec != "mp4a" {
+		t.Fatalf("expect avc1 and mp4a tracks but got %v", tracks)
+	}
+
+	samples, err := m.Samples(tracks[0].ID)
+	if err != nil {
+		t.Fatalf("get video samples failed, err %v", err)
+	}
+	if len(samples) != videoFrames {
+		t.Fatalf("expect %d video samples but got %d", videoFrames, len(samples))
+	}
+	for i, s := range samples {
+		if expect := compositionTimes[i] - compositionTimes[0] + samples[0].CompositionTime; s.CompositionTime != expect {
+			t.Fatalf("video sample %d expect composition time %d but got %d", i, expect, s.CompositionTime)
+		}
+	}
+	if !samples[0].IsSync {
+		t.Errorf("expect the first video sample is sync")
+	}
+	if _, err := m.ExtractAVCES(int(tracks[0].ID)); err != nil {
+		t.Errorf("extract video from remuxed mp4 failed, err %v", err)
+	}
+
+	if audioTrak := m.Moov.Trak[1]; len(audioTrak.Edts) != 1 || audioTrak.Edts[0].Elst == nil || audioTrak.Edts[0].Elst.MediaTime[0] != -1 {
+		t.Errorf("expect empty edit to delay audio track but got %v", audioTrak.Edts)
+	}
+
+	gotAudio, err := m.ExtractAudio(int(tracks[1].ID))
+	if err != nil {
+		t.Fatalf("extract audio from remuxed mp4 failed, err %v", err)
+	}
+	if len(gotAudio.RawFrames) != len(expectAudio.RawFrames) {
+		t.Fatalf("expect %d audio frames but got %d", len(expectAudio.RawFrames), len(gotAudio.RawFrames))
+	}
+	for i := range gotAudio.RawFrames {
+		if !bytes.Equal(gotAudio.RawFrames[i], expectAudio.RawFrames[i]) {
+			t.Fatalf("audio frame %d mismatch", i)
+		}
+	}
+
+	var fragmented bytes.Buffer
+	if err := b.WriteFragmented(&fragmented, mp4.FragmentOptions{Sidx: true}); err != nil {
+		t.Fatalf("write fragmented expect nil but got %v", err)
+	}
+	fmp4File := filepath.Join(t.TempDir(), "flv_fmp4.mp4")
+	if err := os.WriteFile(fmp4File, fragmented.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f := mp4.New(fmp4File)
+	if err := f.Parse(); err != nil {
+		t.Fatalf("parse fragmented mp4 failed, err %v", err)
+	}
+	defer f.Close()
+	if fragmentedSamples, err := f.Samples(tracks[0].ID); err != nil || len(fragmentedSamples) != videoFrames {
+		t.Errorf("expect %d fragmented video samples but got %d, err %v", videoFrames, len(fragmentedSamples), err)
+	}
+}
+
+func TestFromMP4(t *testing.T) {
+	mp4File := "../../assets/sintel_trailer-720p-firstgopfmp4.mp4"
+	flvFile := "../../assets/sintel_trailer-720p-firstgopfmp4.flv" // remuxed from the same source
+
+	m := mp4.New(mp4File)
+	if err := m.Parse(); err != nil && err != io.EOF {
+		t.Fatalf("parse %s failed, err %v", mp4File, err)
+	}
+	defer m.Close()
+	expectAudio, err := m.ExtractAudio(2)
+	if err != nil {
+		t.Fatalf("extract audio from mp4 failed, err %v", err)
+	}
+
+	h := New(flvFile)
+	if err := h.Parse(); err != io.EOF {
+		t.Fatalf("parse %s expect EOF but got %v", flvFile, err)
+	}
+
+	f, err := FromMP4(&m.Boxes)
+	if err != nil {
+		t.Fatalf("remux to flv expect nil but got %v", err)
+	}
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatalf("encode flv expect nil but got %v", err)
+	}
+
+	got := FLV{}
+	if err := got.Parse(bytes.NewReader(buf.Bytes())); err != io.EOF {
+		t.Fatalf("parse remuxed flv expect EOF but got %v", err)
+	}
+	if len(got.Tags) != len(f.Tags) || len(got.PreviousTagSize) != len(f.PreviousTagSize) {
+		t.Fatalf("expect %d tags but got %d", len(f.Tags), len(got.Tags))
+	}
+	if _, ok := got.Tags[0].(*script.Tag); !ok {
+		t.Errorf("expect onMetaData as the first tag but got %T", got.Tags[0])
+	}
+
+	var lastTimestamp int32
+	for _, ft := range got.Tags {
+		if ft.GetTagHeader().TimestampCalculated < lastTimestamp {
+			t.Fatalf("expect tags interleaved by timestamp but %d after %d", ft.GetTagHeader().TimestampCalculated, lastTimestamp)
+		}
+		lastTimestamp = ft.GetTagHeader().TimestampCalculated
+	}
+
+	// timestamps of frames should be the same as flv remuxed from the same source, allow 1ms rounding difference
+	type frame struct {
+		timestamp int32
+		cts       int32
+		key       bool
+	}
+	frames := func(tags []tag.Tag, tagType uint8) []frame {
+		var frames []frame
+		for _, ft := range tags {
+			switch tt := ft.(type) {
+			case *video.Tag:
+				if tagType == tag.TypeVideo && *tt.VideoTagHeader.AVCPacketType == video.AVCPacketTypeNALU {
+					frames = append(frames, frame{tt.Header.TimestampCalculated, *tt.VideoTagHeader.CompositionTime, tt.VideoTagHeader.FrameType == video.FrameTypeKey})
+				}
+			case *audio.Tag:
+				if tagType == tag.TypeAudio && *tt.AudioTagHeader.AACPacketType == audio.AACPacketTypeRaw {
+					frames = append(frames, frame{tt.Header.TimestampCalculated, 0, true})
+				}
+			}
+		}
+		return frames
+	}
+	near := func(a, b int32) bool { return a-b <= 1 && b-a <= 1 }
+	for _, tagType := range []uint8{tag.TypeVideo, tag.TypeAudio} {
+		expectFrames, gotFrames := frames(h.Tags, tagType), frames(got.Tags, tagType)
+		if len(gotFrames) != len(expectFrames) {
+			t.Fatalf("tag type %d expect %d frames but got %d", tagType, len(expectFrames), len(gotFrames))
+		}
+		for i := range gotFrames {
+			e, g := expectFrames[i], gotFrames[i]
+			if g.key != e.key || !near(g.timestamp, e.timestamp) || !near(g.timestamp+g.cts, e.timestamp+e.cts) {
+				t.Fatalf("tag type %d frame %d expect %+v but got %+v", tagType, i, e, g)
+			}
+		}
+	}
+
+	gotAudio, err := got.ExtractAudio()
+	if err != nil {
+		t.Fatalf("extract audio from remuxed flv failed, err %v", err)
+	}
+	if len(gotAudio.RawFrames) != len(expectAudio.RawFrames) {
+		t.Fatalf("expect %d audio frames but got %d", len(expectAudio.RawFrames), len(gotAudio.RawFrames))
+	}
+	for i := range gotAudio.RawFrames {
+		if !bytes.Equal(gotAudio.RawFrames[i], expectAudio.RawFrames[i]) {
+			t.Fatalf("audio frame %d mismatch", i)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package flv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/container/flv/tag/audio"
+	"github.com/wangyoucao577/medialib/container/flv/tag/script"
+	"github.com/wangyoucao577/medialib/container/flv/tag/video"
+	avcc "github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/avcC"
+	"github.com/wangyoucao577/medialib/util"
+)
+
+// Reader reads FLV Header and tags one by one, so that large input can be processed in constant memory.
+// Only decoder configurations(e.g., AVCDecoderConfigurationRecord) are kept for later tags parsing.
+type Reader struct {
+	r *countReader
+
+	header     Header
+	headerRead bool
+
+	avcConfig        *avcc.AVCDecoderConfigurationRecord
+	exSequenceStarts video.ExSequenceStarts // for enhanced video tags
+
+	lastTagSize     int64  // size of the latest read tag for checking
+	previousTagSize uint32 // the latest read PreviousTagSize
+	hasPrevTagSize  bool   // whether PreviousTagSize has been read by the latest Next
+	tagOffset       int64  // offset of the latest read tag from the beginning of the input
+	tagData         []byte // raw data of the latest read tag, i.e., DataSize bytes after tag header
+
+	onPreviousTagSize func(size uint32) // called once a PreviousTagSize has been read
+
+	decryptionKey []byte // AES-128 key to decrypt tags with Filter=1
+}
+
+// decrypter decrypts body of tags with Filter=1.
+type decrypter interface {
+	Decrypt(key []byte) error
+}
+
+// NewReader creates FLV Reader.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		r:                &countReader{r: r},
+		exSequenceStarts: video.ExSequenceStarts{},
+	}
+}
+
+// ReadHeader reads FLV Header, it returns the header has been read if called again.
+func (fr *Reader) ReadHeader() (Header, error) {
+	if fr.headerRead {
+		return fr.header, nil
+	}
+
+	if err := fr.header.Parse(fr.r); err != nil {
+		return fr.header, err
+	}
+	fr.headerRead = true
+	return fr.header, nil
+}
+
+// SetDecryptionKey sets AES-128 key to decrypt tags with Filter=1, otherwise their bodies are kept encrypted.
+func (fr *Reader) SetDecryptionKey(key []byte) {
+	fr.decryptionKey = key
+}
+
+// Next reads the next tag, FLV Header will be read first if it hasn't been read.
+// It returns io.EOF once no more tags.
+func (fr *Reader) Next() (tag.Tag, error) {
+	if _, err := fr.ReadHeader(); err != nil {
+		return nil, err
+	}
+
+	// parse previous tag size
+	tagSizeData := make([]byte, 4) // fixed 4 bytes
+	fr.hasPrevTagSize = false
+	if err := util.ReadOrError(fr.r, tagSizeData); err != nil {
+		return nil, err
+	}
+	fr.previousTagSize, fr.hasPrevTagSize = binary.BigEndian.Uint32(tagSizeData), true
+	if fr.onPreviousTagSize != nil {
+		fr.onPreviousTagSize(fr.previousTagSize)
+	}
+	if fr.previousTagSize != uint32(fr.lastTagSize) {
+		glog.Warningf("PreviousTagSize %d != LastParsedTagSize %d", fr.previousTagSize, fr.lastTagSize)
+	}
+
+	// parse tag header
+	tagOffset := fr.r.n
+	tagHeader := tag.Header{}
+	if err := tagHeader.Parse(fr.r); err != nil {
+		return nil, err
+	}
+
+	// parse tag data
+	var t tag.Tag
+	if tagHeader.TagType == tag.TypeAudio {
+		t = &audio.Tag{Header: tagHeader}
+	} else if tagHeader.TagType == tag.TypeVideo {
+		videoTag := &video.Tag{Header: tagHeader}
+		videoTag.SetAVCConfig(fr.avcConfig)
+		videoTag.SetExSequenceStarts(fr.exSequenceStarts)
+		t = videoTag
+	} else if tagHeader.TagType == tag.TypeSriptData {
+		t = &script.Tag{Header: tagHeader}
+	}
+	if t == nil {
+		return nil, fmt.Errorf("invalid tag type %d", tagHeader.TagType)
+	}
+
+	// read the whole tag data at first, so that it can be forwarded as is even if not fully parsed
+	data := make([]byte, tagHeader.DataSize)
+	if err := util.ReadOrError(fr.r, data); err != nil {
+		return nil, err
+	}
+	if err := t.ParsePayload(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	if d, ok := t.(decrypter); ok && tagHeader.Filter == 1 && fr.decryptionKey != nil {
+		if err := d.Decrypt(fr.decryptionKey); err != nil {
+			return nil, fmt.Errorf("decrypt tag at offset %d failed, err %v", tagOffset, err)
+		}
+	}
+	fr.tagOffset, fr.tagData = tagOffset, data
+	fr.lastTagSize = t.Size() // cache parsed tag size for checking
+
+	if t.GetTagHeader().TagType == tag.TypeVideo { // cache avcConfig for later slice parsing
+		videoTag, ok := t.(*video.Tag)
+		if !ok {
+			return nil, fmt.Errorf("invalid video tag %v", videoTag)
+		}
+		if videoTag.VideoTagHeader.AVCPacketType != nil &&
+			*videoTag.VideoTagHeader.AVCPacketType == video.AVCPacketTypeSequenceHeader &&
+			videoTag.TagBody != nil && videoTag.TagBody.AVCVideoPacket != nil &&
+			videoTag.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord != nil {
+			fr.avcConfig = videoTag.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord
+		}
+	}
+	return t, nil
+}
+
+// PreviousTagSize returns the PreviousTagSize right before the tag returned by the latest Next,
+// or the last one once Next returns io.EOF. ok is false if it hasn't been read by the latest Next.
+func (fr *Reader) PreviousTagSize() (size uint32, ok bool) {
+	return fr.previousTagSize, fr.hasPrevTagSize
+}
+
+// TagOffset returns offset of the tag returned by the latest Next from the beginning of the input.
+func (fr *Reader) TagOffset() int64 {
+	return fr.tagOffset
+}
+
+// TagData returns raw data of the tag returned by the latest Next, i.e., DataSize bytes after tag header.
+// It's kept as read even if the tag has been decrypted.
+func (fr *Reader) TagData() []byte {
+	return fr.tagData
+}
+
+// countReader counts read bytes.
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
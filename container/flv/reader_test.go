@@ -0,0 +1,162 @@
+package flv
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/util/dump"
+)
+
+func TestReader(t *testing.T) {
+	flvFile := "../../assets/sintel_trailer-720p-firstgopfmp4.flv"
+
+	data, err := os.ReadFile(flvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := FLV{}
+	if err := f.Parse(bytes.NewReader(data)); err != io.EOF {
+		t.Fatalf("parse %s expect EOF but got %v", flvFile, err)
+	}
+
+	fr := NewReader(bytes.NewReader(data))
+	var count int
+	for {
+		tg, err := fr.Next()
+		if err == io.EOF {
+			if size, ok := fr.PreviousTagSize(); !ok || count >= len(f.PreviousTagSize) || size != f.PreviousTagSize[count] {
+				t.Errorf("expect the last PreviousTagSize %v but got %d(%v)", f.PreviousTagSize[len(f.PreviousTagSize)-1], size, ok)
+			}
+			break
+		} else if err != nil {
+			t.Fatalf("read tag %d expect nil but got %v", count, err)
+		}
+		if count >= len(f.Tags) {
+			t.Fatalf("expect %d tags but got more", len(f.Tags))
+		}
+		if size, ok := fr.PreviousTagSize(); !ok || size != f.PreviousTagSize[count] {
+			t.Errorf("tag %d expect PreviousTagSize %d but got %d(%v)", count, f.PreviousTagSize[count], size, ok)
+		}
+
+		// tag should be the same as parsed one, and locates at TagOffset
+		var expect, got bytes.Buffer
+		if err := f.Tags[count].Encode(&expect); err != nil {
+			t.Fatal(err)
+		}
+		if err := tg.Encode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Bytes(), expect.Bytes()) {
+			t.Errorf("tag %d expect same as parsed one", count)
+		}
+		if end := fr.TagOffset() + int64(got.Len()); end > int64(len(data)) || !bytes.Equal(data[fr.TagOffset():end], got.Bytes()) {
+			t.Errorf("tag %d expect locates at offset %d", count, fr.TagOffset())
+		}
+		if start := fr.TagOffset() + tag.HeaderSize; !bytes.Equal(fr.TagData(), data[start:start+int64(tg.GetTagHeader().DataSize)]) {
+			t.Errorf("tag %d expect raw data same as input", count)
+		}
+		count++
+	}
+	if count != len(f.Tags) {
+		t.Errorf("expect %d tags but got %d", len(f.Tags), count)
+	}
+}
+
+func TestHandlerStreaming(t *testing.T) {
+	flvFile := "../../assets/sintel_trailer-720p-firstgopfmp4.flv"
+
+	h := New(flvFile)
+	if err := h.Parse(); err != io.EOF {
+		t.Fatalf("parse %s expect EOF but got %v", flvFile, err)
+	}
+
+	var tags int
+	s := New(flvFile)
+	if err := s.Walk(func(tag.Tag) error { tags++; return nil }); err != nil {
+		t.Fatalf("walk expect nil but got %v", err)
+	}
+	if tags != len(h.Tags) || s.Header != h.Header {
+		t.Errorf("walk expect %d tags and header %v but got %d tags and header %v", len(h.Tags), h.Header, tags, s.Header)
+	}
+
+	for _, c := range []struct {
+		name   string
+		expect func(w io.Writer) error
+		got    func(w io.Writer) error
+	}{
+		{"es", func(w io.Writer) error {
+			e, err := h.ExtractES()
+			if err != nil {
+				return err
+			}
+			_, err = e.Dump(w)
+			return err
+		}, func(w io.Writer) error { return s.WriteES(w, false) }},
+		{"annexb_es", func(w io.Writer) error {
+			e, err := h.ExtractAnnexBES()
+			if err != nil {
+				return err
+			}
+			_, err = e.Dump(w)
+			return err
+		}, func(w io.Writer) error { return s.WriteES(w, true) }},
+		{"walk_es", func(w io.Writer) error {
+			e, err := h.ExtractES()
+			if err != nil {
+				return err
+			}
+			return writeJSON(w, e)
+		}, func(w io.Writer) error {
+			e, err := s.WalkES()
+			if err != nil {
+				return err
+			}
+			return writeJSON(w, e)
+		}},
+		{"aac", func(w io.Writer) error {
+			a, err := h.ExtractAudio()
+			if err != nil {
+				return err
+			}
+			_, err = a.Dump(w)
+			return err
+		}, s.WriteAudio},
+		{"mp4", func(w io.Writer) error {
+			b, err := h.ToMP4()
+			if err != nil {
+				return err
+			}
+			return b.Encode(w)
+		}, func(w io.Writer) error {
+			defer s.Close()
+			b, err := s.RemuxMP4()
+			if err != nil {
+				return err
+			}
+			return b.Encode(w)
+		}},
+	} {
+		var expect, got bytes.Buffer
+		if err := c.expect(&expect); err != nil {
+			t.Fatalf("%s expect nil but got %v", c.name, err)
+		}
+		if err := c.got(&got); err != nil {
+			t.Fatalf("streaming %s expect nil but got %v", c.name, err)
+		}
+		if expect.Len() == 0 || !bytes.Equal(got.Bytes(), expect.Bytes()) {
+			t.Errorf("streaming %s expect %d bytes same as in memory but got %d bytes", c.name, expect.Len(), got.Len())
+		}
+	}
+}
+
+func writeJSON(w io.Writer, m dump.Marshaler) error {
+	j, err := m.JSON()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(j)
+	return err
+}
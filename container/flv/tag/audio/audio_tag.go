@@ -2,9 +2,12 @@
 package audio
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/audio/aac"
 	"github.com/wangyoucao577/medialib/container/flv/tag"
 	"github.com/wangyoucao577/medialib/util"
 )
@@ -13,7 +16,11 @@ import (
 type Tag struct {
 	Header         tag.Header `json:"TagHeader"`
 	AudioTagHeader TagHeader  `json:"AudioTagHeader"`
-	Body           *TagBody   `json:"AudioTagBody"`
+
+	// only if Filter=1, Body will be nil unless decrypted
+	Encryption *tag.Encryption `json:"Encryption,omitempty"`
+
+	Body *TagBody `json:"AudioTagBody"`
 }
 
 // GetTagHeader returns tag header.
@@ -46,19 +53,117 @@ func (t *Tag) ParsePayload(r io.Reader) error {
 	}
 	remainBytes := uint64(t.Header.DataSize) - parsedBytes
 
+	if t.Header.Filter == 1 {
+		t.Encryption = &tag.Encryption{}
+		return t.Encryption.Parse(r, uint32(parsedBytes), uint32(remainBytes))
+	}
+
 	data := make([]byte, remainBytes)
 	if err := util.ReadOrError(r, data); err != nil {
 		return err
 	}
+	t.parseBody(data)
+	return nil
+}
 
+// Decrypt decrypts the encrypted body by AES-128 key and parses it as TagBody, Encryption will be kept for encoding.
+func (t *Tag) Decrypt(key []byte) error {
+	if t.Encryption == nil {
+		return fmt.Errorf("tag type %d(%s) is not encrypted", t.Header.TagType, tag.TypeDescription(int(t.Header.TagType)))
+	}
+	data, err := t.Encryption.Decrypt(key)
+	if err != nil {
+		return err
+	}
+	t.parseBody(data)
+	return nil
+}
+
+// parseBody parses data after AudioTagHeader as TagBody.
+func (t *Tag) parseBody(data []byte) {
 	// MUST AAC here, and MUST have AACPacketType
 	aacAudioData := &AACAudioData{}
 	if *t.AudioTagHeader.AACPacketType == AACPacketTypeSequenceHeader {
 		aacAudioData.AudioSpecificConfig = data
+
+		config := &aac.AudioSpecificConfig{}
+		if _, err := config.Parse(bytes.NewReader(data), len(data)); err != nil {
+			glog.Warningf("tag timestamp %d parse AudioSpecificConfig %x failed, err %v", t.Header.TimestampCalculated, data, err)
+		} else {
+			aacAudioData.DecodedAudioSpecificConfig = config
+			t.AudioTagHeader.checkAudioSpecificConfig(config, t.Header.TimestampCalculated)
+		}
 	} else {
 		aacAudioData.RawAACFrameData = data
 	}
 	t.Body = &TagBody{AACAudioData: aacAudioData}
+}
 
-	return nil
+// NewAACSequenceHeaderTag creates AAC sequence header tag by AudioSpecificConfig bytes.
+func NewAACSequenceHeaderTag(timestamp uint32, audioSpecificConfig []byte) *Tag {
+	return newAACTag(timestamp, AACPacketTypeSequenceHeader, &AACAudioData{AudioSpecificConfig: audioSpecificConfig})
+}
+
+// NewAACRawTag creates AAC raw tag by a raw AAC frame.
+func NewAACRawTag(timestamp uint32, rawAACFrameData []byte) *Tag {
+	return newAACTag(timestamp, AACPacketTypeRaw, &AACAudioData{RawAACFrameData: rawAACFrameData})
+}
+
+func newAACTag(timestamp uint32, aacPacketType uint8, aacAudioData *AACAudioData) *Tag {
+	t := &Tag{
+		Header: tag.NewHeader(tag.TypeAudio, timestamp),
+
+		// If the SoundFormat indicates AAC, the SoundType should be set to 1 (stereo) and the SoundRate should be set to 3 (44 kHz).
+		// However, this does not mean that AAC audio in FLV is always stereo, 44 kHz data.
+		// Instead, the Flash Player ignores these values and extracts the channel and sample rate data is encoded in the AAC bit stream.
+		AudioTagHeader: TagHeader{
+			SoundFormat:   SoundFormatAAC,
+			SoundRate:     SoundRate44,
+			SoundSize:     SoundSize16bit,
+			SoundType:     SoundTypeStereo,
+			AACPacketType: &aacPacketType,
+		},
+		Body: &TagBody{AACAudioData: aacAudioData},
+	}
+	t.Header.DataSize = t.encodedDataSize()
+	return t
+}
+
+func (t *Tag) encodedDataSize() uint32 {
+	size := t.AudioTagHeader.encodedSize()
+	if t.Encryption != nil {
+		return size + t.Encryption.EncodedSize()
+	}
+	if t.Body != nil && t.Body.AACAudioData != nil {
+		size += uint32(len(t.Body.AACAudioData.AudioSpecificConfig) + len(t.Body.AACAudioData.RawAACFrameData))
+	}
+	return size
+}
+
+// Encode writes tag header, AudioTagHeader and TagBody(or Encryption if Filter=1), DataSize will be calculated by payload.
+func (t *Tag) Encode(w io.Writer) error {
+	if t.AudioTagHeader.SoundFormat != SoundFormatAAC {
+		return fmt.Errorf("sound format %d(%s) doesn't support yet", t.AudioTagHeader.SoundFormat, SoundFormatDescription(int(t.AudioTagHeader.SoundFormat)))
+	}
+
+	h := t.Header
+	h.DataSize = t.encodedDataSize()
+	if err := h.Encode(w); err != nil {
+		return err
+	}
+	if err := t.AudioTagHeader.encode(w); err != nil {
+		return err
+	}
+
+	if t.Encryption != nil { // encrypted body will be written even if decrypted
+		return t.Encryption.Encode(w)
+	}
+	if t.Body == nil || t.Body.AACAudioData == nil {
+		return nil
+	}
+	if _, err := w.Write(t.Body.AACAudioData.AudioSpecificConfig); err != nil {
+		return err
+	}
+	_, err := w.Write(t.Body.AACAudioData.RawAACFrameData)
+	return err
 }
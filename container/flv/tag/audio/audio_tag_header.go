@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/audio/aac"
 	"github.com/wangyoucao577/medialib/util"
 )
 
@@ -107,3 +109,50 @@ func (t *TagHeader) parse(r io.Reader) (uint64, error) {
 
 	return parsedBytes, nil
 }
+
+func (t *TagHeader) encodedSize() uint32 {
+	if t.SoundFormat == SoundFormatAAC {
+		return 2
+	}
+	return 1
+}
+
+func (t *TagHeader) encode(w io.Writer) error {
+	data := []byte{(t.SoundFormat&0xF)<<4 | (t.SoundRate&0x3)<<2 | (t.SoundSize&0x1)<<1 | t.SoundType&0x1}
+	if t.SoundFormat == SoundFormatAAC {
+		if t.AACPacketType == nil {
+			return fmt.Errorf("sound format %d(%s) requires AACPacketType", t.SoundFormat, SoundFormatDescription(int(t.SoundFormat)))
+		}
+		data = append(data, *t.AACPacketType)
+	}
+
+	_, err := w.Write(data)
+	return err
+}
+
+// checkAudioSpecificConfig warns if SoundRate or SoundType contradicts the decoded AudioSpecificConfig.
+// Players ignore these header bits for AAC and use the AudioSpecificConfig instead, so it's not an error.
+// The spec requires SoundRate 3(44 kHz) and SoundType 1(stereo) for AAC, which are not checked.
+func (t *TagHeader) checkAudioSpecificConfig(config *aac.AudioSpecificConfig, timestamp int32) {
+	if t.SoundRate == SoundRate44 && t.SoundType == SoundTypeStereo {
+		return
+	}
+
+	if f := config.OutputFrequency(); f > 0 {
+		if rate := nearestSoundRate(f); rate != t.SoundRate {
+			glog.Warningf("tag timestamp %d SoundRate %d(%s) contradicts AudioSpecificConfig sampling frequency %d Hz(%s)",
+				timestamp, t.SoundRate, SoundRateDescription(int(t.SoundRate)), f, SoundRateDescription(int(rate)))
+		}
+	}
+
+	if channels := config.Channels(); channels > 0 {
+		soundType := uint8(SoundTypeStereo)
+		if channels == 1 {
+			soundType = SoundTypeMono
+		}
+		if soundType != t.SoundType {
+			glog.Warningf("tag timestamp %d SoundType %d(%s) contradicts AudioSpecificConfig %d channels",
+				timestamp, t.SoundType, SoundTypeDescription(int(t.SoundType)), channels)
+		}
+	}
+}
@@ -1,9 +1,14 @@
 package audio
 
+import "github.com/wangyoucao577/medialib/audio/aac"
+
 // AACAudioData reprensents AACAudioData.
 type AACAudioData struct {
 	AudioSpecificConfig []byte `json:"AudioSpecificConfig,omitempty"`
 	RawAACFrameData     []byte `json:"RawAACFrameData,omitempty"`
+
+	// decoded from AudioSpecificConfig bytes when parsing, not used for encoding
+	DecodedAudioSpecificConfig *aac.AudioSpecificConfig `json:"DecodedAudioSpecificConfig,omitempty"`
 }
 
 // TagBody represents audio tag payload.
@@ -23,3 +23,28 @@ func SoundRateDescription(t int) string {
 	}
 	return d
 }
+
+// soundRateFrequencies represents sampling frequency in Hz of each sound rate.
+var soundRateFrequencies = []float64{
+	SoundRate5p5: 5512.5,
+	SoundRate11:  11025,
+	SoundRate22:  22050,
+	SoundRate44:  44100,
+}
+
+// nearestSoundRate returns the sound rate that closest to the frequency, ratio rather than difference is compared
+// since sound rates are doubled one by one.
+func nearestSoundRate(frequency uint32) uint8 {
+	var nearest uint8
+	var nearestRatio float64
+	for rate, f := range soundRateFrequencies {
+		ratio := float64(frequency) / f
+		if ratio < 1 {
+			ratio = 1 / ratio
+		}
+		if rate == 0 || ratio < nearestRatio {
+			nearest, nearestRatio = uint8(rate), ratio
+		}
+	}
+	return nearest
+}
@@ -0,0 +1,216 @@
+package tag
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/wangyoucao577/medialib/util"
+)
+
+// Filter names of EncryptionTagHeader, defined in Annex F. FLV Encryption.
+const (
+	FilterNameEncryption          = "Encryption" // EncryptionFilterParams follows
+	FilterNameSelectiveEncryption = "SE"         // SelectiveEncryptionFilterParams follows
+)
+
+// IVSize is size of the initialization vector for AES-128-CBC.
+const IVSize = 16
+
+// Encryption represents EncryptionTagHeader, FilterParams and the encrypted body of a tag with Filter=1,
+// which locate after AudioTagHeader or VideoTagHeader, or at the beginning of script data tag.
+type Encryption struct {
+	EncryptionTagHeader EncryptionTagHeader `json:"EncryptionTagHeader"`
+	FilterParams        FilterParams        `json:"FilterParams"`
+
+	// Boundaries of EncryptedBody in the tag data, i.e., offset from the end of the tag header.
+	BodyOffset uint32 `json:"EncryptedBodyOffset"`
+	BodySize   uint32 `json:"EncryptedBodySize"`
+
+	EncryptedBody []byte `json:"-"`
+}
+
+// EncryptionTagHeader represents EncryptionTagHeader.
+type EncryptionTagHeader struct {
+	NumFilters uint8  `json:"NumFilters"` // Number of filters applied to the packet. Shall be 1.
+	FilterName string `json:"FilterName"` // Name of the filter, "Encryption" or "SE".
+	Length     uint32 `json:"Length"`     // Length of FilterParams in bytes. 24 bits
+}
+
+// FilterParams represents FilterParams, only one of them presents depends on FilterName.
+type FilterParams struct {
+	EncryptionFilterParams          *EncryptionFilterParams          `json:"EncryptionFilterParams,omitempty"`
+	SelectiveEncryptionFilterParams *SelectiveEncryptionFilterParams `json:"SelectiveEncryptionFilterParams,omitempty"`
+
+	Data []byte `json:"Data,omitempty"` // of unknown filter
+}
+
+// EncryptionFilterParams represents EncryptionFilterParams.
+type EncryptionFilterParams struct {
+	IV []byte `json:"IV"` // 16 bytes
+}
+
+// SelectiveEncryptionFilterParams represents SelectiveEncryptionFilterParams.
+type SelectiveEncryptionFilterParams struct {
+	EncryptedAU uint8 `json:"EncryptedAU"` // 1 bit, whether the packet is encrypted
+	// 7 bits reserved here
+
+	IV []byte `json:"IV,omitempty"` // 16 bytes, only if EncryptedAU
+}
+
+// Parse parses EncryptionTagHeader, FilterParams and encrypted body, offset is the parsed bytes of tag data before them,
+// and size is the remain bytes of tag data.
+func (e *Encryption) Parse(r io.Reader, offset, size uint32) error {
+	data := make([]byte, size)
+	if err := util.ReadOrError(r, data); err != nil {
+		return err
+	}
+
+	// NumFilters UI8, FilterName SCRIPTDATASTRING, Length UI24
+	if len(data) < 3 {
+		return fmt.Errorf("encryption tag header requires at least 3 bytes but only %d", len(data))
+	}
+	e.EncryptionTagHeader.NumFilters = data[0]
+	nameLength := int(binary.BigEndian.Uint16(data[1:3]))
+	pos := 3
+	if len(data) < pos+nameLength+3 {
+		return fmt.Errorf("encryption tag header filter name length %d but only %d bytes", nameLength, len(data)-pos)
+	}
+	e.EncryptionTagHeader.FilterName = string(data[pos : pos+nameLength])
+	pos += nameLength
+	e.EncryptionTagHeader.Length = binary.BigEndian.Uint32([]byte{0, data[pos], data[pos+1], data[pos+2]})
+	pos += 3
+
+	if len(data) < pos+int(e.EncryptionTagHeader.Length) {
+		return fmt.Errorf("filter params length %d but only %d bytes", e.EncryptionTagHeader.Length, len(data)-pos)
+	}
+	if err := e.FilterParams.parse(e.EncryptionTagHeader.FilterName, data[pos:pos+int(e.EncryptionTagHeader.Length)]); err != nil {
+		return err
+	}
+	pos += int(e.EncryptionTagHeader.Length)
+
+	e.BodyOffset = offset + uint32(pos)
+	e.BodySize = uint32(len(data) - pos)
+	e.EncryptedBody = data[pos:]
+	return nil
+}
+
+func (f *FilterParams) parse(filterName string, data []byte) error {
+	switch filterName {
+	case FilterNameEncryption:
+		if len(data) != IVSize {
+			return fmt.Errorf("EncryptionFilterParams expect %d bytes but got %d", IVSize, len(data))
+		}
+		f.EncryptionFilterParams = &EncryptionFilterParams{IV: data}
+	case FilterNameSelectiveEncryption:
+		if len(data) < 1 {
+			return fmt.Errorf("empty SelectiveEncryptionFilterParams")
+		}
+		f.SelectiveEncryptionFilterParams = &SelectiveEncryptionFilterParams{EncryptedAU: data[0] >> 7}
+		if f.SelectiveEncryptionFilterParams.EncryptedAU == 1 {
+			if len(data) != 1+IVSize {
+				return fmt.Errorf("SelectiveEncryptionFilterParams expect %d bytes but got %d", 1+IVSize, len(data))
+			}
+			f.SelectiveEncryptionFilterParams.IV = data[1:]
+		}
+	default:
+		f.Data = data
+	}
+	return nil
+}
+
+// EncodedSize returns size of encoded EncryptionTagHeader, FilterParams and encrypted body.
+func (e *Encryption) EncodedSize() uint32 {
+	return 1 + 2 + uint32(len(e.EncryptionTagHeader.FilterName)) + 3 + uint32(len(e.filterParams())) + uint32(len(e.EncryptedBody))
+}
+
+// Encode writes EncryptionTagHeader, FilterParams and encrypted body, Length will be calculated by FilterParams.
+func (e *Encryption) Encode(w io.Writer) error {
+	params := e.filterParams()
+	if len(e.EncryptionTagHeader.FilterName) > 0xFFFF || len(params) > 0xFFFFFF {
+		return fmt.Errorf("invalid filter name %q or filter params length %d", e.EncryptionTagHeader.FilterName, len(params))
+	}
+
+	if err := util.WriteBigEndian(w, e.EncryptionTagHeader.NumFilters, uint16(len(e.EncryptionTagHeader.FilterName))); err != nil {
+		return err
+	}
+	length := uint32(len(params))
+	return util.WriteBigEndian(w, []byte(e.EncryptionTagHeader.FilterName),
+		[]byte{byte(length >> 16), byte(length >> 8), byte(length)}, params, e.EncryptedBody)
+}
+
+func (e *Encryption) filterParams() []byte {
+	f := e.FilterParams
+	switch {
+	case f.EncryptionFilterParams != nil:
+		return f.EncryptionFilterParams.IV
+	case f.SelectiveEncryptionFilterParams != nil:
+		return append([]byte{f.SelectiveEncryptionFilterParams.EncryptedAU << 7}, f.SelectiveEncryptionFilterParams.IV...)
+	}
+	return f.Data
+}
+
+// Encrypted returns whether the body is encrypted, it's false only if the selective encryption indicates not.
+func (e *Encryption) Encrypted() bool {
+	return e.FilterParams.SelectiveEncryptionFilterParams == nil || e.FilterParams.SelectiveEncryptionFilterParams.EncryptedAU == 1
+}
+
+// Decrypt decrypts the body by AES-128-CBC with the key and IV of FilterParams, then removes the PKCS#7 padding.
+// The body will be returned as it is if it's not encrypted.
+func (e *Encryption) Decrypt(key []byte) ([]byte, error) {
+	if !e.Encrypted() {
+		return e.EncryptedBody, nil
+	}
+
+	var iv []byte
+	if e.FilterParams.EncryptionFilterParams != nil {
+		iv = e.FilterParams.EncryptionFilterParams.IV
+	} else if e.FilterParams.SelectiveEncryptionFilterParams != nil {
+		iv = e.FilterParams.SelectiveEncryptionFilterParams.IV
+	} else {
+		return nil, fmt.Errorf("filter %q doesn't support yet", e.EncryptionTagHeader.FilterName)
+	}
+
+	data, err := DecryptAES128CBC(key, iv, e.EncryptedBody)
+	if err != nil {
+		return nil, err
+	}
+
+	// remove padding
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty encrypted body")
+	}
+	padding := int(data[len(data)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(data) {
+		return nil, fmt.Errorf("invalid padding %d, the key may be wrong", padding)
+	}
+	for _, b := range data[len(data)-padding:] {
+		if int(b) != padding {
+			return nil, fmt.Errorf("invalid padding %d, the key may be wrong", padding)
+		}
+	}
+	return data[:len(data)-padding], nil
+}
+
+// DecryptAES128CBC decrypts data by AES-128-CBC without removing padding.
+func DecryptAES128CBC(key, iv, data []byte) ([]byte, error) {
+	if len(key) != 16 {
+		return nil, fmt.Errorf("AES-128 key expect 16 bytes but got %d", len(key))
+	}
+	if len(iv) != IVSize {
+		return nil, fmt.Errorf("IV expect %d bytes but got %d", IVSize, len(iv))
+	}
+	if len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("encrypted data size %d is not multiple of block size %d", len(data), aes.BlockSize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+	return out, nil
+}
@@ -5,6 +5,7 @@ import "io"
 // Tag represents tag mandantory functions.
 type Tag interface {
 	ParsePayload(io.Reader) error
+	Encode(io.Writer) error // writes tag header and payload
 
 	GetTagHeader() Header
 	Size() int64 // total bytes of the tag, equal to (HeaderSize(11bytes) + DataSize)
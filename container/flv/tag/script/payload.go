@@ -30,3 +30,26 @@ func (t *TagBody) parse(r io.Reader) (uint64, error) {
 
 	return parsedBytes, nil
 }
+
+func (t *TagBody) encodedSize() (uint32, error) {
+	n, err := t.encode(io.Discard)
+	return uint32(n), err
+}
+
+func (t *TagBody) encode(w io.Writer) (int, error) {
+	var encodedBytes int
+
+	if bytes, err := t.Name.Encode(w); err != nil {
+		return encodedBytes, err
+	} else {
+		encodedBytes += bytes
+	}
+
+	if bytes, err := t.Value.Encode(w); err != nil {
+		return encodedBytes, err
+	} else {
+		encodedBytes += bytes
+	}
+
+	return encodedBytes, nil
+}
@@ -2,16 +2,27 @@
 package script
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 
 	"github.com/golang/glog"
 	"github.com/wangyoucao577/medialib/container/flv/tag"
 	"github.com/wangyoucao577/medialib/util"
+	"github.com/wangyoucao577/medialib/util/amf/amf0"
 )
 
+// OnMetaData is the name of script data tag that carries metadata of the FLV file.
+const OnMetaData = "onMetaData"
+
+// Tag represents script data tag.
 type Tag struct {
-	Header  tag.Header `json:"TagHeader"`
-	TagBody *TagBody   `json:"TagBody,omitempty"`
+	Header tag.Header `json:"TagHeader"`
+
+	// only if Filter=1, TagBody will be nil unless decrypted
+	Encryption *tag.Encryption `json:"Encryption,omitempty"`
+
+	TagBody *TagBody `json:"TagBody,omitempty"`
 }
 
 // GetTagHeader returns tag header.
@@ -30,6 +41,27 @@ func (t *Tag) ParsePayload(r io.Reader) error {
 		return err
 	}
 
+	if t.Header.Filter == 1 {
+		t.Encryption = &tag.Encryption{}
+		return t.Encryption.Parse(r, 0, t.Header.DataSize)
+	}
+	return t.parseBody(r, uint64(t.Header.DataSize))
+}
+
+// Decrypt decrypts the encrypted body by AES-128 key and parses it as TagBody, Encryption will be kept for encoding.
+func (t *Tag) Decrypt(key []byte) error {
+	if t.Encryption == nil {
+		return fmt.Errorf("tag type %d(%s) is not encrypted", t.Header.TagType, tag.TypeDescription(int(t.Header.TagType)))
+	}
+	data, err := t.Encryption.Decrypt(key)
+	if err != nil {
+		return err
+	}
+	return t.parseBody(bytes.NewReader(data), uint64(len(data)))
+}
+
+// parseBody parses bodySize bytes as TagBody.
+func (t *Tag) parseBody(r io.Reader, bodySize uint64) error {
 	var parsedBytes uint64
 
 	t.TagBody = &TagBody{}
@@ -39,8 +71,8 @@ func (t *Tag) ParsePayload(r io.Reader) error {
 		parsedBytes += bytes
 	}
 
-	if parsedBytes < uint64(t.Header.DataSize) {
-		remainBytes := uint64(t.Header.DataSize) - parsedBytes
+	if parsedBytes < bodySize {
+		remainBytes := bodySize - parsedBytes
 		glog.Warningf("tag type %d(%s) still has %d bytes NOT parse",
 			t.Header.TagType, tag.TypeDescription(int(t.Header.TagType)), remainBytes)
 		if err := util.ReadOrError(r, make([]byte, remainBytes)); err != nil {
@@ -50,3 +82,50 @@ func (t *Tag) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// NewTag creates script data tag by name and value.
+func NewTag(timestamp uint32, name string, value amf0.ValueType) (*Tag, error) {
+	t := &Tag{
+		Header:  tag.NewHeader(tag.TypeSriptData, timestamp),
+		TagBody: &TagBody{Name: amf0.NewString(name), Value: value},
+	}
+
+	var err error
+	if t.Header.DataSize, err = t.TagBody.encodedSize(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// NewOnMetaDataTag creates `onMetaData` script data tag with properties in ECMA array,
+// e.g., duration, width, height, videocodecid, audiocodecid, etc.
+func NewOnMetaDataTag(properties ...amf0.ObjectProperty) (*Tag, error) {
+	return NewTag(0, OnMetaData, amf0.NewECMAArray(properties...))
+}
+
+// Encode writes tag header and TagBody(or Encryption if Filter=1), DataSize will be calculated by payload.
+func (t *Tag) Encode(w io.Writer) error {
+	if t.Encryption != nil { // encrypted body will be written even if decrypted
+		h := t.Header
+		h.DataSize = t.Encryption.EncodedSize()
+		if err := h.Encode(w); err != nil {
+			return err
+		}
+		return t.Encryption.Encode(w)
+	}
+	if t.TagBody == nil {
+		return fmt.Errorf("empty script data tag body")
+	}
+
+	h := t.Header
+	var err error
+	if h.DataSize, err = t.TagBody.encodedSize(); err != nil {
+		return err
+	}
+	if err := h.Encode(w); err != nil {
+		return err
+	}
+
+	_, err = t.TagBody.encode(w)
+	return err
+}
@@ -108,3 +108,37 @@ func (h Header) Validate() error {
 
 	return nil
 }
+
+// NewHeader creates tag header by tag type and timestamp in milliseconds,
+// DataSize should be set later once payload is ready.
+func NewHeader(tagType uint8, timestamp uint32) Header {
+	return Header{
+		TagType:             tagType,
+		Timestamp:           timestamp & 0xFFFFFF,
+		TimestampExtended:   uint8(timestamp >> 24),
+		TimestampCalculated: int32(timestamp),
+	}
+}
+
+// Encode writes Tag Header.
+func (h Header) Encode(w io.Writer) error {
+	if h.DataSize > 0xFFFFFF {
+		return fmt.Errorf("tag type %d data size %d exceeds 24 bits", h.TagType, h.DataSize)
+	}
+
+	data := make([]byte, HeaderSize)
+	data[0] = (h.Filter&0x1)<<5 | h.TagType&0x1F
+	putUint24(data[1:], h.DataSize)
+	putUint24(data[4:], h.Timestamp)
+	data[7] = h.TimestampExtended
+	putUint24(data[8:], h.StreamID)
+
+	_, err := w.Write(data)
+	return err
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0] = byte(v >> 16)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v)
+}
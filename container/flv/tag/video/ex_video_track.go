@@ -0,0 +1,313 @@
+package video
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	av1c "github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/av1C"
+	avcc "github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/avcC"
+	hvcc "github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/hvcC"
+	"github.com/wangyoucao577/medialib/util/amf/amf0"
+	"github.com/wangyoucao577/medialib/video/avc/es"
+	hevces "github.com/wangyoucao577/medialib/video/hevc/es"
+)
+
+// ExVideoTrack represents a track in enhanced video tag body, which is the only one with TrackID 0 if not multitrack.
+type ExVideoTrack struct {
+	FourCC  string `json:"FourCC"`
+	TrackID uint8  `json:"TrackID"`
+
+	// Composition time offset in milliseconds, only presents in coded frames of AVC and HEVC.
+	CompositionTime *int32 `json:"CompositionTime,omitempty"`
+
+	// sequence start
+	AVCDecoderConfigurationRecord  *avcc.AVCDecoderConfigurationRecord  `json:"avc_config,omitempty"`
+	HEVCDecoderConfigurationRecord *hvcc.HEVCDecoderConfigurationRecord `json:"hevc_config,omitempty"`
+	AV1CodecConfigurationRecord    *av1c.AV1CodecConfigurationRecord    `json:"av1_config,omitempty"`
+
+	// coded frames
+	LengthNALU     []es.LengthNALU     `json:"length_nalu,omitempty"`      // AVC
+	HEVCLengthNALU []hevces.LengthNALU `json:"hevc_length_nalu,omitempty"` // HEVC
+
+	// metadata, e.g., colorInfo
+	MetadataName  *amf0.ValueType `json:"MetadataName,omitempty"`
+	MetadataValue *amf0.ValueType `json:"MetadataValue,omitempty"`
+
+	// Payload that isn't parsed, e.g., VP9 configuration record, AV1 or VP9 frames, MPEG2-TS sequence start.
+	Data []byte `json:"-"`
+
+	lengthSize uint32 `json:"-"` // size of the length before each NAL unit in coded frames
+}
+
+// ExSequenceStarts caches sequence start tracks by TrackID, which are required to parse coded frames of enhanced video tags.
+type ExSequenceStarts map[uint8]*ExVideoTrack
+
+// SetLengthSize sets size of the length before each NAL unit for encoding AVC or HEVC coded frames, 4 by default.
+func (e *ExVideoTrack) SetLengthSize(l uint32) {
+	e.lengthSize = l
+}
+
+// LengthSize returns size of the length before each NAL unit, which comes from decoder configuration record if exist.
+func (e *ExVideoTrack) LengthSize() uint32 {
+	if e.AVCDecoderConfigurationRecord != nil {
+		return e.AVCDecoderConfigurationRecord.LengthSize()
+	}
+	if e.HEVCDecoderConfigurationRecord != nil {
+		return e.HEVCDecoderConfigurationRecord.LengthSize()
+	}
+	if e.lengthSize > 0 {
+		return e.lengthSize
+	}
+	return 4
+}
+
+// hasCompositionTime returns whether coded frames of the codec contain composition time offset.
+func (e *ExVideoTrack) hasCompositionTime(packetType uint8) bool {
+	return packetType == PacketTypeCodedFrames && (e.FourCC == FourCCAVC || e.FourCC == FourCCHEVC)
+}
+
+func (e *ExVideoTrack) parse(data []byte, packetType uint8, sequenceStarts ExSequenceStarts) error {
+	switch packetType {
+	case PacketTypeSequenceStart:
+		r := bytes.NewReader(data)
+		switch e.FourCC {
+		case FourCCAVC:
+			e.AVCDecoderConfigurationRecord = &avcc.AVCDecoderConfigurationRecord{}
+			if _, err := e.AVCDecoderConfigurationRecord.Parse(r); err != nil {
+				return err
+			}
+		case FourCCHEVC:
+			e.HEVCDecoderConfigurationRecord = &hvcc.HEVCDecoderConfigurationRecord{}
+			if _, err := e.HEVCDecoderConfigurationRecord.Parse(r); err != nil {
+				return err
+			}
+		case FourCCAV1:
+			e.AV1CodecConfigurationRecord = &av1c.AV1CodecConfigurationRecord{}
+			if _, err := e.AV1CodecConfigurationRecord.Parse(r); err != nil {
+				return err
+			}
+			e.AV1CodecConfigurationRecord.ConfigOBUs = data[len(data)-r.Len():]
+		default:
+			e.Data = data
+		}
+		if sequenceStarts != nil {
+			sequenceStarts[e.TrackID] = e
+		}
+
+	case PacketTypeCodedFrames, PacketTypeCodedFramesX:
+		if e.FourCC != FourCCAVC && e.FourCC != FourCCHEVC {
+			e.Data = data
+			return nil
+		}
+
+		if e.hasCompositionTime(packetType) {
+			if len(data) < 3 {
+				return fmt.Errorf("track %d expect composition time but only %d bytes", e.TrackID, len(data))
+			}
+			cts := int32(binary.BigEndian.Uint32([]byte{data[0], data[1], data[2], 0x00})) >> 8 // SI24
+			e.CompositionTime = &cts
+			data = data[3:]
+		}
+
+		var start *ExVideoTrack
+		if s, ok := sequenceStarts[e.TrackID]; ok && s.FourCC == e.FourCC {
+			start = s
+			e.lengthSize = s.LengthSize()
+		}
+		if e.FourCC == FourCCAVC {
+			videoES := es.ElementaryStream{}
+			videoES.SetLengthSize(e.LengthSize())
+			if start != nil && start.AVCDecoderConfigurationRecord != nil &&
+				len(start.AVCDecoderConfigurationRecord.LengthSPSNALU) > 0 && len(start.AVCDecoderConfigurationRecord.LengthPPSNALU) > 0 {
+				videoES.SetSequenceHeaders(start.AVCDecoderConfigurationRecord.LengthSPSNALU[0].NALUnit.SequenceParameterSetData,
+					start.AVCDecoderConfigurationRecord.LengthPPSNALU[0].NALUnit.PictureParameterSet)
+			}
+			if _, err := videoES.Parse(bytes.NewReader(data), len(data)); err != nil {
+				return err
+			}
+			e.LengthNALU = videoES.LengthNALU
+		} else {
+			videoES := hevces.ElementaryStream{}
+			videoES.SetLengthSize(e.LengthSize())
+			if _, err := videoES.Parse(bytes.NewReader(data), len(data)); err != nil {
+				return err
+			}
+			e.HEVCLengthNALU = videoES.LengthNALU
+		}
+
+	case PacketTypeMetadata:
+		r := bytes.NewReader(data)
+		e.MetadataName, e.MetadataValue = &amf0.ValueType{}, &amf0.ValueType{}
+		if _, err := e.MetadataName.Decode(r); err != nil {
+			return err
+		}
+		if _, err := e.MetadataValue.Decode(r); err != nil {
+			return err
+		}
+
+	default: // e.g., sequence end, MPEG2-TS sequence start
+		if len(data) > 0 {
+			e.Data = data
+		}
+	}
+
+	return nil
+}
+
+func (e *ExVideoTrack) encode(w io.Writer, packetType uint8) error {
+	switch packetType {
+	case PacketTypeSequenceStart:
+		if e.AVCDecoderConfigurationRecord != nil {
+			return e.AVCDecoderConfigurationRecord.Encode(w)
+		}
+		if e.HEVCDecoderConfigurationRecord != nil {
+			return e.HEVCDecoderConfigurationRecord.Encode(w)
+		}
+		if e.AV1CodecConfigurationRecord != nil {
+			return e.AV1CodecConfigurationRecord.Encode(w)
+		}
+
+	case PacketTypeCodedFrames, PacketTypeCodedFramesX:
+		if e.hasCompositionTime(packetType) {
+			var cts int32
+			if e.CompositionTime != nil {
+				cts = *e.CompositionTime
+			}
+			if _, err := w.Write([]byte{byte(cts >> 16), byte(cts >> 8), byte(cts)}); err != nil {
+				return err
+			}
+		}
+
+		var raws [][]byte
+		for i := range e.LengthNALU {
+			raws = append(raws, e.LengthNALU[i].NALU.Raw())
+		}
+		for i := range e.HEVCLengthNALU {
+			raws = append(raws, e.HEVCLengthNALU[i].NALU.Raw())
+		}
+		lengthSize := e.LengthSize()
+		if len(raws) > 0 && (lengthSize == 0 || lengthSize > 4) {
+			return fmt.Errorf("invalid length size %d", lengthSize)
+		}
+		length := make([]byte, 4)
+		for _, raw := range raws {
+			binary.BigEndian.PutUint32(length, uint32(len(raw)))
+			if _, err := w.Write(length[4-lengthSize:]); err != nil {
+				return err
+			}
+			if _, err := w.Write(raw); err != nil {
+				return err
+			}
+		}
+
+	case PacketTypeMetadata:
+		if e.MetadataName != nil && e.MetadataValue != nil {
+			if _, err := e.MetadataName.Encode(w); err != nil {
+				return err
+			}
+			_, err := e.MetadataValue.Encode(w)
+			return err
+		}
+	}
+
+	_, err := w.Write(e.Data)
+	return err
+}
+
+// parseExVideoTracks parses tracks of enhanced video tag body.
+func (t *Tag) parseExVideoTracks(data []byte) ([]ExVideoTrack, error) {
+	h := &t.VideoTagHeader
+	var tracks []ExVideoTrack
+
+	for {
+		track := ExVideoTrack{FourCC: h.FourCC}
+		payload := data
+		if h.MultitrackType != nil {
+			if *h.MultitrackType == MultitrackTypeManyTracksManyCodecs {
+				if len(data) < 4 {
+					return tracks, fmt.Errorf("expect FourCC but only %d bytes", len(data))
+				}
+				track.FourCC = string(data[:4])
+				data = data[4:]
+			}
+			if len(data) < 1 {
+				return tracks, fmt.Errorf("expect TrackID but only %d bytes", len(data))
+			}
+			track.TrackID = data[0]
+			data = data[1:]
+
+			payload = data
+			if *h.MultitrackType != MultitrackTypeOneTrack {
+				if len(data) < 3 {
+					return tracks, fmt.Errorf("expect size of track %d but only %d bytes", track.TrackID, len(data))
+				}
+				size := int(binary.BigEndian.Uint32([]byte{0, data[0], data[1], data[2]}))
+				data = data[3:]
+				if len(data) < size {
+					return tracks, fmt.Errorf("track %d size %d but only %d bytes", track.TrackID, size, len(data))
+				}
+				payload = data[:size]
+			}
+		}
+		data = data[len(payload):]
+
+		if err := track.parse(payload, *h.PacketType, t.exSequenceStarts); err != nil {
+			return tracks, fmt.Errorf("track %d FourCC %s parse failed, err %v", track.TrackID, track.FourCC, err)
+		}
+		tracks = append(tracks, track)
+
+		if h.MultitrackType == nil || *h.MultitrackType == MultitrackTypeOneTrack || len(data) == 0 {
+			break
+		}
+	}
+
+	return tracks, nil
+}
+
+// encodeExVideoTracks writes tracks of enhanced video tag body.
+func (t *Tag) encodeExVideoTracks(w io.Writer) error {
+	if t.TagBody == nil {
+		return nil
+	}
+	h := &t.VideoTagHeader
+
+	for i := range t.TagBody.ExVideoTracks {
+		track := &t.TagBody.ExVideoTracks[i]
+		if h.MultitrackType == nil {
+			return track.encode(w, *h.PacketType)
+		}
+
+		if *h.MultitrackType == MultitrackTypeManyTracksManyCodecs {
+			if len(track.FourCC) != 4 {
+				return fmt.Errorf("invalid FourCC %q of track %d", track.FourCC, track.TrackID)
+			}
+			if _, err := w.Write([]byte(track.FourCC)); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write([]byte{track.TrackID}); err != nil {
+			return err
+		}
+		if *h.MultitrackType == MultitrackTypeOneTrack {
+			return track.encode(w, *h.PacketType)
+		}
+
+		var buf bytes.Buffer
+		if err := track.encode(&buf, *h.PacketType); err != nil {
+			return err
+		}
+		if buf.Len() > 0xFFFFFF {
+			return fmt.Errorf("track %d size %d overflow", track.TrackID, buf.Len())
+		}
+		size := uint32(buf.Len())
+		if _, err := w.Write([]byte{byte(size >> 16), byte(size >> 8), byte(size)}); err != nil {
+			return err
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
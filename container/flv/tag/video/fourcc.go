@@ -0,0 +1,9 @@
+package video
+
+// FourCC of video codecs in enhanced video tag.
+const (
+	FourCCAVC  = "avc1"
+	FourCCHEVC = "hvc1"
+	FourCCAV1  = "av01"
+	FourCCVP9  = "vp09"
+)
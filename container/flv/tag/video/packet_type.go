@@ -0,0 +1,66 @@
+package video
+
+// Packet Types of enhanced video tag, see Enhanced RTMP v2.
+const (
+	PacketTypeSequenceStart        = 0
+	PacketTypeCodedFrames          = 1
+	PacketTypeSequenceEnd          = 2
+	PacketTypeCodedFramesX         = 3
+	PacketTypeMetadata             = 4
+	PacketTypeMPEG2TSSequenceStart = 5
+	PacketTypeMultitrack           = 6
+	PacketTypeModEx                = 7
+)
+
+var packetTypeDescriptions = map[int]string{
+	PacketTypeSequenceStart:        "sequence start",
+	PacketTypeCodedFrames:          "coded frames",
+	PacketTypeSequenceEnd:          "sequence end",
+	PacketTypeCodedFramesX:         "coded frames without composition time offset",
+	PacketTypeMetadata:             "metadata",
+	PacketTypeMPEG2TSSequenceStart: "MPEG2-TS sequence start",
+	PacketTypeMultitrack:           "multitrack",
+	PacketTypeModEx:                "modifier extension",
+}
+
+// PacketTypeDescription returns description of enhanced video Packet Type.
+func PacketTypeDescription(t int) string {
+	d, ok := packetTypeDescriptions[t]
+	if !ok {
+		return ""
+	}
+	return d
+}
+
+// Multitrack Types of enhanced video tag.
+const (
+	MultitrackTypeOneTrack             = 0
+	MultitrackTypeManyTracks           = 1
+	MultitrackTypeManyTracksManyCodecs = 2
+)
+
+var multitrackTypeDescriptions = map[int]string{
+	MultitrackTypeOneTrack:             "one track",
+	MultitrackTypeManyTracks:           "many tracks",
+	MultitrackTypeManyTracksManyCodecs: "many tracks many codecs",
+}
+
+// MultitrackTypeDescription returns description of Multitrack Type.
+func MultitrackTypeDescription(t int) string {
+	d, ok := multitrackTypeDescriptions[t]
+	if !ok {
+		return ""
+	}
+	return d
+}
+
+// Video Commands, which present if FrameType is video info/command frame and Packet Type isn't metadata.
+const (
+	VideoCommandStartSeek = 0
+	VideoCommandEndSeek   = 1
+)
+
+// ModEx Types
+const (
+	ModExTypeTimestampOffsetNano = 0
+)
@@ -12,5 +12,8 @@ type AVCVideoPacket struct {
 
 // TagBody represents video tag payload.
 type TagBody struct {
-	AVCVideoPacket *AVCVideoPacket `json:"AVCVideoPacket"`
+	AVCVideoPacket *AVCVideoPacket `json:"AVCVideoPacket,omitempty"`
+
+	// Tracks of enhanced video tag, only one if not multitrack.
+	ExVideoTracks []ExVideoTrack `json:"ExVideoTracks,omitempty"`
 }
@@ -2,6 +2,8 @@
 package video
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
 
@@ -16,9 +18,14 @@ import (
 type Tag struct {
 	Header         tag.Header `json:"TagHeader"`
 	VideoTagHeader TagHeader  `json:"VideoTagHeader"`
-	TagBody        *TagBody   `json:"VideoTagBody,omitempty"`
 
-	avcConfig *avcc.AVCDecoderConfigurationRecord `json:"-"`
+	// only if Filter=1, TagBody will be nil unless decrypted
+	Encryption *tag.Encryption `json:"Encryption,omitempty"`
+
+	TagBody *TagBody `json:"VideoTagBody,omitempty"`
+
+	avcConfig        *avcc.AVCDecoderConfigurationRecord `json:"-"`
+	exSequenceStarts ExSequenceStarts                    `json:"-"`
 }
 
 // SetAVCConfig pass in AVCDecoderConfigurationRecord for slice parsing.
@@ -26,6 +33,12 @@ func (t *Tag) SetAVCConfig(avcConfig *avcc.AVCDecoderConfigurationRecord) {
 	t.avcConfig = avcConfig
 }
 
+// SetExSequenceStarts pass in sequence starts of enhanced video tracks for coded frames parsing.
+// Sequence starts parsed by this tag will be stored in it too.
+func (t *Tag) SetExSequenceStarts(s ExSequenceStarts) {
+	t.exSequenceStarts = s
+}
+
 // GetTagHeader returns tag header.
 func (t *Tag) GetTagHeader() tag.Header {
 	return t.Header
@@ -55,18 +68,46 @@ func (t *Tag) ParsePayload(r io.Reader) error {
 			t.Header.DataSize, parsedBytes)
 	}
 
+	bodySize := uint64(t.Header.DataSize) - parsedBytes
+
+	if t.Header.Filter == 1 {
+		t.Encryption = &tag.Encryption{}
+		return t.Encryption.Parse(r, uint32(parsedBytes), uint32(bodySize))
+	}
+	return t.parseBody(r, bodySize)
+}
+
+// Decrypt decrypts the encrypted body by AES-128 key and parses it as TagBody, Encryption will be kept for encoding.
+func (t *Tag) Decrypt(key []byte) error {
+	if t.Encryption == nil {
+		return fmt.Errorf("tag type %d(%s) is not encrypted", t.Header.TagType, tag.TypeDescription(int(t.Header.TagType)))
+	}
+	data, err := t.Encryption.Decrypt(key)
+	if err != nil {
+		return err
+	}
+	return t.parseBody(bytes.NewReader(data), uint64(len(data)))
+}
+
+// parseBody parses bodySize bytes after VideoTagHeader as TagBody.
+func (t *Tag) parseBody(r io.Reader, bodySize uint64) error {
+	if t.VideoTagHeader.IsExHeader {
+		return t.parseExPayload(r, bodySize)
+	}
+
 	if t.VideoTagHeader.CodecID != CodecIDAVC {
 		//TODO: parse payload
 		glog.Warningf("tag type %d(%s) codec %d(%s) doesn't implemented yet, ignore size %d",
 			t.Header.TagType, tag.TypeDescription(int(t.Header.TagType)),
 			t.VideoTagHeader.CodecID, CodecIDDescription(int(t.VideoTagHeader.CodecID)),
-			t.Header.DataSize-uint32(parsedBytes))
-		if err := util.ReadOrError(r, make([]byte, t.Header.DataSize-uint32(parsedBytes))); err != nil {
+			bodySize)
+		if err := util.ReadOrError(r, make([]byte, bodySize)); err != nil {
 			return err
 		}
 		return nil
 	}
 
+	var parsedBytes uint64
 	var tagBody *TagBody
 	if *t.VideoTagHeader.AVCPacketType == AVCPacketTypeSequenceHeader {
 		tagBody = &TagBody{AVCVideoPacket: &AVCVideoPacket{}}
@@ -85,7 +126,7 @@ func (t *Tag) ParsePayload(r io.Reader) error {
 					t.avcConfig.LengthPPSNALU[0].NALUnit.PictureParameterSet)
 			}
 		}
-		if bytes, err := videoES.Parse(r, int(t.Header.DataSize-uint32(parsedBytes))); err != nil {
+		if bytes, err := videoES.Parse(r, int(bodySize)); err != nil {
 			return err
 		} else {
 			parsedBytes += bytes
@@ -97,8 +138,8 @@ func (t *Tag) ParsePayload(r io.Reader) error {
 	// else // nothing to do, no payload need to parse
 	t.TagBody = tagBody
 
-	if parsedBytes < uint64(t.Header.DataSize) {
-		remainBytes := uint64(t.Header.DataSize) - parsedBytes
+	if parsedBytes < bodySize {
+		remainBytes := bodySize - parsedBytes
 		glog.Warningf("tag type %d(%s) still has %d bytes NOT parse",
 			t.Header.TagType, tag.TypeDescription(int(t.Header.TagType)), remainBytes)
 		if err := util.ReadOrError(r, make([]byte, remainBytes)); err != nil {
@@ -108,3 +149,216 @@ func (t *Tag) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// parseExPayload parses bodySize bytes of enhanced video tag body after ExVideoTagHeader.
+func (t *Tag) parseExPayload(r io.Reader, bodySize uint64) error {
+	data := make([]byte, bodySize)
+	if err := util.ReadOrError(r, data); err != nil {
+		return err
+	}
+
+	if t.VideoTagHeader.VideoCommand != nil { // no body for video command
+		if len(data) > 0 {
+			glog.Warningf("tag type %d(%s) video command %d still has %d bytes NOT parse",
+				t.Header.TagType, tag.TypeDescription(int(t.Header.TagType)), *t.VideoTagHeader.VideoCommand, len(data))
+		}
+		return nil
+	}
+
+	tracks, err := t.parseExVideoTracks(data)
+	if err != nil {
+		return err
+	}
+	t.TagBody = &TagBody{ExVideoTracks: tracks}
+	return nil
+}
+
+// NewAVCSequenceHeaderTag creates AVC sequence header tag by AVCDecoderConfigurationRecord.
+func NewAVCSequenceHeaderTag(timestamp uint32, avcConfig *avcc.AVCDecoderConfigurationRecord) *Tag {
+	return newAVCTag(timestamp, FrameTypeKey, AVCPacketTypeSequenceHeader, 0, avcConfig,
+		&TagBody{AVCVideoPacket: &AVCVideoPacket{AVCDecoderConfigurationRecord: avcConfig}})
+}
+
+// NewAVCNALUTag creates AVC NALU tag by NAL units of a frame,
+// the avcConfig is required to decide size of the length before each NAL unit.
+func NewAVCNALUTag(timestamp uint32, compositionTime int32, keyFrame bool,
+	avcConfig *avcc.AVCDecoderConfigurationRecord, lengthNALU []es.LengthNALU) *Tag {
+	frameType := uint8(FrameTypeInner)
+	if keyFrame {
+		frameType = FrameTypeKey
+	}
+	return newAVCTag(timestamp, frameType, AVCPacketTypeNALU, compositionTime, avcConfig,
+		&TagBody{AVCVideoPacket: &AVCVideoPacket{LengthNALU: lengthNALU}})
+}
+
+// NewAVCEndOfSequenceTag creates AVC end of sequence tag.
+func NewAVCEndOfSequenceTag(timestamp uint32) *Tag {
+	return newAVCTag(timestamp, FrameTypeKey, AVCPacketTypeEOS, 0, nil, nil)
+}
+
+func newAVCTag(timestamp uint32, frameType, avcPacketType uint8, compositionTime int32,
+	avcConfig *avcc.AVCDecoderConfigurationRecord, tagBody *TagBody) *Tag {
+	t := &Tag{
+		Header: tag.NewHeader(tag.TypeVideo, timestamp),
+		VideoTagHeader: TagHeader{
+			FrameType:       frameType,
+			CodecID:         CodecIDAVC,
+			AVCPacketType:   &avcPacketType,
+			CompositionTime: &compositionTime,
+		},
+		TagBody:   tagBody,
+		avcConfig: avcConfig,
+	}
+	t.Header.DataSize = t.encodedDataSize()
+	return t
+}
+
+// NewExVideoTag creates enhanced video tag by tracks, multitrack will be used if more than one tracks or TrackID isn't 0.
+// Size of the length before each NAL unit for AVC or HEVC coded frames can be set by ExVideoTrack.SetLengthSize.
+func NewExVideoTag(timestamp uint32, frameType, packetType uint8, tracks ...ExVideoTrack) (*Tag, error) {
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("enhanced video tag requires at least one track")
+	}
+
+	h := TagHeader{
+		FrameType:  frameType,
+		IsExHeader: true,
+		PacketType: &packetType,
+		FourCC:     tracks[0].FourCC,
+	}
+	if len(tracks) > 1 || tracks[0].TrackID != 0 {
+		multitrackType := uint8(MultitrackTypeOneTrack)
+		if len(tracks) > 1 {
+			multitrackType = MultitrackTypeManyTracks
+		}
+		for i := range tracks {
+			if tracks[i].FourCC != h.FourCC {
+				multitrackType = MultitrackTypeManyTracksManyCodecs
+				h.FourCC = ""
+				break
+			}
+		}
+		h.MultitrackType = &multitrackType
+	}
+
+	t := &Tag{
+		Header:         tag.NewHeader(tag.TypeVideo, timestamp),
+		VideoTagHeader: h,
+		TagBody:        &TagBody{ExVideoTracks: tracks},
+	}
+	body, err := t.encodeExBody()
+	if err != nil {
+		return nil, err
+	}
+	t.Header.DataSize = t.VideoTagHeader.encodedSize() + uint32(len(body))
+	return t, nil
+}
+
+// lengthSize returns size of the length before each NAL unit, 4 bytes if no avcConfig.
+func (t *Tag) lengthSize() uint32 {
+	if t.avcConfig != nil {
+		return t.avcConfig.LengthSize()
+	}
+	return 4
+}
+
+func (t *Tag) encodedDataSize() uint32 {
+	size := t.VideoTagHeader.encodedSize()
+	if t.TagBody == nil || t.TagBody.AVCVideoPacket == nil {
+		return size
+	}
+
+	if t.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord != nil {
+		size += uint32(t.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord.EncodedSize())
+	}
+	for i := range t.TagBody.AVCVideoPacket.LengthNALU {
+		size += t.lengthSize() + uint32(len(t.TagBody.AVCVideoPacket.LengthNALU[i].NALU.Raw()))
+	}
+	return size
+}
+
+// Encode writes tag header, VideoTagHeader and TagBody(or Encryption if Filter=1), DataSize will be calculated by payload.
+func (t *Tag) Encode(w io.Writer) error {
+	if t.Encryption != nil { // encrypted body will be written even if decrypted
+		h := t.Header
+		h.DataSize = t.VideoTagHeader.encodedSize() + t.Encryption.EncodedSize()
+		if err := h.Encode(w); err != nil {
+			return err
+		}
+		if err := t.VideoTagHeader.encode(w); err != nil {
+			return err
+		}
+		return t.Encryption.Encode(w)
+	}
+	if t.VideoTagHeader.IsExHeader {
+		return t.encodeEx(w)
+	}
+	if t.VideoTagHeader.CodecID != CodecIDAVC {
+		return fmt.Errorf("codec %d(%s) doesn't support yet", t.VideoTagHeader.CodecID, CodecIDDescription(int(t.VideoTagHeader.CodecID)))
+	}
+
+	h := t.Header
+	h.DataSize = t.encodedDataSize()
+	if err := h.Encode(w); err != nil {
+		return err
+	}
+	if err := t.VideoTagHeader.encode(w); err != nil {
+		return err
+	}
+
+	if t.TagBody == nil || t.TagBody.AVCVideoPacket == nil {
+		return nil
+	}
+	if t.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord != nil {
+		if err := t.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord.Encode(w); err != nil {
+			return err
+		}
+	}
+
+	lengthSize := t.lengthSize()
+	if lengthSize == 0 || lengthSize > 4 {
+		return fmt.Errorf("invalid length size %d", lengthSize)
+	}
+	length := make([]byte, 4)
+	for i := range t.TagBody.AVCVideoPacket.LengthNALU {
+		raw := t.TagBody.AVCVideoPacket.LengthNALU[i].NALU.Raw()
+		binary.BigEndian.PutUint32(length, uint32(len(raw)))
+		if _, err := w.Write(length[4-lengthSize:]); err != nil {
+			return err
+		}
+		if _, err := w.Write(raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeExBody returns encoded enhanced video tag body.
+func (t *Tag) encodeExBody() ([]byte, error) {
+	var buf bytes.Buffer
+	if t.VideoTagHeader.isVideoCommand() {
+		return buf.Bytes(), nil
+	}
+	if err := t.encodeExVideoTracks(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *Tag) encodeEx(w io.Writer) error {
+	body, err := t.encodeExBody()
+	if err != nil {
+		return err
+	}
+
+	h := t.Header
+	h.DataSize = t.VideoTagHeader.encodedSize() + uint32(len(body))
+	if err := h.Encode(w); err != nil {
+		return err
+	}
+	if err := t.VideoTagHeader.encode(w); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
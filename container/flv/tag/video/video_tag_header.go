@@ -3,8 +3,10 @@ package video
 import (
 	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"io"
 
+	"github.com/golang/glog"
 	"github.com/wangyoucao577/medialib/util"
 )
 
@@ -17,7 +19,7 @@ type TagHeader struct {
 	// 3 = disposable inter frame (H.263 only)
 	// 4 = generated key frame (reserved for server use only)
 	// 5 = video info/command frame
-	FrameType uint8 `json:"FrameType"` // 4 bits
+	FrameType uint8 `json:"FrameType"` // 4 bits, or 3 bits if IsExHeader
 
 	// 	Codec Identifier. The following values are defined:
 	// 2 = Sorenson H.263
@@ -40,6 +42,26 @@ type TagHeader struct {
 	// See ISO 14496-12, 8.15.3 for an explanation of composition times.
 	// The offset in an FLV file is always in milliseconds.
 	CompositionTime *int32 `json:"CompositionTime,omitempty"`
+
+	// Below fields are defined by Enhanced RTMP v2 (ExVideoTagHeader), which present if IsExHeader.
+	IsExHeader bool `json:"IsExHeader,omitempty"` // 1 bit
+
+	// Packet Type replaces CodecID, see PacketType* for values.
+	// It's the real one after ModEx and Multitrack are resolved.
+	PacketType *uint8 `json:"PacketType,omitempty"` // 4 bits
+
+	// Nanoseconds offset of the tag timestamp, from ModEx.
+	TimestampNanoOffset *uint32 `json:"TimestampNanoOffset,omitempty"` // 24 bits
+
+	// Video command if FrameType is video info/command frame and PacketType isn't metadata, there's no body then.
+	VideoCommand *uint8 `json:"VideoCommand,omitempty"`
+
+	// Multitrack Type if the tag is multitrack, see MultitrackType* for values.
+	MultitrackType *uint8 `json:"MultitrackType,omitempty"` // 4 bits
+
+	// Codec FourCC shared by all tracks, e.g., avc1, hvc1, av01, vp09.
+	// It's empty if MultitrackType is many tracks many codecs, which FourCC is stored per track.
+	FourCC string `json:"FourCC,omitempty"`
 }
 
 // MarshalJSON implements json.Marshaler.
@@ -48,29 +70,58 @@ func (t *TagHeader) MarshalJSON() ([]byte, error) {
 		FrameType            uint8  `json:"FrameType"`
 		FrameTypeDescription string `json:"FrameTypeDescription"`
 
-		CodecID            uint8  `json:"CodecID"`
-		CodecIDDescription string `json:"CodecIDDescription"`
+		CodecID            *uint8 `json:"CodecID,omitempty"`
+		CodecIDDescription string `json:"CodecIDDescription,omitempty"`
 
 		AVCPacketType            *uint8 `json:"AVCPacketType,omitempty"`
 		AVCPacketTypeDescription string `json:"AVCPacketTypeDescription,omitempty"`
 
 		CompositionTime *int32 `json:"CompositionTime,omitempty"`
+
+		IsExHeader                bool    `json:"IsExHeader,omitempty"`
+		PacketType                *uint8  `json:"PacketType,omitempty"`
+		PacketTypeDescription     string  `json:"PacketTypeDescription,omitempty"`
+		TimestampNanoOffset       *uint32 `json:"TimestampNanoOffset,omitempty"`
+		VideoCommand              *uint8  `json:"VideoCommand,omitempty"`
+		MultitrackType            *uint8  `json:"MultitrackType,omitempty"`
+		MultitrackTypeDescription string  `json:"MultitrackTypeDescription,omitempty"`
+		FourCC                    string  `json:"FourCC,omitempty"`
 	}{
 		FrameType:            t.FrameType,
 		FrameTypeDescription: FrameTypeDescription(int(t.FrameType)),
 
-		CodecID:            t.CodecID,
-		CodecIDDescription: CodecIDDescription(int(t.CodecID)),
-
-		AVCPacketType:            t.AVCPacketType,
-		AVCPacketTypeDescription: AVCPacketTypeDescription(int(*t.AVCPacketType)),
-
+		AVCPacketType:   t.AVCPacketType,
 		CompositionTime: t.CompositionTime,
+
+		IsExHeader:          t.IsExHeader,
+		PacketType:          t.PacketType,
+		TimestampNanoOffset: t.TimestampNanoOffset,
+		VideoCommand:        t.VideoCommand,
+		MultitrackType:      t.MultitrackType,
+		FourCC:              t.FourCC,
+	}
+	if !t.IsExHeader {
+		tj.CodecID = &t.CodecID
+		tj.CodecIDDescription = CodecIDDescription(int(t.CodecID))
+	}
+	if t.AVCPacketType != nil {
+		tj.AVCPacketTypeDescription = AVCPacketTypeDescription(int(*t.AVCPacketType))
+	}
+	if t.PacketType != nil {
+		tj.PacketTypeDescription = PacketTypeDescription(int(*t.PacketType))
+	}
+	if t.MultitrackType != nil {
+		tj.MultitrackTypeDescription = MultitrackTypeDescription(int(*t.MultitrackType))
 	}
 
 	return json.Marshal(tj)
 }
 
+// IsMultitrack returns whether the enhanced video tag contains multiple tracks.
+func (t *TagHeader) IsMultitrack() bool {
+	return t.IsExHeader && t.MultitrackType != nil
+}
+
 func (t *TagHeader) parse(r io.Reader) (uint64, error) {
 	var parsedBytes uint64
 
@@ -81,6 +132,13 @@ func (t *TagHeader) parse(r io.Reader) (uint64, error) {
 		parsedBytes += 1
 	}
 
+	t.IsExHeader = (data[0]>>7)&0x1 == 1
+	if t.IsExHeader {
+		t.FrameType = (data[0] >> 4) & 0x7
+		bytes, err := t.parseEx(r, data[0]&0xF)
+		return parsedBytes + bytes, err
+	}
+
 	t.FrameType = (data[0] >> 4) & 0xF
 	t.CodecID = data[0] & 0xF
 
@@ -93,9 +151,181 @@ func (t *TagHeader) parse(r io.Reader) (uint64, error) {
 		}
 
 		t.AVCPacketType = &data[0]
-		cts := int32(binary.BigEndian.Uint32([]byte{0x00, data[1], data[2], data[3]}))
+		cts := int32(binary.BigEndian.Uint32([]byte{data[1], data[2], data[3], 0x00})) >> 8 // SI24
 		t.CompositionTime = &cts
 	}
 
 	return parsedBytes, nil
 }
+
+// parseEx parses remain fields of ExVideoTagHeader after the first byte.
+func (t *TagHeader) parseEx(r io.Reader, packetType uint8) (uint64, error) {
+	var parsedBytes uint64
+	data := make([]byte, 4)
+
+	for packetType == PacketTypeModEx {
+		if err := util.ReadOrError(r, data[:1]); err != nil {
+			return parsedBytes, err
+		}
+		parsedBytes += 1
+		modExDataSize := int(data[0]) + 1
+		if modExDataSize == 256 {
+			if err := util.ReadOrError(r, data[:2]); err != nil {
+				return parsedBytes, err
+			}
+			parsedBytes += 2
+			modExDataSize = int(binary.BigEndian.Uint16(data[:2])) + 1
+		}
+
+		modExData := make([]byte, modExDataSize)
+		if err := util.ReadOrError(r, modExData); err != nil {
+			return parsedBytes, err
+		}
+		parsedBytes += uint64(modExDataSize)
+
+		if err := util.ReadOrError(r, data[:1]); err != nil {
+			return parsedBytes, err
+		}
+		parsedBytes += 1
+		modExType := (data[0] >> 4) & 0xF
+		packetType = data[0] & 0xF
+
+		if modExType == ModExTypeTimestampOffsetNano && modExDataSize >= 3 {
+			offset := uint32(modExData[0])<<16 | uint32(modExData[1])<<8 | uint32(modExData[2])
+			t.TimestampNanoOffset = &offset
+		} else {
+			glog.Warningf("ModEx type %d with %d bytes data doesn't support yet, ignore it", modExType, modExDataSize)
+		}
+	}
+	t.PacketType = &packetType
+
+	if t.FrameType == FrameTypeVideoInfoOrCommand && packetType != PacketTypeMetadata {
+		if err := util.ReadOrError(r, data[:1]); err != nil {
+			return parsedBytes, err
+		}
+		parsedBytes += 1
+		t.VideoCommand = &data[0]
+		return parsedBytes, nil
+	}
+
+	if packetType == PacketTypeMultitrack {
+		if err := util.ReadOrError(r, data[:1]); err != nil {
+			return parsedBytes, err
+		}
+		parsedBytes += 1
+		multitrackType := (data[0] >> 4) & 0xF
+		packetType = data[0] & 0xF
+		if packetType == PacketTypeMultitrack {
+			return parsedBytes, fmt.Errorf("nested multitrack is not allowed")
+		}
+		t.MultitrackType = &multitrackType
+		t.PacketType = &packetType
+
+		if multitrackType == MultitrackTypeManyTracksManyCodecs {
+			return parsedBytes, nil // FourCC per track
+		}
+	}
+
+	if err := util.ReadOrError(r, data); err != nil {
+		return parsedBytes, err
+	}
+	parsedBytes += 4
+	t.FourCC = string(data)
+
+	return parsedBytes, nil
+}
+
+func (t *TagHeader) encodedSize() uint32 {
+	if t.IsExHeader {
+		size := uint32(1)
+		if t.TimestampNanoOffset != nil {
+			size += 5 // size, 3 bytes offset, type
+		}
+		if t.isVideoCommand() {
+			return size + 1
+		}
+		if t.MultitrackType != nil {
+			size += 1
+			if *t.MultitrackType == MultitrackTypeManyTracksManyCodecs {
+				return size
+			}
+		}
+		return size + 4
+	}
+	if t.CodecID == CodecIDAVC {
+		return 5
+	}
+	return 1
+}
+
+func (t *TagHeader) encode(w io.Writer) error {
+	if t.IsExHeader {
+		return t.encodeEx(w)
+	}
+
+	data := []byte{(t.FrameType&0xF)<<4 | t.CodecID&0xF}
+	if t.CodecID == CodecIDAVC {
+		if t.AVCPacketType == nil {
+			return fmt.Errorf("codec %d(%s) requires AVCPacketType", t.CodecID, CodecIDDescription(int(t.CodecID)))
+		}
+		var cts int32
+		if t.CompositionTime != nil {
+			cts = *t.CompositionTime
+		}
+		data = append(data, *t.AVCPacketType, byte(cts>>16), byte(cts>>8), byte(cts))
+	}
+
+	_, err := w.Write(data)
+	return err
+}
+
+func (t *TagHeader) isVideoCommand() bool {
+	return t.FrameType == FrameTypeVideoInfoOrCommand && t.PacketType != nil && *t.PacketType != PacketTypeMetadata
+}
+
+func (t *TagHeader) encodeEx(w io.Writer) error {
+	if t.PacketType == nil {
+		return fmt.Errorf("enhanced video tag requires PacketType")
+	}
+	packetType := *t.PacketType
+
+	// packet types are written in sequence: ModEx -> Multitrack -> the real one
+	nextPacketType := packetType
+	if t.MultitrackType != nil {
+		nextPacketType = PacketTypeMultitrack
+	}
+	firstPacketType := nextPacketType
+	if t.TimestampNanoOffset != nil {
+		firstPacketType = PacketTypeModEx
+	}
+
+	data := []byte{1<<7 | (t.FrameType&0x7)<<4 | firstPacketType&0xF}
+	if t.TimestampNanoOffset != nil {
+		offset := *t.TimestampNanoOffset
+		data = append(data, 3-1, byte(offset>>16), byte(offset>>8), byte(offset), ModExTypeTimestampOffsetNano<<4|nextPacketType&0xF)
+	}
+
+	if t.isVideoCommand() {
+		var command uint8
+		if t.VideoCommand != nil {
+			command = *t.VideoCommand
+		}
+		_, err := w.Write(append(data, command))
+		return err
+	}
+
+	if t.MultitrackType != nil {
+		data = append(data, (*t.MultitrackType&0xF)<<4|packetType&0xF)
+		if *t.MultitrackType == MultitrackTypeManyTracksManyCodecs {
+			_, err := w.Write(data)
+			return err
+		}
+	}
+	if len(t.FourCC) != 4 {
+		return fmt.Errorf("invalid FourCC %q", t.FourCC)
+	}
+	data = append(data, t.FourCC...)
+
+	_, err := w.Write(data)
+	return err
+}
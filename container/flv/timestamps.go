@@ -0,0 +1,354 @@
+package flv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/ghodss/yaml"
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/container/flv/tag/video"
+)
+
+// Timestamp correction strategies.
+const (
+	// TimestampStrategyOffset shifts the tag and all following tags of the same stream by an offset,
+	// so that the stream continues from the previous tag with the expected frame duration.
+	TimestampStrategyOffset = "offset"
+
+	// TimestampStrategyClamp replaces timestamp of the outlier tag by the previous one plus the expected frame duration,
+	// following tags keep their original timestamps as long as they're continuous to the previous original one,
+	// so a permanent backward or jump is kept after the first tag.
+	TimestampStrategyClamp = "clamp"
+)
+
+// Kinds of timestamp discontinuities.
+const (
+	DiscontinuityJump                = "jump"                 // timestamp jumps forward more than JumpThreshold
+	DiscontinuityBackward            = "backward"             // timestamp goes backward, e.g., reset
+	DiscontinuityNegativeComposition = "negative_composition" // negative CompositionTime of video tag
+	DiscontinuityDrift               = "drift"                // audio and video timestamps drift more than DriftThreshold
+)
+
+// Default thresholds in milliseconds.
+const (
+	DefaultJumpThreshold  = 1000
+	DefaultDriftThreshold = 1000
+)
+
+// TimestampOptions represents options of timestamps analysis and repair.
+type TimestampOptions struct {
+	Strategy       string // TimestampStrategyOffset or TimestampStrategyClamp, only for repair
+	JumpThreshold  int32  // milliseconds, DefaultJumpThreshold if 0
+	DriftThreshold int32  // milliseconds, DefaultDriftThreshold if 0
+
+	// Expected frame duration in milliseconds, 0 means estimated by the median of positive deltas of the stream.
+	VideoFrameDuration int32
+	AudioFrameDuration int32
+}
+
+// TimestampDiscontinuity represents a discontinuity found in a stream.
+type TimestampDiscontinuity struct {
+	Kind      string `json:"kind"`
+	TagType   uint8  `json:"tag_type"`   // the tag that discontinuity found
+	TagIndex  int    `json:"tag_index"`  // index of the tag in all tags, starts from 0
+	TagOffset int64  `json:"tag_offset"` // offset of the tag from the beginning of the input
+
+	Previous  int32 `json:"previous"`  // previous timestamp of the same stream, or the other stream for drift
+	Timestamp int32 `json:"timestamp"` // timestamp of the tag
+	Delta     int32 `json:"delta"`     // Timestamp - Previous, or CompositionTime for negative_composition
+}
+
+// StreamTimestamps represents timestamps summary of a stream.
+type StreamTimestamps struct {
+	TagType         uint8  `json:"tag_type"`
+	Name            string `json:"name"`
+	Tags            int    `json:"tags"`
+	First           int32  `json:"first"`
+	Last            int32  `json:"last"`
+	FrameDuration   int32  `json:"frame_duration"` // expected frame duration in milliseconds
+	Discontinuities int    `json:"discontinuities"`
+	Corrected       int    `json:"corrected,omitempty"` // count of tags whose timestamp has been corrected by repair
+}
+
+// TimestampReport represents timestamps analysis result, which implements dump.Marshaler.
+type TimestampReport struct {
+	Streams         []StreamTimestamps       `json:"streams"`
+	Discontinuities []TimestampDiscontinuity `json:"discontinuities"`
+}
+
+// tagTimestamp represents position and timestamp of a tag for repair.
+type tagTimestamp struct {
+	offset    int64
+	tagType   uint8
+	timestamp int32
+}
+
+// timestampScan represents everything collected by scanning tags.
+type timestampScan struct {
+	report TimestampReport
+	tags   []tagTimestamp
+}
+
+// AnalyzeTimestamps reads all tags to report timestamp discontinuities per stream(audio and video),
+// script data tags are ignored.
+func AnalyzeTimestamps(r io.Reader, opts TimestampOptions) (*TimestampReport, error) {
+	s, err := scanTimestamps(r, opts, false)
+	if err != nil {
+		return nil, err
+	}
+	return &s.report, nil
+}
+
+// RepairTimestamps rewrites FLV from r to w with corrected timestamps, so that timestamps of each stream are monotonic and
+// no jump more than JumpThreshold by offset, or outlier timestamps are replaced by clamp. Only timestamps in tag headers are changed, CompositionTime is reported but kept as it is.
+// The input will be read twice, the returned report contains discontinuities of the input and count of corrected tags.
+func RepairTimestamps(r io.ReaderAt, size int64, w io.Writer, opts TimestampOptions) (*TimestampReport, error) {
+	if opts.Strategy != TimestampStrategyOffset && opts.Strategy != TimestampStrategyClamp {
+		return nil, fmt.Errorf("unknown timestamp strategy %q", opts.Strategy)
+	}
+
+	s, err := scanTimestamps(io.NewSectionReader(r, 0, size), opts, true)
+	if err != nil {
+		return nil, err
+	}
+	corrected := s.correct(opts)
+
+	var begin int64
+	timestamp := make([]byte, 4)
+	for i, t := range s.tags {
+		if corrected[i] == t.timestamp {
+			continue
+		}
+		// Timestamp(24 bits) and TimestampExtended(8 bits) locate at [4, 8) of tag header
+		if err := copyRange(w, r, begin, t.offset+4); err != nil {
+			return nil, err
+		}
+		ts := uint32(corrected[i])
+		timestamp[0], timestamp[1], timestamp[2], timestamp[3] = byte(ts>>16), byte(ts>>8), byte(ts), byte(ts>>24)
+		if _, err := w.Write(timestamp); err != nil {
+			return nil, err
+		}
+		begin = t.offset + 8
+	}
+	if err := copyRange(w, r, begin, size); err != nil {
+		return nil, err
+	}
+	return &s.report, nil
+}
+
+// scanTimestamps reads all tags to collect discontinuities, timestamps of tags will be kept if keepTags.
+func scanTimestamps(r io.Reader, opts TimestampOptions, keepTags bool) (*timestampScan, error) {
+	jumpThreshold, driftThreshold := opts.JumpThreshold, opts.DriftThreshold
+	if jumpThreshold <= 0 {
+		jumpThreshold = DefaultJumpThreshold
+	}
+	if driftThreshold <= 0 {
+		driftThreshold = DefaultDriftThreshold
+	}
+
+	s := timestampScan{report: TimestampReport{Streams: []StreamTimestamps{}, Discontinuities: []TimestampDiscontinuity{}}}
+	streams := map[uint8]*StreamTimestamps{}
+	deltas := map[uint8][]int32{}
+	var drifting bool
+
+	fr := NewReader(r)
+	for index := 0; ; index++ {
+		t, err := fr.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		h := t.GetTagHeader()
+		if keepTags {
+			s.tags = append(s.tags, tagTimestamp{offset: fr.TagOffset(), tagType: h.TagType, timestamp: h.TimestampCalculated})
+		}
+		if h.TagType != tag.TypeAudio && h.TagType != tag.TypeVideo {
+			continue
+		}
+		discontinuity := func(kind string, previous, delta int32) {
+			s.report.Discontinuities = append(s.report.Discontinuities, TimestampDiscontinuity{
+				Kind: kind, TagType: h.TagType, TagIndex: index, TagOffset: fr.TagOffset(),
+				Previous: previous, Timestamp: h.TimestampCalculated, Delta: delta,
+			})
+			streams[h.TagType].Discontinuities++
+		}
+
+		st, ok := streams[h.TagType]
+		if !ok {
+			st = &StreamTimestamps{TagType: h.TagType, Name: tag.TypeDescription(int(h.TagType)), First: h.TimestampCalculated}
+			streams[h.TagType] = st
+		} else {
+			delta := h.TimestampCalculated - st.Last
+			if delta < 0 {
+				discontinuity(DiscontinuityBackward, st.Last, delta)
+			} else if delta > jumpThreshold {
+				discontinuity(DiscontinuityJump, st.Last, delta)
+			} else if delta > 0 {
+				deltas[h.TagType] = append(deltas[h.TagType], delta)
+			}
+		}
+		st.Tags++
+		st.Last = h.TimestampCalculated
+
+		if vt, ok := t.(*video.Tag); ok {
+			if cts, ok := videoCompositionTime(vt); ok && cts < 0 {
+				discontinuity(DiscontinuityNegativeComposition, h.TimestampCalculated, cts)
+			}
+		}
+
+		// drift between audio and video, only report once until they're close again
+		if as, vs := streams[tag.TypeAudio], streams[tag.TypeVideo]; as != nil && vs != nil {
+			other := as.Last
+			if h.TagType == tag.TypeAudio {
+				other = vs.Last
+			}
+			drift := h.TimestampCalculated - other
+			if drift > driftThreshold || drift < -driftThreshold {
+				if !drifting {
+					discontinuity(DiscontinuityDrift, other, drift)
+				}
+				drifting = true
+			} else {
+				drifting = false
+			}
+		}
+	}
+
+	for _, tagType := range []uint8{tag.TypeVideo, tag.TypeAudio} {
+		st, ok := streams[tagType]
+		if !ok {
+			continue
+		}
+		st.FrameDuration = opts.VideoFrameDuration
+		if tagType == tag.TypeAudio {
+			st.FrameDuration = opts.AudioFrameDuration
+		}
+		if st.FrameDuration <= 0 {
+			st.FrameDuration = median(deltas[tagType])
+		}
+		s.report.Streams = append(s.report.Streams, *st)
+	}
+	return &s, nil
+}
+
+// correct returns corrected timestamps of all tags, script data tags are kept as they are.
+// A tag is discontinuous if it goes backward or jumps forward more than JumpThreshold compare to the previous
+// corrected one of the same stream, then it will be corrected to the previous corrected one plus the frame duration.
+// For clamp, a tag which is continuous to the previous original one is kept, so that only the outlier is rewritten.
+func (s *timestampScan) correct(opts TimestampOptions) []int32 {
+	jumpThreshold := opts.JumpThreshold
+	if jumpThreshold <= 0 {
+		jumpThreshold = DefaultJumpThreshold
+	}
+
+	type state struct {
+		started  bool
+		previous int32 // corrected
+		original int32 // previous original
+		offset   int32
+	}
+	states := map[uint8]*state{}
+	streams := map[uint8]*StreamTimestamps{}
+	for i := range s.report.Streams {
+		streams[s.report.Streams[i].TagType] = &s.report.Streams[i]
+		states[s.report.Streams[i].TagType] = &state{}
+	}
+
+	corrected := make([]int32, len(s.tags))
+	for i, t := range s.tags {
+		st, ok := states[t.tagType]
+		if !ok {
+			corrected[i] = t.timestamp
+			continue
+		}
+
+		ts := t.timestamp + st.offset
+		if st.started {
+			if delta := ts - st.previous; delta < 0 || delta > jumpThreshold {
+				expected := st.previous + streams[t.tagType].FrameDuration
+				if opts.Strategy == TimestampStrategyOffset {
+					st.offset += expected - ts
+					ts = expected
+				} else if delta := t.timestamp - st.original; delta < 0 || delta > jumpThreshold {
+					ts = expected
+				}
+			}
+		}
+		st.started, st.previous, st.original = true, ts, t.timestamp
+
+		corrected[i] = ts
+		if ts != t.timestamp {
+			streams[t.tagType].Corrected++
+		}
+	}
+	return corrected
+}
+
+// videoCompositionTime returns CompositionTime of the video tag, the first track's one for multitrack.
+func videoCompositionTime(vt *video.Tag) (int32, bool) {
+	if vt.VideoTagHeader.CompositionTime != nil {
+		return *vt.VideoTagHeader.CompositionTime, true
+	}
+	if vt.TagBody != nil {
+		for _, track := range vt.TagBody.ExVideoTracks {
+			if track.CompositionTime != nil {
+				return *track.CompositionTime, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// median returns median of values, or 0 if empty.
+func median(values []int32) int32 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]int32, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+// JSON marshals report to JSON representation
+func (r TimestampReport) JSON() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// JSONIndent marshals report to JSON representation with customized indent.
+func (r TimestampReport) JSONIndent(prefix, indent string) ([]byte, error) {
+	return json.MarshalIndent(r, prefix, indent)
+}
+
+// YAML formats report to YAML representation.
+func (r TimestampReport) YAML() ([]byte, error) {
+	j, err := json.Marshal(r)
+	if err != nil {
+		return j, err
+	}
+	return yaml.JSONToYAML(j)
+}
+
+// CSV formats discontinuities of report to CSV representation.
+func (r TimestampReport) CSV() ([]byte, error) {
+	records := [][]string{
+		{"Kind", "TagType", "TagIndex", "TagOffset", "Previous", "Timestamp", "Delta"}, // csv header
+	}
+	for _, d := range r.Discontinuities {
+		records = append(records, []string{d.Kind, tag.TypeDescription(int(d.TagType)), strconv.Itoa(d.TagIndex),
+			strconv.FormatInt(d.TagOffset, 10), strconv.Itoa(int(d.Previous)), strconv.Itoa(int(d.Timestamp)), strconv.Itoa(int(d.Delta))})
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w := csv.NewWriter(buf)
+	err := w.WriteAll(records)
+
+	return buf.Bytes(), err
+}
@@ -0,0 +1,224 @@
+package flv
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/container/flv/tag/audio"
+	"github.com/wangyoucao577/medialib/container/flv/tag/script"
+	"github.com/wangyoucao577/medialib/container/flv/tag/video"
+)
+
+func TestRepairTimestamps(t *testing.T) {
+	flvFile := "../../assets/sintel_trailer-720p-firstgopfmp4.flv"
+
+	data, err := os.ReadFile(flvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := FLV{}
+	if err := f.Parse(bytes.NewReader(data)); err != io.EOF {
+		t.Fatalf("parse %s expect EOF but got %v", flvFile, err)
+	}
+	original := make([]int32, len(f.Tags))
+	for i, ft := range f.Tags {
+		original[i] = ft.GetTagHeader().TimestampCalculated
+	}
+
+	// reset timestamps to 0 at the middle, then jump 5 seconds forward
+	resetIndex, jumpIndex := len(f.Tags)/2, len(f.Tags)*3/4
+	resetBase := original[resetIndex]
+	for _, ts := range original[resetIndex:] {
+		if ts < resetBase {
+			resetBase = ts
+		}
+	}
+	brokenTimestamps := append([]int32{}, original...)
+	for i := resetIndex; i < len(f.Tags); i++ {
+		ts := original[i] - resetBase
+		if i >= jumpIndex {
+			ts += 5000
+		}
+		setTagTimestamp(f.Tags[i], uint32(ts))
+		brokenTimestamps[i] = ts
+	}
+	// the first tag of each stream since the reset or the jump is the outlier, clamp keeps discontinuity at the second one
+	outliers, kept := map[int]bool{}, map[int]bool{}
+	for _, from := range []int{resetIndex, jumpIndex} {
+		seen := map[uint8]int{}
+		for i := from; i < len(f.Tags); i++ {
+			tagType := f.Tags[i].GetTagHeader().TagType
+			if tagType == tag.TypeSriptData {
+				continue
+			}
+			seen[tagType]++
+			switch seen[tagType] {
+			case 1:
+				outliers[i] = true
+			case 2:
+				kept[i] = true
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	broken := buf.Bytes()
+
+	report, err := AnalyzeTimestamps(bytes.NewReader(broken), TimestampOptions{})
+	if err != nil {
+		t.Fatalf("analyze expect nil but got %v", err)
+	}
+	kinds := map[uint8]map[string]int{}
+	for _, d := range report.Discontinuities {
+		if kinds[d.TagType] == nil {
+			kinds[d.TagType] = map[string]int{}
+		}
+		kinds[d.TagType][d.Kind]++
+	}
+	if len(report.Streams) != 2 {
+		t.Fatalf("expect video and audio streams but got %+v", report.Streams)
+	}
+	for _, st := range report.Streams {
+		if kinds[st.TagType][DiscontinuityBackward] != 1 || kinds[st.TagType][DiscontinuityJump] != 1 {
+			t.Errorf("stream %s expect 1 backward and 1 jump but got %v", st.Name, kinds[st.TagType])
+		}
+		if st.FrameDuration <= 0 {
+			t.Errorf("stream %s expect positive frame duration but got %d", st.Name, st.FrameDuration)
+		}
+	}
+	if kinds[tag.TypeVideo][DiscontinuityDrift]+kinds[tag.TypeAudio][DiscontinuityDrift] == 0 {
+		t.Errorf("expect drift around the jump but got %v", kinds)
+	}
+
+	for _, strategy := range []string{TimestampStrategyOffset, TimestampStrategyClamp} {
+		var repaired bytes.Buffer
+		r, err := RepairTimestamps(bytes.NewReader(broken), int64(len(broken)), &repaired, TimestampOptions{Strategy: strategy})
+		if err != nil {
+			t.Fatalf("%s repair expect nil but got %v", strategy, err)
+		}
+		if repaired.Len() != len(broken) {
+			t.Errorf("%s repair expect %d bytes but got %d", strategy, len(broken), repaired.Len())
+		}
+		for _, st := range r.Streams {
+			if st.Corrected == 0 {
+				t.Errorf("%s stream %s expect corrected tags but got 0", strategy, st.Name)
+			}
+		}
+
+		again, err := AnalyzeTimestamps(bytes.NewReader(repaired.Bytes()), TimestampOptions{})
+		if err != nil {
+			t.Fatalf("%s analyze repaired expect nil but got %v", strategy, err)
+		}
+		for _, d := range again.Discontinuities {
+			// clamp keeps the permanent backward and jump after the outliers
+			if (d.Kind == DiscontinuityBackward || d.Kind == DiscontinuityJump) && (strategy == TimestampStrategyOffset || !kept[d.TagIndex]) {
+				t.Errorf("%s repaired expect no backward or jump but got %+v", strategy, d)
+			}
+		}
+
+		fixed := FLV{}
+		if err := fixed.Parse(bytes.NewReader(repaired.Bytes())); err != io.EOF {
+			t.Fatalf("%s parse repaired expect EOF but got %v", strategy, err)
+		}
+		last := map[uint8]int{}
+		for i, ft := range fixed.Tags {
+			h := ft.GetTagHeader()
+			j, ok := last[h.TagType]
+			last[h.TagType] = i
+			if !ok || h.TagType == tag.TypeSriptData {
+				continue
+			}
+			got := h.TimestampCalculated - fixed.Tags[j].GetTagHeader().TimestampCalculated
+			frameDuration := streamFrameDuration(r, h.TagType)
+
+			if strategy == TimestampStrategyOffset {
+				// offset keeps original deltas except the discontinuous ones
+				if expect := original[i] - original[j]; i != resetIndex && i != jumpIndex && got != expect && got != frameDuration {
+					t.Errorf("offset tag %d expect delta %d but got %d", i, expect, got)
+				}
+				continue
+			}
+
+			// clamp only rewrites the outliers, following tags keep their timestamps and deltas
+			if outliers[i] {
+				if got != frameDuration {
+					t.Errorf("clamp outlier tag %d expect delta %d but got %d", i, frameDuration, got)
+				}
+				continue
+			}
+			if h.TimestampCalculated != brokenTimestamps[i] {
+				t.Errorf("clamp tag %d expect timestamp %d but got %d", i, brokenTimestamps[i], h.TimestampCalculated)
+			}
+			if expect := original[i] - original[j]; !outliers[j] && got != expect {
+				t.Errorf("clamp tag %d expect delta %d but got %d", i, expect, got)
+			}
+		}
+	}
+
+	// a single outlier is clamped without discontinuity left
+	spikeIndex := len(f.Tags) / 4
+	for f.Tags[spikeIndex].GetTagHeader().TagType != tag.TypeVideo {
+		spikeIndex++
+	}
+	for i, ft := range f.Tags {
+		setTagTimestamp(ft, uint32(original[i]))
+	}
+	setTagTimestamp(f.Tags[spikeIndex], uint32(original[spikeIndex]+100000))
+	buf.Reset()
+	if err := f.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var clamped bytes.Buffer
+	r, err := RepairTimestamps(bytes.NewReader(buf.Bytes()), int64(buf.Len()), &clamped, TimestampOptions{Strategy: TimestampStrategyClamp})
+	if err != nil {
+		t.Fatalf("clamp spike expect nil but got %v", err)
+	}
+	if corrected := r.Streams[0].Corrected + r.Streams[1].Corrected; corrected != 1 {
+		t.Errorf("clamp spike expect 1 corrected tag but got %d", corrected)
+	}
+	again, err := AnalyzeTimestamps(bytes.NewReader(clamped.Bytes()), TimestampOptions{})
+	if err != nil {
+		t.Fatalf("clamp spike analyze expect nil but got %v", err)
+	}
+	for _, d := range again.Discontinuities {
+		if d.Kind == DiscontinuityBackward || d.Kind == DiscontinuityJump {
+			t.Errorf("clamp spike expect no backward or jump but got %+v", d)
+		}
+	}
+
+	// nothing to repair
+	var repaired bytes.Buffer
+	if _, err := RepairTimestamps(bytes.NewReader(data), int64(len(data)), &repaired, TimestampOptions{Strategy: TimestampStrategyClamp}); err != nil {
+		t.Fatalf("repair %s expect nil but got %v", flvFile, err)
+	}
+	if !bytes.Equal(repaired.Bytes(), data) {
+		t.Errorf("repair %s expect same as input", flvFile)
+	}
+}
+
+func streamFrameDuration(r *TimestampReport, tagType uint8) int32 {
+	for _, st := range r.Streams {
+		if st.TagType == tagType {
+			return st.FrameDuration
+		}
+	}
+	return 0
+}
+
+func setTagTimestamp(t tag.Tag, timestamp uint32) {
+	h := tag.NewHeader(t.GetTagHeader().TagType, timestamp)
+	h.DataSize, h.Filter = t.GetTagHeader().DataSize, t.GetTagHeader().Filter
+	switch tt := t.(type) {
+	case *audio.Tag:
+		tt.Header = h
+	case *video.Tag:
+		tt.Header = h
+	case *script.Tag:
+		tt.Header = h
+	}
+}
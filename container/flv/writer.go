@@ -0,0 +1,70 @@
+package flv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+)
+
+// Writer writes FLV Header and tags one by one, the PreviousTagSize will be filled automatically.
+type Writer struct {
+	w io.Writer
+
+	headerWritten bool
+}
+
+// NewWriter creates FLV Writer.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// WriteHeader writes FLV Header and the first PreviousTagSize which is always 0.
+func (fw *Writer) WriteHeader(h Header) error {
+	if fw.headerWritten {
+		return fmt.Errorf("header has been written already")
+	}
+
+	if err := h.Encode(fw.w); err != nil {
+		return err
+	}
+	if err := fw.writePreviousTagSize(0); err != nil {
+		return err
+	}
+
+	fw.headerWritten = true
+	return nil
+}
+
+// WriteTag writes a tag and its size as the following PreviousTagSize.
+func (fw *Writer) WriteTag(t tag.Tag) error {
+	if !fw.headerWritten {
+		return fmt.Errorf("header should be written before tags")
+	}
+
+	cw := countWriter{w: fw.w}
+	if err := t.Encode(&cw); err != nil {
+		return err
+	}
+	return fw.writePreviousTagSize(uint32(cw.n))
+}
+
+func (fw *Writer) writePreviousTagSize(size uint32) error {
+	data := make([]byte, 4) // fixed 4 bytes
+	binary.BigEndian.PutUint32(data, size)
+	_, err := fw.w.Write(data)
+	return err
+}
+
+// countWriter counts written bytes.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,142 @@
+package flv
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/container/flv/tag/audio"
+	"github.com/wangyoucao577/medialib/container/flv/tag/script"
+	"github.com/wangyoucao577/medialib/container/flv/tag/video"
+	avcc "github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/avcC"
+	"github.com/wangyoucao577/medialib/util/amf/amf0"
+	"github.com/wangyoucao577/medialib/video/avc/es"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	flvFile := "../../assets/sintel_trailer-720p-firstgopfmp4.flv"
+
+	data, err := os.ReadFile(flvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := FLV{}
+	if err := f.Parse(bytes.NewReader(data)); err != io.EOF {
+		t.Fatalf("parse %s expect EOF but got %v", flvFile, err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatalf("encode expect nil but got %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("encode expect %d bytes same as %s but got %d bytes", len(data), flvFile, buf.Len())
+	}
+}
+
+func TestWriter(t *testing.T) {
+	flvFile := "../../assets/sintel_trailer-720p-firstgopfmp4.flv"
+
+	h := New(flvFile)
+	if err := h.Parse(); err != io.EOF {
+		t.Fatalf("parse %s expect EOF but got %v", flvFile, err)
+	}
+	var avcConfig *avcc.AVCDecoderConfigurationRecord
+	var nalus []es.LengthNALU
+	for _, ft := range h.Tags {
+		vt, ok := ft.(*video.Tag)
+		if !ok || vt.TagBody == nil {
+			continue
+		}
+		if vt.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord != nil && avcConfig == nil {
+			avcConfig = vt.TagBody.AVCVideoPacket.AVCDecoderConfigurationRecord
+		}
+		if len(vt.TagBody.AVCVideoPacket.LengthNALU) > 0 && nalus == nil {
+			nalus = vt.TagBody.AVCVideoPacket.LengthNALU
+		}
+	}
+	if avcConfig == nil || nalus == nil {
+		t.Fatalf("avc config or nalus not found in %s", flvFile)
+	}
+
+	const largeTimestamp = 0x01000010 // requires TimestampExtended
+	metadata, err := script.NewOnMetaDataTag(
+		amf0.NewObjectProperty("duration", amf0.NewNumber(1.5)),
+		amf0.NewObjectProperty("encoder", amf0.NewString("medialib")),
+		amf0.NewObjectProperty("stereo", amf0.NewBoolean(true)),
+	)
+	if err != nil {
+		t.Fatalf("create onMetaData tag expect nil but got %v", err)
+	}
+	tags := []tag.Tag{
+		metadata,
+		video.NewAVCSequenceHeaderTag(0, avcConfig),
+		audio.NewAACSequenceHeaderTag(0, []byte{0x12, 0x10}),
+		video.NewAVCNALUTag(largeTimestamp, -40, true, avcConfig, nalus),
+		audio.NewAACRawTag(largeTimestamp, []byte{0x21, 0x00, 0x49, 0x90}),
+		video.NewAVCEndOfSequenceTag(largeTimestamp),
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.WriteTag(tags[0]); err == nil {
+		t.Errorf("write tag before header expect error but got nil")
+	}
+	if err := w.WriteHeader(NewHeader(true, true)); err != nil {
+		t.Fatalf("write header expect nil but got %v", err)
+	}
+	for _, ft := range tags {
+		if err := w.WriteTag(ft); err != nil {
+			t.Fatalf("write tag %#v expect nil but got %v", ft, err)
+		}
+	}
+
+	f := FLV{}
+	if err := f.Parse(bytes.NewReader(buf.Bytes())); err != io.EOF {
+		t.Fatalf("parse written flv expect EOF but got %v", err)
+	}
+	if f.Header.TypeFlagsAudio != 1 || f.Header.TypeFlagsVideo != 1 || f.Header.Version != 1 {
+		t.Errorf("expect header with audio and video but got %+v", f.Header)
+	}
+	if len(f.Tags) != len(tags) {
+		t.Fatalf("expect %d tags but got %d", len(tags), len(f.Tags))
+	}
+	for i, ft := range f.Tags {
+		if ft.GetTagHeader() != tags[i].GetTagHeader() {
+			t.Errorf("tag %d expect header %+v but got %+v", i, tags[i].GetTagHeader(), ft.GetTagHeader())
+		}
+		if f.PreviousTagSize[i+1] != uint32(ft.Size()) {
+			t.Errorf("tag %d expect PreviousTagSize %d but got %d", i, ft.Size(), f.PreviousTagSize[i+1])
+		}
+	}
+	if ts := f.Tags[3].GetTagHeader().TimestampCalculated; ts != largeTimestamp {
+		t.Errorf("expect timestamp %d but got %d", largeTimestamp, ts)
+	}
+
+	st := f.Tags[0].(*script.Tag)
+	if name, err := st.TagBody.Name.AsString(); err != nil || name != script.OnMetaData {
+		t.Errorf("expect script name %s but got %s, err %v", script.OnMetaData, name, err)
+	}
+	if props := st.TagBody.Value.Value.(*amf0.ECMAArrayPayload).ObjectProperty; len(props) != 4 || props[1].String.Str != "encoder" {
+		t.Errorf("expect 3 metadata properties with object end but got %+v", props)
+	}
+
+	vt := f.Tags[3].(*video.Tag)
+	if vt.VideoTagHeader.FrameType != video.FrameTypeKey || *vt.VideoTagHeader.CompositionTime != -40 {
+		t.Errorf("expect key frame with composition time -40 but got %+v", vt.VideoTagHeader)
+	}
+	if got := vt.TagBody.AVCVideoPacket.LengthNALU; len(got) != len(nalus) || !bytes.Equal(got[0].NALU.Raw(), nalus[0].NALU.Raw()) {
+		t.Errorf("expect nalus same as written")
+	}
+
+	a, err := f.ExtractAudio()
+	if err != nil {
+		t.Fatalf("extract audio expect nil but got %v", err)
+	}
+	if len(a.RawFrames) != 1 || a.AudioSpecificConfig.ChannelConfiguration != 2 {
+		t.Errorf("expect 1 stereo frame but got %d frames, config %+v", len(a.RawFrames), a.AudioSpecificConfig)
+	}
+}
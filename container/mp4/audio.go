@@ -0,0 +1,62 @@
+package mp4
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/wangyoucao577/medialib/audio/aac"
+	"github.com/wangyoucao577/medialib/container/mp4/box"
+	"github.com/wangyoucao577/medialib/container/mp4/box/trak"
+)
+
+// ExtractAudio extracts AAC raw frames from mp4a track, which can be dumped as ADTS stream.
+// Use trackID to select the specified one, trackID <= 0 means use the first found one.
+func (b *Boxes) ExtractAudio(trackID int) (*aac.ADTS, error) {
+
+	if b.Moov == nil || (b.MoofMdat == nil && b.Mdat == nil) {
+		return nil, fmt.Errorf("moov, moof or mdat not found")
+	}
+
+	track, err := b.handlerTrack(trackID, box.TypeSoun)
+	if err != nil {
+		return nil, err
+	}
+	trackID = int(track.Tkhd.TrackID)
+
+	a := aac.ADTS{}
+	if err := parseAudioSpecificConfig(track, &a.AudioSpecificConfig); err != nil {
+		return nil, err
+	}
+
+	samples, err := b.Samples(uint32(trackID))
+	if err != nil {
+		return nil, err
+	}
+	for _, sample := range samples {
+		data, err := b.ReadSample(sample)
+		if err != nil {
+			return &a, err
+		}
+		a.RawFrames = append(a.RawFrames, data)
+	}
+
+	return &a, nil
+}
+
+// parseAudioSpecificConfig parses AudioSpecificConfig in esds of the mp4a track.
+func parseAudioSpecificConfig(track *trak.Box, asc *aac.AudioSpecificConfig) error {
+	trackID := track.Tkhd.TrackID
+	stsd := track.Mdia.Minf.Stbl.Stsd
+	if len(stsd.MP4VisualSampleEntries) == 0 || stsd.MP4VisualSampleEntries[0].Esds == nil {
+		return fmt.Errorf("trackID %d is not aac", trackID)
+	}
+	config := stsd.MP4VisualSampleEntries[0].Esds.ESDescriptor.DecoderConfigDescriptor.DecoderSpecificInfo.Data
+	if len(config) == 0 {
+		return fmt.Errorf("trackID %d AudioSpecificConfig not found", trackID)
+	}
+
+	if _, err := asc.Parse(bytes.NewReader(config), len(config)); err != nil {
+		return fmt.Errorf("trackID %d parse AudioSpecificConfig failed, err %v", trackID, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/wangyoucao577/medialib/audio/aac"
+)
+
+func TestExtractAudio(t *testing.T) {
+	fmp4File := "../../assets/sintel_trailer-720p-firstgopfmp4.mp4"
+
+	h := New(fmp4File)
+	if err := h.Parse(); err != nil {
+		t.Fatalf("parse %s failed, err %v", fmp4File, err)
+	}
+	defer h.Close()
+
+	a, err := h.ExtractAudio(0)
+	if err != nil {
+		t.Fatalf("extract audio expect nil but got %v", err)
+	}
+	if a.AudioSpecificConfig.AudioObjectType != aac.AudioObjectTypeAACLC || a.AudioSpecificConfig.ChannelConfiguration == 0 {
+		t.Errorf("expect AAC-LC with channel configuration but got %+v", a.AudioSpecificConfig)
+	}
+
+	samples, err := h.Samples(2)
+	if err != nil {
+		t.Fatalf("get audio samples failed, err %v", err)
+	}
+	if len(a.RawFrames) != len(samples) {
+		t.Fatalf("expect %d raw frames but got %d", len(samples), len(a.RawFrames))
+	}
+
+	var buf bytes.Buffer
+	n, err := a.Dump(&buf)
+	if err != nil {
+		t.Fatalf("dump adts expect nil but got %v", err)
+	}
+
+	// walk through frames by adts header
+	data := buf.Bytes()
+	for i := range a.RawFrames {
+		if len(data) < aac.ADTSHeaderSize || data[0] != 0xFF || data[1]&0xF0 != 0xF0 {
+			t.Fatalf("frame %d expect adts syncword", i)
+		}
+		frameLength := int(data[3]&0x3)<<11 | int(data[4])<<3 | int(data[5]>>5)
+		if !bytes.Equal(data[aac.ADTSHeaderSize:frameLength], a.RawFrames[i]) {
+			t.Fatalf("frame %d raw data mismatch", i)
+		}
+		data = data[frameLength:]
+	}
+	if len(data) != 0 || n != buf.Len() {
+		t.Errorf("expect all %d bytes consumed but remain %d", n, len(data))
+	}
+
+	if _, err := h.ExtractAudio(1); err == nil {
+		t.Errorf("extract audio from video track expect error but got nil")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 
 	"github.com/golang/glog"
 	"github.com/google/uuid"
@@ -50,6 +51,13 @@ func (h Header) String() string {
 	return fmt.Sprintf("Size:%d Type:%s LargeSize:%d UserType:%s payloadSize:%d headerSize:%d", h.Size, h.Type[:], h.LargeSize, h.UserType[:], h.payloadSize, h.headerSize)
 }
 
+// NewHeader creates a header with box type for a new box, its size will be calculated when encoding.
+func NewHeader(boxType string) Header {
+	h := Header{}
+	copy(h.Type[:], boxType)
+	return h
+}
+
 // PayloadSize returns payload size, 0 means continue to the end.
 func (h Header) PayloadSize() uint64 {
 	return h.payloadSize
@@ -147,6 +155,70 @@ func (h *Header) Validate() error {
 	return nil
 }
 
+// useLargeSize returns whether 64 bits large size should be used to encode the box.
+func (h Header) useLargeSize(payloadSize uint64) bool {
+	if h.Size == 1 { // keep large size if it was
+		return true
+	}
+	return payloadSize+h.encodedHeaderSize(false) > math.MaxUint32
+}
+
+// extendsToEnd returns whether the parsed box extends to the end of the file, i.e., size 0.
+func (h Header) extendsToEnd() bool {
+	return h.Size == 0 && h.headerSize > 0
+}
+
+func (h Header) encodedHeaderSize(largeSize bool) uint64 {
+	size := uint64(8) // size(4) + type(4)
+	if largeSize {
+		size += 8
+	}
+	if h.Type.String() == TypeUUID {
+		size += uint64(len(uuid.UUID{}))
+	}
+	return size
+}
+
+// EncodedSize returns total box bytes once encoded with specified payload size.
+func (h Header) EncodedSize(payloadSize uint64) uint64 {
+	return h.encodedHeaderSize(h.useLargeSize(payloadSize)) + payloadSize
+}
+
+// Encode writes header with specified payload size.
+// Size 0 and large size will be kept if the header was parsed from them.
+func (h Header) Encode(w io.Writer, payloadSize uint64) error {
+	largeSize := h.useLargeSize(payloadSize)
+	boxSize := h.encodedHeaderSize(largeSize) + payloadSize
+
+	var size uint32
+	if largeSize {
+		size = 1
+	} else if !h.extendsToEnd() {
+		size = uint32(boxSize)
+	}
+	if err := util.WriteBigEndian(w, size, h.Type); err != nil {
+		return err
+	}
+
+	if largeSize {
+		if err := util.WriteBigEndian(w, boxSize); err != nil {
+			return err
+		}
+	}
+
+	if h.Type.String() == TypeUUID {
+		var userType uuid.UUID
+		if h.UserType != nil {
+			userType = *h.UserType
+		}
+		if err := util.WriteBigEndian(w, userType); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // String serializes Header.
 func (h FullHeader) String() string {
 	return fmt.Sprintf("Header:{%v} Version:%d Flags:%x",
@@ -168,10 +240,26 @@ func (f *FullHeader) ParseVersionFlag(r io.Reader) error {
 		return err
 	} else {
 		f.Version = data[0]
-		f.Flags = binary.BigEndian.Uint32(data)
+		f.Flags = binary.BigEndian.Uint32(data) & 0xFFFFFF
 	}
 
 	// minus used bytes for accurate payload size
 	f.PayloadSizeMinus(4)
 	return nil
 }
+
+// EncodedSize returns total box bytes once encoded with specified payload size,
+// which doesn't include `version` and `flag`.
+func (f FullHeader) EncodedSize(payloadSize uint64) uint64 {
+	return f.Header.EncodedSize(payloadSize + 4)
+}
+
+// Encode writes header, `version` and `flag` with specified payload size,
+// which doesn't include `version` and `flag`.
+func (f FullHeader) Encode(w io.Writer, payloadSize uint64) error {
+	if err := f.Header.Encode(w, payloadSize+4); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, uint32(f.Version)<<24|f.Flags&0xFFFFFF)
+}
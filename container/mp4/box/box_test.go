@@ -48,6 +48,44 @@ func TestParseHeader(t *testing.T) {
 	}
 }
 
+func TestEncodeHeader(t *testing.T) {
+	cases := [][]byte{
+		// size(4) + type(4)
+		{0x00, 0x00, 0x00, 0x0A, 'f', 'r', 'e', 'e', 0x01, 0x02},
+
+		// size 0, i.e., extends to the end
+		{0x00, 0x00, 0x00, 0x00, 'm', 'd', 'a', 't', 0x01, 0x02},
+
+		// size(4) + type(4) + largeSize(8)
+		{0x00, 0x00, 0x00, 0x01, 'm', 'd', 'a', 't', 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x12, 0x01, 0x02},
+
+		// size(4) + type(4) + uuid(16)
+		{0x00, 0x00, 0x00, 0x1A, 'u', 'u', 'i', 'd', 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F, 0x01, 0x02},
+	}
+
+	for _, c := range cases {
+		r := bytes.NewReader(c)
+		boxHeader := Header{}
+		if err := boxHeader.Parse(r, uint64(len(c))); err != nil {
+			t.Errorf("parse %v expect nil but got %v", c, err)
+			continue
+		}
+
+		payloadSize := uint64(r.Len())
+		if size := boxHeader.EncodedSize(payloadSize); size != uint64(len(c)) {
+			t.Errorf("encoded size of %v expect %d but got %d", c, len(c), size)
+		}
+
+		var buf bytes.Buffer
+		if err := boxHeader.Encode(&buf, payloadSize); err != nil {
+			t.Errorf("encode %v expect nil but got %v", c, err)
+		}
+		if expect := c[:len(c)-int(payloadSize)]; !bytes.Equal(buf.Bytes(), expect) {
+			t.Errorf("encode header expect %v but got %v", expect, buf.Bytes())
+		}
+	}
+}
+
 func TestParseBox(t *testing.T) {
 
 	cases := []struct {
@@ -92,6 +130,14 @@ func (b *testBox) ParsePayload(r io.Reader) error {
 	return nil
 }
 
+func (b *testBox) EncodedSize() uint64 {
+	return b.Header.EncodedSize(0)
+}
+
+func (b *testBox) Encode(w io.Writer) error {
+	return b.Header.Encode(w, 0)
+}
+
 func (t *testBox) CreateSubBox(h Header) (Box, error) {
 	if h.Type.String() == "mock" { // use self as mock
 		return &testBox{}, nil
@@ -18,6 +18,7 @@ const (
 	TypeUUID = "uuid"
 
 	TypeFtyp   = "ftyp"
+	TypeStyp   = "styp"
 	TypeFree   = "free"
 	TypeSkip   = "skip"
 	TypeWide   = "wide"
@@ -44,6 +45,7 @@ const (
 	TypeStsc   = "stsc"
 	TypeStsz   = "stsz"
 	TypeStco   = "stco"
+	TypeCo64   = "co64"
 	TypeCtts   = "ctts"
 	TypeSdtp   = "sdtp"
 	TypeDref   = "dref"
@@ -90,6 +92,7 @@ var boxTypes = map[string]BasicInfo{
 	TypeUUID: {Name: "UUID"},
 
 	TypeFtyp:   {Name: "File Type Box"},
+	TypeStyp:   {Name: "Segment Type Box"},
 	TypeFree:   {Name: "Free Space Box"},
 	TypeSkip:   {Name: "Free Space Box"},
 	TypeWide:   {Name: "Wide box"},
@@ -116,6 +119,7 @@ var boxTypes = map[string]BasicInfo{
 	TypeStsc:   {Name: "Sample To Chunk Box"},
 	TypeStsz:   {Name: "Sample Size Box"},
 	TypeStco:   {Name: "Chunk Offset Box"},
+	TypeCo64:   {Name: "Chunk Large Offset Box"},
 	TypeCtts:   {Name: "Composition Time to Sample Box"},
 	TypeSdtp:   {Name: "Independent and Disposable Samples Box"},
 	TypeDref:   {Name: "Data Reference Box"},
@@ -0,0 +1,87 @@
+// Package co64 represents Chunk Large Offset Box.
+package co64
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/container/mp4/box"
+	"github.com/wangyoucao577/medialib/util"
+)
+
+// Box represents a co64 box.
+type Box struct {
+	box.FullHeader `json:"full_header"`
+
+	EntryCount   uint32   `json:"entry_count"`
+	ChunkOffsets []uint64 `json:"chunk_offset,omitempty"`
+}
+
+// New creates a new Box.
+func New(h box.Header) box.Box {
+	return &Box{
+		FullHeader: box.FullHeader{
+			Header: h,
+		},
+	}
+}
+
+// ParsePayload parse payload which requires basic box already exist.
+func (b *Box) ParsePayload(r io.Reader) error {
+	if err := b.Validate(); err != nil {
+		glog.Warningf("box %s invalid, err %v", b.Type, err)
+		return nil
+	}
+
+	// parse full header additional information first
+	if err := b.FullHeader.ParseVersionFlag(r); err != nil {
+		return err
+	}
+
+	// start to parse payload
+	var parsedBytes uint64
+
+	data := make([]byte, 4)
+	if err := util.ReadOrError(r, data); err != nil {
+		return err
+	} else {
+		b.EntryCount = binary.BigEndian.Uint32(data)
+		parsedBytes += 4
+	}
+
+	data = make([]byte, 8)
+	for i := 0; i < int(b.EntryCount); i++ {
+		var chunkOffset uint64
+
+		if err := util.ReadOrError(r, data); err != nil {
+			return err
+		} else {
+			chunkOffset = binary.BigEndian.Uint64(data)
+			parsedBytes += 8
+		}
+
+		b.ChunkOffsets = append(b.ChunkOffsets, chunkOffset)
+	}
+
+	if parsedBytes != b.PayloadSize() {
+		return fmt.Errorf("box %s parsed bytes != payload size: %d != %d", b.Type, parsedBytes, b.PayloadSize())
+	}
+
+	return nil
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(4 + 8*uint64(len(b.ChunkOffsets)))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, 4+8*uint64(len(b.ChunkOffsets))); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, uint32(len(b.ChunkOffsets)), b.ChunkOffsets)
+}
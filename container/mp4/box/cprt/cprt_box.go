@@ -65,3 +65,22 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(2 + uint64(len(b.Notice)))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, 2+uint64(len(b.Notice))); err != nil {
+		return err
+	}
+
+	language := uint16(b.Pad&0x1) << 15
+	for i := 0; i < len(b.Language); i++ {
+		language |= uint16(b.Language[i]&0x1F) << (5 * (2 - i))
+	}
+
+	return util.WriteBigEndian(w, language, []byte(b.Notice))
+}
@@ -50,3 +50,17 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(8 + uint64(len(b.Value)))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.Header.Encode(w, 8+uint64(len(b.Value))); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, b.Type, b.Language, []byte(b.Value))
+}
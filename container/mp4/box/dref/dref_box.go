@@ -22,6 +22,7 @@ type Box struct {
 	UrnEntries []urn.Box `json:"urn,omitempty"`
 
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
 }
 
 // New creates a new Box.
@@ -50,6 +51,7 @@ func (b *Box) CreateSubBox(h box.Header) (box.Box, error) {
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
+	b.order.Record(h.Type.String())
 
 	switch h.Type.String() {
 	case box.TypeUrl:
@@ -107,3 +109,34 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (b *Box) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	for i := range b.UrlEntries {
+		groups[box.TypeUrl] = append(groups[box.TypeUrl], &b.UrlEntries[i])
+	}
+	for i := range b.UrnEntries {
+		groups[box.TypeUrn] = append(groups[box.TypeUrn], &b.UrnEntries[i])
+	}
+
+	return b.order.Sort(groups, []string{box.TypeUrl, box.TypeUrn})
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(4 + box.EncodedBoxesSize(b.subBoxes()))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	subBoxes := b.subBoxes()
+	if err := b.FullHeader.Encode(w, 4+box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	if err := util.WriteBigEndian(w, uint32(len(subBoxes))); err != nil {
+		return err
+	}
+	return box.EncodeBoxes(w, subBoxes)
+}
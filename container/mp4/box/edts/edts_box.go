@@ -16,6 +16,7 @@ type Box struct {
 	Elst *elst.Box `json:"elst,omitempty"`
 
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
 }
 
 // New creates a new Box.
@@ -41,6 +42,7 @@ func (b *Box) CreateSubBox(h box.Header) (box.Box, error) {
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
+	b.order.Record(h.Type.String())
 
 	switch h.Type.String() {
 	case box.TypeElst:
@@ -78,3 +80,28 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (b *Box) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	if b.Elst != nil {
+		groups[box.TypeElst] = []box.Box{b.Elst}
+	}
+
+	return b.order.Sort(groups, []string{box.TypeElst})
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(box.EncodedBoxesSize(b.subBoxes()))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	subBoxes := b.subBoxes()
+	if err := b.Header.Encode(w, box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	return box.EncodeBoxes(w, subBoxes)
+}
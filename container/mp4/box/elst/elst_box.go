@@ -79,7 +79,7 @@ func (b *Box) ParsePayload(r io.Reader) error {
 			b.SegmentDuration = append(b.SegmentDuration, uint64(num))
 
 			num = binary.BigEndian.Uint32(data[4:])
-			b.MediaTime = append(b.MediaTime, int64(num))
+			b.MediaTime = append(b.MediaTime, int64(int32(num))) // signed, e.g., -1 for empty edit
 			parsedBytes += 8
 		}
 
@@ -101,3 +101,45 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+func (b *Box) encodedPayloadSize() uint64 {
+	entrySize := uint64(12)
+	if b.Version == 1 {
+		entrySize = 20
+	}
+	return 4 + entrySize*uint64(len(b.SegmentDuration))
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(b.encodedPayloadSize())
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, b.encodedPayloadSize()); err != nil {
+		return err
+	}
+
+	if err := util.WriteBigEndian(w, uint32(len(b.SegmentDuration))); err != nil {
+		return err
+	}
+
+	for i := range b.SegmentDuration {
+		if b.Version == 1 {
+			if err := util.WriteBigEndian(w, b.SegmentDuration[i], b.MediaTime[i]); err != nil {
+				return err
+			}
+		} else {
+			if err := util.WriteBigEndian(w, uint32(b.SegmentDuration[i]), int32(b.MediaTime[i])); err != nil {
+				return err
+			}
+		}
+
+		if err := util.WriteBigEndian(w, b.MediaRateInteger[i], b.MediaRateFraction[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -53,3 +53,17 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(uint64(len(b.Data)))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.Header.Encode(w, uint64(len(b.Data))); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, b.Data)
+}
@@ -1,4 +1,4 @@
-// Package ftyp defines File Type Box.
+// Package ftyp defines File Type Box, which is also used by Segment Type Box(styp) since they have the same syntax.
 package ftyp
 
 import (
@@ -67,3 +67,21 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+func (b *Box) encodedPayloadSize() uint64 {
+	return 8 + 4*uint64(len(b.CompatibleBrands))
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(b.encodedPayloadSize())
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.Header.Encode(w, b.encodedPayloadSize()); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, b.MajorBrand, b.MinorVersion, b.CompatibleBrands)
+}
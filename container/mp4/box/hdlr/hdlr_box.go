@@ -20,6 +20,9 @@ type Box struct {
 	HandlerType box.FixedArray4Bytes `json:"handler_type"`
 	// reserved 32 * 3 = 96 bits
 	Name string `json:"name"`
+
+	reserved         [12]byte // keep as it is, e.g., quicktime stores component manufacturer in it
+	nameUnterminated bool     // no trailing 0 in parsed name, which is possible in quicktime
 }
 
 // New creates a new Box.
@@ -60,7 +63,7 @@ func (b *Box) ParsePayload(r io.Reader) error {
 	}
 
 	// reserved bytes
-	if err := util.ReadOrError(r, make([]byte, 12)); err != nil {
+	if err := util.ReadOrError(r, b.reserved[:]); err != nil {
 		return err
 	} else {
 		parsedBytes += 12
@@ -75,9 +78,34 @@ func (b *Box) ParsePayload(r io.Reader) error {
 		if err := util.ReadOrError(r, data); err != nil {
 			return err
 		}
+		b.nameUnterminated = !bytes.HasSuffix(data, []byte{0})
 		data = bytes.TrimSuffix(data, []byte{0}) // trim last 0 to avoid `\u0000` in encoded json
 		b.Name = string(data)
+	} else {
+		b.nameUnterminated = true
 	}
 
 	return nil
 }
+
+func (b *Box) encodedName() []byte {
+	if b.nameUnterminated {
+		return []byte(b.Name)
+	}
+	return append([]byte(b.Name), 0)
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(20 + uint64(len(b.encodedName())))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	name := b.encodedName()
+	if err := b.FullHeader.Encode(w, 20+uint64(len(name))); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, b.Predefined, b.HandlerType, b.reserved, name)
+}
@@ -21,6 +21,7 @@ type Box struct {
 	Data         []data.Box  `json:"unknown_type_data,omitempty"` // unspecified types
 
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
 }
 
 // New creates a new Box.
@@ -52,11 +53,14 @@ func (b *Box) CreateSubBox(h box.Header) (box.Box, error) {
 	switch h.Type.String() {
 	case box.TypeDottoo:
 		b.EncodingTool = createdBox.(*dottoo.Box)
+		b.order.Record(box.TypeDottoo)
 	case box.TypeDesc:
 		b.Desc = createdBox.(*desc.Box)
+		b.order.Record(box.TypeDesc)
 	default:
 		b.Data = append(b.Data, *createdBox.(*data.Box))
 		createdBox = &b.Data[len(b.Data)-1]
+		b.order.Record(box.TypeData) // all unspecified types
 	}
 
 	return createdBox, nil
@@ -90,3 +94,34 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (b *Box) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	if b.EncodingTool != nil {
+		groups[box.TypeDottoo] = []box.Box{b.EncodingTool}
+	}
+	if b.Desc != nil {
+		groups[box.TypeDesc] = []box.Box{b.Desc}
+	}
+	for i := range b.Data {
+		groups[box.TypeData] = append(groups[box.TypeData], &b.Data[i])
+	}
+
+	return b.order.Sort(groups, []string{box.TypeDottoo, box.TypeDesc, box.TypeData})
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(box.EncodedBoxesSize(b.subBoxes()))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	subBoxes := b.subBoxes()
+	if err := b.Header.Encode(w, box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	return box.EncodeBoxes(w, subBoxes)
+}
@@ -20,6 +20,12 @@ type Box interface {
 
 	// Parse payload. It requires BasicBox(Header) has been set to the subset Box.
 	ParsePayload(r io.Reader) error
+
+	// EncodedSize returns total bytes of the box once encoded, includes header.
+	EncodedSize() uint64
+
+	// Encode writes the whole box, includes header and payload.
+	Encode(w io.Writer) error
 }
 
 // ParentBox defines functions if a box possible to have sub/child box.
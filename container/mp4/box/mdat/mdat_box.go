@@ -14,7 +14,19 @@ import (
 type Box struct {
 	box.Header `json:"header"`
 
-	Data []byte `json:"-"`
+	Offset uint64 `json:"offset"` // payload offset from the beginning of the input
+	Length uint64 `json:"length"` // payload length, it's available even if the box runs to the end of the input(size 0)
+
+	Data []byte `json:"-"` // only be filled if the input isn't seekable, otherwise read payload lazily by ReadAt
+
+	ra      io.ReaderAt // input for lazily reading if seekable
+	payload io.ReaderAt // payload for lazily reading if set by SetPayload
+}
+
+// seekableReader represents input that supports lazy reading.
+type seekableReader interface {
+	io.ReadSeeker
+	io.ReaderAt
 }
 
 // New creates a new Box.
@@ -25,6 +37,8 @@ func New(h box.Header) box.Box {
 }
 
 // ParsePayload parse payload which requires basic box already exist.
+// If r implements both io.ReadSeeker and io.ReaderAt(e.g. *os.File), the payload will be skipped and only its
+// offset and length will be recorded, otherwise the payload will be read into memory.
 func (b *Box) ParsePayload(r io.Reader) error {
 	if err := b.Validate(); err != nil {
 		glog.Warningf("box %s invalid, err %v", b.Type, err)
@@ -33,14 +47,116 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	payloadSize := b.PayloadSize()
 
-	if payloadSize == 0 {
-		return fmt.Errorf("TODO: box %s payload size 0, need to read until EOF", b.Type)
+	if sr, ok := r.(seekableReader); ok {
+		if offset, err := sr.Seek(0, io.SeekCurrent); err == nil { // e.g., stdin pipe is not seekable actually
+			return b.skipPayload(sr, uint64(offset), payloadSize)
+		}
+	}
+
+	if payloadSize == 0 { // read until EOF
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		b.Data = data
+		b.Length = uint64(len(data))
+		return nil
 	}
 
 	b.Data = make([]byte, payloadSize)
 	if err := util.ReadOrError(r, b.Data); err != nil {
 		return err
 	}
+	b.Length = payloadSize
+
+	return nil
+}
+
+func (b *Box) skipPayload(sr seekableReader, offset, payloadSize uint64) error {
+	b.Offset = offset
+	b.ra = sr
+
+	if payloadSize == 0 { // box extends to the end of the input
+		end, err := sr.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+		b.Length = uint64(end) - offset
+		return nil
+	}
+
+	if _, err := sr.Seek(int64(payloadSize), io.SeekCurrent); err != nil {
+		return err
+	}
+	b.Length = payloadSize
 
 	return nil
 }
+
+// SetPayload sets payload to be read lazily from ra, whose offset is relative to the beginning of the payload.
+// It's useful to write large payload without loading it into memory, Data will be ignored then.
+func (b *Box) SetPayload(ra io.ReaderAt, length uint64) {
+	b.payload = ra
+	b.Length = length
+	b.Data = nil
+}
+
+// ReadAt implements io.ReaderAt to read payload bytes, off is relative to the beginning of the payload.
+func (b *Box) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("box %s invalid offset %d", b.Type, off)
+	}
+	length := b.payloadLength()
+	if uint64(off) >= length {
+		return 0, io.EOF
+	}
+
+	var eof bool
+	if remain := length - uint64(off); uint64(len(p)) > remain {
+		p = p[:remain]
+		eof = true
+	}
+
+	var n int
+	var err error
+	if b.payload != nil {
+		n, err = b.payload.ReadAt(p, off)
+	} else if b.ra != nil {
+		n, err = b.ra.ReadAt(p, int64(b.Offset)+off)
+	} else {
+		n = copy(p, b.Data[off:])
+	}
+	if err == nil && eof {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// payloadLength returns payload length, in memory data will be used if the payload isn't read lazily.
+func (b *Box) payloadLength() uint64 {
+	if b.ra == nil && b.payload == nil {
+		return uint64(len(b.Data))
+	}
+	return b.Length
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(b.payloadLength())
+}
+
+// EncodedHeaderSize returns header bytes of the box once encoded, i.e., offset of payload in the encoded box.
+func (b *Box) EncodedHeaderSize() uint64 {
+	return b.EncodedSize() - b.payloadLength()
+}
+
+// Encode writes the box, payload will be read from the input if it hasn't been loaded into memory.
+func (b *Box) Encode(w io.Writer) error {
+	length := b.payloadLength()
+	if err := b.Header.Encode(w, length); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(w, io.NewSectionReader(b, 0, int64(length)))
+	return err
+}
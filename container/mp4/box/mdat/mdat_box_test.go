@@ -0,0 +1,67 @@
+package mdat
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/wangyoucao577/medialib/container/mp4/box"
+)
+
+// onlyReader hides io.Seeker and io.ReaderAt to force in-memory parsing.
+type onlyReader struct {
+	io.Reader
+}
+
+func TestParsePayload(t *testing.T) {
+	payload := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07}
+
+	cases := []struct {
+		data           []byte
+		seekable       bool
+		expectedOffset uint64
+		expectedLength uint64
+	}{
+		{append([]byte{0x00, 0x00, 0x00, 0x10, 'm', 'd', 'a', 't'}, payload...), true, 8, 8},
+		{append([]byte{0x00, 0x00, 0x00, 0x10, 'm', 'd', 'a', 't'}, payload...), false, 0, 8},
+
+		// size 0 means box extends to the end of the input
+		{append([]byte{0x00, 0x00, 0x00, 0x00, 'm', 'd', 'a', 't'}, payload...), true, 8, 8},
+		{append([]byte{0x00, 0x00, 0x00, 0x00, 'm', 'd', 'a', 't'}, payload...), false, 0, 8},
+
+		// large size
+		{append([]byte{0x00, 0x00, 0x00, 0x01, 'm', 'd', 'a', 't', 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x18}, payload...), true, 16, 8},
+	}
+
+	for _, c := range cases {
+		var r io.Reader = bytes.NewReader(c.data)
+		if !c.seekable {
+			r = onlyReader{r}
+		}
+
+		h := box.Header{}
+		if err := h.Parse(r, uint64(len(c.data))); err != nil {
+			t.Fatalf("parse header %v failed, err %v", c.data, err)
+		}
+		b := New(h).(*Box)
+		if err := b.ParsePayload(r); err != nil {
+			t.Errorf("parse %v expect nil but got %v", c.data, err)
+			continue
+		}
+
+		if b.Offset != c.expectedOffset || b.Length != c.expectedLength {
+			t.Errorf("parse %v expect offset %d length %d but got %d %d", c.data, c.expectedOffset, c.expectedLength, b.Offset, b.Length)
+		}
+		if c.seekable && b.Data != nil {
+			t.Errorf("parse %v expect lazy payload but got %v", c.data, b.Data)
+		}
+
+		got := make([]byte, 4)
+		if _, err := b.ReadAt(got, 2); err != nil || !bytes.Equal(got, payload[2:6]) {
+			t.Errorf("read %v expect %v but got %v, err %v", c.data, payload[2:6], got, err)
+		}
+		if n, err := b.ReadAt(got, 6); err != io.EOF || n != 2 {
+			t.Errorf("read %v at tail expect %d bytes and EOF but got %d, err %v", c.data, 2, n, err)
+		}
+	}
+}
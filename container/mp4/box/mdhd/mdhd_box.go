@@ -158,3 +158,39 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+func (b *Box) encodedPayloadSize() uint64 {
+	if b.Version == 1 {
+		return 28 + 4
+	}
+	return 16 + 4
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(b.encodedPayloadSize())
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, b.encodedPayloadSize()); err != nil {
+		return err
+	}
+
+	if b.Version == 1 {
+		if err := util.WriteBigEndian(w, b.CreationTime, b.ModificationTime, b.Timescale, b.Duration); err != nil {
+			return err
+		}
+	} else {
+		if err := util.WriteBigEndian(w, uint32(b.CreationTime), uint32(b.ModificationTime), b.Timescale, uint32(b.Duration)); err != nil {
+			return err
+		}
+	}
+
+	language := uint16(b.Pad&0x1) << 15
+	for i := 0; i < len(b.Language); i++ {
+		language |= uint16((b.Language[i]-languageCodeOffset)&0x1F) << (5 * (2 - i))
+	}
+
+	return util.WriteBigEndian(w, language, b.Predefined)
+}
@@ -20,6 +20,7 @@ type Box struct {
 	Minf *minf.Box `json:"minf,omitempty"`
 
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
 }
 
 // New creates a new Box.
@@ -47,6 +48,7 @@ func (b *Box) CreateSubBox(h box.Header) (box.Box, error) {
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
+	b.order.Record(h.Type.String())
 
 	switch h.Type.String() {
 	case box.TypeMdhd:
@@ -91,3 +93,34 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (b *Box) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	if b.Mdhd != nil {
+		groups[box.TypeMdhd] = []box.Box{b.Mdhd}
+	}
+	if b.Hdlr != nil {
+		groups[box.TypeHdlr] = []box.Box{b.Hdlr}
+	}
+	if b.Minf != nil {
+		groups[box.TypeMinf] = []box.Box{b.Minf}
+	}
+
+	return b.order.Sort(groups, []string{box.TypeMdhd, box.TypeHdlr, box.TypeMinf})
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(box.EncodedBoxesSize(b.subBoxes()))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	subBoxes := b.subBoxes()
+	if err := b.Header.Encode(w, box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	return box.EncodeBoxes(w, subBoxes)
+}
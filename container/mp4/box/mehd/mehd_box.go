@@ -54,3 +54,27 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+func (b *Box) encodedPayloadSize() uint64 {
+	if b.Version == 1 {
+		return 8
+	}
+	return 4
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(b.encodedPayloadSize())
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, b.encodedPayloadSize()); err != nil {
+		return err
+	}
+
+	if b.Version == 1 {
+		return util.WriteBigEndian(w, b.FragmentDuration)
+	}
+	return util.WriteBigEndian(w, uint32(b.FragmentDuration))
+}
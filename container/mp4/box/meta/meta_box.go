@@ -12,18 +12,21 @@ import (
 
 // Box represents a meta box.
 type Box struct {
-	box.Header `json:"header"`
+	box.FullHeader `json:"full_header"`
 
 	Hdlr *hdlr.Box `json:"hdlr,omitempty"`
 	Ilst *ilst.Box `json:"ilst,omitempty"`
 
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
 }
 
 // New creates a new Box.
 func New(h box.Header) box.Box {
 	return &Box{
-		Header: h,
+		FullHeader: box.FullHeader{
+			Header: h,
+		},
 
 		boxesCreator: map[string]box.NewFunc{
 			box.TypeHdlr: hdlr.New,
@@ -44,6 +47,7 @@ func (b *Box) CreateSubBox(h box.Header) (box.Box, error) {
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
+	b.order.Record(h.Type.String())
 
 	switch h.Type.String() {
 	case box.TypeHdlr:
@@ -61,6 +65,11 @@ func (b *Box) ParsePayload(r io.Reader) error {
 		return nil
 	}
 
+	// parse full header additional information first
+	if err := b.FullHeader.ParseVersionFlag(r); err != nil {
+		return err
+	}
+
 	var parsedBytes uint64
 	for {
 		readBytes, err := box.ParseBox(r, b, b.PayloadSize()-parsedBytes)
@@ -82,3 +91,31 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (b *Box) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	if b.Hdlr != nil {
+		groups[box.TypeHdlr] = []box.Box{b.Hdlr}
+	}
+	if b.Ilst != nil {
+		groups[box.TypeIlst] = []box.Box{b.Ilst}
+	}
+
+	return b.order.Sort(groups, []string{box.TypeHdlr, box.TypeIlst})
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(box.EncodedBoxesSize(b.subBoxes()))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	subBoxes := b.subBoxes()
+	if err := b.FullHeader.Encode(w, box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	return box.EncodeBoxes(w, subBoxes)
+}
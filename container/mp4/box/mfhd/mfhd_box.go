@@ -56,3 +56,17 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(4)
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, 4); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, b.SequenceNumber)
+}
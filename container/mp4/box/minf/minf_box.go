@@ -26,6 +26,7 @@ type Box struct {
 	hdlrPassed *hdlr.Box `json:"-"`              // passed from parent for later use
 
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
 }
 
 // New creates a new Box.
@@ -60,6 +61,7 @@ func (b *Box) CreateSubBox(h box.Header) (box.Box, error) {
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
+	b.order.Record(h.Type.String())
 
 	switch h.Type.String() {
 	case box.TypeStbl:
@@ -111,3 +113,40 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (b *Box) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	if b.Vmhd != nil {
+		groups[box.TypeVmhd] = []box.Box{b.Vmhd}
+	}
+	if b.Smhd != nil {
+		groups[box.TypeSmhd] = []box.Box{b.Smhd}
+	}
+	if b.Hdlr != nil {
+		groups[box.TypeHdlr] = []box.Box{b.Hdlr}
+	}
+	if b.Dinf != nil {
+		groups[box.TypeDinf] = []box.Box{b.Dinf}
+	}
+	if b.Stbl != nil {
+		groups[box.TypeStbl] = []box.Box{b.Stbl}
+	}
+
+	return b.order.Sort(groups, []string{box.TypeVmhd, box.TypeSmhd, box.TypeHdlr, box.TypeDinf, box.TypeStbl})
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(box.EncodedBoxesSize(b.subBoxes()))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	subBoxes := b.subBoxes()
+	if err := b.Header.Encode(w, box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	return box.EncodeBoxes(w, subBoxes)
+}
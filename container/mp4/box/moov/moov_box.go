@@ -25,6 +25,7 @@ type Box struct {
 	Meta []meta.Box `json:"meta,omitempty"`
 
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
 }
 
 // New creates a new Box.
@@ -54,6 +55,7 @@ func (b *Box) CreateSubBox(h box.Header) (box.Box, error) {
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
+	b.order.Record(h.Type.String())
 
 	switch h.Type.String() {
 	case box.TypeMvhd:
@@ -107,3 +109,40 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (b *Box) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	if b.Mvhd != nil {
+		groups[box.TypeMvhd] = []box.Box{b.Mvhd}
+	}
+	for i := range b.Trak {
+		groups[box.TypeTrak] = append(groups[box.TypeTrak], &b.Trak[i])
+	}
+	if b.Mvex != nil {
+		groups[box.TypeMvex] = []box.Box{b.Mvex}
+	}
+	if b.Udta != nil {
+		groups[box.TypeUdta] = []box.Box{b.Udta}
+	}
+	for i := range b.Meta {
+		groups[box.TypeMeta] = append(groups[box.TypeMeta], &b.Meta[i])
+	}
+
+	return b.order.Sort(groups, []string{box.TypeMvhd, box.TypeTrak, box.TypeMvex, box.TypeUdta, box.TypeMeta})
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(box.EncodedBoxesSize(b.subBoxes()))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	subBoxes := b.subBoxes()
+	if err := b.Header.Encode(w, box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	return box.EncodeBoxes(w, subBoxes)
+}
@@ -14,10 +14,11 @@ import (
 type Box struct {
 	box.Header `json:"header"`
 
-	Mehd *mehd.Box `json:"mehd,omitempty"`
-	Trex *trex.Box `json:"trex"`
+	Mehd *mehd.Box  `json:"mehd,omitempty"`
+	Trex []trex.Box `json:"trex"` // one per track
 
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
 }
 
 // New creates a new Box.
@@ -44,12 +45,14 @@ func (b *Box) CreateSubBox(h box.Header) (box.Box, error) {
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
+	b.order.Record(h.Type.String())
 
 	switch h.Type.String() {
 	case box.TypeMehd:
 		b.Mehd = createdBox.(*mehd.Box)
 	case box.TypeTrex:
-		b.Trex = createdBox.(*trex.Box)
+		b.Trex = append(b.Trex, *createdBox.(*trex.Box))
+		createdBox = &b.Trex[len(b.Trex)-1]
 	}
 
 	return createdBox, nil
@@ -83,3 +86,31 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (b *Box) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	if b.Mehd != nil {
+		groups[box.TypeMehd] = []box.Box{b.Mehd}
+	}
+	for i := range b.Trex {
+		groups[box.TypeTrex] = append(groups[box.TypeTrex], &b.Trex[i])
+	}
+
+	return b.order.Sort(groups, []string{box.TypeMehd, box.TypeTrex})
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(box.EncodedBoxesSize(b.subBoxes()))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	subBoxes := b.subBoxes()
+	if err := b.Header.Encode(w, box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	return box.EncodeBoxes(w, subBoxes)
+}
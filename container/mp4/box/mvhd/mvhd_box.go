@@ -190,3 +190,34 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+func (b *Box) encodedPayloadSize() uint64 {
+	if b.Version == 1 {
+		return 28 + 80
+	}
+	return 16 + 80
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(b.encodedPayloadSize())
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, b.encodedPayloadSize()); err != nil {
+		return err
+	}
+
+	if b.Version == 1 {
+		if err := util.WriteBigEndian(w, b.CreationTime, b.ModificationTime, b.Timescale, b.Duration); err != nil {
+			return err
+		}
+	} else {
+		if err := util.WriteBigEndian(w, uint32(b.CreationTime), uint32(b.ModificationTime), b.Timescale, uint32(b.Duration)); err != nil {
+			return err
+		}
+	}
+
+	return util.WriteBigEndian(w, b.Rate, b.Volume, make([]byte, 10), b.Matrix, b.PreDefined, b.NextTrackID)
+}
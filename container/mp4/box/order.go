@@ -0,0 +1,29 @@
+package box
+
+// Order records sub box types in parsed sequence, so that sub boxes can be encoded in the same sequence.
+type Order []string
+
+// Record appends parsed sub box type.
+func (o *Order) Record(boxType string) {
+	*o = append(*o, boxType)
+}
+
+// Sort returns sub boxes in recorded sequence, which are grouped by type.
+// Boxes that haven't been recorded(e.g., created manually) will be appended by the sequence of defaultTypes.
+func (o Order) Sort(groups map[string][]Box, defaultTypes []string) []Box {
+	var boxes []Box
+	used := map[string]int{}
+
+	for _, t := range o {
+		if used[t] < len(groups[t]) {
+			boxes = append(boxes, groups[t][used[t]])
+			used[t]++
+		}
+	}
+
+	for _, t := range defaultTypes {
+		boxes = append(boxes, groups[t][used[t]:]...)
+	}
+
+	return boxes
+}
@@ -22,6 +22,7 @@ type AV1SampleEntry struct {
 	Pasp      *pasp.Box                `json:"pasp,omitempty"`
 
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
 }
 
 // New creates a new Box.
@@ -53,6 +54,7 @@ func (a *AV1SampleEntry) CreateSubBox(h box.Header) (box.Box, error) {
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
+	a.order.Record(h.Type.String())
 
 	switch h.Type.String() {
 	case box.TypeAv1C:
@@ -99,3 +101,38 @@ func (a *AV1SampleEntry) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (a *AV1SampleEntry) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	if a.AV1Config != nil {
+		groups[box.TypeAv1C] = []box.Box{a.AV1Config}
+	}
+	if a.Btrt != nil {
+		groups[box.TypeBtrt] = []box.Box{a.Btrt}
+	}
+	if a.Pasp != nil {
+		groups[box.TypePasp] = []box.Box{a.Pasp}
+	}
+
+	return a.order.Sort(groups, []string{box.TypeAv1C, box.TypeBtrt, box.TypePasp})
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (a *AV1SampleEntry) EncodedSize() uint64 {
+	return a.Header.EncodedSize(a.VisualSampleEntry.EncodedDataSize() + box.EncodedBoxesSize(a.subBoxes()))
+}
+
+// Encode writes the box.
+func (a *AV1SampleEntry) Encode(w io.Writer) error {
+	subBoxes := a.subBoxes()
+	if err := a.Header.Encode(w, a.VisualSampleEntry.EncodedDataSize()+box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	if err := a.VisualSampleEntry.EncodeData(w); err != nil {
+		return err
+	}
+
+	return box.EncodeBoxes(w, subBoxes)
+}
@@ -15,6 +15,8 @@ type AV1ConfigrationBox struct {
 	box.Header `json:"header"`
 
 	AV1Config AV1CodecConfigurationRecord `json:"av1_config"`
+
+	unparsed []byte // remain bytes that haven't been parsed yet
 }
 
 // New creates a new Box.
@@ -46,8 +48,8 @@ func (a *AV1ConfigrationBox) ParsePayload(r io.Reader) error {
 			remainBytes := a.PayloadSize() - parsedBytes
 			glog.Warningf("sample entry box type %s still has %d bytes hasn't been parsed yet, ignore them", a.Type, remainBytes)
 
-			// ignore pre_defined 2 bytes in here
-			if err := util.ReadOrError(r, make([]byte, remainBytes)); err != nil {
+			a.unparsed = make([]byte, remainBytes)
+			if err := util.ReadOrError(r, a.unparsed); err != nil {
 				return err
 			}
 		}
@@ -55,3 +57,20 @@ func (a *AV1ConfigrationBox) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (a *AV1ConfigrationBox) EncodedSize() uint64 {
+	return a.Header.EncodedSize(a.AV1Config.EncodedSize() + uint64(len(a.unparsed)))
+}
+
+// Encode writes the box.
+func (a *AV1ConfigrationBox) Encode(w io.Writer) error {
+	if err := a.Header.Encode(w, a.AV1Config.EncodedSize()+uint64(len(a.unparsed))); err != nil {
+		return err
+	}
+
+	if err := a.AV1Config.Encode(w); err != nil {
+		return err
+	}
+	return util.WriteBigEndian(w, a.unparsed)
+}
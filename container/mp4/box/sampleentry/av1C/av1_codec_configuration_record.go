@@ -68,3 +68,24 @@ func (a *AV1CodecConfigurationRecord) Parse(r io.Reader) (uint64, error) {
 
 	return parsedBytes, nil
 }
+
+// EncodedSize returns bytes of AV1CodecConfigurationRecord once encoded.
+func (a *AV1CodecConfigurationRecord) EncodedSize() uint64 {
+	return 4 + uint64(len(a.ConfigOBUs))
+}
+
+// Encode writes AV1CodecConfigurationRecord.
+func (a *AV1CodecConfigurationRecord) Encode(w io.Writer) error {
+	var initialPresentationDelayMinusOne uint8
+	if a.InitialPresentationDelayPresent > 0 && a.InitialPresentationDelayMinusOne != nil {
+		initialPresentationDelayMinusOne = *a.InitialPresentationDelayMinusOne & 0xF
+	}
+
+	return util.WriteBigEndian(w,
+		(a.Marker&0x1)<<7|a.Version&0x7F,
+		(a.SeqProfile&0x7)<<5|a.SeqLevelIdx0&0x1F,
+		(a.SeqTier0&0x1)<<7|(a.HighBitdepth&0x1)<<6|(a.TwelveBit&0x1)<<5|(a.Monochrome&0x1)<<4|
+			(a.ChromaSubsamplingX&0x1)<<3|(a.ChromaSubsamplingY&0x1)<<2|a.ChromaSamplePosition&0x3,
+		(a.InitialPresentationDelayPresent&0x1)<<4|initialPresentationDelayMinusOne,
+		a.ConfigOBUs)
+}
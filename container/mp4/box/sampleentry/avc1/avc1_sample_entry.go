@@ -20,6 +20,7 @@ type AVCSampleEntry struct {
 	Btrt      *btrt.Box                `json:"btrt,omitempty"`
 
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
 }
 
 // New creates a new Box.
@@ -50,6 +51,7 @@ func (a *AVCSampleEntry) CreateSubBox(h box.Header) (box.Box, error) {
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
+	a.order.Record(h.Type.String())
 
 	switch h.Type.String() {
 	case box.TypeAvcC:
@@ -94,3 +96,35 @@ func (a *AVCSampleEntry) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (a *AVCSampleEntry) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	if a.AVCConfig != nil {
+		groups[box.TypeAvcC] = []box.Box{a.AVCConfig}
+	}
+	if a.Btrt != nil {
+		groups[box.TypeBtrt] = []box.Box{a.Btrt}
+	}
+
+	return a.order.Sort(groups, []string{box.TypeAvcC, box.TypeBtrt})
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (a *AVCSampleEntry) EncodedSize() uint64 {
+	return a.Header.EncodedSize(a.VisualSampleEntry.EncodedDataSize() + box.EncodedBoxesSize(a.subBoxes()))
+}
+
+// Encode writes the box.
+func (a *AVCSampleEntry) Encode(w io.Writer) error {
+	subBoxes := a.subBoxes()
+	if err := a.Header.Encode(w, a.VisualSampleEntry.EncodedDataSize()+box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	if err := a.VisualSampleEntry.EncodeData(w); err != nil {
+		return err
+	}
+
+	return box.EncodeBoxes(w, subBoxes)
+}
@@ -15,6 +15,8 @@ type AVCConfigrationBox struct {
 	box.Header `json:"header"`
 
 	AVCConfig AVCDecoderConfigurationRecord `json:"avc_config"`
+
+	unparsed []byte // remain bytes that haven't been parsed yet
 }
 
 // New creates a new Box.
@@ -46,8 +48,8 @@ func (a *AVCConfigrationBox) ParsePayload(r io.Reader) error {
 			remainBytes := a.PayloadSize() - parsedBytes
 			glog.Warningf("sample entry box type %s still has %d bytes hasn't been parsed yet, ignore them", a.Type, remainBytes)
 
-			// ignore pre_defined 2 bytes in here
-			if err := util.ReadOrError(r, make([]byte, remainBytes)); err != nil {
+			a.unparsed = make([]byte, remainBytes)
+			if err := util.ReadOrError(r, a.unparsed); err != nil {
 				return err
 			}
 		}
@@ -55,3 +57,20 @@ func (a *AVCConfigrationBox) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (a *AVCConfigrationBox) EncodedSize() uint64 {
+	return a.Header.EncodedSize(a.AVCConfig.EncodedSize() + uint64(len(a.unparsed)))
+}
+
+// Encode writes the box.
+func (a *AVCConfigrationBox) Encode(w io.Writer) error {
+	if err := a.Header.Encode(w, a.AVCConfig.EncodedSize()+uint64(len(a.unparsed))); err != nil {
+		return err
+	}
+
+	if err := a.AVCConfig.Encode(w); err != nil {
+		return err
+	}
+	return util.WriteBigEndian(w, a.unparsed)
+}
@@ -159,3 +159,40 @@ func (a *AVCDecoderConfigurationRecord) Parse(r io.Reader) (uint64, error) {
 
 	return parsedBytes, nil
 }
+
+// EncodedSize returns bytes of AVCDecoderConfigurationRecord once encoded.
+func (a *AVCDecoderConfigurationRecord) EncodedSize() uint64 {
+	size := uint64(7)
+	for _, ps := range [][]LengthParameterSetNALU{a.LengthSPSNALU, a.LengthPPSNALU} {
+		for i := range ps {
+			size += 2 + uint64(len(ps[i].NALUnit.Raw()))
+		}
+	}
+	return size
+}
+
+// Encode writes AVCDecoderConfigurationRecord.
+func (a *AVCDecoderConfigurationRecord) Encode(w io.Writer) error {
+	if err := util.WriteBigEndian(w, a.ConfigurationVersion, a.AVCProfileIndication, a.ProfileCompatibility, a.AVCLevelIndication,
+		0xFC|a.LengthSizeMinusOne&0x3, 0xE0|uint8(len(a.LengthSPSNALU))&0x1F); err != nil {
+		return err
+	}
+	if err := encodeParameterSets(w, a.LengthSPSNALU); err != nil {
+		return err
+	}
+
+	if err := util.WriteBigEndian(w, uint8(len(a.LengthPPSNALU))); err != nil {
+		return err
+	}
+	return encodeParameterSets(w, a.LengthPPSNALU)
+}
+
+func encodeParameterSets(w io.Writer, ps []LengthParameterSetNALU) error {
+	for i := range ps {
+		raw := ps[i].NALUnit.Raw()
+		if err := util.WriteBigEndian(w, uint16(len(raw)), raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -65,3 +65,17 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(12)
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.Header.Encode(w, 12); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, b.BufferSizeDB, b.MaxBitrate, b.AvgBitrate)
+}
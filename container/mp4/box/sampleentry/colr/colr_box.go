@@ -12,6 +12,8 @@ import (
 // Box represents a colr box.
 type Box struct {
 	box.Header `json:"header"`
+
+	Data []byte `json:"-"` // raw payload that hasn't been parsed yet
 }
 
 // New creates a new Box.
@@ -30,9 +32,24 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	//TODO: these can be removed if we have supported full AVCDecoderConfigurationRecord parsing
 	glog.Warningf("box type %s still has %d bytes hasn't been parsed yet, ignore them", b.Type, b.PayloadSize())
-	if err := util.ReadOrError(r, make([]byte, b.PayloadSize())); err != nil {
+	b.Data = make([]byte, b.PayloadSize())
+	if err := util.ReadOrError(r, b.Data); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(uint64(len(b.Data)))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.Header.Encode(w, uint64(len(b.Data))); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, b.Data)
+}
@@ -1,13 +1,23 @@
 package esds
 
 import (
+	"bytes"
 	"encoding/binary"
 	"io"
 
 	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/audio/aac"
 	"github.com/wangyoucao577/medialib/util"
 )
 
+// Object type indications of AAC, defined in https://mp4ra.org/#/object_types.
+const (
+	ObjectTypeIndicationAudioMPEG4        = 0x40 // Audio ISO/IEC 14496-3
+	ObjectTypeIndicationAudioMPEG2AACMain = 0x66 // Audio ISO/IEC 13818-7 Main Profile
+	ObjectTypeIndicationAudioMPEG2AACLC   = 0x67 // Audio ISO/IEC 13818-7 LowComplexity Profile
+	ObjectTypeIndicationAudioMPEG2AACSSR  = 0x68 // Audio ISO/IEC 13818-7 Scaleable Sampling Rate Profile
+)
+
 // DecoderConfigDescriptor represents DecoderConfigDescriptor.
 type DecoderConfigDescriptor struct {
 	Descriptor Descriptor `json:"descriptor"`
@@ -75,6 +85,7 @@ func (d *DecoderConfigDescriptor) parse(r io.Reader) (uint64, error) {
 		} else {
 			parsedBytes += bytes
 		}
+		d.decodeAudioSpecificConfig()
 	}
 
 	if parsedBytes-parsedHeaderBytes != uint64(d.Descriptor.Size) {
@@ -83,3 +94,52 @@ func (d *DecoderConfigDescriptor) parse(r io.Reader) (uint64, error) {
 
 	return parsedBytes, nil
 }
+
+// decodeAudioSpecificConfig decodes DecoderSpecificInfo as AudioSpecificConfig for AAC, failure will be ignored
+// since the raw data is still available.
+func (d *DecoderConfigDescriptor) decodeAudioSpecificConfig() {
+	switch d.ObjectTypeIndication {
+	case ObjectTypeIndicationAudioMPEG4, ObjectTypeIndicationAudioMPEG2AACMain,
+		ObjectTypeIndicationAudioMPEG2AACLC, ObjectTypeIndicationAudioMPEG2AACSSR:
+	default:
+		return
+	}
+	if len(d.DecoderSpecificInfo.Data) == 0 {
+		return
+	}
+
+	config := &aac.AudioSpecificConfig{}
+	if _, err := config.Parse(bytes.NewReader(d.DecoderSpecificInfo.Data), len(d.DecoderSpecificInfo.Data)); err != nil {
+		glog.Warningf("parse AudioSpecificConfig %x failed, err %v", d.DecoderSpecificInfo.Data, err)
+		return
+	}
+	d.DecoderSpecificInfo.AudioSpecificConfig = config
+}
+
+func (d *DecoderConfigDescriptor) encodedPayloadSize() uint64 {
+	size := uint64(13)
+	if d.DecoderSpecificInfo.Descriptor.Tag == ClassTagDecSpecificInfoTag {
+		size += d.DecoderSpecificInfo.encodedSize()
+	}
+	return size
+}
+
+func (d *DecoderConfigDescriptor) encodedSize() uint64 {
+	return d.Descriptor.encodedSize(d.encodedPayloadSize())
+}
+
+func (d *DecoderConfigDescriptor) encode(w io.Writer) error {
+	if err := d.Descriptor.encode(w, d.encodedPayloadSize()); err != nil {
+		return err
+	}
+
+	streamType := uint32(d.StreamType&0x3F)<<2 | uint32(d.UpStream&0x1)<<1 | 0x1 // 1 bit reserved
+	if err := util.WriteBigEndian(w, d.ObjectTypeIndication, streamType<<24|d.BufferSizeDB&0xFFFFFF, d.MaxBitrate, d.AvgBitrate); err != nil {
+		return err
+	}
+
+	if d.DecoderSpecificInfo.Descriptor.Tag == ClassTagDecSpecificInfoTag {
+		return d.DecoderSpecificInfo.encode(w)
+	}
+	return nil
+}
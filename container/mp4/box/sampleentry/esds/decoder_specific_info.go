@@ -3,6 +3,7 @@ package esds
 import (
 	"io"
 
+	"github.com/wangyoucao577/medialib/audio/aac"
 	"github.com/wangyoucao577/medialib/util"
 )
 
@@ -11,6 +12,9 @@ type DecoderSpecificInfo struct {
 	Descriptor Descriptor `json:"descriptor"`
 
 	Data []byte `json:"data"`
+
+	// decoded from Data if it's AAC, not used for encoding
+	AudioSpecificConfig *aac.AudioSpecificConfig `json:"audio_specific_config,omitempty"`
 }
 
 func (d *DecoderSpecificInfo) parse(r io.Reader) (uint64, error) {
@@ -34,3 +38,14 @@ func (d *DecoderSpecificInfo) parse(r io.Reader) (uint64, error) {
 
 	return parsedBytes, nil
 }
+
+func (d *DecoderSpecificInfo) encodedSize() uint64 {
+	return d.Descriptor.encodedSize(uint64(len(d.Data)))
+}
+
+func (d *DecoderSpecificInfo) encode(w io.Writer) error {
+	if err := d.Descriptor.encode(w, uint64(len(d.Data))); err != nil {
+		return err
+	}
+	return util.WriteBigEndian(w, d.Data)
+}
@@ -10,6 +10,8 @@ import (
 type Descriptor struct {
 	Tag  uint8  `json:"tag"`
 	Size uint32 `json:"size"`
+
+	sizeBytes int // bytes to store size in file, up to 4 bytes
 }
 
 func (d *Descriptor) parse(r io.Reader) (uint64, error) {
@@ -53,6 +55,7 @@ func (d *Descriptor) readSize(r io.Reader) (uint64, error) {
 			msb = (data[0] >> 7) & 0x1
 			s := uint32(data[0] & 0x7F)
 			d.Size = d.Size<<7 + s
+			d.sizeBytes++
 			parsedBytes += 1
 		}
 
@@ -63,3 +66,34 @@ func (d *Descriptor) readSize(r io.Reader) (uint64, error) {
 
 	return parsedBytes, nil
 }
+
+// sizeFieldBytes returns bytes to store specified size, prefer to keep the same as parsed.
+func (d *Descriptor) sizeFieldBytes(payloadSize uint64) int {
+	n := 1
+	for n < 4 && payloadSize >= 1<<(7*n) {
+		n++
+	}
+	if d.sizeBytes > n {
+		n = d.sizeBytes
+	}
+	return n
+}
+
+func (d *Descriptor) encodedSize(payloadSize uint64) uint64 {
+	return 1 + uint64(d.sizeFieldBytes(payloadSize)) + payloadSize
+}
+
+func (d *Descriptor) encode(w io.Writer, payloadSize uint64) error {
+	data := []byte{d.Tag}
+
+	n := d.sizeFieldBytes(payloadSize)
+	for i := n - 1; i >= 0; i-- {
+		s := byte(payloadSize>>(7*i)) & 0x7F
+		if i > 0 {
+			s |= 0x80 // more bytes for the size
+		}
+		data = append(data, s)
+	}
+
+	return util.WriteBigEndian(w, data)
+}
@@ -110,3 +110,62 @@ func (e *ESDescriptor) parse(r io.Reader) (uint64, error) {
 
 	return parsedBytes, nil
 }
+
+func (e *ESDescriptor) encodedPayloadSize() uint64 {
+	size := uint64(3)
+	if e.StreamDependenceFlag == 0x1 {
+		size += 2
+	}
+	if e.URLFlag == 0x1 {
+		size += 1 + uint64(len(e.URLstring))
+	}
+	if e.OCRStreamFlag == 0x1 {
+		size += 2
+	}
+
+	size += e.DecoderConfigDescriptor.encodedSize()
+	if e.SLConfigDescriptor != nil {
+		size += e.SLConfigDescriptor.encodedSize()
+	}
+	return size
+}
+
+func (e *ESDescriptor) encodedSize() uint64 {
+	return e.Descriptor.encodedSize(e.encodedPayloadSize())
+}
+
+func (e *ESDescriptor) encode(w io.Writer) error {
+	if err := e.Descriptor.encode(w, e.encodedPayloadSize()); err != nil {
+		return err
+	}
+
+	if err := util.WriteBigEndian(w, e.ESID,
+		(e.StreamDependenceFlag&0x1)<<7|(e.URLFlag&0x1)<<6|(e.OCRStreamFlag&0x1)<<5|e.StreamPriority&0x1F); err != nil {
+		return err
+	}
+
+	if e.StreamDependenceFlag == 0x1 {
+		if err := util.WriteBigEndian(w, e.DependsOnESID); err != nil {
+			return err
+		}
+	}
+	if e.URLFlag == 0x1 {
+		if err := util.WriteBigEndian(w, uint8(len(e.URLstring)), []byte(e.URLstring)); err != nil {
+			return err
+		}
+	}
+	if e.OCRStreamFlag == 0x1 {
+		if err := util.WriteBigEndian(w, e.OCR_ES_Id); err != nil {
+			return err
+		}
+	}
+
+	if err := e.DecoderConfigDescriptor.encode(w); err != nil {
+		return err
+	}
+
+	if e.SLConfigDescriptor != nil {
+		return e.SLConfigDescriptor.encode(w)
+	}
+	return nil
+}
@@ -18,6 +18,8 @@ type Box struct {
 	box.FullHeader `json:"full_header"`
 
 	ESDescriptor `json:"es_descriptor"`
+
+	unparsed []byte // remain bytes that haven't been parsed yet
 }
 
 // New creates a new Box.
@@ -52,10 +54,28 @@ func (b *Box) ParsePayload(r io.Reader) error {
 	if b.PayloadSize() > parsedBytes {
 		glog.Warningf("box type %s remain bytes %d parsing TODO", b.Type, b.PayloadSize()-parsedBytes)
 		//TODO: parse payload
-		if err := util.ReadOrError(r, make([]byte, b.PayloadSize()-parsedBytes)); err != nil {
+		b.unparsed = make([]byte, b.PayloadSize()-parsedBytes)
+		if err := util.ReadOrError(r, b.unparsed); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(b.ESDescriptor.encodedSize() + uint64(len(b.unparsed)))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, b.ESDescriptor.encodedSize()+uint64(len(b.unparsed))); err != nil {
+		return err
+	}
+
+	if err := b.ESDescriptor.encode(w); err != nil {
+		return err
+	}
+	return util.WriteBigEndian(w, b.unparsed)
+}
@@ -35,3 +35,14 @@ func (s *SLConfigDescriptor) parse(r io.Reader) (uint64, error) {
 
 	return parsedBytes, nil
 }
+
+func (s *SLConfigDescriptor) encodedSize() uint64 {
+	return s.Descriptor.encodedSize(1)
+}
+
+func (s *SLConfigDescriptor) encode(w io.Writer) error {
+	if err := s.Descriptor.encode(w, 1); err != nil {
+		return err
+	}
+	return util.WriteBigEndian(w, s.Predefined)
+}
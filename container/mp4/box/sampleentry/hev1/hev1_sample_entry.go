@@ -29,6 +29,7 @@ type HEVCSampleEntry struct {
 	Btrt *btrt.Box `json:"btrt,omitempty"`
 
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
 }
 
 // New creates a new Box.
@@ -63,6 +64,7 @@ func (a *HEVCSampleEntry) CreateSubBox(h box.Header) (box.Box, error) {
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
+	a.order.Record(h.Type.String())
 
 	switch h.Type.String() {
 	case box.TypeHvcC:
@@ -115,3 +117,47 @@ func (a *HEVCSampleEntry) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (a *HEVCSampleEntry) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	if a.HvccConfig != nil {
+		groups[box.TypeHvcC] = []box.Box{a.HvccConfig}
+	}
+	if a.LhvcConfig != nil {
+		groups[box.TypeLhvC] = []box.Box{a.LhvcConfig}
+	}
+	if a.Colr != nil {
+		groups[box.TypeColr] = []box.Box{a.Colr}
+	}
+	if a.Hfov != nil {
+		groups[box.TypeHfov] = []box.Box{a.Hfov}
+	}
+	if a.Vexu != nil {
+		groups[box.TypeVexu] = []box.Box{a.Vexu}
+	}
+	if a.Btrt != nil {
+		groups[box.TypeBtrt] = []box.Box{a.Btrt}
+	}
+
+	return a.order.Sort(groups, []string{box.TypeHvcC, box.TypeLhvC, box.TypeColr, box.TypeHfov, box.TypeVexu, box.TypeBtrt})
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (a *HEVCSampleEntry) EncodedSize() uint64 {
+	return a.Header.EncodedSize(a.VisualSampleEntry.EncodedDataSize() + box.EncodedBoxesSize(a.subBoxes()))
+}
+
+// Encode writes the box.
+func (a *HEVCSampleEntry) Encode(w io.Writer) error {
+	subBoxes := a.subBoxes()
+	if err := a.Header.Encode(w, a.VisualSampleEntry.EncodedDataSize()+box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	if err := a.VisualSampleEntry.EncodeData(w); err != nil {
+		return err
+	}
+
+	return box.EncodeBoxes(w, subBoxes)
+}
@@ -63,7 +63,7 @@ func (h *HEVCDecoderConfigurationRecord) Parse(r io.Reader) (uint64, error) {
 		h.ConfigurationVersion = data[0]
 		h.GeneralProfileSpace = (data[1] >> 6) & 0x3
 		h.GeneralTierFlag = (data[1] >> 5) & 1
-		h.GeneralProfileSpace = data[1] & 0x1F
+		h.GeneralProfileIdc = data[1] & 0x1F
 
 		parsedBytes += 2
 	}
@@ -139,15 +139,13 @@ func (h *HEVCDecoderConfigurationRecord) Parse(r io.Reader) (uint64, error) {
 				parsedBytes += 2
 			}
 
-			if lenNALU.NALUnitLength == 0 {
-				continue
-			}
-
-			lenNALU.NALUnit = make([]byte, lenNALU.NALUnitLength)
-			if err := util.ReadOrError(r, lenNALU.NALUnit); err != nil {
-				return parsedBytes, err
-			} else {
-				parsedBytes += uint64(lenNALU.NALUnitLength)
+			if lenNALU.NALUnitLength > 0 {
+				lenNALU.NALUnit = make([]byte, lenNALU.NALUnitLength)
+				if err := util.ReadOrError(r, lenNALU.NALUnit); err != nil {
+					return parsedBytes, err
+				} else {
+					parsedBytes += uint64(lenNALU.NALUnitLength)
+				}
 			}
 
 			h.Arrays[i].LengthNALUs = append(h.Arrays[i].LengthNALUs, lenNALU)
@@ -157,3 +155,57 @@ func (h *HEVCDecoderConfigurationRecord) Parse(r io.Reader) (uint64, error) {
 
 	return parsedBytes, nil
 }
+
+// LengthSize returns NALU prefix length size.
+func (h *HEVCDecoderConfigurationRecord) LengthSize() uint32 {
+	return uint32(h.LengthSizeMinusOne) + 1
+}
+
+// EncodedSize returns bytes of HEVCDecoderConfigurationRecord once encoded.
+func (h *HEVCDecoderConfigurationRecord) EncodedSize() uint64 {
+	size := uint64(23)
+	for i := range h.Arrays {
+		size += 3
+		for j := range h.Arrays[i].LengthNALUs {
+			size += 2 + uint64(len(h.Arrays[i].LengthNALUs[j].NALUnit))
+		}
+	}
+	return size
+}
+
+// Encode writes HEVCDecoderConfigurationRecord.
+func (h *HEVCDecoderConfigurationRecord) Encode(w io.Writer) error {
+	constraintIndicatorFlags := make([]byte, 8)
+	binary.BigEndian.PutUint64(constraintIndicatorFlags, h.GeneralConstraintIndicatorFlags)
+
+	if err := util.WriteBigEndian(w,
+		h.ConfigurationVersion,
+		(h.GeneralProfileSpace&0x3)<<6|(h.GeneralTierFlag&0x1)<<5|h.GeneralProfileIdc&0x1F,
+		h.GeneralProfileCompatibilityFlags,
+		constraintIndicatorFlags[2:], // 48 bits
+		h.GeneralLevelIdc,
+		0xF000|h.MinSpatialSegmentationIdc&0xFFF,
+		0xFC|h.ParallelismType&0x3,
+		0xFC|h.ChromaFormatIdc&0x3,
+		0xF8|h.BitDepthLumaMinus8&0x7,
+		0xF8|h.BitDepthChromaMinus8&0x7,
+		h.AvgFrameRate,
+		(h.ConstantFrameRate&0x3)<<6|(h.NumTemporalLayers&0x7)<<3|(h.TemporalIdNested&0x1)<<2|h.LengthSizeMinusOne&0x3,
+		uint8(len(h.Arrays))); err != nil {
+		return err
+	}
+
+	for _, array := range h.Arrays {
+		if err := util.WriteBigEndian(w, (array.ArrayCompleteness&0x1)<<7|array.NALUnitType&0x3F, uint16(len(array.LengthNALUs))); err != nil {
+			return err
+		}
+
+		for _, lengthNALU := range array.LengthNALUs {
+			if err := util.WriteBigEndian(w, uint16(len(lengthNALU.NALUnit)), lengthNALU.NALUnit); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
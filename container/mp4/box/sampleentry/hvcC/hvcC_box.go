@@ -15,6 +15,8 @@ type HEVCConfigrationBox struct {
 	box.Header `json:"header"`
 
 	HEVCConfig HEVCDecoderConfigurationRecord `json:"hevc_config"`
+
+	unparsed []byte // remain bytes that haven't been parsed yet
 }
 
 // New creates a new Box.
@@ -46,8 +48,8 @@ func (h *HEVCConfigrationBox) ParsePayload(r io.Reader) error {
 			remainBytes := h.PayloadSize() - parsedBytes
 			glog.Warningf("sample entry box type %s still has %d bytes hasn't been parsed yet, ignore them", h.Type, remainBytes)
 
-			// ignore pre_defined 2 bytes in here
-			if err := util.ReadOrError(r, make([]byte, remainBytes)); err != nil {
+			h.unparsed = make([]byte, remainBytes)
+			if err := util.ReadOrError(r, h.unparsed); err != nil {
 				return err
 			}
 		}
@@ -55,3 +57,20 @@ func (h *HEVCConfigrationBox) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (h *HEVCConfigrationBox) EncodedSize() uint64 {
+	return h.Header.EncodedSize(h.HEVCConfig.EncodedSize() + uint64(len(h.unparsed)))
+}
+
+// Encode writes the box.
+func (h *HEVCConfigrationBox) Encode(w io.Writer) error {
+	if err := h.Header.Encode(w, h.HEVCConfig.EncodedSize()+uint64(len(h.unparsed))); err != nil {
+		return err
+	}
+
+	if err := h.HEVCConfig.Encode(w); err != nil {
+		return err
+	}
+	return util.WriteBigEndian(w, h.unparsed)
+}
@@ -20,6 +20,7 @@ type MP4VisualSampleEntry struct {
 	Btrt *btrt.Box `json:"btrt,omitempty"`
 
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
 }
 
 // New creates a new Box.
@@ -50,6 +51,7 @@ func (m *MP4VisualSampleEntry) CreateSubBox(h box.Header) (box.Box, error) {
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
+	m.order.Record(h.Type.String())
 
 	switch h.Type.String() {
 	case box.TypeEsds:
@@ -94,3 +96,35 @@ func (m *MP4VisualSampleEntry) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (m *MP4VisualSampleEntry) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	if m.Esds != nil {
+		groups[box.TypeEsds] = []box.Box{m.Esds}
+	}
+	if m.Btrt != nil {
+		groups[box.TypeBtrt] = []box.Box{m.Btrt}
+	}
+
+	return m.order.Sort(groups, []string{box.TypeEsds, box.TypeBtrt})
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (m *MP4VisualSampleEntry) EncodedSize() uint64 {
+	return m.Header.EncodedSize(m.AudioSampleEntry.EncodedDataSize() + box.EncodedBoxesSize(m.subBoxes()))
+}
+
+// Encode writes the box.
+func (m *MP4VisualSampleEntry) Encode(w io.Writer) error {
+	subBoxes := m.subBoxes()
+	if err := m.Header.Encode(w, m.AudioSampleEntry.EncodedDataSize()+box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	if err := m.AudioSampleEntry.EncodeData(w); err != nil {
+		return err
+	}
+
+	return box.EncodeBoxes(w, subBoxes)
+}
@@ -42,3 +42,17 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(8)
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.Header.Encode(w, 8); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, b.HSpacing, b.VSpacing)
+}
@@ -45,3 +45,13 @@ func (s *SampleEntry) ParseData(r io.Reader) error {
 	s.PayloadSizeMinus(int(parsedBytes))
 	return nil
 }
+
+// EncodedDataSize returns bytes of sample entry data once encoded.
+func (s *SampleEntry) EncodedDataSize() uint64 {
+	return 8
+}
+
+// EncodeData writes sample entry data, which requires header has been written.
+func (s *SampleEntry) EncodeData(w io.Writer) error {
+	return util.WriteBigEndian(w, make([]byte, 6), s.DataReferenceIndex)
+}
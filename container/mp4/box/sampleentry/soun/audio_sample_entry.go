@@ -75,3 +75,31 @@ func (a *AudioSampleEntry) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedDataSize returns bytes of audio sample entry data once encoded, which doesn't include optional boxes.
+func (a *AudioSampleEntry) EncodedDataSize() uint64 {
+	return a.SampleEntry.EncodedDataSize() + 20
+}
+
+// EncodeData writes audio sample entry data, which requires header has been written.
+func (a *AudioSampleEntry) EncodeData(w io.Writer) error {
+	if err := a.SampleEntry.EncodeData(w); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, make([]byte, 8), a.ChannelCount, a.SampleSize, uint32(0), a.SampleRate<<16)
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (a *AudioSampleEntry) EncodedSize() uint64 {
+	return a.Header.EncodedSize(a.EncodedDataSize())
+}
+
+// Encode writes the box.
+func (a *AudioSampleEntry) Encode(w io.Writer) error {
+	if err := a.Header.Encode(w, a.EncodedDataSize()); err != nil {
+		return err
+	}
+
+	return a.EncodeData(w)
+}
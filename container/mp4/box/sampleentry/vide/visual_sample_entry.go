@@ -150,3 +150,35 @@ func (v *VisualSampleEntry) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedDataSize returns bytes of visual sample entry data once encoded, which doesn't include optional boxes.
+func (v *VisualSampleEntry) EncodedDataSize() uint64 {
+	return v.SampleEntry.EncodedDataSize() + 70
+}
+
+// EncodeData writes visual sample entry data, which requires header has been written.
+func (v *VisualSampleEntry) EncodeData(w io.Writer) error {
+	if err := v.SampleEntry.EncodeData(w); err != nil {
+		return err
+	}
+
+	compressorNameData := make([]byte, 32)
+	copy(compressorNameData, v.Compressorname)
+
+	return util.WriteBigEndian(w, make([]byte, 16), v.Width, v.Height, v.Horizresolution, v.Vertresolution,
+		uint32(0), v.FrameCount, compressorNameData, v.Depth, int16(-1))
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (v *VisualSampleEntry) EncodedSize() uint64 {
+	return v.Header.EncodedSize(v.EncodedDataSize())
+}
+
+// Encode writes the box.
+func (v *VisualSampleEntry) Encode(w io.Writer) error {
+	if err := v.Header.Encode(w, v.EncodedDataSize()); err != nil {
+		return err
+	}
+
+	return v.EncodeData(w)
+}
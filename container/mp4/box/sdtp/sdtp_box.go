@@ -14,6 +14,7 @@ type Box struct {
 	box.FullHeader `json:"full_header"`
 
 	//TODO: payloads
+	Data []byte `json:"-"` // raw payload that hasn't been parsed yet
 }
 
 // New creates a new Box.
@@ -40,10 +41,25 @@ func (b *Box) ParsePayload(r io.Reader) error {
 	if b.PayloadSize() > 0 {
 		//TODO: parse payload
 		glog.Warningf("sdtp payload size %d but ignoring", b.PayloadSize())
-		if err := util.ReadOrError(r, make([]byte, b.PayloadSize())); err != nil {
+		b.Data = make([]byte, b.PayloadSize())
+		if err := util.ReadOrError(r, b.Data); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(uint64(len(b.Data)))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, uint64(len(b.Data))); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, b.Data)
+}
@@ -105,3 +105,52 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+func (b *Box) encodedPayloadSize() uint64 {
+	size := uint64(16)
+	if b.Version != 0 {
+		size = 24
+	}
+	return size + 4 + 12*uint64(len(b.References))
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(b.encodedPayloadSize())
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, b.encodedPayloadSize()); err != nil {
+		return err
+	}
+
+	if err := util.WriteBigEndian(w, b.ReferenceID, b.Timescale); err != nil {
+		return err
+	}
+
+	if b.Version != 0 {
+		if err := util.WriteBigEndian(w, b.EarliestPresentationTime, b.FirstOffset); err != nil {
+			return err
+		}
+	} else {
+		if err := util.WriteBigEndian(w, uint32(b.EarliestPresentationTime), uint32(b.FirstOffset)); err != nil {
+			return err
+		}
+	}
+
+	if err := util.WriteBigEndian(w, uint16(0), uint16(len(b.References))); err != nil {
+		return err
+	}
+
+	for _, ref := range b.References {
+		if err := util.WriteBigEndian(w,
+			uint32(ref.ReferenceType&0x1)<<31|ref.ReferencedSize&0x7FFFFFFF,
+			ref.SubsegmentDuration,
+			uint32(ref.StartsWithSAP&0x1)<<31|uint32(ref.SAPtype&0x7)<<28|ref.SAPDeltaTime&0xFFFFFFF); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,10 +2,13 @@
 package stbl
 
 import (
+	"fmt"
 	"io"
+	"math"
 
 	"github.com/golang/glog"
 	"github.com/wangyoucao577/medialib/container/mp4/box"
+	"github.com/wangyoucao577/medialib/container/mp4/box/co64"
 	"github.com/wangyoucao577/medialib/container/mp4/box/ctts"
 	"github.com/wangyoucao577/medialib/container/mp4/box/hdlr"
 	"github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/sgpd"
@@ -28,6 +31,7 @@ type Box struct {
 	Stsc *stsc.Box  `json:"stsc,omitempty"`
 	Stsz *stsz.Box  `json:"stsz,omitempty"`
 	Stco *stco.Box  `json:"stco,omitempty"`
+	Co64 *co64.Box  `json:"co64,omitempty"`
 	Ctts *ctts.Box  `json:"ctts,omitempty"`
 	Sdtp *sdtp.Box  `json:"sdtp,omitempty"`
 	Sgpd []sgpd.Box `json:"sgpd,omitempty"`
@@ -36,6 +40,7 @@ type Box struct {
 	hdlr *hdlr.Box `json:"-"`
 
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
 }
 
 // SetHdlr passes hdlr box for later use.
@@ -55,6 +60,7 @@ func New(h box.Header) box.Box {
 			box.TypeStsc: stsc.New,
 			box.TypeStsz: stsz.New,
 			box.TypeStco: stco.New,
+			box.TypeCo64: co64.New,
 			box.TypeCtts: ctts.New,
 			box.TypeSdtp: sdtp.New,
 			box.TypeSgpd: sgpd.New,
@@ -74,6 +80,7 @@ func (b *Box) CreateSubBox(h box.Header) (box.Box, error) {
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
+	b.order.Record(h.Type.String())
 
 	switch h.Type.String() {
 	case box.TypeStsd:
@@ -91,6 +98,8 @@ func (b *Box) CreateSubBox(h box.Header) (box.Box, error) {
 		b.Stsz = createdBox.(*stsz.Box)
 	case box.TypeStco:
 		b.Stco = createdBox.(*stco.Box)
+	case box.TypeCo64:
+		b.Co64 = createdBox.(*co64.Box)
 	case box.TypeCtts:
 		b.Ctts = createdBox.(*ctts.Box)
 	case box.TypeSdtp:
@@ -131,3 +140,154 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (b *Box) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	if b.Stsd != nil {
+		groups[box.TypeStsd] = []box.Box{b.Stsd}
+	}
+	if b.Stts != nil {
+		groups[box.TypeStts] = []box.Box{b.Stts}
+	}
+	if b.Ctts != nil {
+		groups[box.TypeCtts] = []box.Box{b.Ctts}
+	}
+	if b.Stss != nil {
+		groups[box.TypeStss] = []box.Box{b.Stss}
+	}
+	if b.Sdtp != nil {
+		groups[box.TypeSdtp] = []box.Box{b.Sdtp}
+	}
+	if b.Stsc != nil {
+		groups[box.TypeStsc] = []box.Box{b.Stsc}
+	}
+	if b.Stsz != nil {
+		groups[box.TypeStsz] = []box.Box{b.Stsz}
+	}
+	if b.Stco != nil {
+		groups[box.TypeStco] = []box.Box{b.Stco}
+	}
+	if b.Co64 != nil {
+		groups[box.TypeCo64] = []box.Box{b.Co64}
+	}
+	for i := range b.Sgpd {
+		groups[box.TypeSgpd] = append(groups[box.TypeSgpd], &b.Sgpd[i])
+	}
+
+	return b.order.Sort(groups, []string{box.TypeStsd, box.TypeStts, box.TypeCtts, box.TypeStss, box.TypeSdtp, box.TypeStsc, box.TypeStsz, box.TypeStco, box.TypeCo64, box.TypeSgpd})
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(box.EncodedBoxesSize(b.subBoxes()))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	subBoxes := b.subBoxes()
+	if err := b.Header.Encode(w, box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	return box.EncodeBoxes(w, subBoxes)
+}
+
+// SampleLocation represents where a sample stored.
+type SampleLocation struct {
+	Offset uint64 // offset from the beginning of the file
+	Size   uint32
+}
+
+// ChunkOffsets returns chunk offsets from either stco or co64.
+func (b *Box) ChunkOffsets() ([]uint64, error) {
+	if b.Co64 != nil {
+		return b.Co64.ChunkOffsets, nil
+	}
+	if b.Stco != nil {
+		offsets := make([]uint64, len(b.Stco.ChunkOffsets))
+		for i, o := range b.Stco.ChunkOffsets {
+			offsets[i] = uint64(o)
+		}
+		return offsets, nil
+	}
+	return nil, fmt.Errorf("neither stco nor co64 found")
+}
+
+// SetChunkOffsets sets chunk offsets to stco, or co64 if it's already used or any offset overflows 32 bits.
+func (b *Box) SetChunkOffsets(offsets []uint64) {
+	useCo64 := b.Co64 != nil
+	for _, o := range offsets {
+		if o > math.MaxUint32 {
+			useCo64 = true
+			break
+		}
+	}
+
+	if useCo64 {
+		if b.Co64 == nil {
+			b.Co64 = co64.New(box.NewHeader(box.TypeCo64)).(*co64.Box)
+		}
+		b.Co64.EntryCount = uint32(len(offsets))
+		b.Co64.ChunkOffsets = offsets
+		b.Stco = nil
+		return
+	}
+
+	if b.Stco == nil {
+		b.Stco = stco.New(box.NewHeader(box.TypeStco)).(*stco.Box)
+	}
+	b.Stco.EntryCount = uint32(len(offsets))
+	b.Stco.ChunkOffsets = make([]uint32, len(offsets))
+	for i, o := range offsets {
+		b.Stco.ChunkOffsets[i] = uint32(o)
+	}
+}
+
+// SampleLocations resolves locations of all samples by stsc, stco/co64 and stsz.
+func (b *Box) SampleLocations() ([]SampleLocation, error) {
+	if b.Stsc == nil || b.Stsz == nil {
+		return nil, fmt.Errorf("stsc or stsz not found")
+	}
+	chunkOffsets, err := b.ChunkOffsets()
+	if err != nil {
+		return nil, err
+	}
+
+	sampleCount := int(b.Stsz.SampleCount)
+	if b.Stsz.SampleSize == 0 && len(b.Stsz.EntrySizes) < sampleCount {
+		return nil, fmt.Errorf("stsz sample count %d but only %d entry sizes", sampleCount, len(b.Stsz.EntrySizes))
+	}
+
+	locations := make([]SampleLocation, 0, sampleCount)
+	for i, entry := range b.Stsc.Entries {
+		if entry.FirstChunk == 0 || int(entry.FirstChunk) > len(chunkOffsets) {
+			return nil, fmt.Errorf("stsc entry %d invalid first_chunk %d, chunk count %d", i, entry.FirstChunk, len(chunkOffsets))
+		}
+
+		lastChunk := len(chunkOffsets) // the last entry applies to all remaining chunks
+		if i+1 < len(b.Stsc.Entries) {
+			lastChunk = int(b.Stsc.Entries[i+1].FirstChunk) - 1
+		}
+		if lastChunk > len(chunkOffsets) {
+			return nil, fmt.Errorf("stsc entry %d invalid next first_chunk %d, chunk count %d", i, lastChunk+1, len(chunkOffsets))
+		}
+
+		for chunk := int(entry.FirstChunk); chunk <= lastChunk; chunk++ {
+			offset := chunkOffsets[chunk-1]
+			for j := 0; j < int(entry.SamplesPerChunk) && len(locations) < sampleCount; j++ {
+				size := b.Stsz.SampleSize
+				if size == 0 {
+					size = b.Stsz.EntrySizes[len(locations)]
+				}
+				locations = append(locations, SampleLocation{Offset: offset, Size: size})
+				offset += uint64(size)
+			}
+		}
+	}
+
+	if len(locations) != sampleCount {
+		return locations, fmt.Errorf("resolved sample count %d != stsz sample count %d", len(locations), sampleCount)
+	}
+	return locations, nil
+}
@@ -0,0 +1,93 @@
+package stbl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wangyoucao577/medialib/container/mp4/box/co64"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stco"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stsc"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stsz"
+)
+
+func TestSampleLocations(t *testing.T) {
+	cases := []struct {
+		stbl        Box
+		expected    []SampleLocation
+		expectedErr bool
+	}{
+		// 2 chunks with 2 samples, then 1 chunk with 1 sample
+		{Box{
+			Stsc: &stsc.Box{Entries: []stsc.ChunkEntry{{FirstChunk: 1, SamplesPerChunk: 2}, {FirstChunk: 3, SamplesPerChunk: 1}}},
+			Stsz: &stsz.Box{SampleCount: 5, EntrySizes: []uint32{1, 2, 3, 4, 5}},
+			Stco: &stco.Box{ChunkOffsets: []uint32{100, 200, 300}},
+		}, []SampleLocation{{100, 1}, {101, 2}, {200, 3}, {203, 4}, {300, 5}}, false},
+
+		// constant sample size, large offsets
+		{Box{
+			Stsc: &stsc.Box{Entries: []stsc.ChunkEntry{{FirstChunk: 1, SamplesPerChunk: 3}}},
+			Stsz: &stsz.Box{SampleSize: 10, SampleCount: 4},
+			Co64: &co64.Box{ChunkOffsets: []uint64{1 << 32, 1<<32 + 100}},
+		}, []SampleLocation{{1 << 32, 10}, {1<<32 + 10, 10}, {1<<32 + 20, 10}, {1<<32 + 100, 10}}, false},
+
+		// chunks not enough for samples
+		{Box{
+			Stsc: &stsc.Box{Entries: []stsc.ChunkEntry{{FirstChunk: 1, SamplesPerChunk: 1}}},
+			Stsz: &stsz.Box{SampleSize: 10, SampleCount: 2},
+			Stco: &stco.Box{ChunkOffsets: []uint32{100}},
+		}, nil, true},
+
+		// invalid first chunk
+		{Box{
+			Stsc: &stsc.Box{Entries: []stsc.ChunkEntry{{FirstChunk: 2, SamplesPerChunk: 1}}},
+			Stsz: &stsz.Box{SampleSize: 10, SampleCount: 1},
+			Stco: &stco.Box{ChunkOffsets: []uint32{100}},
+		}, nil, true},
+
+		// no chunk offsets
+		{Box{
+			Stsc: &stsc.Box{Entries: []stsc.ChunkEntry{{FirstChunk: 1, SamplesPerChunk: 1}}},
+			Stsz: &stsz.Box{SampleSize: 10, SampleCount: 1},
+		}, nil, true},
+	}
+
+	for i, c := range cases {
+		locations, err := c.stbl.SampleLocations()
+		if (err != nil) != c.expectedErr {
+			t.Errorf("case %d expect error %v but got %v", i, c.expectedErr, err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(locations, c.expected) {
+			t.Errorf("case %d expect %v but got %v", i, c.expected, locations)
+		}
+	}
+}
+
+func TestSetChunkOffsets(t *testing.T) {
+	cases := []struct {
+		stbl          Box
+		offsets       []uint64
+		expectedCo64  bool
+		expectedEntry int
+	}{
+		{Box{Stco: &stco.Box{ChunkOffsets: []uint32{1}}}, []uint64{100, 200}, false, 2},
+		{Box{Stco: &stco.Box{ChunkOffsets: []uint32{1}}}, []uint64{100, 1 << 32}, true, 2},
+		{Box{Co64: &co64.Box{ChunkOffsets: []uint64{1}}}, []uint64{100}, true, 1},
+		{Box{}, []uint64{100}, false, 1},
+	}
+
+	for i, c := range cases {
+		c.stbl.SetChunkOffsets(c.offsets)
+		if (c.stbl.Co64 != nil) != c.expectedCo64 || (c.stbl.Stco != nil) == c.expectedCo64 {
+			t.Errorf("case %d expect co64 %v but got stco %v co64 %v", i, c.expectedCo64, c.stbl.Stco, c.stbl.Co64)
+			continue
+		}
+		offsets, err := c.stbl.ChunkOffsets()
+		if err != nil || !reflect.DeepEqual(offsets, c.offsets) {
+			t.Errorf("case %d expect %v but got %v, err %v", i, c.offsets, offsets, err)
+		}
+		if size := c.stbl.EncodedSize(); c.expectedCo64 && size != 8+16+8*uint64(c.expectedEntry) || !c.expectedCo64 && size != 8+16+4*uint64(c.expectedEntry) {
+			t.Errorf("case %d unexpected encoded size %d", i, size)
+		}
+	}
+}
@@ -91,3 +91,17 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(4 + 12*uint64(len(b.Entries)))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, 4+12*uint64(len(b.Entries))); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, uint32(len(b.Entries)), b.Entries)
+}
@@ -16,6 +16,24 @@ import (
 	"github.com/wangyoucao577/medialib/util"
 )
 
+// sampleEntryTypes are supported sample entry types in default sequence.
+var sampleEntryTypes = []string{box.TypeAvc1, box.TypeHev1, box.TypeHvc1, box.TypeAv01, box.TypeMp4a}
+
+// sampleEntryHandlerTypes are handler types of supported sample entry types.
+var sampleEntryHandlerTypes = map[string]string{
+	box.TypeAvc1: box.TypeVide,
+	box.TypeHev1: box.TypeVide,
+	box.TypeHvc1: box.TypeVide,
+	box.TypeAv01: box.TypeVide,
+	box.TypeMp4a: box.TypeSoun,
+}
+
+// typeUnknown is recorded in order for all unsupported sample entries.
+const typeUnknown = "unknown"
+
+// encodingTypes are sample entry types in default sequence for encoding, unsupported ones come last.
+var encodingTypes = append(append([]string{}, sampleEntryTypes...), typeUnknown)
+
 // Box represents a stsd box.
 type Box struct {
 	box.FullHeader `json:"full_header"`
@@ -26,11 +44,13 @@ type Box struct {
 	HVC1SampleEntries      []hev1.HEVCSampleEntry      `json:"hvc1,omitempty"`
 	AV01SampleEntries      []av01.AV1SampleEntry       `json:"av01,omitempty"`
 	MP4VisualSampleEntries []mp4a.MP4VisualSampleEntry `json:"mp4a,omitempty"`
+	UnknownSampleEntries   []UnknownSampleEntry        `json:"unknown,omitempty"`
 
 	// passed from parent for later use
 	hdlr *hdlr.Box `json:"-"`
 
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
 }
 
 // New creates a new Box.
@@ -52,39 +72,38 @@ func New(h box.Header) box.Box {
 
 // CreateSubBox tries to create sub level box.
 func (b *Box) CreateSubBox(h box.Header) (box.Box, error) {
-	creator, ok := b.boxesCreator[h.Type.String()]
-	if !ok {
-		glog.V(2).Infof("unknown box type %s, size %d payload %d", h.Type.String(), h.Size, h.PayloadSize())
-		return nil, box.ErrUnknownBoxType
+	boxType := h.Type.String()
+	creator, ok := b.boxesCreator[boxType]
+	if !ok || sampleEntryHandlerTypes[boxType] != b.hdlr.HandlerType.String() {
+		glog.V(2).Infof("unknown sample entry type %s in handler_type %s, size %d payload %d", boxType, b.hdlr.HandlerType.String(), h.Size, h.PayloadSize())
+		boxType, creator = typeUnknown, newUnknownSampleEntry // keep raw data, so that it won't be lost once encoded
 	}
 
 	createdBox := creator(h)
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
-
-	switch b.hdlr.HandlerType.String() {
-	case box.TypeVide:
-		switch h.Type.String() {
-		case box.TypeAvc1:
-			b.AVC1SampleEntries = append(b.AVC1SampleEntries, *createdBox.(*avc1.AVCSampleEntry))
-			createdBox = &b.AVC1SampleEntries[len(b.AVC1SampleEntries)-1]
-		case box.TypeHev1:
-			b.HEV1SampleEntries = append(b.HEV1SampleEntries, *createdBox.(*hev1.HEVCSampleEntry))
-			createdBox = &b.HEV1SampleEntries[len(b.HEV1SampleEntries)-1]
-		case box.TypeHvc1:
-			b.HVC1SampleEntries = append(b.HVC1SampleEntries, *createdBox.(*hev1.HEVCSampleEntry))
-			createdBox = &b.HVC1SampleEntries[len(b.HVC1SampleEntries)-1]
-		case box.TypeAv01:
-			b.AV01SampleEntries = append(b.AV01SampleEntries, *createdBox.(*av01.AV1SampleEntry))
-			createdBox = &b.AV01SampleEntries[len(b.AV01SampleEntries)-1]
-		}
-	case box.TypeSoun:
-		switch h.Type.String() {
-		case box.TypeMp4a:
-			b.MP4VisualSampleEntries = append(b.MP4VisualSampleEntries, *createdBox.(*mp4a.MP4VisualSampleEntry))
-			createdBox = &b.MP4VisualSampleEntries[len(b.MP4VisualSampleEntries)-1]
-		}
+	b.order.Record(boxType)
+
+	switch boxType {
+	case box.TypeAvc1:
+		b.AVC1SampleEntries = append(b.AVC1SampleEntries, *createdBox.(*avc1.AVCSampleEntry))
+		createdBox = &b.AVC1SampleEntries[len(b.AVC1SampleEntries)-1]
+	case box.TypeHev1:
+		b.HEV1SampleEntries = append(b.HEV1SampleEntries, *createdBox.(*hev1.HEVCSampleEntry))
+		createdBox = &b.HEV1SampleEntries[len(b.HEV1SampleEntries)-1]
+	case box.TypeHvc1:
+		b.HVC1SampleEntries = append(b.HVC1SampleEntries, *createdBox.(*hev1.HEVCSampleEntry))
+		createdBox = &b.HVC1SampleEntries[len(b.HVC1SampleEntries)-1]
+	case box.TypeAv01:
+		b.AV01SampleEntries = append(b.AV01SampleEntries, *createdBox.(*av01.AV1SampleEntry))
+		createdBox = &b.AV01SampleEntries[len(b.AV01SampleEntries)-1]
+	case box.TypeMp4a:
+		b.MP4VisualSampleEntries = append(b.MP4VisualSampleEntries, *createdBox.(*mp4a.MP4VisualSampleEntry))
+		createdBox = &b.MP4VisualSampleEntries[len(b.MP4VisualSampleEntries)-1]
+	default:
+		b.UnknownSampleEntries = append(b.UnknownSampleEntries, *createdBox.(*UnknownSampleEntry))
+		createdBox = &b.UnknownSampleEntries[len(b.UnknownSampleEntries)-1]
 	}
 
 	return createdBox, nil
@@ -143,3 +162,65 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (b *Box) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	for i := range b.AVC1SampleEntries {
+		groups[box.TypeAvc1] = append(groups[box.TypeAvc1], &b.AVC1SampleEntries[i])
+	}
+	for i := range b.HEV1SampleEntries {
+		groups[box.TypeHev1] = append(groups[box.TypeHev1], &b.HEV1SampleEntries[i])
+	}
+	for i := range b.HVC1SampleEntries {
+		groups[box.TypeHvc1] = append(groups[box.TypeHvc1], &b.HVC1SampleEntries[i])
+	}
+	for i := range b.AV01SampleEntries {
+		groups[box.TypeAv01] = append(groups[box.TypeAv01], &b.AV01SampleEntries[i])
+	}
+	for i := range b.MP4VisualSampleEntries {
+		groups[box.TypeMp4a] = append(groups[box.TypeMp4a], &b.MP4VisualSampleEntries[i])
+	}
+	for i := range b.UnknownSampleEntries {
+		groups[typeUnknown] = append(groups[typeUnknown], &b.UnknownSampleEntries[i])
+	}
+
+	return b.order.Sort(groups, encodingTypes)
+}
+
+// SampleEntryType returns type of the first sample entry, e.g., avc1, hvc1, mp4a, or empty if no supported one.
+func (b *Box) SampleEntryType() string {
+	counts := map[string]int{
+		box.TypeAvc1: len(b.AVC1SampleEntries),
+		box.TypeHev1: len(b.HEV1SampleEntries),
+		box.TypeHvc1: len(b.HVC1SampleEntries),
+		box.TypeAv01: len(b.AV01SampleEntries),
+		box.TypeMp4a: len(b.MP4VisualSampleEntries),
+	}
+	for _, types := range [][]string{b.order, sampleEntryTypes} { // parsed sequence first
+		for _, t := range types {
+			if counts[t] > 0 {
+				return t
+			}
+		}
+	}
+	return ""
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(4 + box.EncodedBoxesSize(b.subBoxes()))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	subBoxes := b.subBoxes()
+	if err := b.FullHeader.Encode(w, 4+box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	if err := util.WriteBigEndian(w, uint32(len(subBoxes))); err != nil {
+		return err
+	}
+	return box.EncodeBoxes(w, subBoxes)
+}
@@ -0,0 +1,41 @@
+package stsd
+
+import (
+	"io"
+
+	"github.com/wangyoucao577/medialib/container/mp4/box"
+	"github.com/wangyoucao577/medialib/util"
+)
+
+// UnknownSampleEntry represents an unsupported sample entry, e.g., mp4v, tx3g, which is kept as raw data.
+type UnknownSampleEntry struct {
+	box.Header `json:"header"`
+
+	Data []byte `json:"-"`
+}
+
+// newUnknownSampleEntry creates a new UnknownSampleEntry.
+func newUnknownSampleEntry(h box.Header) box.Box {
+	return &UnknownSampleEntry{
+		Header: h,
+	}
+}
+
+// ParsePayload reads the payload as is, no matter whether the type is valid.
+func (e *UnknownSampleEntry) ParsePayload(r io.Reader) error {
+	e.Data = make([]byte, e.PayloadSize())
+	return util.ReadOrError(r, e.Data)
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (e *UnknownSampleEntry) EncodedSize() uint64 {
+	return e.Header.EncodedSize(uint64(len(e.Data)))
+}
+
+// Encode writes the box.
+func (e *UnknownSampleEntry) Encode(w io.Writer) error {
+	if err := e.Header.Encode(w, uint64(len(e.Data))); err != nil {
+		return err
+	}
+	return util.WriteBigEndian(w, e.Data)
+}
@@ -68,3 +68,17 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(4 + 4*uint64(len(b.SampleNumbers)))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, 4+4*uint64(len(b.SampleNumbers))); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, uint32(len(b.SampleNumbers)), b.SampleNumbers)
+}
@@ -78,3 +78,27 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+func (b *Box) encodedPayloadSize() uint64 {
+	if b.SampleSize != 0 {
+		return 8
+	}
+	return 8 + 4*uint64(len(b.EntrySizes))
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(b.encodedPayloadSize())
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, b.encodedPayloadSize()); err != nil {
+		return err
+	}
+
+	if b.SampleSize != 0 {
+		return util.WriteBigEndian(w, b.SampleSize, b.SampleCount)
+	}
+	return util.WriteBigEndian(w, b.SampleSize, uint32(len(b.EntrySizes)), b.EntrySizes)
+}
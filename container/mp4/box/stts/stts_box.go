@@ -77,3 +77,26 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(4 + 8*uint64(len(b.SampleCounts)))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, 4+8*uint64(len(b.SampleCounts))); err != nil {
+		return err
+	}
+
+	if err := util.WriteBigEndian(w, uint32(len(b.SampleCounts))); err != nil {
+		return err
+	}
+	for i := range b.SampleCounts {
+		if err := util.WriteBigEndian(w, b.SampleCounts[i], b.SampleDeltas[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
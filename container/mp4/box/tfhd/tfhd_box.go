@@ -110,3 +110,47 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+func (b *Box) encodedPayloadSize() uint64 {
+	size := uint64(4)
+	if (b.Flags & 0x1) > 0 {
+		size += 8
+	}
+	for _, flag := range []uint32{0x2, 0x8, 0x10, 0x20} {
+		if (b.Flags & flag) > 0 {
+			size += 4
+		}
+	}
+	return size
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(b.encodedPayloadSize())
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, b.encodedPayloadSize()); err != nil {
+		return err
+	}
+
+	values := []interface{}{b.TrackID}
+	if (b.Flags & 0x1) > 0 {
+		values = append(values, b.BaseDataOffset)
+	}
+	if (b.Flags & 0x2) > 0 {
+		values = append(values, b.SampleDescriptionIndex)
+	}
+	if (b.Flags & 0x8) > 0 {
+		values = append(values, b.DefaultSampleDuration)
+	}
+	if (b.Flags & 0x10) > 0 {
+		values = append(values, b.DefaultSampleSize)
+	}
+	if (b.Flags & 0x20) > 0 {
+		values = append(values, b.DefaultSampleFlags)
+	}
+
+	return util.WriteBigEndian(w, values...)
+}
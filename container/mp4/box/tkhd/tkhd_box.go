@@ -217,3 +217,35 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+func (b *Box) encodedPayloadSize() uint64 {
+	if b.Version == 1 {
+		return 32 + 60
+	}
+	return 20 + 60
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(b.encodedPayloadSize())
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, b.encodedPayloadSize()); err != nil {
+		return err
+	}
+
+	if b.Version == 1 {
+		if err := util.WriteBigEndian(w, b.CreationTime, b.ModificationTime, b.TrackID, uint32(0), b.Duration); err != nil {
+			return err
+		}
+	} else {
+		if err := util.WriteBigEndian(w, uint32(b.CreationTime), uint32(b.ModificationTime), b.TrackID, uint32(0), uint32(b.Duration)); err != nil {
+			return err
+		}
+	}
+
+	return util.WriteBigEndian(w, make([]byte, 8), b.Layer, b.AlternativeGroup, b.Volume, uint16(0), b.Matrix,
+		uint32(fixedpoint.To16x16(float64(b.Width))), uint32(fixedpoint.To16x16(float64(b.Height))))
+}
@@ -20,6 +20,7 @@ type Box struct {
 	Tfdt *tfdt.Box  `json:"tfdt,omitempty"`
 
 	boxesCreator map[string]box.NewFunc
+	order        box.Order `json:"-"`
 }
 
 // New creates a new Box.
@@ -47,6 +48,7 @@ func (b *Box) CreateSubBox(h box.Header) (box.Box, error) {
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
+	b.order.Record(h.Type.String())
 
 	switch h.Type.String() {
 	case box.TypeTfhd:
@@ -89,3 +91,34 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (b *Box) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	if b.Tfhd != nil {
+		groups[box.TypeTfhd] = []box.Box{b.Tfhd}
+	}
+	if b.Tfdt != nil {
+		groups[box.TypeTfdt] = []box.Box{b.Tfdt}
+	}
+	for i := range b.Trun {
+		groups[box.TypeTrun] = append(groups[box.TypeTrun], &b.Trun[i])
+	}
+
+	return b.order.Sort(groups, []string{box.TypeTfhd, box.TypeTfdt, box.TypeTrun})
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(box.EncodedBoxesSize(b.subBoxes()))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	subBoxes := b.subBoxes()
+	if err := b.Header.Encode(w, box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	return box.EncodeBoxes(w, subBoxes)
+}
@@ -24,6 +24,7 @@ type Box struct {
 	Meta []meta.Box `json:"meta,omitempty"`
 
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
 }
 
 // New creates a new Box.
@@ -55,6 +56,7 @@ func (b *Box) CreateSubBox(h box.Header) (box.Box, error) {
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
+	b.order.Record(h.Type.String())
 
 	switch h.Type.String() {
 	case box.TypeTkhd:
@@ -102,3 +104,40 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// subBoxes returns sub boxes in sequence for encoding.
+func (b *Box) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	if b.Tkhd != nil {
+		groups[box.TypeTkhd] = []box.Box{b.Tkhd}
+	}
+	for i := range b.Edts {
+		groups[box.TypeEdts] = append(groups[box.TypeEdts], &b.Edts[i])
+	}
+	if b.Mdia != nil {
+		groups[box.TypeMdia] = []box.Box{b.Mdia}
+	}
+	if b.Uuid != nil {
+		groups[box.TypeUUID] = []box.Box{b.Uuid}
+	}
+	for i := range b.Meta {
+		groups[box.TypeMeta] = append(groups[box.TypeMeta], &b.Meta[i])
+	}
+
+	return b.order.Sort(groups, []string{box.TypeTkhd, box.TypeEdts, box.TypeMdia, box.TypeUUID, box.TypeMeta})
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(box.EncodedBoxesSize(b.subBoxes()))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	subBoxes := b.subBoxes()
+	if err := b.Header.Encode(w, box.EncodedBoxesSize(subBoxes)); err != nil {
+		return err
+	}
+
+	return box.EncodeBoxes(w, subBoxes)
+}
@@ -63,3 +63,17 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(20)
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, 20); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, b.TrackID, b.DefaultSampleDescriptionIndex, b.DefaultSampleDuration, b.DefaultSampleSize, b.DefaultSampleFlags)
+}
@@ -110,8 +110,10 @@ func (b *Box) ParsePayload(r io.Reader) error {
 			if err := util.ReadOrError(r, data); err != nil {
 				return err
 			} else {
-				// don't need to check b.Version since we can use `int64` to compatible for both
 				sampleCompositionTimeOffset := int64(binary.BigEndian.Uint32(data))
+				if b.Version == 1 { // signed in version 1
+					sampleCompositionTimeOffset = int64(int32(binary.BigEndian.Uint32(data)))
+				}
 				b.SampleCompositionTimeOffset = append(b.SampleCompositionTimeOffset, sampleCompositionTimeOffset)
 				parsedBytes += 4
 			}
@@ -124,3 +126,64 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+func (b *Box) encodedPayloadSize() uint64 {
+	size := uint64(4)
+	for _, flag := range []uint32{0x1, 0x4} {
+		if (b.Flags & flag) > 0 {
+			size += 4
+		}
+	}
+
+	var sampleSize uint64
+	for _, flag := range []uint32{0x100, 0x200, 0x400, 0x800} {
+		if (b.Flags & flag) > 0 {
+			sampleSize += 4
+		}
+	}
+	return size + sampleSize*uint64(b.SampleCount)
+}
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(b.encodedPayloadSize())
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, b.encodedPayloadSize()); err != nil {
+		return err
+	}
+
+	values := []interface{}{b.SampleCount}
+	if (b.Flags & 0x1) > 0 {
+		values = append(values, b.DataOffset)
+	}
+	if (b.Flags & 0x4) > 0 {
+		values = append(values, b.FirstSampleFlags)
+	}
+	if err := util.WriteBigEndian(w, values...); err != nil {
+		return err
+	}
+
+	for i := 0; i < int(b.SampleCount); i++ {
+		values = values[:0]
+		if (b.Flags & 0x100) > 0 {
+			values = append(values, b.SampleDuration[i])
+		}
+		if (b.Flags & 0x200) > 0 {
+			values = append(values, b.SampleSize[i])
+		}
+		if (b.Flags & 0x400) > 0 {
+			values = append(values, b.SampleFlags[i])
+		}
+		if (b.Flags & 0x800) > 0 {
+			values = append(values, uint32(b.SampleCompositionTimeOffset[i]))
+		}
+		if err := util.WriteBigEndian(w, values...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
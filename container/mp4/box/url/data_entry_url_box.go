@@ -48,3 +48,17 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(uint64(len(b.Location)))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, uint64(len(b.Location))); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, []byte(b.Location))
+}
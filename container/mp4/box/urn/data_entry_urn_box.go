@@ -12,6 +12,8 @@ import (
 // Box represents a urn box.
 type Box struct {
 	box.FullHeader `json:"full_header"`
+
+	Data []byte `json:"-"` // raw payload that hasn't been parsed yet
 }
 
 // New creates a new Box.
@@ -37,9 +39,24 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	glog.Warningf("box type %s payload bytes %d parsing TODO", b.Type, b.PayloadSize())
 	//TODO: parse payload
-	if err := util.ReadOrError(r, make([]byte, b.PayloadSize())); err != nil {
+	b.Data = make([]byte, b.PayloadSize())
+	if err := util.ReadOrError(r, b.Data); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.FullHeader.EncodedSize(uint64(len(b.Data)))
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	if err := b.FullHeader.Encode(w, uint64(len(b.Data))); err != nil {
+		return err
+	}
+
+	return util.WriteBigEndian(w, b.Data)
+}
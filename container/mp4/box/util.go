@@ -58,3 +58,22 @@ func ParseBox(r io.Reader, pb ParentBox, bytesAvailable uint64) (uint64, error)
 
 	return boxHeader.BoxSize(), nil
 }
+
+// EncodedBoxesSize returns total bytes of boxes once encoded.
+func EncodedBoxesSize(boxes []Box) uint64 {
+	var size uint64
+	for _, b := range boxes {
+		size += b.EncodedSize()
+	}
+	return size
+}
+
+// EncodeBoxes writes boxes one by one.
+func EncodeBoxes(w io.Writer, boxes []Box) error {
+	for _, b := range boxes {
+		if err := b.Encode(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
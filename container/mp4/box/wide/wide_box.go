@@ -30,3 +30,13 @@ func (b *Box) ParsePayload(r io.Reader) error {
 
 	return nil
 }
+
+// EncodedSize returns total bytes of the box once encoded.
+func (b *Box) EncodedSize() uint64 {
+	return b.Header.EncodedSize(0)
+}
+
+// Encode writes the box.
+func (b *Box) Encode(w io.Writer) error {
+	return b.Header.Encode(w, 0)
+}
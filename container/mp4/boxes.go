@@ -16,6 +16,7 @@ import (
 	"github.com/wangyoucao577/medialib/container/mp4/box/moof"
 	"github.com/wangyoucao577/medialib/container/mp4/box/moov"
 	"github.com/wangyoucao577/medialib/container/mp4/box/sidx"
+	"github.com/wangyoucao577/medialib/container/mp4/box/trak"
 	"github.com/wangyoucao577/medialib/container/mp4/box/wide"
 	"github.com/wangyoucao577/medialib/util"
 	"github.com/wangyoucao577/medialib/video/avc/annexbes"
@@ -26,11 +27,14 @@ import (
 type MoofMdat struct {
 	Moof moof.Box `json:"moof"`
 	Mdat mdat.Box `json:"mdat"`
+
+	moofOffset uint64 // offset of the first byte of moof from the beginning of the input
 }
 
 // Boxes represents mp4 boxes.
 type Boxes struct {
 	Ftyp     *ftyp.Box  `json:"ftyp,omitempty"`
+	Styp     []ftyp.Box `json:"styp,omitempty"`
 	Free     []free.Box `json:"free,omitempty"`
 	Wide     *wide.Box  `json:"wide,omitempty"`
 	Moov     *moov.Box  `json:"moov,omitempty"`
@@ -42,12 +46,15 @@ type Boxes struct {
 
 	// internal vars for parsing or other handling
 	boxesCreator map[string]box.NewFunc `json:"-"`
+	order        box.Order              `json:"-"`
+	parsedBytes  uint64                 `json:"-"` // bytes parsed from the beginning of the input
 }
 
 func newBoxes() Boxes {
 	return Boxes{
 		boxesCreator: map[string]box.NewFunc{
 			box.TypeFtyp: ftyp.New,
+			box.TypeStyp: ftyp.New,
 			box.TypeFree: free.New,
 			box.TypeSkip: free.New,
 			box.TypeWide: wide.New,
@@ -97,16 +104,23 @@ func (b *Boxes) CreateSubBox(h box.Header) (box.Box, error) {
 	if createdBox == nil {
 		glog.Fatalf("create box type %s failed", h.Type.String())
 	}
+	b.order.Record(h.Type.String())
 
 	switch h.Type.String() {
 	case box.TypeFtyp:
 		b.Ftyp = createdBox.(*ftyp.Box)
+	case box.TypeStyp:
+		b.Styp = append(b.Styp, *createdBox.(*ftyp.Box))
+		createdBox = &b.Styp[len(b.Styp)-1] // reference to the last empty styp box
 	case box.TypeFree, box.TypeSkip:
 		b.Free = append(b.Free, *createdBox.(*free.Box))
 		createdBox = &b.Free[len(b.Free)-1] // reference to the last empty free box
 	case box.TypeWide:
 		b.Wide = createdBox.(*wide.Box)
 	case box.TypeMdat:
+		// payload offset from the beginning of the input, which will be refreshed if the input is seekable
+		createdBox.(*mdat.Box).Offset = b.parsedBytes + h.HeaderSize()
+
 		if len(b.MoofMdat) > 0 {
 			if err := b.MoofMdat[len(b.MoofMdat)-1].Mdat.Validate(); err == nil { // expect error
 				glog.Warningf("expect empty mdat but got a valid one %v", b.MoofMdat[len(b.MoofMdat)-1].Mdat)
@@ -123,7 +137,7 @@ func (b *Boxes) CreateSubBox(h box.Header) (box.Box, error) {
 		b.Moov = createdBox.(*moov.Box)
 	case box.TypeMoof:
 		// Moof is required present before Mdat, so always create a new one if moof encountered.
-		b.MoofMdat = append(b.MoofMdat, MoofMdat{Moof: *createdBox.(*moof.Box)})
+		b.MoofMdat = append(b.MoofMdat, MoofMdat{Moof: *createdBox.(*moof.Box), moofOffset: b.parsedBytes})
 		createdBox = &b.MoofMdat[len(b.MoofMdat)-1].Moof // reference to the last empty moof box
 	case box.TypeSidx:
 		b.Sidx = append(b.Sidx, *createdBox.(*sidx.Box))
@@ -134,10 +148,14 @@ func (b *Boxes) CreateSubBox(h box.Header) (box.Box, error) {
 }
 
 // ParsePayload acts as an root box to parse all sub boxes.
+// If r implements both io.ReadSeeker and io.ReaderAt(e.g. *os.File), mdat payloads will not be loaded into memory
+// but read lazily when needed, in which case r should keep available until boxes are no longer used.
 func (b *Boxes) ParsePayload(r io.Reader) error {
 
 	for {
-		if _, err := box.ParseBox(r, b, math.MaxUint64); err != nil {
+		readBytes, err := box.ParseBox(r, b, math.MaxUint64)
+		b.parsedBytes += readBytes
+		if err != nil {
 			if err == io.EOF || err == box.ErrInsufficientSize {
 				break
 			} else if err == box.ErrUnknownBoxType {
@@ -150,67 +168,218 @@ func (b *Boxes) ParsePayload(r io.Reader) error {
 	return nil
 }
 
-// ExtractES extracts AVC or HEVC Elementary Stream.
+// subBoxes returns top level boxes in sequence for encoding.
+func (b *Boxes) subBoxes() []box.Box {
+	groups := map[string][]box.Box{}
+	if b.Ftyp != nil {
+		groups[box.TypeFtyp] = []box.Box{b.Ftyp}
+	}
+	for i := range b.Styp {
+		groups[box.TypeStyp] = append(groups[box.TypeStyp], &b.Styp[i])
+	}
+	for i := range b.Free { // free and skip share the same structure
+		t := b.Free[i].Type.String()
+		groups[t] = append(groups[t], &b.Free[i])
+	}
+	if b.Wide != nil {
+		groups[box.TypeWide] = []box.Box{b.Wide}
+	}
+	if b.Moov != nil {
+		groups[box.TypeMoov] = []box.Box{b.Moov}
+	}
+	for i := range b.Sidx {
+		groups[box.TypeSidx] = append(groups[box.TypeSidx], &b.Sidx[i])
+	}
+	for i := range b.Mdat { // mdat that before any moof
+		groups[box.TypeMdat] = append(groups[box.TypeMdat], &b.Mdat[i])
+	}
+	for i := range b.MoofMdat {
+		if err := b.MoofMdat[i].Moof.Validate(); err == nil {
+			groups[box.TypeMoof] = append(groups[box.TypeMoof], &b.MoofMdat[i].Moof)
+		}
+		if err := b.MoofMdat[i].Mdat.Validate(); err == nil {
+			groups[box.TypeMdat] = append(groups[box.TypeMdat], &b.MoofMdat[i].Mdat)
+		}
+	}
+
+	return b.order.Sort(groups, []string{box.TypeFtyp, box.TypeStyp, box.TypeFree, box.TypeSkip, box.TypeWide, box.TypeMoov, box.TypeSidx, box.TypeMoof, box.TypeMdat})
+}
+
+// EncodedSize returns total bytes of all boxes once encoded.
+func (b *Boxes) EncodedSize() uint64 {
+	return box.EncodedBoxesSize(b.subBoxes())
+}
+
+// Encode writes all boxes, which is expected to be byte-identical with the input if nothing changed after parsing.
+// mdat payloads that haven't been loaded into memory will be read from the input, so it should keep available.
+func (b *Boxes) Encode(w io.Writer) error {
+	return box.EncodeBoxes(w, b.subBoxes())
+}
+
+// ExtractES extracts AVC or HEVC Elementary Stream by sample entry type of the track, i.e., avc1 or hvc1/hev1.
 // Use trackID to select the specified one, trackID <= 0 means use the first found one.
-func (b *Boxes) ExtractES(trackID int) (*es.ElementaryStream, error) {
+func (b *Boxes) ExtractES(trackID int) (ElementaryStream, error) {
+	codec, err := b.videoSampleEntryType(trackID)
+	if err != nil {
+		return nil, err
+	}
 
-	if b.Moov == nil || (b.MoofMdat == nil && b.Mdat == nil) {
-		return nil, fmt.Errorf("moov, moof or mdat not found")
+	var e ElementaryStream
+	switch codec {
+	case box.TypeAvc1:
+		e, err = b.ExtractAVCES(trackID)
+	case box.TypeHev1, box.TypeHvc1:
+		e, err = b.ExtractHEVCES(trackID)
+	default:
+		return nil, fmt.Errorf("trackID %d sample entry %s es extraction doesn't support yet", trackID, codec)
+	}
+	if err != nil {
+		return nil, err
 	}
+	return e, nil
+}
 
-	trackFound := false
+// ExtractAVCES extracts AVC Elementary Stream, use ExtractHEVCES for HEVC instead.
+// Use trackID to select the specified one, trackID <= 0 means use the first found one.
+func (b *Boxes) ExtractAVCES(trackID int) (*es.ElementaryStream, error) {
 	e := es.ElementaryStream{}
-	for _, track := range b.Moov.Trak {
-		if track.Mdia.Hdlr.HandlerType.String() == box.TypeVide {
-			if trackID > 0 && uint32(trackID) != track.Tkhd.TrackID {
-				continue
-			}
-			if trackID <= 0 {
-				trackID = int(track.Tkhd.TrackID)
-			}
-			trackFound = true
-			e.SetLengthSize(uint32(track.Mdia.Minf.Stbl.Stsd.AVC1SampleEntries[0].AVCConfig.AVCConfig.LengthSize()))
-			break
+	if err := b.extractLengthPrefixedES(trackID, &e, avcLengthSize); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// lengthPrefixedES represents Elementary Stream of NAL units prefixed by NALUnitLength, i.e., samples of AVC or HEVC.
+type lengthPrefixedES interface {
+	SetLengthSize(l uint32)
+	Parse(r io.Reader, size int) (uint64, error)
+}
+
+// extractLengthPrefixedES reads all samples of the video track into e.
+// config is codec specific, it reads the decoder configuration record of the track then returns NALUnitLength size.
+func (b *Boxes) extractLengthPrefixedES(trackID int, e lengthPrefixedES, config func(track *trak.Box) (uint32, error)) error {
+
+	if b.Moov == nil || (b.MoofMdat == nil && b.Mdat == nil) {
+		return fmt.Errorf("moov, moof or mdat not found")
+	}
+
+	track, err := b.handlerTrack(trackID, box.TypeVide)
+	if err != nil {
+		return err
+	}
+
+	lengthSize, err := config(track)
+	if err != nil {
+		return err
+	}
+	e.SetLengthSize(lengthSize)
+
+	samples, err := b.Samples(track.Tkhd.TrackID)
+	if err != nil {
+		return err
+	}
+	for _, sample := range samples {
+		data, err := b.ReadSample(sample)
+		if err != nil {
+			return err
+		}
+		if _, err := e.Parse(bytes.NewReader(data), len(data)); err != nil {
+			return err
 		}
 	}
 
-	if !trackFound {
-		return nil, fmt.Errorf("trackID %d not found", trackID)
+	return nil
+}
+
+// videoSampleEntryType returns sample entry type of the video track, e.g., avc1, hvc1, trackID <= 0 means the first found one.
+func (b *Boxes) videoSampleEntryType(trackID int) (string, error) {
+	if b.Moov == nil {
+		return "", fmt.Errorf("moov not found")
+	}
+	track, err := b.handlerTrack(trackID, box.TypeVide)
+	if err != nil {
+		return "", err
 	}
+	return track.Mdia.Minf.Stbl.Stsd.SampleEntryType(), nil
+}
 
-	// fragment-mp4 if exist
-	for i := 0; i < len(b.MoofMdat); i++ {
-		for _, tf := range b.MoofMdat[i].Moof.Traf {
-			if int(tf.Tfhd.TrackID) != trackID {
-				continue
-			}
+// avcLengthSize returns NALUnitLength size in avcC of the avc1 track.
+func avcLengthSize(track *trak.Box) (uint32, error) {
+	stsd := track.Mdia.Minf.Stbl.Stsd
+	if len(stsd.AVC1SampleEntries) == 0 || stsd.AVC1SampleEntries[0].AVCConfig == nil {
+		return 0, fmt.Errorf("trackID %d is not avc", track.Tkhd.TrackID)
+	}
+	return stsd.AVC1SampleEntries[0].AVCConfig.AVCConfig.LengthSize(), nil
+}
 
-			for _, tr := range tf.Trun {
-				var startPos uint32
-				for _, sampleSize := range tr.SampleSize {
-					data := b.MoofMdat[i].Mdat.Data[startPos : startPos+sampleSize]
-					if _, err := e.Parse(bytes.NewReader(data), len(data)); err != nil {
-						return &e, err
-					}
-					startPos += sampleSize
-				}
-			}
-			break
+// handlerTrack returns the track by trackID and handler type(e.g., vide, soun), trackID <= 0 means the first found one.
+func (b *Boxes) handlerTrack(trackID int, handlerType string) (*trak.Box, error) {
+	for i := range b.Moov.Trak {
+		track := &b.Moov.Trak[i]
+		if track.Tkhd == nil || track.Mdia == nil || track.Mdia.Hdlr == nil || track.Mdia.Hdlr.HandlerType.String() != handlerType {
+			continue
+		}
+		if trackID > 0 && uint32(trackID) != track.Tkhd.TrackID {
+			continue
+		}
+		if track.Mdia.Minf == nil || track.Mdia.Minf.Stbl == nil || track.Mdia.Minf.Stbl.Stsd == nil {
+			return nil, fmt.Errorf("trackID %d stsd not found", track.Tkhd.TrackID)
 		}
+		return track, nil
 	}
 
-	// mp4 if exist
-	for i := 0; i < len(b.Mdat); i++ {
+	return nil, fmt.Errorf("trackID %d not found", trackID)
+}
 
+// readMdatAt reads mdat payload, offset is from the beginning of the input, e.g. chunk offset in stco/co64.
+func (b *Boxes) readMdatAt(p []byte, offset uint64) error {
+	mdats := make([]*mdat.Box, 0, len(b.Mdat)+len(b.MoofMdat))
+	for i := range b.Mdat {
+		mdats = append(mdats, &b.Mdat[i])
+	}
+	for i := range b.MoofMdat {
+		mdats = append(mdats, &b.MoofMdat[i].Mdat)
 	}
 
-	return &e, nil
+	for _, m := range mdats {
+		if offset < m.Offset || offset+uint64(len(p)) > m.Offset+m.Length {
+			continue
+		}
+
+		_, err := m.ReadAt(p, int64(offset-m.Offset))
+		return err
+	}
+
+	return fmt.Errorf("no mdat contains offset %d size %d", offset, len(p))
+}
+
+// ExtractAnnexBES extracts AVC or HEVC Elementary Stream with AnnexB byte format by sample entry type of the track.
+// Use trackID to select the specified one, trackID <= 0 means use the first found one.
+func (b *Boxes) ExtractAnnexBES(trackID int) (ElementaryStream, error) {
+	codec, err := b.videoSampleEntryType(trackID)
+	if err != nil {
+		return nil, err
+	}
+
+	var e ElementaryStream
+	switch codec {
+	case box.TypeAvc1:
+		e, err = b.ExtractAVCAnnexBES(trackID)
+	case box.TypeHev1, box.TypeHvc1:
+		e, err = b.ExtractHEVCAnnexBES(trackID)
+	default:
+		return nil, fmt.Errorf("trackID %d sample entry %s annexb_es extraction doesn't support yet", trackID, codec)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
 }
 
-// ExtractAnnexBES extracts AVC or HEVC Elementary Stream with AnnexB byte format.
+// ExtractAVCAnnexBES extracts AVC Elementary Stream with AnnexB byte format, use ExtractHEVCAnnexBES for HEVC instead.
 // Use trackID to select the specified one, trackID <= 0 means use the first found one.
-func (b *Boxes) ExtractAnnexBES(trackID int) (*annexbes.ElementaryStream, error) {
-	mp4ES, err := b.ExtractES(trackID)
+func (b *Boxes) ExtractAVCAnnexBES(trackID int) (*annexbes.ElementaryStream, error) {
+	mp4ES, err := b.ExtractAVCES(trackID)
 	if err != nil {
 		return nil, err
 	}
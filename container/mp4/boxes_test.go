@@ -0,0 +1,281 @@
+package mp4
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/wangyoucao577/medialib/container/mp4/box"
+	"github.com/wangyoucao577/medialib/container/mp4/box/ctts"
+	"github.com/wangyoucao577/medialib/container/mp4/box/mdat"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stsc"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stsd"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stss"
+)
+
+// onlyReader hides io.Seeker and io.ReaderAt to force in-memory parsing.
+type onlyReader struct {
+	io.Reader
+}
+
+func TestEncode(t *testing.T) {
+	cases := []string{
+		"../../assets/sintel_trailer-720p-firstgopfmp4.mp4",
+	}
+
+	for _, c := range cases {
+		expect, err := os.ReadFile(c)
+		if err != nil {
+			t.Fatalf("read %s failed, err %v", c, err)
+		}
+
+		// parse from seekable input to read mdat payload lazily
+		h := New(c)
+		if err := h.Parse(); err != nil {
+			t.Fatalf("parse %s failed, err %v", c, err)
+		}
+		defer h.Close()
+
+		if size := h.EncodedSize(); size != uint64(len(expect)) {
+			t.Errorf("encoded size of %s expect %d but got %d", c, len(expect), size)
+		}
+
+		var buf bytes.Buffer
+		if err := h.Encode(&buf); err != nil {
+			t.Errorf("encode %s expect nil but got %v", c, err)
+		}
+		if !bytes.Equal(buf.Bytes(), expect) {
+			t.Errorf("encode %s expect identical bytes but got different, size %d vs %d", c, buf.Len(), len(expect))
+		}
+
+		// parse from in memory input
+		b := newBoxes()
+		if err := b.ParsePayload(onlyReader{bytes.NewReader(expect)}); err != nil {
+			t.Fatalf("parse %s in memory failed, err %v", c, err)
+		}
+		buf.Reset()
+		if err := b.Encode(&buf); err != nil {
+			t.Errorf("encode %s in memory expect nil but got %v", c, err)
+		}
+		if !bytes.Equal(buf.Bytes(), expect) {
+			t.Errorf("encode %s in memory expect identical bytes but got different, size %d vs %d", c, buf.Len(), len(expect))
+		}
+	}
+}
+
+func TestEncodeUnknownSampleEntry(t *testing.T) {
+	fmp4File := "../../assets/sintel_trailer-720p-firstgopfmp4.mp4"
+
+	data, err := os.ReadFile(fmp4File)
+	if err != nil {
+		t.Fatalf("read %s failed, err %v", fmp4File, err)
+	}
+	b := newBoxes()
+	if err := b.ParsePayload(bytes.NewReader(data)); err != nil {
+		t.Fatalf("parse %s failed, err %v", fmp4File, err)
+	}
+
+	// an unsupported sample entry, e.g., a mp4v entry following avc1
+	unknown := stsd.UnknownSampleEntry{Header: box.Header{Type: box.FixedArray4Bytes{'m', 'p', '4', 'v'}}, Data: bytes.Repeat([]byte{0x01}, 78)}
+	b.Moov.Trak[0].Mdia.Minf.Stbl.Stsd.UnknownSampleEntries = append(b.Moov.Trak[0].Mdia.Minf.Stbl.Stsd.UnknownSampleEntries, unknown)
+	var expect bytes.Buffer
+	if err := b.Encode(&expect); err != nil {
+		t.Fatalf("encode %s with unknown sample entry failed, err %v", fmp4File, err)
+	}
+
+	for _, r := range []io.Reader{bytes.NewReader(expect.Bytes()), onlyReader{bytes.NewReader(expect.Bytes())}} {
+		p := newBoxes()
+		if err := p.ParsePayload(r); err != nil {
+			t.Fatalf("parse %s with unknown sample entry failed, err %v", fmp4File, err)
+		}
+		var got bytes.Buffer
+		if err := p.Encode(&got); err != nil {
+			t.Errorf("encode %s with unknown sample entry expect nil but got %v", fmp4File, err)
+		}
+		if !bytes.Equal(got.Bytes(), expect.Bytes()) {
+			t.Errorf("encode %s with unknown sample entry expect identical bytes but got different, size %d vs %d", fmp4File, got.Len(), expect.Len())
+		}
+
+		sd := p.Moov.Trak[0].Mdia.Minf.Stbl.Stsd
+		if sd.EntryCount != 2 || len(sd.AVC1SampleEntries) != 1 || len(sd.UnknownSampleEntries) != 1 {
+			t.Errorf("expect avc1 and unknown sample entries but got entry_count %d, %d avc1 %d unknown", sd.EntryCount, len(sd.AVC1SampleEntries), len(sd.UnknownSampleEntries))
+		} else if got := sd.UnknownSampleEntries[0]; got.Type != unknown.Type || !bytes.Equal(got.Data, unknown.Data) {
+			t.Errorf("expect unknown sample entry %s kept as is but got %s", unknown.Type, got.Type)
+		}
+	}
+}
+
+func TestEncodeProgressive(t *testing.T) {
+	fmp4File := "../../assets/sintel_trailer-720p-firstgopfmp4.mp4"
+
+	h := New(fmp4File)
+	if err := h.Parse(); err != nil {
+		t.Fatalf("parse %s failed, err %v", fmp4File, err)
+	}
+	defer h.Close()
+
+	for _, expect := range [][]byte{progressiveMP4(t, h, false), progressiveMP4(t, h, true)} {
+		for _, r := range []io.Reader{bytes.NewReader(expect), onlyReader{bytes.NewReader(expect)}} {
+			p := newBoxes()
+			if err := p.ParsePayload(r); err != nil {
+				t.Fatalf("parse progressive mp4 failed, err %v", err)
+			}
+			if size := p.EncodedSize(); size != uint64(len(expect)) {
+				t.Errorf("encoded size of progressive mp4 expect %d but got %d", len(expect), size)
+			}
+			var got bytes.Buffer
+			if err := p.Encode(&got); err != nil {
+				t.Errorf("encode progressive mp4 expect nil but got %v", err)
+			}
+			if !bytes.Equal(got.Bytes(), expect) {
+				t.Errorf("encode progressive mp4 expect identical bytes but got different, size %d vs %d", got.Len(), len(expect))
+			}
+		}
+	}
+}
+
+// progressiveMP4 remuxes fragmented mp4 to progressive mp4, samples of each track are stored in two interleaved chunks.
+// The moov will be placed after mdat if moovAtEnd, otherwise before mdat.
+func progressiveMP4(t *testing.T, h *Handler, moovAtEnd bool) []byte {
+	initSegment, err := h.InitSegment()
+	if err != nil {
+		t.Fatalf("create init segment failed, err %v", err)
+	}
+	p := Boxes{Ftyp: h.Ftyp, Moov: initSegment.Moov}
+	p.Moov.Mvex = nil
+
+	type chunk struct {
+		track   int
+		samples []Sample
+	}
+	var chunks []chunk
+	for half := 0; half < 2; half++ {
+		for i, track := range p.Moov.Trak {
+			samples, err := h.Samples(track.Tkhd.TrackID)
+			if err != nil {
+				t.Fatalf("get samples of track %d failed, err %v", track.Tkhd.TrackID, err)
+			}
+			if half == 0 {
+				samples = samples[:(len(samples)+1)/2]
+			} else {
+				samples = samples[(len(samples)+1)/2:]
+			}
+			if len(samples) > 0 {
+				chunks = append(chunks, chunk{i, samples})
+			}
+		}
+	}
+
+	var payload []byte
+	chunkStarts := make([][]uint32, len(p.Moov.Trak))
+	for _, c := range chunks {
+		st := p.Moov.Trak[c.track].Mdia.Minf.Stbl
+		st.Stsc.Entries = append(st.Stsc.Entries, stsc.ChunkEntry{FirstChunk: uint32(len(chunkStarts[c.track]) + 1), SamplesPerChunk: uint32(len(c.samples)), SampleDescriptionIndex: 1})
+		chunkStarts[c.track] = append(chunkStarts[c.track], uint32(len(payload)))
+
+		for _, s := range c.samples {
+			data, err := h.ReadSample(s)
+			if err != nil {
+				t.Fatalf("read sample failed, err %v", err)
+			}
+			payload = append(payload, data...)
+
+			sampleNumber := uint32(len(st.Stsz.EntrySizes) + 1)
+			st.Stsz.EntrySizes = append(st.Stsz.EntrySizes, s.Size)
+			st.Stsz.SampleCount = sampleNumber
+			st.Stts.SampleCounts = append(st.Stts.SampleCounts, 1)
+			st.Stts.SampleDeltas = append(st.Stts.SampleDeltas, s.Duration)
+			if s.CompositionTime != int64(s.DecodeTime) {
+				if st.Ctts == nil {
+					st.Ctts = ctts.New(box.NewHeader(box.TypeCtts)).(*ctts.Box)
+					st.Ctts.Version = 1
+					for i := uint32(1); i < sampleNumber; i++ {
+						st.Ctts.SampleCounts = append(st.Ctts.SampleCounts, 1)
+						st.Ctts.SampleOffsets = append(st.Ctts.SampleOffsets, 0)
+					}
+				}
+			}
+			if st.Ctts != nil {
+				st.Ctts.SampleCounts = append(st.Ctts.SampleCounts, 1)
+				st.Ctts.SampleOffsets = append(st.Ctts.SampleOffsets, s.CompositionTime-int64(s.DecodeTime))
+			}
+			if !s.IsSync && st.Stss == nil {
+				st.Stss = stss.New(box.NewHeader(box.TypeStss)).(*stss.Box)
+				for i := uint32(1); i < sampleNumber; i++ {
+					st.Stss.SampleNumbers = append(st.Stss.SampleNumbers, i)
+				}
+			}
+			if s.IsSync && st.Stss != nil {
+				st.Stss.SampleNumbers = append(st.Stss.SampleNumbers, sampleNumber)
+			}
+		}
+	}
+	for i, track := range p.Moov.Trak {
+		track.Mdia.Minf.Stbl.Stco.ChunkOffsets = make([]uint32, len(chunkStarts[i])) // set size before calculate offsets
+	}
+
+	m := mdat.New(box.NewHeader(box.TypeMdat)).(*mdat.Box)
+	m.Data = payload
+	p.Mdat = []mdat.Box{*m}
+
+	mdatPayloadOffset := uint32(p.Ftyp.EncodedSize() + p.Moov.EncodedSize() + 8)
+	if moovAtEnd {
+		mdatPayloadOffset = uint32(p.Ftyp.EncodedSize() + 8)
+		p.order = box.Order{box.TypeFtyp, box.TypeMdat, box.TypeMoov}
+	}
+	for i, track := range p.Moov.Trak {
+		for j, start := range chunkStarts[i] {
+			track.Mdia.Minf.Stbl.Stco.ChunkOffsets[j] = mdatPayloadOffset + start
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := p.Encode(&buf); err != nil {
+		t.Fatalf("encode progressive mp4 failed, err %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractESProgressive(t *testing.T) {
+	const trackID = 1
+	fmp4File := "../../assets/sintel_trailer-720p-firstgopfmp4.mp4"
+
+	h := New(fmp4File)
+	if err := h.Parse(); err != nil {
+		t.Fatalf("parse %s failed, err %v", fmp4File, err)
+	}
+	defer h.Close()
+
+	expect, err := h.ExtractAVCES(trackID)
+	if err != nil {
+		t.Fatalf("extract es from %s failed, err %v", fmp4File, err)
+	}
+
+	data := progressiveMP4(t, h, false)
+	for _, r := range []io.Reader{bytes.NewReader(data), onlyReader{bytes.NewReader(data)}} {
+		b := newBoxes()
+		if err := b.ParsePayload(r); err != nil {
+			t.Fatalf("parse progressive mp4 failed, err %v", err)
+		}
+		if len(b.MoofMdat) > 0 || len(b.Mdat) != 1 {
+			t.Fatalf("expect progressive mp4 but got %d moof and %d mdat", len(b.MoofMdat), len(b.Mdat))
+		}
+
+		got, err := b.ExtractAVCES(trackID)
+		if err != nil {
+			t.Errorf("extract es from progressive mp4 expect nil but got %v", err)
+			continue
+		}
+		if len(got.LengthNALU) != len(expect.LengthNALU) {
+			t.Errorf("extract es from progressive mp4 expect %d nalus but got %d", len(expect.LengthNALU), len(got.LengthNALU))
+			continue
+		}
+		for i := range got.LengthNALU {
+			if !bytes.Equal(got.LengthNALU[i].NALU.RawBytes, expect.LengthNALU[i].NALU.RawBytes) {
+				t.Errorf("extract es from progressive mp4 nalu %d mismatch", i)
+				break
+			}
+		}
+	}
+}
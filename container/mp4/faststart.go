@@ -0,0 +1,126 @@
+package mp4
+
+import (
+	"fmt"
+
+	"github.com/wangyoucao577/medialib/container/mp4/box"
+	"github.com/wangyoucao577/medialib/container/mp4/box/mdat"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stbl"
+)
+
+// FastStart relocates moov right after ftyp so that playback can start before the whole file downloaded,
+// chunk offsets in stco/co64 will be recalculated for the relocation and stco will be upgraded to co64 if overflows.
+// It only prepares boxes for Encode, since mdat payloads are still read from the original input,
+// parse the encoded output again if samples are needed.
+func (b *Boxes) FastStart() error {
+	if b.Moov == nil {
+		return fmt.Errorf("moov not found")
+	}
+	if len(b.MoofMdat) > 0 {
+		return fmt.Errorf("fragmented mp4 is not supported")
+	}
+
+	// move moov right after ftyp
+	order := make(box.Order, 0, len(b.order))
+	for _, t := range b.order {
+		if t != box.TypeMoov {
+			order = append(order, t)
+		}
+	}
+	index := -1
+	for i, t := range order {
+		if t == box.TypeFtyp {
+			index = i + 1
+			break
+		}
+	}
+	if index < 0 { // ftyp has not been recorded, e.g., created manually
+		index = 0
+		if b.Ftyp != nil {
+			order = append(box.Order{box.TypeFtyp}, order...)
+			index = 1
+		}
+	}
+	b.order = append(order[:index], append(box.Order{box.TypeMoov}, order[index:]...)...)
+
+	// chunk offsets relative to the mdat that contains them, which keep the same after relocation
+	type chunks struct {
+		stbl    *stbl.Box
+		mdats   []int
+		offsets []uint64
+	}
+	var tracksChunks []chunks
+	for _, track := range b.Moov.Trak {
+		if track.Mdia == nil || track.Mdia.Minf == nil || track.Mdia.Minf.Stbl == nil {
+			continue
+		}
+		st := track.Mdia.Minf.Stbl
+		if st.Stco == nil && st.Co64 == nil {
+			continue
+		}
+		offsets, err := st.ChunkOffsets()
+		if err != nil {
+			return err
+		}
+
+		c := chunks{stbl: st, mdats: make([]int, len(offsets)), offsets: make([]uint64, len(offsets))}
+		for i, o := range offsets {
+			index, err := b.mdatIndex(o)
+			if err != nil {
+				return fmt.Errorf("track %d chunk %d, err %v", track.Tkhd.TrackID, i+1, err)
+			}
+			c.mdats[i] = index
+			c.offsets[i] = o - b.Mdat[index].Offset
+		}
+		tracksChunks = append(tracksChunks, c)
+	}
+
+	// moov size may increase due to stco upgrades to co64, which will change mdat offsets again
+	for {
+		moovSize := b.Moov.EncodedSize()
+		mdatOffsets := b.encodedMdatOffsets()
+
+		for _, c := range tracksChunks {
+			offsets := make([]uint64, len(c.offsets))
+			for i := range c.offsets {
+				offsets[i] = mdatOffsets[c.mdats[i]] + c.offsets[i]
+			}
+			c.stbl.SetChunkOffsets(offsets)
+		}
+
+		if b.Moov.EncodedSize() == moovSize {
+			break
+		}
+	}
+
+	return nil
+}
+
+// mdatIndex returns index of the mdat that contains the offset.
+func (b *Boxes) mdatIndex(offset uint64) (int, error) {
+	for i := range b.Mdat {
+		if offset >= b.Mdat[i].Offset && offset <= b.Mdat[i].Offset+b.Mdat[i].Length { // the end is possible if the chunk is empty
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no mdat contains offset %d", offset)
+}
+
+// encodedMdatOffsets returns payload offsets of mdat once encoded.
+func (b *Boxes) encodedMdatOffsets() []uint64 {
+	offsets := make([]uint64, len(b.Mdat))
+
+	var pos uint64
+	for _, sb := range b.subBoxes() {
+		if m, ok := sb.(*mdat.Box); ok {
+			for i := range b.Mdat {
+				if m == &b.Mdat[i] {
+					offsets[i] = pos + m.EncodedHeaderSize()
+					break
+				}
+			}
+		}
+		pos += sb.EncodedSize()
+	}
+	return offsets
+}
@@ -0,0 +1,87 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/wangyoucao577/medialib/container/mp4/box"
+)
+
+func TestFastStart(t *testing.T) {
+	fmp4File := "../../assets/sintel_trailer-720p-firstgopfmp4.mp4"
+
+	h := New(fmp4File)
+	if err := h.Parse(); err != nil {
+		t.Fatalf("parse %s failed, err %v", fmp4File, err)
+	}
+	defer h.Close()
+
+	if err := h.FastStart(); err == nil {
+		t.Errorf("fast start fragmented mp4 expect error but got nil")
+	}
+
+	b := newBoxes()
+	if err := b.ParsePayload(bytes.NewReader(progressiveMP4(t, h, true))); err != nil {
+		t.Fatalf("parse progressive mp4 failed, err %v", err)
+	}
+	if typeIndex(b.order, box.TypeMoov) < typeIndex(b.order, box.TypeMdat) {
+		t.Fatalf("expect moov after mdat but got order %v", b.order)
+	}
+
+	if err := b.FastStart(); err != nil {
+		t.Fatalf("fast start expect nil but got %v", err)
+	}
+	var buf bytes.Buffer
+	if err := b.Encode(&buf); err != nil {
+		t.Fatalf("encode expect nil but got %v", err)
+	}
+	if uint64(buf.Len()) != b.EncodedSize() {
+		t.Errorf("expect encoded size %d but got %d", b.EncodedSize(), buf.Len())
+	}
+
+	fastStart := newBoxes()
+	if err := fastStart.ParsePayload(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("parse fast start mp4 failed, err %v", err)
+	}
+	if fastStart.order[0] != box.TypeFtyp || fastStart.order[1] != box.TypeMoov || typeIndex(fastStart.order, box.TypeMoov) > typeIndex(fastStart.order, box.TypeMdat) {
+		t.Errorf("expect moov right after ftyp but got order %v", fastStart.order)
+	}
+
+	for _, track := range b.Moov.Trak {
+		expect, err := h.Samples(track.Tkhd.TrackID)
+		if err != nil {
+			t.Fatalf("get samples of track %d failed, err %v", track.Tkhd.TrackID, err)
+		}
+		got, err := fastStart.Samples(track.Tkhd.TrackID)
+		if err != nil {
+			t.Fatalf("get fast start samples of track %d failed, err %v", track.Tkhd.TrackID, err)
+		}
+		if len(got) != len(expect) {
+			t.Fatalf("track %d expect %d samples but got %d", track.Tkhd.TrackID, len(expect), len(got))
+		}
+		for i := range got {
+			expectData, err := h.ReadSample(expect[i])
+			if err != nil {
+				t.Fatalf("read sample failed, err %v", err)
+			}
+			gotData, err := fastStart.ReadSample(got[i])
+			if err != nil {
+				t.Fatalf("read fast start sample failed, err %v", err)
+			}
+			if !bytes.Equal(gotData, expectData) {
+				t.Errorf("track %d sample %d data mismatch", track.Tkhd.TrackID, i)
+				break
+			}
+		}
+	}
+}
+
+// typeIndex returns index of the first box type in order, or -1 if not found.
+func typeIndex(o box.Order, boxType string) int {
+	for i, t := range o {
+		if t == boxType {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,324 @@
+package mp4
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/wangyoucao577/medialib/container/mp4/box"
+	"github.com/wangyoucao577/medialib/container/mp4/box/ftyp"
+	"github.com/wangyoucao577/medialib/container/mp4/box/mdat"
+	"github.com/wangyoucao577/medialib/container/mp4/box/mehd"
+	"github.com/wangyoucao577/medialib/container/mp4/box/mfhd"
+	"github.com/wangyoucao577/medialib/container/mp4/box/moof"
+	"github.com/wangyoucao577/medialib/container/mp4/box/mvex"
+	"github.com/wangyoucao577/medialib/container/mp4/box/sidx"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stco"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stsc"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stsz"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stts"
+	"github.com/wangyoucao577/medialib/container/mp4/box/tfdt"
+	"github.com/wangyoucao577/medialib/container/mp4/box/tfhd"
+	"github.com/wangyoucao577/medialib/container/mp4/box/traf"
+	"github.com/wangyoucao577/medialib/container/mp4/box/trex"
+	"github.com/wangyoucao577/medialib/container/mp4/box/trun"
+)
+
+// sample_flags for fragments, see ISO/IEC 14496-12 8.8.3.1.
+const (
+	syncSampleFlags    = 0x02000000 // sample_depends_on 2, i.e., doesn't depend on others
+	nonSyncSampleFlags = 0x01010000 // sample_depends_on 1 and sample_is_non_sync_sample 1
+)
+
+// FragmentOptions represents options to remux an mp4 to fragmented mp4.
+type FragmentOptions struct {
+	// Expected duration of each fragment. Fragments are always cut at sync samples of the reference track,
+	// i.e., the first video track or the first track if no video, so they might be longer than expected.
+	// 0 means cut at every sync sample.
+	FragmentDuration time.Duration
+
+	Sidx bool // generate a sidx for each fragment
+	Styp bool // generate a styp for each fragment, which is expected by separate media segments
+}
+
+// fragmentTrack represents a track that to be fragmented.
+type fragmentTrack struct {
+	trackID   uint32
+	timescale uint32
+	samples   []Sample
+	next      int // index of the next sample to be fragmented
+}
+
+// InitSegment creates boxes of the initialization segment for fragmented mp4, i.e., ftyp and moov with mvex.
+// Sample tables in the moov will be emptied since samples will be stored in fragments.
+func (b *Boxes) InitSegment() (*Boxes, error) {
+	if b.Moov == nil {
+		return nil, fmt.Errorf("moov not found")
+	}
+
+	// deep copy moov by encoding then parsing to avoid modifying the source
+	var buf bytes.Buffer
+	if err := b.Moov.Encode(&buf); err != nil {
+		return nil, err
+	}
+	copied := newBoxes()
+	if err := copied.ParsePayload(bytes.NewReader(buf.Bytes())); err != nil {
+		return nil, err
+	}
+	m := copied.Moov
+	if m == nil {
+		return nil, fmt.Errorf("copy moov failed")
+	}
+
+	mv := mvex.New(box.NewHeader(box.TypeMvex)).(*mvex.Box)
+	if m.Mvhd != nil {
+		mv.Mehd = mehd.New(box.NewHeader(box.TypeMehd)).(*mehd.Box)
+		mv.Mehd.FragmentDuration = m.Mvhd.Duration
+		if m.Mvhd.Duration > math.MaxUint32 {
+			mv.Mehd.Version = 1
+		}
+	}
+
+	for i := range m.Trak {
+		track := &m.Trak[i]
+		if track.Tkhd == nil {
+			return nil, fmt.Errorf("tkhd not found in trak %d", i)
+		}
+
+		t := trex.New(box.NewHeader(box.TypeTrex)).(*trex.Box)
+		t.TrackID = track.Tkhd.TrackID
+		t.DefaultSampleDescriptionIndex = 1
+		mv.Trex = append(mv.Trex, *t)
+
+		if track.Mdia == nil || track.Mdia.Minf == nil || track.Mdia.Minf.Stbl == nil {
+			return nil, fmt.Errorf("stbl not found in track %d", track.Tkhd.TrackID)
+		}
+		st := track.Mdia.Minf.Stbl
+		st.Stts = stts.New(box.NewHeader(box.TypeStts)).(*stts.Box)
+		st.Stsc = stsc.New(box.NewHeader(box.TypeStsc)).(*stsc.Box)
+		st.Stsz = stsz.New(box.NewHeader(box.TypeStsz)).(*stsz.Box)
+		st.Stco = stco.New(box.NewHeader(box.TypeStco)).(*stco.Box)
+		st.Co64 = nil
+		st.Stss = nil
+		st.Ctts = nil
+		st.Sdtp = nil
+	}
+	m.Mvex = mv
+
+	f := ftyp.New(box.NewHeader(box.TypeFtyp)).(*ftyp.Box)
+	f.MajorBrand = box.FixedArray4Bytes{'i', 's', 'o', '5'}
+	f.MinorVersion = 512
+	f.CompatibleBrands = []box.FixedArray4Bytes{{'i', 's', 'o', '5'}, {'i', 's', 'o', '6'}, {'m', 'p', '4', '1'}}
+
+	return &Boxes{Ftyp: f, Moov: m}, nil
+}
+
+// Fragment remuxes samples of all tracks to fragments, each fragment will be passed to fn in sequence as a media segment,
+// which contains styp, sidx if required and one moof with one mdat.
+func (b *Boxes) Fragment(opts FragmentOptions, fn func(segment *Boxes) error) error {
+	if b.Moov == nil {
+		return fmt.Errorf("moov not found")
+	}
+
+	var tracks []*fragmentTrack
+	var ref *fragmentTrack // fragments will be cut at sync samples of the reference track
+	for _, track := range b.Moov.Trak {
+		if track.Tkhd == nil || track.Mdia == nil || track.Mdia.Mdhd == nil || track.Mdia.Mdhd.Timescale == 0 {
+			return fmt.Errorf("tkhd or mdhd not found or invalid")
+		}
+
+		samples, err := b.Samples(track.Tkhd.TrackID)
+		if err != nil {
+			return err
+		}
+		t := &fragmentTrack{trackID: track.Tkhd.TrackID, timescale: track.Mdia.Mdhd.Timescale, samples: samples}
+		tracks = append(tracks, t)
+
+		if len(samples) == 0 {
+			continue
+		}
+		isVideo := track.Mdia.Hdlr != nil && track.Mdia.Hdlr.HandlerType.String() == box.TypeVide
+		if ref == nil || (isVideo && !b.isVideoTrack(ref.trackID)) {
+			ref = t
+		}
+	}
+	if ref == nil {
+		return fmt.Errorf("no sample found")
+	}
+
+	fragmentDuration := uint64(opts.FragmentDuration) * uint64(ref.timescale) / uint64(time.Second)
+	sequenceNumber := uint32(1)
+	for ref.next < len(ref.samples) {
+		// find the end of the fragment on the reference track
+		start := ref.next
+		end := start + 1
+		for ; end < len(ref.samples); end++ {
+			if ref.samples[end].IsSync && ref.samples[end].DecodeTime-ref.samples[start].DecodeTime >= fragmentDuration {
+				break
+			}
+		}
+
+		// other tracks are cut by the decode time of the reference track, the last fragment takes all remaining samples
+		endTime, endTimescale := uint64(math.MaxUint64), uint64(1)
+		if end < len(ref.samples) {
+			endTime, endTimescale = ref.samples[end].DecodeTime, uint64(ref.timescale)
+		}
+
+		segment, err := b.fragment(opts, tracks, ref, endTime, endTimescale, sequenceNumber)
+		if err != nil {
+			return err
+		}
+		if err := fn(segment); err != nil {
+			return err
+		}
+		sequenceNumber++
+	}
+
+	return nil
+}
+
+// WriteFragmented writes a single fragmented mp4 file, which contains the initialization segment and all fragments.
+func (b *Boxes) WriteFragmented(w io.Writer, opts FragmentOptions) error {
+	initSegment, err := b.InitSegment()
+	if err != nil {
+		return err
+	}
+	if err := initSegment.Encode(w); err != nil {
+		return err
+	}
+
+	opts.Styp = false // not necessary in a single file
+	return b.Fragment(opts, func(segment *Boxes) error {
+		return segment.Encode(w)
+	})
+}
+
+// isVideoTrack returns whether the track is a video track.
+func (b *Boxes) isVideoTrack(trackID uint32) bool {
+	for _, track := range b.Moov.Trak {
+		if track.Tkhd != nil && track.Tkhd.TrackID == trackID {
+			return track.Mdia != nil && track.Mdia.Hdlr != nil && track.Mdia.Hdlr.HandlerType.String() == box.TypeVide
+		}
+	}
+	return false
+}
+
+// fragment creates a media segment by samples before endTime(in endTimescale) of all tracks.
+func (b *Boxes) fragment(opts FragmentOptions, tracks []*fragmentTrack, ref *fragmentTrack, endTime, endTimescale uint64, sequenceNumber uint32) (*Boxes, error) {
+	mf := moof.New(box.NewHeader(box.TypeMoof)).(*moof.Box)
+	mf.Mfhd = mfhd.New(box.NewHeader(box.TypeMfhd)).(*mfhd.Box)
+	mf.Mfhd.SequenceNumber = sequenceNumber
+
+	var payload []byte
+	var trafDataStarts []uint64
+	var refSamples []Sample
+	for _, t := range tracks {
+		start := t.next
+		for ; t.next < len(t.samples); t.next++ { // compare decode time in different timescales
+			if endTime != math.MaxUint64 && t.samples[t.next].DecodeTime*endTimescale >= endTime*uint64(t.timescale) {
+				break
+			}
+		}
+		samples := t.samples[start:t.next]
+		if len(samples) == 0 {
+			continue
+		}
+		if t == ref {
+			refSamples = samples
+		}
+
+		tf := traf.New(box.NewHeader(box.TypeTraf)).(*traf.Box)
+		tf.Tfhd = tfhd.New(box.NewHeader(box.TypeTfhd)).(*tfhd.Box)
+		tf.Tfhd.Flags = 0x020000 // default‐base‐is‐moof
+		tf.Tfhd.TrackID = t.trackID
+
+		tf.Tfdt = tfdt.New(box.NewHeader(box.TypeTfdt)).(*tfdt.Box)
+		tf.Tfdt.Version = 1
+		tf.Tfdt.BaseMediaDecodeTime = samples[0].DecodeTime
+
+		tr := trun.New(box.NewHeader(box.TypeTrun)).(*trun.Box)
+		tr.Flags = 0x1 | 0x100 | 0x200 | 0x400 // data offset, sample duration, size and flags
+		tr.SampleCount = uint32(len(samples))
+		trafDataStarts = append(trafDataStarts, uint64(len(payload)))
+		for _, s := range samples {
+			tr.SampleDuration = append(tr.SampleDuration, s.Duration)
+			tr.SampleSize = append(tr.SampleSize, s.Size)
+			if s.IsSync {
+				tr.SampleFlags = append(tr.SampleFlags, syncSampleFlags)
+			} else {
+				tr.SampleFlags = append(tr.SampleFlags, nonSyncSampleFlags)
+			}
+
+			compositionTimeOffset := s.CompositionTime - int64(s.DecodeTime)
+			tr.SampleCompositionTimeOffset = append(tr.SampleCompositionTimeOffset, compositionTimeOffset)
+			if compositionTimeOffset != 0 {
+				tr.Flags |= 0x800 // sample‐composition‐time‐offsets‐present
+			}
+			if compositionTimeOffset < 0 {
+				tr.Version = 1 // signed composition time offsets
+			}
+
+			data, err := b.ReadSample(s)
+			if err != nil {
+				return nil, fmt.Errorf("read sample of track %d failed, err %v", t.trackID, err)
+			}
+			payload = append(payload, data...)
+		}
+		tf.Trun = []trun.Box{*tr}
+		mf.Traf = append(mf.Traf, *tf)
+	}
+
+	md := mdat.New(box.NewHeader(box.TypeMdat)).(*mdat.Box)
+	md.Data = payload
+
+	// data offsets are relative to the moof
+	mdatHeaderSize := md.EncodedSize() - uint64(len(payload))
+	for i := range mf.Traf {
+		dataOffset := mf.EncodedSize() + mdatHeaderSize + trafDataStarts[i]
+		if dataOffset > math.MaxInt32 {
+			return nil, fmt.Errorf("data offset %d overflow", dataOffset)
+		}
+		mf.Traf[i].Trun[0].DataOffset = int32(dataOffset)
+	}
+
+	segment := &Boxes{MoofMdat: []MoofMdat{{Moof: *mf, Mdat: *md}}}
+
+	if opts.Styp {
+		st := ftyp.New(box.NewHeader(box.TypeStyp)).(*ftyp.Box)
+		st.MajorBrand = box.FixedArray4Bytes{'m', 's', 'd', 'h'}
+		st.CompatibleBrands = []box.FixedArray4Bytes{{'m', 's', 'd', 'h'}, {'m', 's', 'i', 'x'}}
+		segment.Styp = []ftyp.Box{*st}
+	}
+
+	if opts.Sidx && len(refSamples) > 0 {
+		sx := sidx.New(box.NewHeader(box.TypeSidx)).(*sidx.Box)
+		sx.Version = 1
+		sx.ReferenceID = ref.trackID
+		sx.Timescale = ref.timescale
+
+		earliest := refSamples[0].CompositionTime
+		var duration uint64
+		for _, s := range refSamples {
+			if s.CompositionTime < earliest {
+				earliest = s.CompositionTime
+			}
+			duration += uint64(s.Duration)
+		}
+		if earliest > 0 {
+			sx.EarliestPresentationTime = uint64(earliest)
+		}
+
+		sx.ReferenceCount = 1
+		sx.References = []sidx.Reference{{
+			ReferencedSize:     uint32(mf.EncodedSize() + md.EncodedSize()),
+			SubsegmentDuration: uint32(duration),
+			StartsWithSAP:      1,
+			SAPtype:            1,
+		}}
+		segment.Sidx = []sidx.Box{*sx}
+	}
+
+	return segment, nil
+}
@@ -0,0 +1,106 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriteFragmented(t *testing.T) {
+	fmp4File := "../../assets/sintel_trailer-720p-firstgopfmp4.mp4"
+
+	h := New(fmp4File)
+	if err := h.Parse(); err != nil {
+		t.Fatalf("parse %s failed, err %v", fmp4File, err)
+	}
+	defer h.Close()
+
+	progressive := newBoxes()
+	if err := progressive.ParsePayload(bytes.NewReader(progressiveMP4(t, h, false))); err != nil {
+		t.Fatalf("parse progressive mp4 failed, err %v", err)
+	}
+
+	// mark more sync samples on the video track for more fragments since there's only one in the file
+	const syncInterval = 32
+	videoStbl := progressive.Moov.Trak[0].Mdia.Minf.Stbl
+	videoStbl.Stss.SampleNumbers = nil
+	for i := uint32(1); i <= videoStbl.Stsz.SampleCount; i += syncInterval {
+		videoStbl.Stss.SampleNumbers = append(videoStbl.Stss.SampleNumbers, i)
+	}
+	videoSyncSamples := len(videoStbl.Stss.SampleNumbers)
+
+	cases := []struct {
+		FragmentOptions
+		expectedFragments int
+	}{
+		{FragmentOptions{FragmentDuration: 0}, videoSyncSamples},
+		{FragmentOptions{FragmentDuration: time.Second, Sidx: true}, videoSyncSamples},
+		{FragmentOptions{FragmentDuration: 2 * time.Second, Sidx: true}, (videoSyncSamples + 1) / 2},
+		{FragmentOptions{FragmentDuration: time.Hour, Sidx: true}, 1},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := progressive.WriteFragmented(&buf, c.FragmentOptions); err != nil {
+			t.Errorf("fragment with %+v expect nil but got %v", c, err)
+			continue
+		}
+
+		b := newBoxes()
+		if err := b.ParsePayload(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Errorf("parse fragmented mp4 with %+v failed, err %v", c, err)
+			continue
+		}
+		if len(b.Mdat) > 0 || len(b.MoofMdat) == 0 || b.Moov.Mvex == nil || len(b.Moov.Mvex.Trex) != len(b.Moov.Trak) {
+			t.Errorf("fragment with %+v expect fragmented mp4 but got %d mdat %d moof", c, len(b.Mdat), len(b.MoofMdat))
+			continue
+		}
+		if c.Sidx {
+			if len(b.Sidx) != len(b.MoofMdat) {
+				t.Errorf("fragment with %+v expect %d sidx but got %d", c, len(b.MoofMdat), len(b.Sidx))
+			}
+			for i := 0; i < len(b.Sidx) && i < len(b.MoofMdat); i++ {
+				if size := b.MoofMdat[i].Moof.EncodedSize() + b.MoofMdat[i].Mdat.EncodedSize(); uint64(b.Sidx[i].References[0].ReferencedSize) != size {
+					t.Errorf("fragment with %+v sidx %d expect referenced size %d but got %d", c, i, size, b.Sidx[i].References[0].ReferencedSize)
+				}
+			}
+		}
+		if len(b.MoofMdat) != c.expectedFragments {
+			t.Errorf("fragment with %+v expect %d fragments but got %d", c, c.expectedFragments, len(b.MoofMdat))
+		}
+
+		// samples should keep the same except locations
+		for _, track := range progressive.Moov.Trak {
+			trackID := track.Tkhd.TrackID
+			expect, _ := progressive.Samples(trackID)
+			got, err := b.Samples(trackID)
+			if err != nil || len(got) != len(expect) {
+				t.Errorf("fragment with %+v track %d expect %d samples but got %d, err %v", c, trackID, len(expect), len(got), err)
+				continue
+			}
+
+			for i := range got {
+				e, g := expect[i], got[i]
+				if e.DecodeTime != g.DecodeTime || e.CompositionTime != g.CompositionTime || e.Duration != g.Duration || e.Size != g.Size || e.IsSync != g.IsSync {
+					t.Errorf("fragment with %+v track %d sample %d expect %+v but got %+v", c, trackID, i, e, g)
+					break
+				}
+				expectData, _ := progressive.ReadSample(e)
+				gotData, err := b.ReadSample(g)
+				if err != nil || !bytes.Equal(expectData, gotData) {
+					t.Errorf("fragment with %+v track %d sample %d data mismatch, err %v", c, trackID, i, err)
+					break
+				}
+			}
+		}
+
+		// fragments of the video track should start with sync sample
+		for i, mm := range b.MoofMdat {
+			for _, tf := range mm.Moof.Traf {
+				if tf.Tfhd.TrackID == 1 && tf.Trun[0].SampleFlags[0] != syncSampleFlags {
+					t.Errorf("fragment with %+v moof %d expect start with sync sample", c, i)
+				}
+			}
+		}
+	}
+}
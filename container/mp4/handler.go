@@ -26,13 +26,14 @@ func New(filePath string) *Handler {
 }
 
 // Parse parses mp4 file.
+// The file keeps opened after parsing since mdat payloads will be read lazily if the file is seekable,
+// call Close once it's no longer used.
 func (h *Handler) Parse() error {
 
 	if err := h.open(); err != nil {
 		glog.Warningf("open %s failed, err %v", h.filePath, err)
 		return err
 	}
-	defer h.close()
 
 	return h.Boxes.ParsePayload(h.f)
 }
@@ -54,10 +55,12 @@ func (h *Handler) open() error {
 }
 
 // Close closes the mp4 file handler.
-func (h *Handler) close() error {
-	if h == nil || h.f == nil || h.f != os.Stdin {
+func (h *Handler) Close() error {
+	if h == nil || h.f == nil || h.f == os.Stdin {
 		return nil
 	}
 
-	return h.f.Close()
+	err := h.f.Close()
+	h.f = nil
+	return err
 }
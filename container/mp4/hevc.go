@@ -0,0 +1,84 @@
+package mp4
+
+import (
+	"bytes"
+	"fmt"
+
+	hvcc "github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/hvcC"
+	"github.com/wangyoucao577/medialib/container/mp4/box/trak"
+	"github.com/wangyoucao577/medialib/video/hevc/annexbes"
+	"github.com/wangyoucao577/medialib/video/hevc/es"
+	"github.com/wangyoucao577/medialib/video/hevc/nalu"
+)
+
+// ExtractHEVCES extracts HEVC Elementary Stream from hvc1 or hev1 track, parameter sets in hvcC are not included.
+// Use trackID to select the specified one, trackID <= 0 means use the first found one.
+func (b *Boxes) ExtractHEVCES(trackID int) (*es.ElementaryStream, error) {
+	e, _, err := b.extractHEVCES(trackID)
+	return e, err
+}
+
+// ExtractHEVCAnnexBES extracts HEVC Elementary Stream with AnnexB byte format.
+// Parameter sets in hvcC(VPS/SPS/PPS/SEI arrays) come first since they might not be stored in samples, e.g., hvc1.
+// Use trackID to select the specified one, trackID <= 0 means use the first found one.
+func (b *Boxes) ExtractHEVCAnnexBES(trackID int) (*annexbes.ElementaryStream, error) {
+	mp4ES, config, err := b.extractHEVCES(trackID)
+	if err != nil {
+		return nil, err
+	}
+
+	annexbES := annexbes.ElementaryStream{}
+	if annexbES.NALU, err = hevcParameterSets(config); err != nil {
+		return nil, err
+	}
+	for i := range mp4ES.LengthNALU {
+		annexbES.NALU = append(annexbES.NALU, mp4ES.LengthNALU[i].NALU)
+	}
+
+	return &annexbES, nil
+}
+
+// extractHEVCES extracts HEVC Elementary Stream and returns its decoder configuration record in hvcC.
+func (b *Boxes) extractHEVCES(trackID int) (*es.ElementaryStream, *hvcc.HEVCDecoderConfigurationRecord, error) {
+	e := es.ElementaryStream{}
+	var config *hvcc.HEVCDecoderConfigurationRecord
+	err := b.extractLengthPrefixedES(trackID, &e, func(track *trak.Box) (uint32, error) {
+		var err error
+		if config, err = hevcConfig(track); err != nil {
+			return 0, err
+		}
+		return config.LengthSize(), nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &e, config, nil
+}
+
+// hevcConfig returns HEVC decoder configuration record in hvcC of the hvc1 or hev1 track.
+func hevcConfig(track *trak.Box) (*hvcc.HEVCDecoderConfigurationRecord, error) {
+	stsd := track.Mdia.Minf.Stbl.Stsd
+	sampleEntries := stsd.HVC1SampleEntries
+	if len(sampleEntries) == 0 {
+		sampleEntries = stsd.HEV1SampleEntries
+	}
+	if len(sampleEntries) == 0 || sampleEntries[0].HvccConfig == nil {
+		return nil, fmt.Errorf("trackID %d is not hevc", track.Tkhd.TrackID)
+	}
+	return &sampleEntries[0].HvccConfig.HEVCConfig, nil
+}
+
+// hevcParameterSets parses parameter sets in hvcC, i.e., VPS/SPS/PPS/SEI arrays.
+func hevcParameterSets(config *hvcc.HEVCDecoderConfigurationRecord) ([]nalu.NALUnit, error) {
+	var nalus []nalu.NALUnit
+	for _, array := range config.Arrays {
+		for _, ln := range array.LengthNALUs {
+			n := nalu.NALUnit{}
+			if _, err := n.Parse(bytes.NewReader(ln.NALUnit), len(ln.NALUnit)); err != nil {
+				return nil, fmt.Errorf("parse hvcC nalu type %d failed, err %v", array.NALUnitType, err)
+			}
+			nalus = append(nalus, n)
+		}
+	}
+	return nalus, nil
+}
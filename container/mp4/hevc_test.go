@@ -0,0 +1,105 @@
+package mp4
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/hev1"
+	hvcc "github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/hvcC"
+	avces "github.com/wangyoucao577/medialib/video/avc/es"
+	"github.com/wangyoucao577/medialib/video/hevc/annexbes"
+	"github.com/wangyoucao577/medialib/video/hevc/es"
+	"github.com/wangyoucao577/medialib/video/hevc/nalu"
+)
+
+func TestExtractHEVCES(t *testing.T) {
+	const trackID = 1
+	fmp4File := "../../assets/sintel_trailer-720p-firstgopfmp4.mp4"
+
+	h := New(fmp4File)
+	if err := h.Parse(); err != nil {
+		t.Fatalf("parse %s failed, err %v", fmp4File, err)
+	}
+	defer h.Close()
+
+	if _, err := h.ExtractHEVCES(trackID); err == nil {
+		t.Errorf("extract hevc es from avc track expect error but got nil")
+	}
+
+	expect, err := h.ExtractAVCES(trackID)
+	if err != nil {
+		t.Fatalf("extract es from %s failed, err %v", fmp4File, err)
+	}
+	if e, err := h.ExtractES(trackID); err != nil {
+		t.Errorf("extract es from avc track expect nil but got %v", err)
+	} else if _, ok := e.(*avces.ElementaryStream); !ok {
+		t.Errorf("extract es from avc track expect avc es but got %T", e)
+	}
+
+	// pretend the avc track is hevc since both store length prefixed nal units in samples
+	parameterSets := []hvcc.Array{
+		{NALUnitType: nalu.TypeVPS_NUT, NumNalus: 1, LengthNALUs: []hvcc.LengthNALU{{NALUnitLength: 3, NALUnit: []byte{0x40, 0x01, 0x0c}}}},
+		{NALUnitType: nalu.TypeSPS_NUT, NumNalus: 1, LengthNALUs: []hvcc.LengthNALU{{NALUnitLength: 3, NALUnit: []byte{0x42, 0x01, 0x01}}}},
+		{NALUnitType: nalu.TypePPS_NUT, NumNalus: 1, LengthNALUs: []hvcc.LengthNALU{{NALUnitLength: 3, NALUnit: []byte{0x44, 0x01, 0xc1}}}},
+	}
+	stsd := h.Moov.Trak[0].Mdia.Minf.Stbl.Stsd
+	lengthSize := stsd.AVC1SampleEntries[0].AVCConfig.AVCConfig.LengthSize()
+	stsd.AVC1SampleEntries = nil
+	stsd.HVC1SampleEntries = []hev1.HEVCSampleEntry{{
+		HvccConfig: &hvcc.HEVCConfigrationBox{
+			HEVCConfig: hvcc.HEVCDecoderConfigurationRecord{
+				LengthSizeMinusOne: uint8(lengthSize - 1),
+				NumOfArrays:        uint8(len(parameterSets)),
+				Arrays:             parameterSets,
+			},
+		},
+	}}
+
+	if _, err := h.ExtractAVCES(trackID); err == nil {
+		t.Errorf("extract avc es from hevc track expect error but got nil")
+	}
+	if e, err := h.ExtractES(trackID); err != nil {
+		t.Errorf("extract es from hevc track expect nil but got %v", err)
+	} else if _, ok := e.(*es.ElementaryStream); !ok {
+		t.Errorf("extract es from hevc track expect hevc es but got %T", e)
+	}
+	if e, err := h.ExtractAnnexBES(trackID); err != nil {
+		t.Errorf("extract annexb es from hevc track expect nil but got %v", err)
+	} else if _, ok := e.(*annexbes.ElementaryStream); !ok {
+		t.Errorf("extract annexb es from hevc track expect hevc annexb es but got %T", e)
+	}
+
+	got, err := h.ExtractHEVCES(0)
+	if err != nil {
+		t.Fatalf("extract hevc es expect nil but got %v", err)
+	}
+	if len(got.LengthNALU) != len(expect.LengthNALU) {
+		t.Fatalf("extract hevc es expect %d nalus but got %d", len(expect.LengthNALU), len(got.LengthNALU))
+	}
+	for i := range got.LengthNALU {
+		if !bytes.Equal(got.LengthNALU[i].NALU.Raw(), expect.LengthNALU[i].NALU.Raw()) {
+			t.Fatalf("extract hevc es nalu %d mismatch", i)
+		}
+	}
+
+	annexb, err := h.ExtractHEVCAnnexBES(0)
+	if err != nil {
+		t.Fatalf("extract hevc annexb es expect nil but got %v", err)
+	}
+	if len(annexb.NALU) != len(parameterSets)+len(expect.LengthNALU) {
+		t.Fatalf("extract hevc annexb es expect %d nalus but got %d", len(parameterSets)+len(expect.LengthNALU), len(annexb.NALU))
+	}
+	for i, array := range parameterSets {
+		if annexb.NALU[i].NALUnitType != array.NALUnitType || !bytes.Equal(annexb.NALU[i].Raw(), array.LengthNALUs[0].NALUnit) {
+			t.Errorf("extract hevc annexb es expect parameter set nalu type %d at %d but got %d", array.NALUnitType, i, annexb.NALU[i].NALUnitType)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := annexb.Dump(&buf); err != nil {
+		t.Fatalf("dump hevc annexb es expect nil but got %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte{0x00, 0x00, 0x00, 0x01, 0x40, 0x01, 0x0c, 0x00, 0x00, 0x00, 0x01, 0x42}) {
+		t.Errorf("dump hevc annexb es expect vps and sps at the beginning but got % x", buf.Bytes()[:12])
+	}
+}
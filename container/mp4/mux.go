@@ -0,0 +1,460 @@
+package mp4
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/wangyoucao577/medialib/audio/aac"
+	"github.com/wangyoucao577/medialib/container/mp4/box"
+	"github.com/wangyoucao577/medialib/container/mp4/box/ctts"
+	"github.com/wangyoucao577/medialib/container/mp4/box/dinf"
+	"github.com/wangyoucao577/medialib/container/mp4/box/dref"
+	"github.com/wangyoucao577/medialib/container/mp4/box/edts"
+	"github.com/wangyoucao577/medialib/container/mp4/box/elst"
+	"github.com/wangyoucao577/medialib/container/mp4/box/ftyp"
+	"github.com/wangyoucao577/medialib/container/mp4/box/hdlr"
+	"github.com/wangyoucao577/medialib/container/mp4/box/mdat"
+	"github.com/wangyoucao577/medialib/container/mp4/box/mdhd"
+	"github.com/wangyoucao577/medialib/container/mp4/box/mdia"
+	"github.com/wangyoucao577/medialib/container/mp4/box/minf"
+	"github.com/wangyoucao577/medialib/container/mp4/box/moov"
+	"github.com/wangyoucao577/medialib/container/mp4/box/mvhd"
+	"github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/avc1"
+	avcc "github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/avcC"
+	"github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/esds"
+	"github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/hev1"
+	"github.com/wangyoucao577/medialib/container/mp4/box/sampleentry/mp4a"
+	"github.com/wangyoucao577/medialib/container/mp4/box/smhd"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stbl"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stsc"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stsd"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stss"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stsz"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stts"
+	"github.com/wangyoucao577/medialib/container/mp4/box/tkhd"
+	"github.com/wangyoucao577/medialib/container/mp4/box/trak"
+	"github.com/wangyoucao577/medialib/container/mp4/box/url"
+	"github.com/wangyoucao577/medialib/container/mp4/box/vmhd"
+)
+
+const movieTimescale = 1000 // timescale of mvhd
+
+// unityMatrix is the default transformation matrix of mvhd and tkhd.
+var unityMatrix = [9]int32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000}
+
+// MuxSample represents a sample with its data to be muxed.
+// Offset and Size of the Sample will be decided by the data.
+type MuxSample struct {
+	Sample
+	Data []byte
+
+	// Data will be read lazily from Source at SourceOffset if Data is nil, Size of the Sample is required then.
+	// It avoids keeping all samples in memory, but the Source should keep available until boxes have been encoded.
+	Source       io.ReaderAt
+	SourceOffset int64
+}
+
+// size returns size of the sample data.
+func (s *MuxSample) size() uint32 {
+	if s.Data == nil && s.Source != nil {
+		return s.Size
+	}
+	return uint32(len(s.Data))
+}
+
+// MuxTrack represents a track to be muxed.
+// Decode time of the first sample larger than 0 means the track starts later, which will be presented by an empty edit.
+type MuxTrack struct {
+	HandlerType string  // vide or soun
+	Timescale   uint32  // timescale of samples
+	Width       uint32  // video only
+	Height      uint32  // video only
+	SampleEntry box.Box // e.g., created by NewAVCSampleEntry or NewAACSampleEntry
+
+	Samples []MuxSample // in decoding order
+}
+
+// NewAVCSampleEntry creates avc1 sample entry by AVCDecoderConfigurationRecord, width and height come from the SPS.
+func NewAVCSampleEntry(avcConfig *avcc.AVCDecoderConfigurationRecord) (*avc1.AVCSampleEntry, error) {
+	if avcConfig == nil || len(avcConfig.LengthSPSNALU) == 0 || avcConfig.LengthSPSNALU[0].NALUnit.SequenceParameterSetData == nil {
+		return nil, fmt.Errorf("sps not found in avc config")
+	}
+	width, height := avcConfig.LengthSPSNALU[0].NALUnit.SequenceParameterSetData.Resolution()
+
+	entry := avc1.New(box.NewHeader(box.TypeAvc1)).(*avc1.AVCSampleEntry)
+	entry.DataReferenceIndex = 1
+	entry.Width = uint16(width)
+	entry.Height = uint16(height)
+	entry.Horizresolution = 0x00480000 // 72 dpi
+	entry.Vertresolution = 0x00480000  // 72 dpi
+	entry.FrameCount = 1
+	entry.Depth = 0x0018
+
+	entry.AVCConfig = avcc.New(box.NewHeader(box.TypeAvcC)).(*avcc.AVCConfigrationBox)
+	entry.AVCConfig.AVCConfig = *avcConfig
+	return entry, nil
+}
+
+// NewAACSampleEntry creates mp4a sample entry with esds by AudioSpecificConfig bytes.
+func NewAACSampleEntry(audioSpecificConfig []byte) (*mp4a.MP4VisualSampleEntry, error) {
+	config := aac.AudioSpecificConfig{}
+	if _, err := config.Parse(bytes.NewReader(audioSpecificConfig), len(audioSpecificConfig)); err != nil {
+		return nil, fmt.Errorf("parse AudioSpecificConfig failed, err %v", err)
+	}
+
+	entry := mp4a.New(box.NewHeader(box.TypeMp4a)).(*mp4a.MP4VisualSampleEntry)
+	entry.DataReferenceIndex = 1
+	entry.ChannelCount = uint16(config.ChannelConfiguration)
+	entry.SampleSize = 16
+	entry.SampleRate = config.Frequency()
+
+	entry.Esds = esds.New(box.NewHeader(box.TypeEsds)).(*esds.Box)
+	entry.Esds.ESDescriptor = esds.ESDescriptor{
+		Descriptor: esds.Descriptor{Tag: esds.ClassTagES_DescrTag},
+		DecoderConfigDescriptor: esds.DecoderConfigDescriptor{
+			Descriptor:           esds.Descriptor{Tag: esds.ClassTagDecoderConfigDescrTag},
+			ObjectTypeIndication: esds.ObjectTypeIndicationAudioMPEG4,
+			StreamType:           0x05, // AudioStream
+			DecoderSpecificInfo: esds.DecoderSpecificInfo{
+				Descriptor:          esds.Descriptor{Tag: esds.ClassTagDecSpecificInfoTag},
+				Data:                audioSpecificConfig,
+				AudioSpecificConfig: &config,
+			},
+		},
+		SLConfigDescriptor: &esds.SLConfigDescriptor{
+			Descriptor: esds.Descriptor{Tag: esds.ClassTagSLConfigDescrTag},
+			Predefined: 0x02, // reserved for use in MP4 files
+		},
+	}
+	return entry, nil
+}
+
+// Mux creates boxes of a progressive mp4 with moov before mdat by tracks, track IDs start from 1 in sequence.
+// Samples of all tracks are interleaved by decode time in mdat, which is kept in memory unless samples come from Source.
+func Mux(tracks []MuxTrack) (*Boxes, error) {
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("no track to mux")
+	}
+
+	b := newBoxes()
+	b.Ftyp = ftyp.New(box.NewHeader(box.TypeFtyp)).(*ftyp.Box)
+	b.Ftyp.MajorBrand = box.FixedArray4Bytes{'i', 's', 'o', 'm'}
+	b.Ftyp.MinorVersion = 512
+	b.Ftyp.CompatibleBrands = []box.FixedArray4Bytes{{'i', 's', 'o', 'm'}, {'i', 's', 'o', '2'}, {'a', 'v', 'c', '1'}, {'m', 'p', '4', '1'}}
+
+	b.Moov = moov.New(box.NewHeader(box.TypeMoov)).(*moov.Box)
+	b.Moov.Mvhd = mvhd.New(box.NewHeader(box.TypeMvhd)).(*mvhd.Box)
+	b.Moov.Mvhd.Timescale = movieTimescale
+	b.Moov.Mvhd.Rate = 0x00010000 // 1.0
+	b.Moov.Mvhd.Volume = 0x0100   // 1.0
+	b.Moov.Mvhd.Matrix = unityMatrix
+	b.Moov.Mvhd.NextTrackID = uint32(len(tracks) + 1)
+
+	for i := range tracks {
+		t, err := newTrak(uint32(i+1), &tracks[i])
+		if err != nil {
+			return nil, err
+		}
+		if t.Tkhd.Duration > b.Moov.Mvhd.Duration {
+			b.Moov.Mvhd.Duration = t.Tkhd.Duration
+		}
+		b.Moov.Trak = append(b.Moov.Trak, *t)
+	}
+	if b.Moov.Mvhd.Duration > math.MaxUint32 {
+		b.Moov.Mvhd.Version = 1
+	}
+
+	// interleave samples by decode time, consecutive samples of the same track are stored in one chunk
+	var payload []byte
+	var lazyPayload *samplesReader // used once any sample comes from Source
+	var payloadSize uint64
+	chunkOffsets := make([][]uint64, len(tracks))
+	chunkSamples := make([][]uint32, len(tracks))
+	next := make([]int, len(tracks))
+	last := -1
+	for {
+		current := -1
+		for i := range tracks {
+			if next[i] >= len(tracks[i].Samples) {
+				continue
+			}
+			if current < 0 || tracks[i].Samples[next[i]].DecodeTime*uint64(tracks[current].Timescale) <
+				tracks[current].Samples[next[current]].DecodeTime*uint64(tracks[i].Timescale) {
+				current = i
+			}
+		}
+		if current < 0 {
+			break
+		}
+
+		if current != last {
+			chunkOffsets[current] = append(chunkOffsets[current], payloadSize)
+			chunkSamples[current] = append(chunkSamples[current], 0)
+			last = current
+		}
+		chunkSamples[current][len(chunkSamples[current])-1]++
+
+		s := &tracks[current].Samples[next[current]]
+		if s.Data == nil && s.Source != nil && lazyPayload == nil {
+			lazyPayload = &samplesReader{}
+			if len(payload) > 0 { // samples before are in memory
+				lazyPayload.append(0, &MuxSample{Data: payload})
+			}
+		}
+		if lazyPayload != nil {
+			lazyPayload.append(payloadSize, s)
+		} else {
+			payload = append(payload, s.Data...)
+		}
+		payloadSize += uint64(s.size())
+		next[current]++
+	}
+
+	for i := range b.Moov.Trak {
+		st := b.Moov.Trak[i].Mdia.Minf.Stbl
+		for j, n := range chunkSamples[i] {
+			if k := len(st.Stsc.Entries); k > 0 && st.Stsc.Entries[k-1].SamplesPerChunk == n {
+				continue
+			}
+			st.Stsc.Entries = append(st.Stsc.Entries, stsc.ChunkEntry{FirstChunk: uint32(j + 1), SamplesPerChunk: n, SampleDescriptionIndex: 1})
+		}
+		st.Stsc.EntryCount = uint32(len(st.Stsc.Entries))
+		st.SetChunkOffsets(chunkOffsets[i]) // relative to the mdat payload before relocation
+	}
+
+	m := mdat.New(box.NewHeader(box.TypeMdat)).(*mdat.Box)
+	if lazyPayload != nil {
+		m.SetPayload(lazyPayload, payloadSize)
+	} else {
+		m.Data = payload
+		m.Length = uint64(len(payload))
+	}
+	b.Mdat = []mdat.Box{*m}
+
+	// relocate chunk offsets as moov before mdat, then refresh mdat offset to keep samples readable
+	if err := b.FastStart(); err != nil {
+		return nil, err
+	}
+	b.Mdat[0].Offset = b.encodedMdatOffsets()[0]
+
+	return &b, nil
+}
+
+// newTrak creates trak by the track, chunk related boxes(stsc, stco) are left empty.
+func newTrak(trackID uint32, t *MuxTrack) (*trak.Box, error) {
+	if t.Timescale == 0 {
+		return nil, fmt.Errorf("track %d invalid timescale 0", trackID)
+	}
+
+	var duration uint64
+	for _, s := range t.Samples {
+		duration += uint64(s.Duration)
+	}
+	movieDuration := duration * movieTimescale / uint64(t.Timescale)
+
+	tr := trak.New(box.NewHeader(box.TypeTrak)).(*trak.Box)
+	tr.Tkhd = tkhd.New(box.NewHeader(box.TypeTkhd)).(*tkhd.Box)
+	tr.Tkhd.Flags = 0x000003 // track_enabled and track_in_movie
+	tr.Tkhd.TrackID = trackID
+	tr.Tkhd.Matrix = unityMatrix
+
+	// the track starts later than others, delay presentation by an empty edit
+	if len(t.Samples) > 0 && t.Samples[0].DecodeTime > 0 {
+		delay := t.Samples[0].DecodeTime * movieTimescale / uint64(t.Timescale)
+		el := elst.New(box.NewHeader(box.TypeElst)).(*elst.Box)
+		el.EntryCount = 2
+		el.SegmentDuration = []uint64{delay, movieDuration}
+		el.MediaTime = []int64{-1, 0} // empty edit, then the whole media
+		el.MediaRateInteger = []int16{1, 1}
+		el.MediaRateFraction = []int16{0, 0}
+		if delay+movieDuration > math.MaxUint32 {
+			el.Version = 1
+		}
+		ed := edts.New(box.NewHeader(box.TypeEdts)).(*edts.Box)
+		ed.Elst = el
+		tr.Edts = []edts.Box{*ed}
+		movieDuration += delay
+	}
+	tr.Tkhd.Duration = movieDuration
+	if movieDuration > math.MaxUint32 {
+		tr.Tkhd.Version = 1
+	}
+
+	tr.Mdia = mdia.New(box.NewHeader(box.TypeMdia)).(*mdia.Box)
+	tr.Mdia.Mdhd = mdhd.New(box.NewHeader(box.TypeMdhd)).(*mdhd.Box)
+	tr.Mdia.Mdhd.Timescale = t.Timescale
+	tr.Mdia.Mdhd.Duration = duration
+	if duration > math.MaxUint32 {
+		tr.Mdia.Mdhd.Version = 1
+	}
+	tr.Mdia.Mdhd.Language = [3]byte{'u', 'n', 'd'}
+
+	tr.Mdia.Hdlr = hdlr.New(box.NewHeader(box.TypeHdlr)).(*hdlr.Box)
+	copy(tr.Mdia.Hdlr.HandlerType[:], t.HandlerType)
+
+	tr.Mdia.Minf = minf.New(box.NewHeader(box.TypeMinf)).(*minf.Box)
+	switch t.HandlerType {
+	case box.TypeVide:
+		tr.Tkhd.Width = float32(t.Width)
+		tr.Tkhd.Height = float32(t.Height)
+		tr.Mdia.Hdlr.Name = "VideoHandler"
+		tr.Mdia.Minf.Vmhd = vmhd.New(box.NewHeader(box.TypeVmhd)).(*vmhd.Box)
+		tr.Mdia.Minf.Vmhd.Flags = 0x000001
+	case box.TypeSoun:
+		tr.Tkhd.Volume = 0x0100 // 1.0
+		tr.Mdia.Hdlr.Name = "SoundHandler"
+		tr.Mdia.Minf.Smhd = smhd.New(box.NewHeader(box.TypeSmhd)).(*smhd.Box)
+	default:
+		return nil, fmt.Errorf("track %d handler type %s doesn't support yet", trackID, t.HandlerType)
+	}
+
+	u := url.New(box.NewHeader(box.TypeUrl)).(*url.Box)
+	u.Flags = 0x000001 // media data is in the same file
+	tr.Mdia.Minf.Dinf = dinf.New(box.NewHeader(box.TypeDinf)).(*dinf.Box)
+	tr.Mdia.Minf.Dinf.Dref = dref.New(box.NewHeader(box.TypeDref)).(*dref.Box)
+	tr.Mdia.Minf.Dinf.Dref.EntryCount = 1
+	tr.Mdia.Minf.Dinf.Dref.UrlEntries = []url.Box{*u}
+
+	st, err := newStbl(trackID, t)
+	if err != nil {
+		return nil, err
+	}
+	tr.Mdia.Minf.Stbl = st
+
+	return tr, nil
+}
+
+// newStbl creates stbl by samples of the track, chunk related boxes(stsc, stco) are left empty.
+func newStbl(trackID uint32, t *MuxTrack) (*stbl.Box, error) {
+	st := stbl.New(box.NewHeader(box.TypeStbl)).(*stbl.Box)
+
+	st.Stsd = stsd.New(box.NewHeader(box.TypeStsd)).(*stsd.Box)
+	st.Stsd.EntryCount = 1
+	switch e := t.SampleEntry.(type) {
+	case *avc1.AVCSampleEntry:
+		st.Stsd.AVC1SampleEntries = []avc1.AVCSampleEntry{*e}
+	case *hev1.HEVCSampleEntry:
+		if e.Type.String() == box.TypeHev1 {
+			st.Stsd.HEV1SampleEntries = []hev1.HEVCSampleEntry{*e}
+		} else {
+			st.Stsd.HVC1SampleEntries = []hev1.HEVCSampleEntry{*e}
+		}
+	case *mp4a.MP4VisualSampleEntry:
+		st.Stsd.MP4VisualSampleEntries = []mp4a.MP4VisualSampleEntry{*e}
+	default:
+		return nil, fmt.Errorf("track %d sample entry %T doesn't support yet", trackID, t.SampleEntry)
+	}
+
+	st.Stts = stts.New(box.NewHeader(box.TypeStts)).(*stts.Box)
+	st.Stsc = stsc.New(box.NewHeader(box.TypeStsc)).(*stsc.Box)
+	st.Stsz = stsz.New(box.NewHeader(box.TypeStsz)).(*stsz.Box)
+	st.Stsz.SampleCount = uint32(len(t.Samples))
+
+	var hasCompositionOffset, hasNonSync bool
+	for _, s := range t.Samples {
+		hasCompositionOffset = hasCompositionOffset || s.CompositionTime != int64(s.DecodeTime)
+		hasNonSync = hasNonSync || !s.IsSync
+	}
+	if hasCompositionOffset {
+		st.Ctts = ctts.New(box.NewHeader(box.TypeCtts)).(*ctts.Box)
+	}
+	if hasNonSync {
+		st.Stss = stss.New(box.NewHeader(box.TypeStss)).(*stss.Box)
+	}
+
+	for i := range t.Samples {
+		s := &t.Samples[i]
+		st.Stsz.EntrySizes = append(st.Stsz.EntrySizes, s.size())
+
+		if n := len(st.Stts.SampleDeltas); n > 0 && st.Stts.SampleDeltas[n-1] == s.Duration {
+			st.Stts.SampleCounts[n-1]++
+		} else {
+			st.Stts.SampleCounts = append(st.Stts.SampleCounts, 1)
+			st.Stts.SampleDeltas = append(st.Stts.SampleDeltas, s.Duration)
+		}
+
+		if st.Ctts != nil {
+			offset := s.CompositionTime - int64(s.DecodeTime)
+			if offset < 0 {
+				st.Ctts.Version = 1 // signed composition time offsets
+			}
+			if n := len(st.Ctts.SampleOffsets); n > 0 && st.Ctts.SampleOffsets[n-1] == offset {
+				st.Ctts.SampleCounts[n-1]++
+			} else {
+				st.Ctts.SampleCounts = append(st.Ctts.SampleCounts, 1)
+				st.Ctts.SampleOffsets = append(st.Ctts.SampleOffsets, offset)
+			}
+		}
+
+		if st.Stss != nil && s.IsSync {
+			st.Stss.SampleNumbers = append(st.Stss.SampleNumbers, uint32(i+1))
+		}
+	}
+	st.Stts.EntryCount = uint32(len(st.Stts.SampleCounts))
+	if st.Ctts != nil {
+		st.Ctts.EntryCount = uint32(len(st.Ctts.SampleCounts))
+	}
+	if st.Stss != nil {
+		st.Stss.EntryCount = uint32(len(st.Stss.SampleNumbers))
+	}
+
+	return st, nil
+}
+
+// samplesReader reads payload of mdat from samples lazily, each sample is stored in memory or comes from Source.
+type samplesReader struct {
+	offsets []uint64 // offsets of samples in payload, ascending
+	samples []*MuxSample
+}
+
+func (r *samplesReader) append(offset uint64, s *MuxSample) {
+	r.offsets = append(r.offsets, offset)
+	r.samples = append(r.samples, s)
+}
+
+// ReadAt implements io.ReaderAt, off is relative to the beginning of the payload.
+func (r *samplesReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("invalid offset %d", off)
+	}
+
+	// the last sample starts at or before off
+	i := sort.Search(len(r.offsets), func(i int) bool { return r.offsets[i] > uint64(off) }) - 1
+	if i < 0 {
+		return 0, io.EOF
+	}
+
+	var n int
+	for ; i < len(r.samples) && n < len(p); i++ {
+		s := r.samples[i]
+		pos := uint64(off) + uint64(n) - r.offsets[i] // position in the sample
+		size := uint64(s.size())
+		if pos >= size {
+			continue // empty sample
+		}
+		count := len(p) - n
+		if uint64(count) > size-pos {
+			count = int(size - pos)
+		}
+
+		if s.Data == nil && s.Source != nil {
+			if m, err := s.Source.ReadAt(p[n:n+count], s.SourceOffset+int64(pos)); m < count {
+				if err == nil {
+					err = io.ErrUnexpectedEOF
+				}
+				return n + m, err
+			}
+		} else {
+			copy(p[n:n+count], s.Data[pos:])
+		}
+		n += count
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
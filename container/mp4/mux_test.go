@@ -0,0 +1,77 @@
+package mp4
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/wangyoucao577/medialib/container/mp4/box"
+)
+
+func TestMuxSource(t *testing.T) {
+	mp4File := "../../assets/sintel_trailer-720p-firstgopfmp4.mp4"
+
+	h := New(mp4File)
+	if err := h.Parse(); err != nil {
+		t.Fatalf("parse %s failed, err %v", mp4File, err)
+	}
+	defer h.Close()
+	source, err := os.Open(mp4File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	var inMemory, fromSource []MuxTrack
+	for _, track := range h.Tracks() {
+		samples, err := h.Samples(track.ID)
+		if err != nil {
+			t.Fatalf("get samples of track %d failed, err %v", track.ID, err)
+		}
+
+		stsd := h.Moov.Trak[track.Index].Mdia.Minf.Stbl.Stsd
+		var entry box.Box
+		switch track.Codec {
+		case box.TypeAvc1:
+			entry = &stsd.AVC1SampleEntries[0]
+		case box.TypeMp4a:
+			entry = &stsd.MP4VisualSampleEntries[0]
+		default:
+			t.Fatalf("unexpected codec %s of track %d", track.Codec, track.ID)
+		}
+		m := MuxTrack{HandlerType: track.HandlerType, Timescale: h.Moov.Trak[track.Index].Mdia.Mdhd.Timescale, SampleEntry: entry}
+		s := m
+		for i, sample := range samples {
+			data, err := h.ReadSample(sample)
+			if err != nil {
+				t.Fatalf("read sample %d of track %d failed, err %v", i, track.ID, err)
+			}
+			m.Samples = append(m.Samples, MuxSample{Sample: sample, Data: data})
+
+			// mix in memory samples in
+			if i%3 == 0 {
+				s.Samples = append(s.Samples, MuxSample{Sample: sample, Data: data})
+			} else {
+				s.Samples = append(s.Samples, MuxSample{Sample: sample, Source: source, SourceOffset: int64(sample.Offset)})
+			}
+		}
+		inMemory, fromSource = append(inMemory, m), append(fromSource, s)
+	}
+
+	var expect, got bytes.Buffer
+	for _, c := range []struct {
+		tracks []MuxTrack
+		buf    *bytes.Buffer
+	}{{inMemory, &expect}, {fromSource, &got}} {
+		b, err := Mux(c.tracks)
+		if err != nil {
+			t.Fatalf("mux expect nil but got %v", err)
+		}
+		if err := b.Encode(c.buf); err != nil {
+			t.Fatalf("encode expect nil but got %v", err)
+		}
+	}
+	if !bytes.Equal(got.Bytes(), expect.Bytes()) {
+		t.Errorf("mux from source expect %d bytes same as in memory but got %d bytes", expect.Len(), got.Len())
+	}
+}
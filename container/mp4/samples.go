@@ -0,0 +1,240 @@
+package mp4
+
+import (
+	"fmt"
+
+	"github.com/wangyoucao577/medialib/container/mp4/box/stbl"
+	"github.com/wangyoucao577/medialib/container/mp4/box/traf"
+	"github.com/wangyoucao577/medialib/container/mp4/box/trak"
+)
+
+// Sample represents a media sample of a track, timestamps are in track timescale(mdhd).
+// Edit lists are not applied.
+type Sample struct {
+	DecodeTime      uint64 `json:"decode_time"`      // DTS
+	CompositionTime int64  `json:"composition_time"` // PTS, i.e., DTS + composition time offset
+	Duration        uint32 `json:"duration"`
+	Offset          uint64 `json:"offset"` // offset from the beginning of the input
+	Size            uint32 `json:"size"`
+	IsSync          bool   `json:"is_sync"` // sync sample, i.e., keyframe for video
+}
+
+// sampleDefaults represents default values of samples in fragments, which come from trex and might be overridden by tfhd.
+type sampleDefaults struct {
+	duration uint32
+	size     uint32
+	flags    uint32
+}
+
+// Samples returns all samples of the track in decoding order,
+// samples in moov(stbl) come first and then samples in fragments(moof) if exist.
+func (b *Boxes) Samples(trackID uint32) ([]Sample, error) {
+	if b.Moov == nil {
+		return nil, fmt.Errorf("moov not found")
+	}
+
+	var track *trak.Box
+	for i := range b.Moov.Trak {
+		if b.Moov.Trak[i].Tkhd != nil && b.Moov.Trak[i].Tkhd.TrackID == trackID {
+			track = &b.Moov.Trak[i]
+			break
+		}
+	}
+	if track == nil {
+		return nil, fmt.Errorf("trackID %d not found", trackID)
+	}
+
+	var samples []Sample
+	if track.Mdia != nil && track.Mdia.Minf != nil && track.Mdia.Minf.Stbl != nil {
+		var err error
+		if samples, err = progressiveSamples(track.Mdia.Minf.Stbl); err != nil {
+			return nil, err
+		}
+	}
+
+	var decodeTime uint64 // continue from samples in moov if no tfdt
+	if len(samples) > 0 {
+		last := samples[len(samples)-1]
+		decodeTime = last.DecodeTime + uint64(last.Duration)
+	}
+
+	defaults := map[uint32]sampleDefaults{}
+	if b.Moov.Mvex != nil {
+		for _, t := range b.Moov.Mvex.Trex {
+			defaults[t.TrackID] = sampleDefaults{t.DefaultSampleDuration, t.DefaultSampleSize, t.DefaultSampleFlags}
+		}
+	}
+
+	for i := range b.MoofMdat {
+		// resolve all track fragments since data offset may rely on the previous one
+		var dataEnd uint64
+		for j := range b.MoofMdat[i].Moof.Traf {
+			tf := &b.MoofMdat[i].Moof.Traf[j]
+			if tf.Tfhd == nil {
+				return nil, fmt.Errorf("tfhd not found in traf %d of moof %d", j, i)
+			}
+
+			baseDataOffset := dataEnd // the end of the data of the preceding track fragment
+			if j == 0 {
+				baseDataOffset = b.MoofMdat[i].moofOffset
+			}
+
+			fragmentSamples, err := fragmentSamples(tf, defaults[tf.Tfhd.TrackID], b.MoofMdat[i].moofOffset, baseDataOffset, decodeTime)
+			if err != nil {
+				return nil, err
+			}
+			if len(fragmentSamples) > 0 {
+				last := fragmentSamples[len(fragmentSamples)-1]
+				dataEnd = last.Offset + uint64(last.Size)
+			} else {
+				dataEnd = baseDataOffset
+			}
+
+			if tf.Tfhd.TrackID != trackID {
+				continue
+			}
+			if len(fragmentSamples) > 0 {
+				last := fragmentSamples[len(fragmentSamples)-1]
+				decodeTime = last.DecodeTime + uint64(last.Duration)
+			}
+			samples = append(samples, fragmentSamples...)
+		}
+	}
+
+	return samples, nil
+}
+
+// ReadSample reads sample data from mdat.
+func (b *Boxes) ReadSample(s Sample) ([]byte, error) {
+	data := make([]byte, s.Size)
+	if err := b.readMdatAt(data, s.Offset); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// progressiveSamples resolves samples from sample table, i.e., stsz, stsc, stco/co64, stts, ctts and stss.
+func progressiveSamples(st *stbl.Box) ([]Sample, error) {
+	if st.Stsz == nil || st.Stsz.SampleCount == 0 {
+		return nil, nil // no sample, e.g., fragmented mp4
+	}
+
+	locations, err := st.SampleLocations()
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]Sample, len(locations))
+	for i, l := range locations {
+		samples[i].Offset = l.Offset
+		samples[i].Size = l.Size
+		samples[i].IsSync = st.Stss == nil // all samples are sync samples if no stss
+	}
+
+	if st.Stts == nil {
+		return nil, fmt.Errorf("stts not found")
+	}
+	var n int
+	var decodeTime uint64
+	for i := 0; i < len(st.Stts.SampleCounts) && i < len(st.Stts.SampleDeltas); i++ {
+		for j := 0; j < int(st.Stts.SampleCounts[i]) && n < len(samples); j++ {
+			samples[n].DecodeTime = decodeTime
+			samples[n].CompositionTime = int64(decodeTime)
+			samples[n].Duration = st.Stts.SampleDeltas[i]
+			decodeTime += uint64(st.Stts.SampleDeltas[i])
+			n++
+		}
+	}
+	if n != len(samples) {
+		return nil, fmt.Errorf("stts sample count %d != stsz sample count %d", n, len(samples))
+	}
+
+	if st.Ctts != nil {
+		n = 0
+		for i := 0; i < len(st.Ctts.SampleCounts) && i < len(st.Ctts.SampleOffsets); i++ {
+			for j := 0; j < int(st.Ctts.SampleCounts[i]) && n < len(samples); j++ {
+				samples[n].CompositionTime += st.Ctts.SampleOffsets[i]
+				n++
+			}
+		}
+	}
+
+	if st.Stss != nil {
+		for _, number := range st.Stss.SampleNumbers {
+			if number == 0 || int(number) > len(samples) {
+				return nil, fmt.Errorf("stss invalid sample number %d, sample count %d", number, len(samples))
+			}
+			samples[number-1].IsSync = true
+		}
+	}
+
+	return samples, nil
+}
+
+// fragmentSamples resolves samples of a traf.
+// The moofOffset is the first byte of the enclosing moof, and baseDataOffset is the implicit base data offset
+// if neither base-data-offset-present nor default-base-is-moof is set, see ISO/IEC 14496-12 8.8.7 for details.
+func fragmentSamples(tf *traf.Box, defaults sampleDefaults, moofOffset, baseDataOffset, decodeTime uint64) ([]Sample, error) {
+	if tf.Tfhd.Flags&0x000001 > 0 { // base‐data‐offset‐present
+		baseDataOffset = tf.Tfhd.BaseDataOffset
+	} else if tf.Tfhd.Flags&0x020000 > 0 { // default‐base‐is‐moof
+		baseDataOffset = moofOffset
+	}
+	if tf.Tfhd.Flags&0x000008 > 0 { // default‐sample‐duration‐present
+		defaults.duration = tf.Tfhd.DefaultSampleDuration
+	}
+	if tf.Tfhd.Flags&0x000010 > 0 { // default‐sample‐size‐present
+		defaults.size = tf.Tfhd.DefaultSampleSize
+	}
+	if tf.Tfhd.Flags&0x000020 > 0 { // default‐sample‐flags‐present
+		defaults.flags = tf.Tfhd.DefaultSampleFlags
+	}
+
+	if tf.Tfdt != nil {
+		decodeTime = tf.Tfdt.BaseMediaDecodeTime
+	}
+
+	offset := baseDataOffset // data starts at base data offset if it's the first run without data offset
+	var samples []Sample
+	for _, tr := range tf.Trun {
+		if tr.Flags&0x1 > 0 { // data‐offset‐present, relative to the base data offset
+			o := int64(baseDataOffset) + int64(tr.DataOffset)
+			if o < 0 {
+				return nil, fmt.Errorf("track %d invalid data offset %d with base data offset %d", tf.Tfhd.TrackID, tr.DataOffset, baseDataOffset)
+			}
+			offset = uint64(o)
+		} // otherwise data starts immediately after the previous run
+
+		for i := 0; i < int(tr.SampleCount); i++ {
+			s := Sample{
+				DecodeTime: decodeTime,
+				Offset:     offset,
+				Duration:   defaults.duration,
+				Size:       defaults.size,
+			}
+
+			flags := defaults.flags
+			if tr.Flags&0x100 > 0 { // sample‐duration‐present
+				s.Duration = tr.SampleDuration[i]
+			}
+			if tr.Flags&0x200 > 0 { // sample‐size‐present
+				s.Size = tr.SampleSize[i]
+			}
+			if tr.Flags&0x400 > 0 { // sample‐flags‐present
+				flags = tr.SampleFlags[i]
+			} else if i == 0 && tr.Flags&0x4 > 0 { // first‐sample‐flags‐present
+				flags = tr.FirstSampleFlags
+			}
+			s.CompositionTime = int64(s.DecodeTime)
+			if tr.Flags&0x800 > 0 { // sample‐composition‐time‐offsets‐present
+				s.CompositionTime += tr.SampleCompositionTimeOffset[i]
+			}
+			s.IsSync = (flags>>16)&0x1 == 0 // sample_is_non_sync_sample
+
+			decodeTime += uint64(s.Duration)
+			offset += uint64(s.Size)
+			samples = append(samples, s)
+		}
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,206 @@
+package mp4
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/wangyoucao577/medialib/container/mp4/box"
+	"github.com/wangyoucao577/medialib/container/mp4/box/ctts"
+	"github.com/wangyoucao577/medialib/container/mp4/box/mdat"
+	"github.com/wangyoucao577/medialib/container/mp4/box/moof"
+	"github.com/wangyoucao577/medialib/container/mp4/box/moov"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stbl"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stco"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stsc"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stss"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stsz"
+	"github.com/wangyoucao577/medialib/container/mp4/box/stts"
+	"github.com/wangyoucao577/medialib/container/mp4/box/tfhd"
+	"github.com/wangyoucao577/medialib/container/mp4/box/tkhd"
+	"github.com/wangyoucao577/medialib/container/mp4/box/traf"
+	"github.com/wangyoucao577/medialib/container/mp4/box/trak"
+	"github.com/wangyoucao577/medialib/container/mp4/box/trun"
+)
+
+func TestProgressiveSamples(t *testing.T) {
+	st := stbl.Box{
+		Stsc: &stsc.Box{Entries: []stsc.ChunkEntry{{FirstChunk: 1, SamplesPerChunk: 2}}},
+		Stsz: &stsz.Box{SampleCount: 4, EntrySizes: []uint32{10, 20, 30, 40}},
+		Stco: &stco.Box{ChunkOffsets: []uint32{100, 1000}},
+		Stts: &stts.Box{SampleCounts: []uint32{3, 1}, SampleDeltas: []uint32{512, 256}},
+		Ctts: &ctts.Box{SampleCounts: []uint32{1, 1, 2}, SampleOffsets: []int64{1024, -512, 0}},
+		Stss: &stss.Box{SampleNumbers: []uint32{1, 4}},
+	}
+	expected := []Sample{
+		{DecodeTime: 0, CompositionTime: 1024, Duration: 512, Offset: 100, Size: 10, IsSync: true},
+		{DecodeTime: 512, CompositionTime: 0, Duration: 512, Offset: 110, Size: 20, IsSync: false},
+		{DecodeTime: 1024, CompositionTime: 1024, Duration: 512, Offset: 1000, Size: 30, IsSync: false},
+		{DecodeTime: 1536, CompositionTime: 1536, Duration: 256, Offset: 1030, Size: 40, IsSync: true},
+	}
+
+	samples, err := progressiveSamples(&st)
+	if err != nil {
+		t.Fatalf("expect nil but got %v", err)
+	}
+	if !reflect.DeepEqual(samples, expected) {
+		t.Errorf("expect %v but got %v", expected, samples)
+	}
+
+	st.Stts.SampleCounts = []uint32{3} // insufficient sample count
+	if _, err := progressiveSamples(&st); err == nil {
+		t.Errorf("expect error for mismatched stts but got nil")
+	}
+}
+
+func TestFragmentedSamples(t *testing.T) {
+	fmp4File := "../../assets/sintel_trailer-720p-firstgopfmp4.mp4"
+
+	h := New(fmp4File)
+	if err := h.Parse(); err != nil {
+		t.Fatalf("parse %s failed, err %v", fmp4File, err)
+	}
+	defer h.Close()
+
+	for _, track := range h.Moov.Trak {
+		trackID := track.Tkhd.TrackID
+		samples, err := h.Samples(trackID)
+		if err != nil {
+			t.Errorf("track %d expect nil but got %v", trackID, err)
+			continue
+		}
+
+		var sampleCount int
+		var duration uint64
+		for _, mm := range h.MoofMdat {
+			for _, tf := range mm.Moof.Traf {
+				if tf.Tfhd.TrackID != trackID {
+					continue
+				}
+				for _, tr := range tf.Trun {
+					sampleCount += int(tr.SampleCount)
+					for _, d := range tr.SampleDuration {
+						duration += uint64(d)
+					}
+				}
+			}
+		}
+		if len(samples) != sampleCount || sampleCount == 0 {
+			t.Errorf("track %d expect %d samples but got %d", trackID, sampleCount, len(samples))
+			continue
+		}
+		if !samples[0].IsSync {
+			t.Errorf("track %d expect first sample is sync sample", trackID)
+		}
+
+		for i := 1; i < len(samples); i++ {
+			if samples[i].DecodeTime != samples[i-1].DecodeTime+uint64(samples[i-1].Duration) {
+				t.Errorf("track %d sample %d decode time %d not continuous with previous %v", trackID, i, samples[i].DecodeTime, samples[i-1])
+				break
+			}
+		}
+		if last := samples[len(samples)-1]; duration > 0 && last.DecodeTime+uint64(last.Duration)-samples[0].DecodeTime != duration {
+			t.Errorf("track %d expect duration %d but got %d", trackID, duration, last.DecodeTime+uint64(last.Duration)-samples[0].DecodeTime)
+		}
+
+		for i, s := range samples {
+			if _, err := h.ReadSample(s); err != nil {
+				t.Errorf("track %d read sample %d %v failed, err %v", trackID, i, s, err)
+				break
+			}
+		}
+	}
+
+	// samples of all tracks should fill the mdat without overlap
+	var allSamples []Sample
+	for _, track := range h.Moov.Trak {
+		samples, _ := h.Samples(track.Tkhd.TrackID)
+		allSamples = append(allSamples, samples...)
+	}
+	sort.Slice(allSamples, func(i, j int) bool { return allSamples[i].Offset < allSamples[j].Offset })
+	for i := 1; i < len(allSamples); i++ {
+		if allSamples[i-1].Offset+uint64(allSamples[i-1].Size) != allSamples[i].Offset {
+			t.Errorf("expect samples stored continuously but got %v and %v", allSamples[i-1], allSamples[i])
+			break
+		}
+	}
+	if m := h.MoofMdat[0].Mdat; allSamples[0].Offset != m.Offset {
+		t.Errorf("expect samples start at mdat payload offset %d but got %d", m.Offset, allSamples[0].Offset)
+	}
+
+	// the first video sample is located at the beginning of the mdat in this file
+	samples, _ := h.Samples(1)
+	data, err := h.ReadSample(samples[0])
+	if err != nil {
+		t.Fatalf("read sample failed, err %v", err)
+	}
+	expect := make([]byte, len(data))
+	if _, err := h.MoofMdat[0].Mdat.ReadAt(expect, 0); err != nil || !bytes.Equal(data, expect) {
+		t.Errorf("expect first video sample at the beginning of mdat, err %v", err)
+	}
+}
+
+func TestInterleavedFragmentSamples(t *testing.T) {
+	const moofOffset, mdatPayloadOffset = 1000, 1208
+
+	payload := make([]byte, 24)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	b := Boxes{
+		Moov: &moov.Box{Trak: []trak.Box{{Tkhd: &tkhd.Box{TrackID: 1}}, {Tkhd: &tkhd.Box{TrackID: 2}}}},
+		MoofMdat: []MoofMdat{{
+			Moof: moof.Box{Traf: []traf.Box{
+				{ // track 2 data stored at first by explicit base data offset
+					Tfhd: &tfhd.Box{FullHeader: box.FullHeader{Flags: 0x000001 | 0x000010}, TrackID: 2, BaseDataOffset: mdatPayloadOffset, DefaultSampleSize: 3},
+					Trun: []trun.Box{{SampleCount: 2}},
+				},
+				{ // track 1 data relative to moof
+					Tfhd: &tfhd.Box{FullHeader: box.FullHeader{Flags: 0x020000 | 0x000010}, TrackID: 1, DefaultSampleSize: 4},
+					Trun: []trun.Box{{FullHeader: box.FullHeader{Flags: 0x1}, SampleCount: 2, DataOffset: mdatPayloadOffset + 6 - moofOffset}},
+				},
+				{ // track 1 data follows the previous track fragment, and the second run follows the first one
+					Tfhd: &tfhd.Box{TrackID: 1},
+					Trun: []trun.Box{
+						{FullHeader: box.FullHeader{Flags: 0x200}, SampleCount: 1, SampleSize: []uint32{5}},
+						{FullHeader: box.FullHeader{Flags: 0x200}, SampleCount: 1, SampleSize: []uint32{5}},
+					},
+				},
+			}},
+			Mdat:       mdat.Box{Offset: mdatPayloadOffset, Length: uint64(len(payload)), Data: payload},
+			moofOffset: moofOffset,
+		}},
+	}
+
+	cases := []struct {
+		trackID  uint32
+		expected [][2]uint64 // offset, size
+	}{
+		{1, [][2]uint64{{1214, 4}, {1218, 4}, {1222, 5}, {1227, 5}}},
+		{2, [][2]uint64{{1208, 3}, {1211, 3}}},
+	}
+
+	for _, c := range cases {
+		samples, err := b.Samples(c.trackID)
+		if err != nil {
+			t.Errorf("track %d expect nil but got %v", c.trackID, err)
+			continue
+		}
+		if len(samples) != len(c.expected) {
+			t.Errorf("track %d expect %d samples but got %d", c.trackID, len(c.expected), len(samples))
+			continue
+		}
+		for i, s := range samples {
+			if s.Offset != c.expected[i][0] || uint64(s.Size) != c.expected[i][1] {
+				t.Errorf("track %d sample %d expect offset %d size %d but got %d %d", c.trackID, i, c.expected[i][0], c.expected[i][1], s.Offset, s.Size)
+				continue
+			}
+			data, err := b.ReadSample(s)
+			if expect := payload[s.Offset-mdatPayloadOffset : s.Offset-mdatPayloadOffset+uint64(s.Size)]; err != nil || !bytes.Equal(data, expect) {
+				t.Errorf("track %d sample %d expect %v but got %v, err %v", c.trackID, i, expect, data, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package mp4
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/wangyoucao577/medialib/audio/aac"
+	"github.com/wangyoucao577/medialib/container/mp4/box"
+	avcannexbes "github.com/wangyoucao577/medialib/video/avc/annexbes"
+	avces "github.com/wangyoucao577/medialib/video/avc/es"
+	"github.com/wangyoucao577/medialib/video/hevc/annexbes"
+	hevces "github.com/wangyoucao577/medialib/video/hevc/es"
+)
+
+// TrackWriter writes samples of a track as raw data one by one, i.e., AnnexB byte stream for AVC/HEVC and ADTS for AAC.
+type TrackWriter struct {
+	w     io.Writer
+	track Track
+
+	lengthSize uint32                  // NALUnitLength size of AVC/HEVC samples
+	asc        aac.AudioSpecificConfig // for ADTS header of AAC samples
+}
+
+// NewTrackWriter creates a writer for samples of the track.
+// Parameter sets in hvcC(VPS/SPS/PPS/SEI arrays) will be written at first for HEVC, same as ExtractHEVCAnnexBES.
+func (b *Boxes) NewTrackWriter(t Track, w io.Writer) (*TrackWriter, error) {
+	if b.Moov == nil {
+		return nil, fmt.Errorf("moov not found")
+	}
+	track, err := b.handlerTrack(int(t.ID), t.HandlerType)
+	if err != nil {
+		return nil, err
+	}
+
+	tw := &TrackWriter{w: w, track: t}
+	switch t.Codec {
+	case box.TypeAvc1:
+		if tw.lengthSize, err = avcLengthSize(track); err != nil {
+			return nil, err
+		}
+	case box.TypeHev1, box.TypeHvc1:
+		config, err := hevcConfig(track)
+		if err != nil {
+			return nil, err
+		}
+		tw.lengthSize = config.LengthSize()
+
+		parameterSets, err := hevcParameterSets(config)
+		if err != nil {
+			return nil, err
+		}
+		if len(parameterSets) > 0 {
+			annexbES := annexbes.ElementaryStream{NALU: parameterSets}
+			if _, err := annexbES.Dump(w); err != nil {
+				return nil, err
+			}
+		}
+	case box.TypeMp4a:
+		if err := parseAudioSpecificConfig(track, &tw.asc); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("trackID %d codec %s extraction doesn't support yet", t.ID, t.Codec)
+	}
+	return tw, nil
+}
+
+// WriteSample converts the sample data to raw data of the track then writes it.
+func (tw *TrackWriter) WriteSample(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var d Dumper
+	switch tw.track.Codec {
+	case box.TypeAvc1:
+		e := avces.ElementaryStream{}
+		e.SetLengthSize(tw.lengthSize)
+		if _, err := e.Parse(bytes.NewReader(data), len(data)); err != nil {
+			return err
+		}
+		annexbES := avcannexbes.ElementaryStream{}
+		for i := range e.LengthNALU {
+			annexbES.NALU = append(annexbES.NALU, e.LengthNALU[i].NALU)
+		}
+		d = &annexbES
+	case box.TypeHev1, box.TypeHvc1:
+		e := hevces.ElementaryStream{}
+		e.SetLengthSize(tw.lengthSize)
+		if _, err := e.Parse(bytes.NewReader(data), len(data)); err != nil {
+			return err
+		}
+		annexbES := annexbes.ElementaryStream{}
+		for i := range e.LengthNALU {
+			annexbES.NALU = append(annexbES.NALU, e.LengthNALU[i].NALU)
+		}
+		d = &annexbES
+	case box.TypeMp4a:
+		d = &aac.ADTS{AudioSpecificConfig: tw.asc, RawFrames: [][]byte{data}}
+	}
+
+	_, err := d.Dump(tw.w)
+	return err
+}
+
+// WalkSamples reads samples of the tracks once in order of their offsets in the input, then passes them to fn one by one,
+// so that interleaved tracks can be extracted in one pass without buffering all samples in memory.
+func (b *Boxes) WalkSamples(tracks TrackList, fn func(t Track, s Sample, data []byte) error) error {
+	type trackSample struct {
+		track  Track
+		sample Sample
+	}
+	var samples []trackSample
+	for _, t := range tracks {
+		trackSamples, err := b.Samples(t.ID)
+		if err != nil {
+			return err
+		}
+		for _, s := range trackSamples {
+			samples = append(samples, trackSample{track: t, sample: s})
+		}
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].sample.Offset < samples[j].sample.Offset })
+
+	for _, s := range samples {
+		data, err := b.ReadSample(s.sample)
+		if err != nil {
+			return err
+		}
+		if err := fn(s.track, s.sample, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/wangyoucao577/medialib/container/mp4/box"
+	"github.com/wangyoucao577/medialib/util/dump"
+	"github.com/wangyoucao577/medialib/util/mediaformat"
+)
+
+// Track represents basic information of a track.
+type Track struct {
+	ID          uint32 `json:"track_id"`
+	Index       int    `json:"index"`        // index in moov, starts from 0
+	HandlerType string `json:"handler_type"` // e.g., vide, soun
+	Codec       string `json:"codec"`        // sample entry type, e.g., avc1, hvc1, mp4a
+}
+
+// Extension returns file extension of the raw data extracted from the track, or empty if not supported.
+func (t Track) Extension() string {
+	switch t.Codec {
+	case box.TypeAvc1:
+		return mediaformat.AsExtension(mediaformat.H264)
+	case box.TypeHev1, box.TypeHvc1:
+		return mediaformat.AsExtension(mediaformat.H265)
+	case box.TypeMp4a:
+		return mediaformat.AsExtension(mediaformat.AAC)
+	}
+	return ""
+}
+
+// TrackList represents a list of tracks, which implements dump.Marshaler.
+type TrackList []Track
+
+// JSON marshals tracks to JSON representation
+func (l TrackList) JSON() ([]byte, error) {
+	return json.Marshal(l)
+}
+
+// JSONIndent marshals tracks to JSON representation with customized indent.
+func (l TrackList) JSONIndent(prefix, indent string) ([]byte, error) {
+	return json.MarshalIndent(l, prefix, indent)
+}
+
+// YAML formats tracks to YAML representation.
+func (l TrackList) YAML() ([]byte, error) {
+	j, err := json.Marshal(l)
+	if err != nil {
+		return j, err
+	}
+	return yaml.JSONToYAML(j)
+}
+
+// CSV formats tracks to CSV representation.
+func (l TrackList) CSV() ([]byte, error) {
+	records := [][]string{
+		{"TrackID", "Index", "HandlerType", "Codec"}, // csv header
+	}
+	for _, t := range l {
+		records = append(records, []string{strconv.FormatUint(uint64(t.ID), 10), strconv.Itoa(t.Index), t.HandlerType, t.Codec})
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w := csv.NewWriter(buf)
+	err := w.WriteAll(records)
+
+	return buf.Bytes(), err
+}
+
+// Tracks returns all tracks in moov.
+func (b *Boxes) Tracks() TrackList {
+	if b.Moov == nil {
+		return nil
+	}
+
+	tracks := make(TrackList, 0, len(b.Moov.Trak))
+	for i, trak := range b.Moov.Trak {
+		t := Track{Index: i}
+		if trak.Tkhd != nil {
+			t.ID = trak.Tkhd.TrackID
+		}
+		if trak.Mdia != nil && trak.Mdia.Hdlr != nil {
+			t.HandlerType = trak.Mdia.Hdlr.HandlerType.String()
+		}
+		if trak.Mdia != nil && trak.Mdia.Minf != nil && trak.Mdia.Minf.Stbl != nil && trak.Mdia.Minf.Stbl.Stsd != nil {
+			t.Codec = trak.Mdia.Minf.Stbl.Stsd.SampleEntryType()
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks
+}
+
+// SelectTracks selects tracks by comma separated selectors, each one could be
+// `id:<track_ID>`, `index:<index in moov>`, `type:<handler_type>`(e.g., vide, soun) or `all`.
+// Empty selector means all tracks. Selected tracks keep the sequence in moov without duplication.
+func (b *Boxes) SelectTracks(selector string) (TrackList, error) {
+	tracks := b.Tracks()
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("no track found")
+	}
+	if len(selector) == 0 {
+		return tracks, nil
+	}
+
+	selected := make([]bool, len(tracks))
+	for _, s := range strings.Split(selector, ",") {
+		s = strings.TrimSpace(s)
+		if s == "all" {
+			return tracks, nil
+		}
+
+		key, value, ok := strings.Cut(s, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid track selector %s", s)
+		}
+
+		var match func(t Track) bool
+		switch key {
+		case "id":
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid track selector %s, err %v", s, err)
+			}
+			match = func(t Track) bool { return uint64(t.ID) == id }
+		case "index":
+			index, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid track selector %s, err %v", s, err)
+			}
+			match = func(t Track) bool { return t.Index == index }
+		case "type":
+			match = func(t Track) bool { return t.HandlerType == value }
+		default:
+			return nil, fmt.Errorf("invalid track selector %s", s)
+		}
+
+		var matched bool
+		for i, t := range tracks {
+			if match(t) {
+				selected[i] = true
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no track matches selector %s", s)
+		}
+	}
+
+	var selectedTracks TrackList
+	for i := range tracks {
+		if selected[i] {
+			selectedTracks = append(selectedTracks, tracks[i])
+		}
+	}
+	return selectedTracks, nil
+}
+
+// SelectTrack selects the first track of handler type by selector, see SelectTracks for selector syntax.
+func (b *Boxes) SelectTrack(selector string, handlerType string) (Track, error) {
+	tracks, err := b.SelectTracks(selector)
+	if err != nil {
+		return Track{}, err
+	}
+	for _, t := range tracks {
+		if t.HandlerType == handlerType {
+			return t, nil
+		}
+	}
+	return Track{}, fmt.Errorf("no %s track selected by %s", handlerType, selector)
+}
+
+// KeepTracks removes all other tracks from moov, e.g., to remux selected tracks only.
+// Samples of removed tracks are still kept in mdat.
+func (b *Boxes) KeepTracks(tracks TrackList) {
+	if b.Moov == nil {
+		return
+	}
+
+	keep := map[uint32]bool{}
+	for _, t := range tracks {
+		keep[t.ID] = true
+	}
+
+	trak := b.Moov.Trak[:0]
+	for _, t := range b.Moov.Trak {
+		if t.Tkhd != nil && keep[t.Tkhd.TrackID] {
+			trak = append(trak, t)
+		}
+	}
+	b.Moov.Trak = trak
+
+	if b.Moov.Mvex != nil {
+		trex := b.Moov.Mvex.Trex[:0]
+		for _, t := range b.Moov.Mvex.Trex {
+			if keep[t.TrackID] {
+				trex = append(trex, t)
+			}
+		}
+		b.Moov.Mvex.Trex = trex
+	}
+}
+
+// Dumper dumps extracted raw data.
+type Dumper interface {
+	Dump(w io.Writer) (int, error)
+}
+
+// ElementaryStream represents extracted AVC or HEVC Elementary Stream.
+type ElementaryStream interface {
+	dump.Marshaler
+	Dumper
+}
@@ -0,0 +1,115 @@
+package mp4
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSelectTracks(t *testing.T) {
+	fmp4File := "../../assets/sintel_trailer-720p-firstgopfmp4.mp4"
+
+	h := New(fmp4File)
+	if err := h.Parse(); err != nil {
+		t.Fatalf("parse %s failed, err %v", fmp4File, err)
+	}
+	defer h.Close()
+
+	video := Track{ID: 1, Index: 0, HandlerType: "vide", Codec: "avc1"}
+	audio := Track{ID: 2, Index: 1, HandlerType: "soun", Codec: "mp4a"}
+	if tracks := h.Tracks(); !reflect.DeepEqual(tracks, TrackList{video, audio}) {
+		t.Fatalf("expect tracks %v but got %v", TrackList{video, audio}, tracks)
+	}
+
+	cases := []struct {
+		selector  string
+		expect    TrackList
+		expectErr bool
+	}{
+		{"", TrackList{video, audio}, false},
+		{"all", TrackList{video, audio}, false},
+		{"id:2", TrackList{audio}, false},
+		{"index:0", TrackList{video}, false},
+		{"type:soun", TrackList{audio}, false},
+		{"type:soun, id:1", TrackList{video, audio}, false},
+		{"id:1,index:0", TrackList{video}, false},
+		{"id:3", nil, true},
+		{"type:text", nil, true},
+		{"id:a", nil, true},
+		{"1", nil, true},
+		{"name:video", nil, true},
+	}
+	for _, c := range cases {
+		got, err := h.SelectTracks(c.selector)
+		if c.expectErr {
+			if err == nil {
+				t.Errorf("select %q expect error but got %v", c.selector, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("select %q expect nil but got %v", c.selector, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.expect) {
+			t.Errorf("select %q expect %v but got %v", c.selector, c.expect, got)
+		}
+	}
+
+	if got, err := h.SelectTrack("", "soun"); err != nil || got != audio {
+		t.Errorf("select the first audio track expect %v but got %v, err %v", audio, got, err)
+	}
+	if _, err := h.SelectTrack("id:1", "soun"); err == nil {
+		t.Errorf("select audio track from video track expect error but got nil")
+	}
+
+	// extract all tracks in one pass, which is expected to be same as extracted separately
+	outputs := map[uint32]*bytes.Buffer{}
+	writers := map[uint32]*TrackWriter{}
+	for _, track := range h.Tracks() {
+		outputs[track.ID] = &bytes.Buffer{}
+		w, err := h.NewTrackWriter(track, outputs[track.ID])
+		if err != nil {
+			t.Fatalf("create writer of track %v expect nil but got %v", track, err)
+		}
+		writers[track.ID] = w
+	}
+	var lastOffset uint64
+	if err := h.WalkSamples(h.Tracks(), func(track Track, s Sample, data []byte) error {
+		if s.Offset < lastOffset {
+			t.Errorf("walk samples expect in order of offset but got %d after %d", s.Offset, lastOffset)
+		}
+		lastOffset = s.Offset
+		return writers[track.ID].WriteSample(data)
+	}); err != nil {
+		t.Fatalf("walk samples expect nil but got %v", err)
+	}
+	expectVideo, err := h.ExtractAnnexBES(int(video.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectAudio, err := h.ExtractAudio(int(audio.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, d := range map[uint32]Dumper{video.ID: expectVideo, audio.ID: expectAudio} {
+		var expect bytes.Buffer
+		if _, err := d.Dump(&expect); err != nil {
+			t.Fatal(err)
+		}
+		if got := outputs[id]; got.Len() == 0 || !bytes.Equal(got.Bytes(), expect.Bytes()) {
+			t.Errorf("track %d expect %d bytes extracted in one pass but got %d", id, expect.Len(), got.Len())
+		}
+	}
+
+	h.KeepTracks(TrackList{audio})
+	if tracks := h.Tracks(); len(tracks) != 1 || tracks[0].ID != audio.ID {
+		t.Errorf("expect only audio track kept but got %v", tracks)
+	}
+	if len(h.Moov.Mvex.Trex) != 1 || h.Moov.Mvex.Trex[0].TrackID != audio.ID {
+		t.Errorf("expect only audio trex kept but got %d", len(h.Moov.Mvex.Trex))
+	}
+	if _, err := h.ExtractAudio(int(audio.ID)); err != nil {
+		t.Errorf("extract audio after keep tracks expect nil but got %v", err)
+	}
+}
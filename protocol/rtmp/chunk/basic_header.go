@@ -26,6 +26,10 @@ const (
 	chunkStreamIDThreshold14bits = 320
 
 	chunkStreamIDCalculateBase22bits = 256
+
+	// MinChunkStreamID and MaxChunkStreamID are the range of chunk stream id, 0 and 1 are reserved to indicate 2 or 3 bytes basic header.
+	MinChunkStreamID = 2
+	MaxChunkStreamID = 65599
 )
 
 // Serialize serializes basic header to binary format.
@@ -41,8 +45,8 @@ func (b *BasicHeader) Serialize() []byte {
 		return data[:2]
 	} else {
 		data[0] |= 0x1
-		data[1] = byte(b.StreamID % chunkStreamIDCalculateBase22bits)
-		data[2] = byte(b.StreamID / chunkStreamIDCalculateBase22bits)
+		data[1] = byte((b.StreamID - chunkStreamIDThreshold6bits) % chunkStreamIDCalculateBase22bits)
+		data[2] = byte((b.StreamID - chunkStreamIDThreshold6bits) / chunkStreamIDCalculateBase22bits)
 		return data[:]
 	}
 }
@@ -72,7 +76,7 @@ func (b *BasicHeader) Parse(r io.Reader) (uint64, error) {
 			return uint64(parsedBytes), err
 		} else {
 			b.StreamID = uint32(data[1])*chunkStreamIDCalculateBase22bits +
-				uint32(data[1]) + chunkStreamIDThreshold6bits
+				uint32(data[0]) + chunkStreamIDThreshold6bits
 			parsedBytes += 2
 		}
 	}
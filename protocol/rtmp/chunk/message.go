@@ -4,7 +4,6 @@ import (
 	"encoding/binary"
 	"io"
 
-	"github.com/golang/glog"
 	"github.com/wangyoucao577/medialib/util"
 )
 
@@ -14,7 +13,7 @@ type Message struct {
 	MessageHeader     *MessageHeader `json:"message_header,omitempty"`
 	ExtendedTimestamp *uint32        `json:"extended_timestamp,omitempty"`
 
-	// TODO: data
+	Data []byte `json:"-"` // chunk data, part of or whole payload of a RTMP message
 }
 
 // Serialize serializes chunk message to binary format.
@@ -31,42 +30,42 @@ func (m *Message) Serialize() []byte {
 	if m.ExtendedTimestamp != nil {
 		timestampData := make([]byte, 4)
 		binary.BigEndian.PutUint32(timestampData, *m.ExtendedTimestamp)
-		data = append(data, timestampData[1:]...)
+		data = append(data, timestampData...)
 	}
 
-	// TODO: data
+	data = append(data, m.Data...)
 
 	return data
 }
 
-// ParsePayload parses chunk message payload from binary format.
+// ParsePayload parses chunk message header and extended timestamp from binary format.
 // It assumes the BasicHeader has been parsed already.
+// Extended timestamp of Fmt3 chunks and chunk data depend on chunk stream state, which are parsed by Reader.
 func (m *Message) ParsePayload(r io.Reader) error {
-	var parsedBytes uint64
-
 	if m.BasicHeader.Fmt < MessageHeaderFmt3 {
 		m.MessageHeader = &MessageHeader{}
-		if bytes, err := m.MessageHeader.Parse(r, m.BasicHeader.Fmt); err != nil {
+		if _, err := m.MessageHeader.Parse(r, m.BasicHeader.Fmt); err != nil {
 			return err
-		} else {
-			parsedBytes += bytes
 		}
 	}
 
 	if m.MessageHeader != nil &&
-		m.MessageHeader.Timestamp == 0xFFFFFF {
-		data := make([]byte, 4)
-		if err := util.ReadOrError(r, data); err != nil {
+		m.MessageHeader.Timestamp == extendedTimestampFlag {
+		if err := m.parseExtendedTimestamp(r); err != nil {
 			return err
-		} else {
-			timestamp := binary.BigEndian.Uint32(data)
-			m.ExtendedTimestamp = &timestamp
-			parsedBytes += 4
 		}
 	}
 
-	// TODO: data
-	glog.Warningf("parsed %d bytes, still need to parse data", parsedBytes)
+	return nil
+}
 
+// parseExtendedTimestamp parses 4 bytes extended timestamp.
+func (m *Message) parseExtendedTimestamp(r io.Reader) error {
+	data := make([]byte, 4)
+	if err := util.ReadOrError(r, data); err != nil {
+		return err
+	}
+	timestamp := binary.BigEndian.Uint32(data)
+	m.ExtendedTimestamp = &timestamp
 	return nil
 }
@@ -7,12 +7,15 @@ import (
 	"github.com/wangyoucao577/medialib/util"
 )
 
+// extendedTimestampFlag in timestamp field indicates the Extended Timestamp field is present.
+const extendedTimestampFlag = 0xFFFFFF
+
 // MessageHeader represents RTMP chunk message header.
 type MessageHeader struct {
 	Timestamp uint32  `json:"timestamp"`                   // first 24 bits except type == 3
 	Length    *uint32 `json:"message_length,omitempty"`    // next 24 bits if (type == 0 || type == 1)
 	TypeID    *uint8  `json:"message_type_id,omitempty"`   // next 8 bits if (type == 0 || type == 1)
-	StreamID  *uint32 `json:"message_stream_id,omitempty"` // next 32 bits if type == 0
+	StreamID  *uint32 `json:"message_stream_id,omitempty"` // next 32 bits(little-endian) if type == 0
 }
 
 // Serialize serializes message header to binary format.
@@ -30,7 +33,7 @@ func (m *MessageHeader) Serialize(fmt uint8) []byte {
 	if fmt <= MessageHeaderFmt1 {
 		if m.Length != nil {
 			lenData := make([]byte, 4)
-			binary.BigEndian.PutUint32(timestampBytes, *m.Length)
+			binary.BigEndian.PutUint32(lenData, *m.Length)
 			data = append(data, lenData[1:]...)
 		}
 
@@ -41,7 +44,7 @@ func (m *MessageHeader) Serialize(fmt uint8) []byte {
 	if fmt == MessageHeaderFmt0 {
 		if m.StreamID != nil {
 			streamIDData := make([]byte, 4)
-			binary.BigEndian.PutUint32(streamIDData, *m.StreamID)
+			binary.LittleEndian.PutUint32(streamIDData, *m.StreamID) // message stream id is little-endian
 			data = append(data, streamIDData...)
 		}
 	}
@@ -86,7 +89,7 @@ func (m *MessageHeader) Parse(r io.Reader, fmt uint8) (uint64, error) {
 		if err := util.ReadOrError(r, data); err != nil {
 			return uint64(parsedBytes), err
 		} else {
-			streamID := binary.BigEndian.Uint32(data)
+			streamID := binary.LittleEndian.Uint32(data) // message stream id is little-endian
 			m.StreamID = &streamID
 			parsedBytes += 4
 		}
@@ -0,0 +1,222 @@
+package chunk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/util"
+)
+
+// chunk size limits
+const (
+	DefaultChunkSize = 128
+	MaxChunkSize     = 0x7FFFFFFF // 31 bits, the first bit of Set Chunk Size payload must be 0
+)
+
+// chunkStreamState keeps the latest message header of a chunk stream, so that Fmt1/2/3 chunks can be resolved.
+type chunkStreamState struct {
+	timestamp      uint32 // absolute timestamp of the latest message
+	timestampDelta uint32 // timestamp delta of the latest Fmt1/2 chunk or timestamp of Fmt0 chunk, will be reused by Fmt3 chunks
+	length         uint32
+	typeID         uint8
+	streamID       uint32
+	extended       bool   // whether the latest Fmt0/1/2 chunk has extended timestamp
+	timestampField uint32 // timestamp or delta of the latest Fmt0/1/2 chunk, repeated as extended timestamp of Fmt3 chunks by Writer
+
+	remaining uint32 // bytes of the message still not read
+	payload   []byte // partial payload of the message in reassembling by ReadMessage
+}
+
+// Reader reads chunks and reassembles them to complete RTMP messages.
+type Reader struct {
+	r *countReader
+
+	chunkSize uint32
+	streams   map[uint32]*chunkStreamState // keyed by chunk stream id
+}
+
+// NewReader creates chunk stream Reader with default chunk size 128.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		r:         &countReader{r: r},
+		chunkSize: DefaultChunkSize,
+		streams:   map[uint32]*chunkStreamState{},
+	}
+}
+
+// ChunkSize returns the current maximum chunk size of incoming chunks.
+func (cr *Reader) ChunkSize() uint32 {
+	return cr.chunkSize
+}
+
+// SetChunkSize sets maximum chunk size of incoming chunks.
+// It will be called automatically once a Set Chunk Size message is read.
+func (cr *Reader) SetChunkSize(size uint32) error {
+	if size == 0 || size > MaxChunkSize {
+		return fmt.Errorf("invalid chunk size %d", size)
+	}
+	cr.chunkSize = size
+	return nil
+}
+
+// BytesRead returns total bytes have been read, e.g., for sending Acknowledgement.
+func (cr *Reader) BytesRead() int64 {
+	return cr.r.n
+}
+
+// ReadMessage reads chunks until a complete RTMP message has been reassembled.
+// Set Chunk Size and Abort messages will be applied to the Reader before returned.
+func (cr *Reader) ReadMessage() (*RTMPMessage, error) {
+	for {
+		c, err := cr.ReadChunk()
+		if err != nil {
+			return nil, err
+		}
+
+		st := cr.streams[c.BasicHeader.StreamID]
+		st.payload = append(st.payload, c.Data...)
+		if st.remaining > 0 {
+			continue
+		}
+
+		m := &RTMPMessage{
+			ChunkStreamID: c.BasicHeader.StreamID,
+			Timestamp:     st.timestamp,
+			TypeID:        st.typeID,
+			StreamID:      st.streamID,
+			Payload:       st.payload,
+		}
+		st.payload = nil
+
+		if err := cr.handleProtocolControl(m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+}
+
+// ReadChunk reads a chunk and resolves its header by chunk stream state.
+// Chunk data will not be reassembled, so prefer ReadMessage unless chunks are wanted.
+func (cr *Reader) ReadChunk() (*Message, error) {
+	c := &Message{}
+	if _, err := c.BasicHeader.Parse(cr.r); err != nil {
+		return nil, err
+	}
+	csid := c.BasicHeader.StreamID
+
+	st, ok := cr.streams[csid]
+	if !ok {
+		if c.BasicHeader.Fmt != MessageHeaderFmt0 {
+			return nil, fmt.Errorf("chunk stream %d starts with fmt %d but expect %d", csid, c.BasicHeader.Fmt, MessageHeaderFmt0)
+		}
+		st = &chunkStreamState{}
+		cr.streams[csid] = st
+	}
+
+	if err := c.ParsePayload(cr.r); err != nil {
+		return nil, err
+	}
+	if c.BasicHeader.Fmt == MessageHeaderFmt3 && st.extended {
+		if err := c.parseExtendedTimestamp(cr.r); err != nil {
+			return nil, err
+		}
+	}
+
+	inProgress := st.remaining > 0
+	if inProgress && c.BasicHeader.Fmt != MessageHeaderFmt3 {
+		glog.Warningf("chunk stream %d got fmt %d chunk with %d of %d bytes message payload read, drop them",
+			csid, c.BasicHeader.Fmt, st.length-st.remaining, st.length)
+		st.payload = nil
+		inProgress = false
+	}
+	cr.resolveHeader(st, c, inProgress)
+	if !inProgress {
+		st.remaining = st.length
+	}
+
+	dataSize := st.remaining
+	if dataSize > cr.chunkSize {
+		dataSize = cr.chunkSize
+	}
+	st.remaining -= dataSize
+	c.Data = make([]byte, dataSize)
+	if dataSize > 0 {
+		if err := util.ReadOrError(cr.r, c.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// resolveHeader updates chunk stream state by the chunk headers.
+func (cr *Reader) resolveHeader(st *chunkStreamState, c *Message, inProgress bool) {
+	var timestamp uint32
+	if c.MessageHeader != nil {
+		timestamp = c.MessageHeader.Timestamp
+		st.extended = timestamp == extendedTimestampFlag
+	}
+	if c.ExtendedTimestamp != nil {
+		timestamp = *c.ExtendedTimestamp
+	}
+
+	switch c.BasicHeader.Fmt {
+	case MessageHeaderFmt0:
+		st.timestamp = timestamp
+		st.timestampDelta = timestamp // RTMP 1.0 5.3.1.2.4, Fmt3 may follow Fmt0 directly with the timestamp as delta
+		st.length = *c.MessageHeader.Length
+		st.typeID = *c.MessageHeader.TypeID
+		st.streamID = *c.MessageHeader.StreamID
+	case MessageHeaderFmt1:
+		st.timestampDelta = timestamp
+		st.timestamp += timestamp
+		st.length = *c.MessageHeader.Length
+		st.typeID = *c.MessageHeader.TypeID
+	case MessageHeaderFmt2:
+		st.timestampDelta = timestamp
+		st.timestamp += timestamp
+	case MessageHeaderFmt3:
+		if !inProgress { // a new message with the same header as the previous one
+			st.timestamp += st.timestampDelta
+		}
+	}
+}
+
+// handleProtocolControl applies protocol control messages that affect chunk stream reading.
+func (cr *Reader) handleProtocolControl(m *RTMPMessage) error {
+	switch m.TypeID {
+	case MessageTypeIDSetPacketSize:
+		if len(m.Payload) < 4 {
+			return fmt.Errorf("set chunk size message expect 4 bytes but got %d", len(m.Payload))
+		}
+		size := binary.BigEndian.Uint32(m.Payload)
+		if err := cr.SetChunkSize(size); err != nil {
+			return err
+		}
+		glog.V(1).Infof("incoming chunk size %d", size)
+	case MessageTypeIDAbort:
+		if len(m.Payload) < 4 {
+			return fmt.Errorf("abort message expect 4 bytes but got %d", len(m.Payload))
+		}
+		csid := binary.BigEndian.Uint32(m.Payload)
+		if st, ok := cr.streams[csid]; ok {
+			glog.V(1).Infof("abort chunk stream %d, drop %d bytes", csid, st.length-st.remaining)
+			st.remaining, st.payload = 0, nil
+		}
+	}
+	return nil
+}
+
+// countReader counts bytes have been read.
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,113 @@
+package chunk
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestReadMessage(t *testing.T) {
+	payload := func(n int, b byte) []byte {
+		return bytes.Repeat([]byte{b}, n)
+	}
+
+	var in []byte
+	// a 307 bytes video message split by default chunk size 128, fmt0 + fmt3 + fmt3
+	in = append(in, 0x06, 0x00, 0x03, 0xE8, 0x00, 0x01, 0x33, MessageTypeIDVideoPacket, 0x01, 0x00, 0x00, 0x00)
+	in = append(in, payload(128, 0xA)...)
+	in = append(in, 0xC6)
+	in = append(in, payload(128, 0xA)...)
+	in = append(in, 0xC6)
+	in = append(in, payload(51, 0xA)...)
+	// audio messages, fmt0 then fmt2 with delta 20 then fmt3 reuses the delta
+	in = append(in, 0x04, 0x00, 0x03, 0xE8, 0x00, 0x00, 0x20, MessageTypeIDAudioPacket, 0x01, 0x00, 0x00, 0x00)
+	in = append(in, payload(32, 0xB)...)
+	in = append(in, 0x84, 0x00, 0x00, 0x14)
+	in = append(in, payload(32, 0xC)...)
+	in = append(in, 0xC4)
+	in = append(in, payload(32, 0xD)...)
+	// set chunk size 256 on chunk stream 2
+	in = append(in, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04, MessageTypeIDSetPacketSize, 0x00, 0x00, 0x00, 0x00)
+	in = append(in, 0x00, 0x00, 0x01, 0x00)
+	// fmt1 on chunk stream 6 with extended timestamp delta, fits in one chunk of 256 bytes
+	in = append(in, 0x46, 0xFF, 0xFF, 0xFF, 0x00, 0x00, 0xC8, MessageTypeIDData, 0x01, 0x00, 0x00, 0x00)
+	in = append(in, payload(200, 0xE)...)
+	// fmt0 with extended timestamp on 3 bytes chunk stream id 64+256, the fmt3 chunk carries the extended timestamp again
+	in = append(in, 0x01, 0x00, 0x01, 0xFF, 0xFF, 0xFF, 0x00, 0x01, 0x2C, MessageTypeIDCommand, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00)
+	in = append(in, payload(256, 0xF)...)
+	in = append(in, 0xC1, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00)
+	in = append(in, payload(44, 0xF)...)
+	// fmt0 at 40 then fmt3 starts a new message, which reuses the fmt0 timestamp as delta
+	in = append(in, 0x07, 0x00, 0x00, 0x28, 0x00, 0x00, 0x10, MessageTypeIDAudioPacket, 0x01, 0x00, 0x00, 0x00)
+	in = append(in, payload(16, 0x1)...)
+	in = append(in, 0xC7)
+	in = append(in, payload(16, 0x2)...)
+
+	expect := []RTMPMessage{
+		{ChunkStreamID: 6, Timestamp: 1000, TypeID: MessageTypeIDVideoPacket, StreamID: 1, Payload: payload(307, 0xA)},
+		{ChunkStreamID: 4, Timestamp: 1000, TypeID: MessageTypeIDAudioPacket, StreamID: 1, Payload: payload(32, 0xB)},
+		{ChunkStreamID: 4, Timestamp: 1020, TypeID: MessageTypeIDAudioPacket, StreamID: 1, Payload: payload(32, 0xC)},
+		{ChunkStreamID: 4, Timestamp: 1040, TypeID: MessageTypeIDAudioPacket, StreamID: 1, Payload: payload(32, 0xD)},
+		{ChunkStreamID: 2, Timestamp: 0, TypeID: MessageTypeIDSetPacketSize, StreamID: 0, Payload: []byte{0x00, 0x00, 0x01, 0x00}},
+		{ChunkStreamID: 6, Timestamp: 1000 + 0x01000000, TypeID: MessageTypeIDData, StreamID: 1, Payload: payload(200, 0xE)},
+		{ChunkStreamID: 320, Timestamp: 0x01000000, TypeID: MessageTypeIDCommand, StreamID: 1, Payload: payload(300, 0xF)},
+		{ChunkStreamID: 7, Timestamp: 40, TypeID: MessageTypeIDAudioPacket, StreamID: 1, Payload: payload(16, 0x1)},
+		{ChunkStreamID: 7, Timestamp: 80, TypeID: MessageTypeIDAudioPacket, StreamID: 1, Payload: payload(16, 0x2)},
+	}
+	r := NewReader(bytes.NewReader(in))
+	for i := range expect {
+		m, err := r.ReadMessage()
+		if err != nil {
+			t.Fatalf("read message %d expect nil but got %v", i, err)
+		}
+		if !reflect.DeepEqual(*m, expect[i]) {
+			t.Errorf("message %d expect %+v but got %+v", i, expect[i], *m)
+		}
+	}
+	if _, err := r.ReadMessage(); err != io.EOF {
+		t.Errorf("expect EOF but got %v", err)
+	}
+	if r.ChunkSize() != 256 {
+		t.Errorf("expect chunk size 256 but got %d", r.ChunkSize())
+	}
+	if r.BytesRead() != int64(len(in)) {
+		t.Errorf("expect %d bytes read but got %d", len(in), r.BytesRead())
+	}
+
+	// fmt1 without previous header
+	if _, err := NewReader(bytes.NewReader([]byte{0x46, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, MessageTypeIDData, 0x00})).ReadMessage(); err == nil {
+		t.Errorf("expect error for fmt1 without previous header but got nil")
+	}
+}
+
+func TestReadChunk(t *testing.T) {
+	var in []byte
+	// a 300 bytes video message split by default chunk size 128, fmt0 + fmt3 + fmt3, then a new message by fmt3
+	in = append(in, 0x06, 0x00, 0x00, 0x28, 0x00, 0x01, 0x2C, MessageTypeIDVideoPacket, 0x01, 0x00, 0x00, 0x00)
+	in = append(in, bytes.Repeat([]byte{0xA}, 128)...)
+	in = append(in, 0xC6)
+	in = append(in, bytes.Repeat([]byte{0xA}, 128)...)
+	in = append(in, 0xC6)
+	in = append(in, bytes.Repeat([]byte{0xA}, 44)...)
+	in = append(in, 0xC6)
+	in = append(in, bytes.Repeat([]byte{0xB}, 128)...)
+
+	expect := []struct {
+		fmt  uint8
+		size int
+	}{{MessageHeaderFmt0, 128}, {MessageHeaderFmt3, 128}, {MessageHeaderFmt3, 44}, {MessageHeaderFmt3, 128}}
+	r := NewReader(bytes.NewReader(in))
+	for i, e := range expect {
+		c, err := r.ReadChunk()
+		if err != nil {
+			t.Fatalf("read chunk %d expect nil but got %v", i, err)
+		}
+		if c.BasicHeader.Fmt != e.fmt || len(c.Data) != e.size {
+			t.Errorf("chunk %d expect fmt %d with %d bytes but got fmt %d with %d bytes", i, e.fmt, e.size, c.BasicHeader.Fmt, len(c.Data))
+		}
+	}
+	if _, err := r.ReadChunk(); err != io.EOF {
+		t.Errorf("expect EOF but got %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+package chunk
+
+// RTMPMessage represents a complete RTMP message which is reassembled from chunks or will be split into chunks.
+type RTMPMessage struct {
+	ChunkStreamID uint32 `json:"chunk_stream_id"`   // chunk stream that carries the message
+	Timestamp     uint32 `json:"timestamp"`         // absolute timestamp in milliseconds
+	TypeID        uint8  `json:"message_type_id"`   // message type, see MessageTypeID*
+	StreamID      uint32 `json:"message_stream_id"` // message stream id
+
+	Payload []byte `json:"-"`
+}
+
+// Length returns payload length of the message.
+func (m *RTMPMessage) Length() uint32 {
+	return uint32(len(m.Payload))
+}
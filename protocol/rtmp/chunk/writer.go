@@ -0,0 +1,134 @@
+package chunk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/golang/glog"
+)
+
+// Writer splits RTMP messages into chunks by the outgoing chunk size,
+// message headers are compressed by Fmt1/2/3 as much as possible according to the previous message on the same chunk stream.
+type Writer struct {
+	w io.Writer
+
+	chunkSize uint32
+	streams   map[uint32]*chunkStreamState // keyed by chunk stream id
+}
+
+// NewWriter creates chunk stream Writer with default chunk size 128.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w:         w,
+		chunkSize: DefaultChunkSize,
+		streams:   map[uint32]*chunkStreamState{},
+	}
+}
+
+// ChunkSize returns the current maximum chunk size of outgoing chunks.
+func (cw *Writer) ChunkSize() uint32 {
+	return cw.chunkSize
+}
+
+// SetChunkSize sets maximum chunk size of outgoing chunks.
+// It will be called automatically once a Set Chunk Size message has been written,
+// so call it directly only if the peer has been informed in other ways.
+func (cw *Writer) SetChunkSize(size uint32) error {
+	if size == 0 || size > MaxChunkSize {
+		return fmt.Errorf("invalid chunk size %d", size)
+	}
+	cw.chunkSize = size
+	return nil
+}
+
+// WriteMessage splits the message into chunks and writes them.
+func (cw *Writer) WriteMessage(m *RTMPMessage) error {
+	if m.ChunkStreamID < MinChunkStreamID || m.ChunkStreamID > MaxChunkStreamID {
+		return fmt.Errorf("invalid chunk stream id %d", m.ChunkStreamID)
+	}
+	if m.Length() > 0xFFFFFF { // 24 bits message length
+		return fmt.Errorf("message length %d exceeds 24 bits", m.Length())
+	}
+
+	st, ok := cw.streams[m.ChunkStreamID]
+	if !ok {
+		st = &chunkStreamState{}
+		cw.streams[m.ChunkStreamID] = st
+	}
+	first := cw.firstChunk(st, m, !ok)
+
+	data := first.Serialize()
+	for offset := len(first.Data); offset < len(m.Payload); {
+		end := cw.chunkEnd(offset, len(m.Payload))
+		c := Message{BasicHeader: BasicHeader{Fmt: MessageHeaderFmt3, StreamID: m.ChunkStreamID}, Data: m.Payload[offset:end]}
+		if st.extended {
+			timestamp := st.timestampField
+			c.ExtendedTimestamp = &timestamp
+		}
+		data = append(data, c.Serialize()...)
+		offset = end
+	}
+
+	if _, err := cw.w.Write(data); err != nil {
+		return err
+	}
+
+	if m.TypeID == MessageTypeIDSetPacketSize && len(m.Payload) >= 4 {
+		size := binary.BigEndian.Uint32(m.Payload)
+		if err := cw.SetChunkSize(size); err != nil {
+			return err
+		}
+		glog.V(1).Infof("outgoing chunk size %d", size)
+	}
+	return nil
+}
+
+// firstChunk composes the first chunk of the message with the most compressed header, and updates chunk stream state.
+func (cw *Writer) firstChunk(st *chunkStreamState, m *RTMPMessage, newStream bool) *Message {
+	c := &Message{BasicHeader: BasicHeader{StreamID: m.ChunkStreamID}}
+
+	delta := m.Timestamp - st.timestamp
+	switch {
+	case newStream || m.StreamID != st.streamID || m.Timestamp < st.timestamp:
+		c.BasicHeader.Fmt = MessageHeaderFmt0
+		st.timestampField, st.timestampDelta = m.Timestamp, m.Timestamp // Fmt3 may follow with the timestamp as delta
+	case m.Length() != st.length || m.TypeID != st.typeID:
+		c.BasicHeader.Fmt = MessageHeaderFmt1
+		st.timestampField, st.timestampDelta = delta, delta
+	case delta != st.timestampDelta:
+		c.BasicHeader.Fmt = MessageHeaderFmt2
+		st.timestampField, st.timestampDelta = delta, delta
+	default:
+		c.BasicHeader.Fmt = MessageHeaderFmt3
+	}
+
+	if c.BasicHeader.Fmt != MessageHeaderFmt3 {
+		length, typeID, streamID := m.Length(), m.TypeID, m.StreamID
+		c.MessageHeader = &MessageHeader{Timestamp: st.timestampField, Length: &length, TypeID: &typeID, StreamID: &streamID}
+		st.extended = st.timestampField >= extendedTimestampFlag
+		if st.extended {
+			c.MessageHeader.Timestamp = extendedTimestampFlag
+		}
+	}
+	if st.extended {
+		timestamp := st.timestampField
+		c.ExtendedTimestamp = &timestamp
+	}
+
+	st.timestamp = m.Timestamp
+	st.length = m.Length()
+	st.typeID = m.TypeID
+	st.streamID = m.StreamID
+
+	c.Data = m.Payload[:cw.chunkEnd(0, len(m.Payload))]
+	return c
+}
+
+// chunkEnd returns end of the chunk data that starts from offset.
+func (cw *Writer) chunkEnd(offset, length int) int {
+	if uint64(length-offset) > uint64(cw.chunkSize) {
+		return offset + int(cw.chunkSize)
+	}
+	return length
+}
@@ -0,0 +1,88 @@
+package chunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestWriteMessage(t *testing.T) {
+	payload := func(n int, b byte) []byte {
+		return bytes.Repeat([]byte{b}, n)
+	}
+	setChunkSize := make([]byte, 4)
+	binary.BigEndian.PutUint32(setChunkSize, 4096)
+
+	cases := []struct {
+		m   RTMPMessage
+		fmt []uint8 // expected fmt of chunks
+	}{
+		{RTMPMessage{ChunkStreamID: 4, Timestamp: 1000, TypeID: MessageTypeIDAudioPacket, StreamID: 1, Payload: payload(32, 0x1)}, []uint8{0}},
+		{RTMPMessage{ChunkStreamID: 4, Timestamp: 1020, TypeID: MessageTypeIDAudioPacket, StreamID: 1, Payload: payload(32, 0x2)}, []uint8{2}},
+		{RTMPMessage{ChunkStreamID: 4, Timestamp: 1040, TypeID: MessageTypeIDAudioPacket, StreamID: 1, Payload: payload(32, 0x3)}, []uint8{3}},
+		{RTMPMessage{ChunkStreamID: 4, Timestamp: 1060, TypeID: MessageTypeIDAudioPacket, StreamID: 1, Payload: payload(300, 0x4)}, []uint8{1, 3, 3}},
+		{RTMPMessage{ChunkStreamID: 6, Timestamp: 1000, TypeID: MessageTypeIDVideoPacket, StreamID: 1, Payload: payload(128, 0x5)}, []uint8{0}},
+		{RTMPMessage{ChunkStreamID: 4, Timestamp: 500, TypeID: MessageTypeIDAudioPacket, StreamID: 1, Payload: payload(300, 0x6)}, []uint8{0, 3, 3}},
+		{RTMPMessage{ChunkStreamID: 4, Timestamp: 500, TypeID: MessageTypeIDAudioPacket, StreamID: 1, Payload: payload(300, 0x7)}, []uint8{2, 3, 3}}, // fmt3 after fmt0 means delta 500
+		{RTMPMessage{ChunkStreamID: 4, Timestamp: 500, TypeID: MessageTypeIDAudioPacket, StreamID: 2, Payload: payload(300, 0x8)}, []uint8{0, 3, 3}},
+		{RTMPMessage{ChunkStreamID: 2, Timestamp: 0, TypeID: MessageTypeIDSetPacketSize, StreamID: 0, Payload: setChunkSize}, []uint8{0}},
+		// extended timestamp, absolute and delta
+		{RTMPMessage{ChunkStreamID: 6, Timestamp: 0x02000000, TypeID: MessageTypeIDVideoPacket, StreamID: 1, Payload: payload(5000, 0x9)}, []uint8{1, 3}},
+		{RTMPMessage{ChunkStreamID: 8, Timestamp: 0x01000000, TypeID: MessageTypeIDData, StreamID: 1, Payload: payload(5000, 0xA)}, []uint8{0, 3}},
+		{RTMPMessage{ChunkStreamID: 8, Timestamp: 0x02000000, TypeID: MessageTypeIDData, StreamID: 1, Payload: payload(5000, 0xB)}, []uint8{3, 3}}, // delta same as fmt0 timestamp
+		{RTMPMessage{ChunkStreamID: 8, Timestamp: 0x03000000, TypeID: MessageTypeIDData, StreamID: 1, Payload: payload(5000, 0xC)}, []uint8{3, 3}},
+		// 2 and 3 bytes basic header, empty payload
+		{RTMPMessage{ChunkStreamID: 300, Timestamp: 0, TypeID: MessageTypeIDCommand, StreamID: 0, Payload: payload(10, 0xD)}, []uint8{0}},
+		{RTMPMessage{ChunkStreamID: 65599, Timestamp: 0, TypeID: MessageTypeIDCommand, StreamID: 0, Payload: nil}, []uint8{0}},
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for i := range cases {
+		if err := w.WriteMessage(&cases[i].m); err != nil {
+			t.Fatalf("write message %d expect nil but got %v", i, err)
+		}
+	}
+	if w.ChunkSize() != 4096 {
+		t.Errorf("expect chunk size 4096 but got %d", w.ChunkSize())
+	}
+	data := buf.Bytes()
+
+	// chunks
+	r := NewReader(bytes.NewReader(data))
+	for i, c := range cases {
+		for j, f := range c.fmt {
+			chunk, err := r.ReadChunk()
+			if err != nil {
+				t.Fatalf("read chunk %d of message %d expect nil but got %v", j, i, err)
+			}
+			if chunk.BasicHeader.Fmt != f || chunk.BasicHeader.StreamID != c.m.ChunkStreamID {
+				t.Errorf("chunk %d of message %d expect fmt %d chunk stream %d but got %+v", j, i, f, c.m.ChunkStreamID, chunk.BasicHeader)
+			}
+		}
+		if c.m.TypeID == MessageTypeIDSetPacketSize {
+			r.SetChunkSize(binary.BigEndian.Uint32(c.m.Payload))
+		}
+	}
+	if _, err := r.ReadChunk(); err != io.EOF {
+		t.Errorf("expect EOF but got %v", err)
+	}
+
+	// messages
+	r = NewReader(bytes.NewReader(data))
+	for i, c := range cases {
+		m, err := r.ReadMessage()
+		if err != nil {
+			t.Fatalf("read message %d expect nil but got %v", i, err)
+		}
+		if !reflect.DeepEqual(*m, c.m) {
+			t.Errorf("message %d expect %+v but got %+v", i, c.m, *m)
+		}
+	}
+
+	if err := w.WriteMessage(&RTMPMessage{ChunkStreamID: 1}); err == nil {
+		t.Errorf("expect error for chunk stream id 1 but got nil")
+	}
+}
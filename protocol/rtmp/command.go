@@ -0,0 +1,129 @@
+package rtmp
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/wangyoucao577/medialib/util/amf/amf0"
+)
+
+// command names
+const (
+	CommandConnect       = "connect"
+	CommandResult        = "_result"
+	CommandError         = "_error"
+	CommandOnStatus      = "onStatus"
+	CommandReleaseStream = "releaseStream"
+	CommandFCPublish     = "FCPublish"
+	CommandFCUnpublish   = "FCUnpublish"
+	CommandCreateStream  = "createStream"
+	CommandDeleteStream  = "deleteStream"
+	CommandPublish       = "publish"
+
+	// SetDataFrame is the data message name to set metadata of a published stream, followed by `onMetaData` and its value.
+	SetDataFrame = "@setDataFrame"
+)
+
+// status levels and codes of onStatus command
+const (
+	StatusLevelStatus = "status"
+	StatusLevelError  = "error"
+
+	StatusCodePublishStart = "NetStream.Publish.Start"
+)
+
+// Command represents an AMF0 command message.
+type Command struct {
+	Name          string           `json:"name"`
+	TransactionID float64          `json:"transaction_id"`
+	Object        amf0.ValueType   `json:"object"` // command object, null if not exist
+	Arguments     []amf0.ValueType `json:"arguments,omitempty"`
+}
+
+// newCommand creates command with null command object if not specified.
+func newCommand(name string, transactionID float64, object *amf0.ValueType, arguments ...amf0.ValueType) *Command {
+	c := &Command{Name: name, TransactionID: transactionID, Object: amf0.NewNull(), Arguments: arguments}
+	if object != nil {
+		c.Object = *object
+	}
+	return c
+}
+
+// Encode encodes command to AMF0 message payload.
+func (c *Command) Encode() ([]byte, error) {
+	var buf bytes.Buffer
+	values := append([]amf0.ValueType{amf0.NewString(c.Name), amf0.NewNumber(c.TransactionID), c.Object}, c.Arguments...)
+	for _, v := range values {
+		if _, err := v.Encode(&buf); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseCommand parses AMF0 command message payload.
+func ParseCommand(payload []byte) (*Command, error) {
+	r := bytes.NewReader(payload)
+
+	var values []amf0.ValueType
+	for r.Len() > 0 {
+		v := amf0.ValueType{}
+		if _, err := v.Decode(r); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	if len(values) < 2 {
+		return nil, fmt.Errorf("command expect at least name and transaction id but got %d values", len(values))
+	}
+
+	c := &Command{Object: amf0.NewNull()}
+	var err error
+	if c.Name, err = values[0].AsString(); err != nil {
+		return nil, fmt.Errorf("invalid command name, err %v", err)
+	}
+	if c.TransactionID, err = values[1].AsNumber(); err != nil {
+		return nil, fmt.Errorf("invalid command %s transaction id, err %v", c.Name, err)
+	}
+	if len(values) > 2 {
+		c.Object = values[2]
+	}
+	if len(values) > 3 {
+		c.Arguments = values[3:]
+	}
+	return c, nil
+}
+
+// Argument returns the i-th argument after command object, or AMF0 undefined if not exist.
+func (c *Command) Argument(i int) amf0.ValueType {
+	if i < 0 || i >= len(c.Arguments) {
+		return amf0.NewUndefined()
+	}
+	return c.Arguments[i]
+}
+
+// StatusCode returns `level` and `code` of the info object of onStatus, _result or _error command.
+func (c *Command) StatusCode() (level, code string) {
+	info := c.Argument(0)
+	if p, ok := lookupProperty(info, "level"); ok {
+		level, _ = p.AsString()
+	}
+	if p, ok := lookupProperty(info, "code"); ok {
+		code, _ = p.AsString()
+	}
+	return
+}
+
+// lookupProperty looks up property value by name if v is an object or ECMA array.
+func lookupProperty(v amf0.ValueType, name string) (amf0.ValueType, bool) {
+	properties, err := v.AsProperties()
+	if err != nil {
+		return amf0.ValueType{}, false
+	}
+	for _, p := range properties {
+		if p.String.Str == name {
+			return p.ValueType, true
+		}
+	}
+	return amf0.ValueType{}, false
+}
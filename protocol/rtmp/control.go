@@ -0,0 +1,95 @@
+package rtmp
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/wangyoucao577/medialib/protocol/rtmp/chunk"
+)
+
+// chunk stream ids for different kinds of messages
+const (
+	chunkStreamIDProtocolControl = 2 // fixed by spec
+	chunkStreamIDCommand         = 3
+	chunkStreamIDAudio           = 4
+	chunkStreamIDData            = 5
+	chunkStreamIDVideo           = 6
+)
+
+// message stream id of protocol control, user control and NetConnection command messages
+const controlStreamID = 0
+
+// default values of a connection
+const (
+	outgoingChunkSize    = 4096
+	defaultWindowAckSize = 2500000
+)
+
+// user control message event types
+const (
+	UserControlStreamBegin      = 0
+	UserControlStreamEOF        = 1
+	UserControlStreamDry        = 2
+	UserControlSetBufferLength  = 3
+	UserControlStreamIsRecorded = 4
+	UserControlPingRequest      = 6
+	UserControlPingResponse     = 7
+)
+
+// limit types of Set Peer Bandwidth message
+const (
+	PeerBandwidthLimitHard    = 0
+	PeerBandwidthLimitSoft    = 1
+	PeerBandwidthLimitDynamic = 2
+)
+
+// newProtocolControlMessage creates protocol control message with 4 bytes value.
+func newProtocolControlMessage(typeID uint8, value uint32) *chunk.RTMPMessage {
+	m := &chunk.RTMPMessage{
+		ChunkStreamID: chunkStreamIDProtocolControl,
+		TypeID:        typeID,
+		StreamID:      controlStreamID,
+		Payload:       make([]byte, 4),
+	}
+	binary.BigEndian.PutUint32(m.Payload, value)
+	return m
+}
+
+// newSetPeerBandwidthMessage creates Set Peer Bandwidth message.
+func newSetPeerBandwidthMessage(size uint32, limitType uint8) *chunk.RTMPMessage {
+	m := newProtocolControlMessage(chunk.MessageTypeIDClientBandwidth, size)
+	m.Payload = append(m.Payload, limitType)
+	return m
+}
+
+// newUserControlMessage creates User Control message with event type and data.
+func newUserControlMessage(eventType uint16, data ...uint32) *chunk.RTMPMessage {
+	m := &chunk.RTMPMessage{
+		ChunkStreamID: chunkStreamIDProtocolControl,
+		TypeID:        chunk.MessageTypeIDControl,
+		StreamID:      controlStreamID,
+		Payload:       make([]byte, 2+4*len(data)),
+	}
+	binary.BigEndian.PutUint16(m.Payload, eventType)
+	for i, d := range data {
+		binary.BigEndian.PutUint32(m.Payload[2+4*i:], d)
+	}
+	return m
+}
+
+// parseUint32Payload parses 4 bytes value of protocol control message.
+func parseUint32Payload(m *chunk.RTMPMessage) (uint32, error) {
+	if len(m.Payload) < 4 {
+		return 0, fmt.Errorf("message type %d(%s) expect at least 4 bytes but got %d",
+			m.TypeID, chunk.MessageTypeIDDescription(int(m.TypeID)), len(m.Payload))
+	}
+	return binary.BigEndian.Uint32(m.Payload), nil
+}
+
+// parseUserControl parses event type and event data of User Control message.
+func parseUserControl(m *chunk.RTMPMessage) (uint16, []byte, error) {
+	if len(m.Payload) < 2 {
+		return 0, nil, fmt.Errorf("user control message expect at least 2 bytes but got %d", len(m.Payload))
+	}
+	return binary.BigEndian.Uint16(m.Payload), m.Payload[2:], nil
+}
@@ -2,6 +2,7 @@
 package rtmp
 
 import (
+	"bytes"
 	"fmt"
 	"net"
 	"net/url"
@@ -10,17 +11,35 @@ import (
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/container/flv"
 	"github.com/wangyoucao577/medialib/protocol"
+	"github.com/wangyoucao577/medialib/protocol/rtmp/chunk"
 )
 
-// Handler represents handler for `flv` structure.
+// Handler represents RTMP client connection.
 type Handler struct {
 	rawURL string
 
 	url        *url.URL
-	serverHost string   // server host or ip address, without port
-	serverPort uint     // server port, 1935 by default
-	conn       net.Conn // conection after dial
+	serverHost string // server host or ip address, without port
+	serverPort uint   // server port, 1935 by default
+	app        string // application name, i.e., path of URL except the last part
+	streamName string // stream name, i.e., the last part of URL path with query
+	tcURL      string // target URL with application, sent by connect command
+
+	session
+
+	streamID   uint32 // message stream id created by createStream
+	publishing bool
+	playing    bool
+
+	pending     []*chunk.RTMPMessage // received media messages of the playing stream
+	tagBuf      *bytes.Buffer        // FLV bytes of the latest received message for tagReader
+	tagReader   *flv.Reader          // parses received messages as tags
+	lastTagSize uint32
+
+	playDuration        time.Duration // stop playing once timestamps of audio/video tags reach it, 0 means no limit
+	firstMediaTimestamp *int32
 }
 
 // NewHandler creates RTMP Handler.
@@ -33,7 +52,7 @@ func NewHandler(url string) (*Handler, error) {
 	return &h, nil
 }
 
-// Connect connects rtmp server.
+// Connect connects rtmp server, i.e., handshake then connect to the application.
 func (h *Handler) Connect(timeout time.Duration) error {
 	startTime := time.Now()
 	serverAddress := net.JoinHostPort(h.serverHost, strconv.FormatInt(int64(h.serverPort), 10))
@@ -45,7 +64,7 @@ func (h *Handler) Connect(timeout time.Duration) error {
 	h.conn = conn
 
 	// timeout for the connecting
-	if err = h.conn.SetWriteDeadline(startTime.Add(timeout)); err != nil {
+	if err = h.conn.SetDeadline(startTime.Add(timeout)); err != nil {
 		return err
 	}
 
@@ -54,6 +73,15 @@ func (h *Handler) Connect(timeout time.Duration) error {
 		return err
 	}
 
+	h.reader = chunk.NewReader(h.conn)
+	h.writer = chunk.NewWriter(h.conn)
+	if err = h.connectApp(); err != nil {
+		return err
+	}
+	if err = h.conn.SetDeadline(time.Time{}); err != nil {
+		return err
+	}
+
 	glog.Infof("connected %s, takes %f seconds", serverAddress, time.Since(startTime).Seconds())
 	return nil
 }
@@ -61,6 +89,9 @@ func (h *Handler) Connect(timeout time.Duration) error {
 // Close closes the handler.
 func (h *Handler) Close() {
 	if h.conn != nil {
+		if h.streamID != 0 {
+			h.closeStream()
+		}
 		if err := h.conn.Close(); err != nil {
 			glog.Warning(err)
 		}
@@ -100,5 +131,20 @@ func (h *Handler) parseURL() error {
 		h.serverPort = protocol.PortRTMP // default port for rtmp
 	}
 
+	path := strings.Split(strings.Trim(h.url.Path, "/"), "/")
+	if len(path) == 1 {
+		h.app = path[0]
+	} else {
+		h.app = strings.Join(path[:len(path)-1], "/")
+		h.streamName = path[len(path)-1]
+		if len(h.url.RawQuery) > 0 {
+			h.streamName += "?" + h.url.RawQuery
+		}
+	}
+	if len(h.app) == 0 {
+		return fmt.Errorf("empty application in url %s", h.rawURL)
+	}
+	h.tcURL = h.url.Scheme + "://" + h.url.Host + "/" + h.app
+
 	return nil
 }
@@ -5,9 +5,11 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/util"
 )
 
 func (h *Handler) handshark() error {
@@ -118,3 +120,50 @@ func (h *Handler) handshark() error {
 
 	return nil
 }
+
+// serverHandshark handshakes as server, i.e., receives c0+c1, sends s0+s1+s2, then receives c2.
+func serverHandshark(conn net.Conn) error {
+	const version = 0x3 // fixed rtmp version 3
+	const packetSize = 1536
+
+	// recv c0+c1
+	c0c1 := make([]byte, 1+packetSize)
+	if err := util.ReadOrError(conn, c0c1); err != nil {
+		return err
+	}
+	c0c1ReadTimestamp := time.Now().UnixMilli()
+	glog.V(1).Infof("recved c0 version 0x%x, c1 timestamp %d", c0c1[0], binary.BigEndian.Uint32(c0c1[1:5]))
+	if c0c1[0] != version {
+		return fmt.Errorf("unsupported client rtmp version 0x%x", c0c1[0])
+	}
+
+	// send s0+s1+s2
+	s0s1s2 := make([]byte, 1+packetSize*2)
+	s0s1s2[0] = version
+	s1 := s0s1s2[1 : 1+packetSize] // timestamp + 4 zero bytes + random 1528 bytes
+	binary.BigEndian.PutUint32(s1, uint32(time.Now().UnixMilli()))
+	if _, err := rand.Read(s1[8:]); err != nil {
+		return err
+	}
+	s2 := s0s1s2[1+packetSize:] // c1 timestamp + timestamp when c1 read + c1 random data
+	copy(s2, c0c1[1:])
+	binary.BigEndian.PutUint32(s2[4:], uint32(c0c1ReadTimestamp))
+	glog.V(1).Infof("send s0 version 0x%x, s1 timestamp %d", version, binary.BigEndian.Uint32(s1))
+	if n, err := conn.Write(s0s1s2); err != nil {
+		return err
+	} else if n != len(s0s1s2) {
+		return fmt.Errorf("connection write %d bytes but expect %d", n, len(s0s1s2))
+	}
+
+	// recv c2
+	c2 := make([]byte, packetSize)
+	if err := util.ReadOrError(conn, c2); err != nil {
+		return err
+	}
+	glog.V(1).Infof("recved c2 timestamp %d timestamp2 %d", binary.BigEndian.Uint32(c2[:4]), binary.BigEndian.Uint32(c2[4:8]))
+	if !bytes.Equal(s1[8:], c2[8:]) { // don't need check strictly
+		glog.Warningf("s1 and c2 random data not equal")
+	}
+
+	return nil
+}
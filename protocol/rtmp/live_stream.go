@@ -0,0 +1,242 @@
+package rtmp
+
+import (
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/container/flv/tag/audio"
+	"github.com/wangyoucao577/medialib/container/flv/tag/video"
+	"github.com/wangyoucao577/medialib/protocol/rtmp/chunk"
+)
+
+// limits of relaying
+const (
+	playerQueueSize   = 8192 // messages queued for a player, the player will be dropped if exceeded
+	maxGOPCacheLength = 4096 // messages cached since the latest key frame, the GOP won't be cached if exceeded
+)
+
+// player represents a playing message stream of a server connection.
+type player struct {
+	conn     *serverConn
+	streamID uint32
+	out      chan *chunk.RTMPMessage // messages to be sent, closed once the player removed
+}
+
+// liveStream represents a stream which is published or played on Server.
+// Metadata, the latest sequence headers and messages since the latest video key frame are cached for players joining later.
+type liveStream struct {
+	key       string
+	publisher *serverConn
+	players   map[*player]struct{}
+
+	metadata            *chunk.RTMPMessage
+	videoSequenceHeader *chunk.RTMPMessage
+	audioSequenceHeader *chunk.RTMPMessage
+	gop                 []*chunk.RTMPMessage
+}
+
+// cachedMessages returns cached messages in order of sending.
+func (ls *liveStream) cachedMessages() []*chunk.RTMPMessage {
+	var messages []*chunk.RTMPMessage
+	for _, m := range []*chunk.RTMPMessage{ls.metadata, ls.videoSequenceHeader, ls.audioSequenceHeader} {
+		if m != nil {
+			messages = append(messages, m)
+		}
+	}
+	return append(messages, ls.gop...)
+}
+
+// cache caches the message if it's metadata, sequence header or within GOP.
+func (ls *liveStream) cache(m *chunk.RTMPMessage) {
+	switch m.TypeID {
+	case chunk.MessageTypeIDData:
+		if _, ok := metadataPayload(m.Payload); ok {
+			ls.metadata = m
+		}
+		return
+	case chunk.MessageTypeIDVideoPacket:
+		if isVideoSequenceHeader(m.Payload) {
+			ls.videoSequenceHeader = m
+			return
+		}
+		if isVideoKeyFrame(m.Payload) {
+			ls.gop = []*chunk.RTMPMessage{m}
+			return
+		}
+	case chunk.MessageTypeIDAudioPacket:
+		if isAACSequenceHeader(m.Payload) {
+			ls.audioSequenceHeader = m
+			return
+		}
+	}
+
+	if len(ls.gop) == 0 { // wait for key frame
+		return
+	}
+	if len(ls.gop) >= maxGOPCacheLength {
+		glog.Warningf("gop of %s exceeds %d messages, drop the cache until next key frame", ls.key, maxGOPCacheLength)
+		ls.gop = nil
+		return
+	}
+	ls.gop = append(ls.gop, m)
+}
+
+// resetCache clears all cached messages.
+func (ls *liveStream) resetCache() {
+	ls.metadata, ls.videoSequenceHeader, ls.audioSequenceHeader, ls.gop = nil, nil, nil, nil
+}
+
+// send queues the message to the player with player's message stream id,
+// it returns false if the queue is full.
+func (p *player) send(m *chunk.RTMPMessage) bool {
+	pm := *m
+	pm.StreamID = p.streamID
+	select {
+	case p.out <- &pm:
+		return true
+	default:
+		return false
+	}
+}
+
+// sendCommand queues the command to the player.
+func (p *player) sendCommand(c *Command) bool {
+	payload, err := c.Encode()
+	if err != nil {
+		glog.Warning(err)
+		return false
+	}
+	return p.send(&chunk.RTMPMessage{ChunkStreamID: chunkStreamIDCommand, TypeID: chunk.MessageTypeIDCommand, Payload: payload})
+}
+
+// startPublish creates the stream if not exist then sets the publisher, it returns false if already published.
+func (s *Server) startPublish(key string, c *serverConn) (*liveStream, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ls := s.liveStream(key)
+	if ls.publisher != nil {
+		return nil, false
+	}
+	ls.publisher = c
+	ls.resetCache()
+	return ls, true
+}
+
+// stopPublish removes the publisher, then notifies players that the stream has been unpublished.
+func (s *Server) stopPublish(ls *liveStream, c *serverConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ls.publisher != c {
+		return
+	}
+	ls.publisher = nil
+	ls.resetCache()
+	for p := range ls.players {
+		status := newCommand(CommandOnStatus, 0, nil, serverStatus(StatusLevelStatus, StatusCodePlayUnpublishNotify, ls.key+" is now unpublished"))
+		if !p.sendCommand(status) || !p.send(newUserControlMessage(UserControlStreamEOF, p.streamID)) {
+			s.dropPlayer(ls, p)
+		}
+	}
+	s.removeIdleStream(ls)
+}
+
+// relay caches the message of publisher, then fans out it to players.
+// `@setDataFrame` will be removed from metadata so that players receive `onMetaData` directly.
+func (s *Server) relay(ls *liveStream, m *chunk.RTMPMessage) {
+	if m.TypeID == chunk.MessageTypeIDData {
+		if payload, ok := metadataPayload(m.Payload); ok {
+			stripped := *m
+			stripped.Payload = payload
+			m = &stripped
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ls.cache(m)
+	for p := range ls.players {
+		if !p.send(m) {
+			s.dropPlayer(ls, p)
+		}
+	}
+}
+
+// startPlay creates the stream if not exist, then queues cached messages to the player and adds it.
+func (s *Server) startPlay(key string, p *player) *liveStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ls := s.liveStream(key)
+	for _, m := range ls.cachedMessages() {
+		p.send(m) // queue is large enough for a cached GOP
+	}
+	ls.players[p] = struct{}{}
+	return ls
+}
+
+// stopPlay removes the player of the connection.
+func (s *Server) stopPlay(ls *liveStream, c *serverConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for p := range ls.players {
+		if p.conn == c {
+			delete(ls.players, p)
+			close(p.out)
+		}
+	}
+	s.removeIdleStream(ls)
+}
+
+// dropPlayer removes the player and closes its connection since it can't catch up, s.mu should be held.
+func (s *Server) dropPlayer(ls *liveStream, p *player) {
+	glog.Warningf("drop player %s of %s since its queue is full", p.conn.remoteAddr, ls.key)
+	delete(ls.players, p)
+	close(p.out)
+	p.conn.conn.Close()
+}
+
+// liveStream returns the stream by key, or creates it if not exist, s.mu should be held.
+func (s *Server) liveStream(key string) *liveStream {
+	ls, ok := s.streams[key]
+	if !ok {
+		ls = &liveStream{key: key, players: map[*player]struct{}{}}
+		s.streams[key] = ls
+	}
+	return ls
+}
+
+// removeIdleStream removes the stream once it's neither published nor played, s.mu should be held.
+func (s *Server) removeIdleStream(ls *liveStream) {
+	if ls.publisher == nil && len(ls.players) == 0 && s.streams[ls.key] == ls {
+		delete(s.streams, ls.key)
+	}
+}
+
+// isVideoSequenceHeader checks whether the video message is AVC sequence header or Enhanced RTMP SequenceStart.
+func isVideoSequenceHeader(payload []byte) bool {
+	if len(payload) < 2 {
+		return false
+	}
+	if payload[0]&0x80 != 0 { // IsExHeader
+		return payload[0]&0x0f == video.PacketTypeSequenceStart
+	}
+	return payload[0]&0x0f == video.CodecIDAVC && payload[1] == video.AVCPacketTypeSequenceHeader
+}
+
+// isVideoKeyFrame checks whether the video message is a key frame.
+func isVideoKeyFrame(payload []byte) bool {
+	if len(payload) < 1 {
+		return false
+	}
+	if payload[0]&0x80 != 0 { // IsExHeader, 3 bits FrameType
+		return (payload[0]>>4)&0x07 == video.FrameTypeKey
+	}
+	return payload[0]>>4 == video.FrameTypeKey
+}
+
+// isAACSequenceHeader checks whether the audio message is AAC sequence header.
+func isAACSequenceHeader(payload []byte) bool {
+	return len(payload) >= 2 && payload[0]>>4 == audio.SoundFormatAAC && payload[1] == audio.AACPacketTypeSequenceHeader
+}
@@ -0,0 +1,151 @@
+package rtmp
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/protocol/rtmp/chunk"
+)
+
+// session represents chunk streams over a connection, which is shared by client and server side.
+type session struct {
+	conn net.Conn // conection after dial or accept
+
+	reader  *chunk.Reader
+	writer  *chunk.Writer
+	writeMu sync.Mutex // messages may be written by different goroutines on server side
+
+	transactionID    float64 // latest used transaction id of commands
+	windowAckSize    uint32  // send Acknowledgement once received bytes reach the window
+	ackedBytes       int64   // received bytes when latest Acknowledgement sent
+	outWindowAckSize uint32  // window size requested by peer via Set Peer Bandwidth
+}
+
+// nextTransactionID returns a new transaction id for command.
+func (h *session) nextTransactionID() float64 {
+	h.transactionID++
+	return h.transactionID
+}
+
+// writeMessage writes the message by chunks.
+func (h *session) writeMessage(m *chunk.RTMPMessage) error {
+	h.writeMu.Lock()
+	defer h.writeMu.Unlock()
+	return h.writer.WriteMessage(m)
+}
+
+// writeCommand writes AMF0 command message to the message stream.
+func (h *session) writeCommand(streamID uint32, c *Command) error {
+	payload, err := c.Encode()
+	if err != nil {
+		return err
+	}
+	glog.V(1).Infof("send command %s transaction id %v on message stream %d", c.Name, c.TransactionID, streamID)
+	return h.writeMessage(&chunk.RTMPMessage{
+		ChunkStreamID: chunkStreamIDCommand,
+		TypeID:        chunk.MessageTypeIDCommand,
+		StreamID:      streamID,
+		Payload:       payload,
+	})
+}
+
+// readMessage reads the next message, protocol control and user control messages will be handled before returned.
+func (h *session) readMessage() (*chunk.RTMPMessage, error) {
+	m, err := h.reader.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.acknowledge(); err != nil {
+		return nil, err
+	}
+
+	switch m.TypeID {
+	case chunk.MessageTypeIDServerBandwidth: // Window Acknowledgement Size
+		if h.windowAckSize, err = parseUint32Payload(m); err != nil {
+			return nil, err
+		}
+		glog.V(1).Infof("window acknowledgement size %d", h.windowAckSize)
+	case chunk.MessageTypeIDClientBandwidth: // Set Peer Bandwidth
+		size, err := parseUint32Payload(m)
+		if err != nil {
+			return nil, err
+		}
+		if size != h.outWindowAckSize {
+			h.outWindowAckSize = size
+			if err := h.writeMessage(newProtocolControlMessage(chunk.MessageTypeIDServerBandwidth, size)); err != nil {
+				return nil, err
+			}
+		}
+	case chunk.MessageTypeIDControl: // User Control
+		event, data, err := parseUserControl(m)
+		if err != nil {
+			return nil, err
+		}
+		if event == UserControlPingRequest {
+			pong := newUserControlMessage(UserControlPingResponse)
+			pong.Payload = append(pong.Payload, data...)
+			if err := h.writeMessage(pong); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+// acknowledge sends Acknowledgement once received bytes reach the window size.
+func (h *session) acknowledge() error {
+	received := h.reader.BytesRead()
+	if h.windowAckSize == 0 || received-h.ackedBytes < int64(h.windowAckSize) {
+		return nil
+	}
+	h.ackedBytes = received
+	return h.writeMessage(newProtocolControlMessage(chunk.MessageTypeIDAcknowledge, uint32(received)))
+}
+
+// waitResult reads messages until _result or _error of the transaction, _error will be returned as error.
+func (h *Handler) waitResult(transactionID float64) (*Command, error) {
+	c, err := h.waitCommand(func(c *Command) bool {
+		return (c.Name == CommandResult || c.Name == CommandError) && c.TransactionID == transactionID
+	})
+	if err != nil {
+		return nil, err
+	}
+	if c.Name == CommandError {
+		level, code := c.StatusCode()
+		return nil, fmt.Errorf("transaction %v failed, level %s code %s", transactionID, level, code)
+	}
+	return c, nil
+}
+
+// waitCommand reads messages until a command matches, others will be ignored.
+func (h *Handler) waitCommand(match func(*Command) bool) (*Command, error) {
+	for {
+		m, err := h.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		switch m.TypeID {
+		case chunk.MessageTypeIDCommand:
+		case chunk.MessageTypeIDAudioPacket, chunk.MessageTypeIDVideoPacket, chunk.MessageTypeIDData, chunk.MessageTypeIDAggregate:
+			if err := h.handlePlayMessage(m); err != nil { // e.g., media messages arrive before onStatus of play
+				return nil, err
+			}
+			continue
+		default:
+			glog.V(2).Infof("ignore message type %d(%s) while waiting command", m.TypeID, chunk.MessageTypeIDDescription(int(m.TypeID)))
+			continue
+		}
+
+		c, err := ParseCommand(m.Payload)
+		if err != nil {
+			return nil, err
+		}
+		if !match(c) {
+			glog.V(1).Infof("ignore command %s transaction id %v", c.Name, c.TransactionID)
+			continue
+		}
+		return c, nil
+	}
+}
@@ -0,0 +1,258 @@
+package rtmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/container/flv"
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/protocol/rtmp/chunk"
+	"github.com/wangyoucao577/medialib/util/amf/amf0"
+)
+
+// command and status codes for playing
+const (
+	CommandPlay = "play"
+
+	StatusCodePlayStart           = "NetStream.Play.Start"
+	StatusCodePlayStop            = "NetStream.Play.Stop"
+	StatusCodePlayUnpublishNotify = "NetStream.Play.UnpublishNotify"
+)
+
+// default arguments for playing
+const (
+	playStartLiveOrRecorded        = -2   // start of play command, live stream first then recorded stream
+	playBufferLengthInMilliseconds = 3000 // sent by Set Buffer Length before play
+)
+
+// Play creates a stream and plays it by the stream name in URL, then tags can be received by ReadTag.
+func (h *Handler) Play(timeout time.Duration) error {
+	if h.conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	if len(h.streamName) == 0 {
+		return fmt.Errorf("empty stream name in url %s", h.rawURL)
+	}
+
+	if err := h.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	transactionID := h.nextTransactionID()
+	if err := h.writeCommand(controlStreamID, newCommand(CommandCreateStream, transactionID, nil)); err != nil {
+		return err
+	}
+	c, err := h.waitResult(transactionID)
+	if err != nil {
+		return fmt.Errorf("create stream failed, err %v", err)
+	}
+	streamID, err := c.Argument(0).AsNumber()
+	if err != nil {
+		return fmt.Errorf("invalid stream id of created stream, err %v", err)
+	}
+	h.streamID = uint32(streamID)
+
+	if err := h.writeMessage(newUserControlMessage(UserControlSetBufferLength, h.streamID, playBufferLengthInMilliseconds)); err != nil {
+		return err
+	}
+	play := newCommand(CommandPlay, 0, nil, amf0.NewString(h.streamName), amf0.NewNumber(playStartLiveOrRecorded))
+	if err := h.writeCommand(h.streamID, play); err != nil {
+		return err
+	}
+	if c, err = h.waitCommand(func(c *Command) bool {
+		level, code := c.StatusCode()
+		return c.Name == CommandOnStatus && (code == StatusCodePlayStart || level == StatusLevelError)
+	}); err != nil {
+		return err
+	}
+	if level, code := c.StatusCode(); level == StatusLevelError {
+		return fmt.Errorf("play %s failed, level %s code %s", h.streamName, level, code)
+	}
+	h.playing = true
+	glog.Infof("play %s on message stream %d", h.streamName, h.streamID)
+
+	return h.conn.SetDeadline(time.Time{})
+}
+
+// SetPlayDuration sets duration to stop playing, i.e., ReadTag returns io.EOF once timestamps of audio/video tags reach it.
+// 0 means playing until the stream stops.
+func (h *Handler) SetPlayDuration(duration time.Duration) {
+	h.playDuration = duration
+}
+
+// ReadMessage reads the next audio, video or data message of the playing stream,
+// aggregate messages will be split. It returns io.EOF once the stream stops.
+func (h *Handler) ReadMessage() (*chunk.RTMPMessage, error) {
+	if !h.playing {
+		return nil, fmt.Errorf("not playing")
+	}
+
+	for len(h.pending) == 0 {
+		m, err := h.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		if err := h.handlePlayMessage(m); err != nil {
+			return nil, err
+		}
+	}
+
+	m := h.pending[0]
+	h.pending = h.pending[1:]
+	return m, nil
+}
+
+// handlePlayMessage queues media messages of the playing stream, or returns io.EOF once the stream stops.
+func (h *Handler) handlePlayMessage(m *chunk.RTMPMessage) error {
+	switch m.TypeID {
+	case chunk.MessageTypeIDAudioPacket, chunk.MessageTypeIDVideoPacket, chunk.MessageTypeIDData:
+		if m.StreamID == h.streamID {
+			h.pending = append(h.pending, m)
+		}
+	case chunk.MessageTypeIDAggregate:
+		if m.StreamID != h.streamID {
+			return nil
+		}
+		messages, err := splitAggregate(m)
+		if err != nil {
+			return err
+		}
+		h.pending = append(h.pending, messages...)
+	case chunk.MessageTypeIDControl:
+		event, data, err := parseUserControl(m)
+		if err != nil {
+			return err
+		}
+		if len(data) >= 4 && binary.BigEndian.Uint32(data) == h.streamID {
+			if event == UserControlStreamBegin {
+				glog.V(1).Infof("stream %d begin", h.streamID)
+			} else if event == UserControlStreamEOF {
+				glog.Infof("stream %d EOF", h.streamID)
+				return io.EOF
+			}
+		}
+	case chunk.MessageTypeIDCommand:
+		c, err := ParseCommand(m.Payload)
+		if err != nil {
+			return err
+		}
+		if c.Name != CommandOnStatus {
+			glog.V(1).Infof("ignore command %s transaction id %v", c.Name, c.TransactionID)
+			return nil
+		}
+		level, code := c.StatusCode()
+		glog.V(1).Infof("onStatus level %s code %s", level, code)
+		if level == StatusLevelError {
+			return fmt.Errorf("play %s failed, level %s code %s", h.streamName, level, code)
+		}
+		if code == StatusCodePlayStop || code == StatusCodePlayUnpublishNotify {
+			glog.Infof("stream %s stopped, %s", h.streamName, code)
+			return io.EOF
+		}
+	}
+	return nil
+}
+
+// splitAggregate splits aggregate message into messages, i.e., FLV tags with back pointers.
+// Timestamps of sub-messages are adjusted to make the first one same as the aggregate message.
+func splitAggregate(m *chunk.RTMPMessage) ([]*chunk.RTMPMessage, error) {
+	var messages []*chunk.RTMPMessage
+	var firstTimestamp int32
+
+	r := bytes.NewReader(m.Payload)
+	for r.Len() > 0 {
+		header := tag.Header{}
+		if err := header.Parse(r); err != nil {
+			return nil, fmt.Errorf("parse aggregate sub-message header failed, err %v", err)
+		}
+		if int64(header.DataSize)+4 > int64(r.Len()) {
+			return nil, fmt.Errorf("aggregate sub-message size %d exceeds remain %d bytes", header.DataSize, r.Len())
+		}
+		if len(messages) == 0 {
+			firstTimestamp = header.TimestampCalculated
+		}
+
+		sub := &chunk.RTMPMessage{
+			ChunkStreamID: m.ChunkStreamID,
+			Timestamp:     m.Timestamp + uint32(header.TimestampCalculated-firstTimestamp),
+			TypeID:        header.TagType,
+			StreamID:      m.StreamID,
+			Payload:       make([]byte, header.DataSize),
+		}
+		if _, err := io.ReadFull(r, sub.Payload); err != nil {
+			return nil, err
+		}
+		if _, err := r.Seek(4, io.SeekCurrent); err != nil { // back pointer
+			return nil, err
+		}
+		messages = append(messages, sub)
+	}
+	return messages, nil
+}
+
+// ReadTag reads the next message of the playing stream and parses it as FLV tag.
+// Data messages except `onMetaData` (e.g., `|RtmpSampleAccess`) will be skipped. It returns io.EOF once the stream stops.
+func (h *Handler) ReadTag() (tag.Tag, error) {
+	if h.tagReader == nil {
+		h.tagBuf = &bytes.Buffer{}
+		if err := flv.NewHeader(true, true).Encode(h.tagBuf); err != nil {
+			return nil, err
+		}
+		h.tagReader = flv.NewReader(h.tagBuf)
+		h.lastTagSize = 0
+	}
+
+	for {
+		m, err := h.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+
+		payload := m.Payload
+		if m.TypeID == chunk.MessageTypeIDData {
+			var ok bool
+			if payload, ok = metadataPayload(payload); !ok {
+				glog.V(1).Infof("skip data message of %d bytes", len(m.Payload))
+				continue
+			}
+		}
+
+		// feed PreviousTagSize and the tag to FLV Reader, so that tags are parsed with decoder configurations
+		header := tag.NewHeader(m.TypeID, m.Timestamp)
+		header.DataSize = uint32(len(payload))
+		if err := binary.Write(h.tagBuf, binary.BigEndian, h.lastTagSize); err != nil {
+			return nil, err
+		}
+		if err := header.Encode(h.tagBuf); err != nil {
+			return nil, err
+		}
+		h.tagBuf.Write(payload)
+		h.lastTagSize = uint32(tag.HeaderSize + len(payload))
+
+		t, err := h.tagReader.Next()
+		if err != nil {
+			return nil, fmt.Errorf("parse message type %d timestamp %d as tag failed, err %v", m.TypeID, m.Timestamp, err)
+		}
+		if h.playDurationReached(t) {
+			glog.Infof("play duration %v reached", h.playDuration)
+			return nil, io.EOF
+		}
+		return t, nil
+	}
+}
+
+// playDurationReached checks whether the audio/video tag reaches play duration since the first one.
+func (h *Handler) playDurationReached(t tag.Tag) bool {
+	header := t.GetTagHeader()
+	if h.playDuration <= 0 || header.TagType == tag.TypeSriptData {
+		return false
+	}
+	if h.firstMediaTimestamp == nil {
+		h.firstMediaTimestamp = &header.TimestampCalculated
+	}
+	return time.Duration(header.TimestampCalculated-*h.firstMediaTimestamp)*time.Millisecond >= h.playDuration
+}
@@ -0,0 +1,185 @@
+package rtmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/wangyoucao577/medialib/container/flv"
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/protocol/rtmp/chunk"
+	"github.com/wangyoucao577/medialib/util/amf/amf0"
+)
+
+func TestPlay(t *testing.T) {
+	flvFile := "../../assets/sintel_trailer-720p-firstgopfmp4.flv"
+	data, err := os.ReadFile(flvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- servePlay(ln, data)
+	}()
+
+	h, err := NewHandler("rtmp://" + ln.Addr().String() + "/live/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Connect(5 * time.Second); err != nil {
+		t.Fatalf("connect expect nil but got %v", err)
+	}
+	if err := h.Play(5 * time.Second); err != nil {
+		t.Fatalf("play expect nil but got %v", err)
+	}
+
+	var buf bytes.Buffer
+	fw := flv.NewWriter(&buf)
+	if err := fw.WriteHeader(flv.NewHeader(true, true)); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		ft, err := h.ReadTag()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("read tag expect nil but got %v", err)
+		}
+		if err := fw.WriteTag(ft); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.Close()
+
+	if err := <-result; err != nil {
+		t.Fatalf("serve expect nil but got %v", err)
+	}
+	if !bytes.Equal(buf.Bytes()[flv.HeaderSize:], data[flv.HeaderSize:]) {
+		t.Errorf("recorded tags mismatch with %s", flvFile)
+	}
+}
+
+// servePlay accepts a connection and responds commands of playing, then sends tags of the FLV file as messages.
+// The last few tags are sent in an aggregate message.
+func servePlay(ln net.Listener, data []byte) error {
+	conn, r, w, err := acceptTestConn(ln)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for {
+		m, err := r.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if m.TypeID != chunk.MessageTypeIDCommand {
+			continue
+		}
+		c, err := ParseCommand(m.Payload)
+		if err != nil {
+			return err
+		}
+
+		switch c.Name {
+		case CommandConnect:
+			err = respondConnect(w, c)
+		case CommandCreateStream:
+			err = writeTestCommand(w, controlStreamID, newCommand(CommandResult, c.TransactionID, nil, amf0.NewNumber(1)))
+		case CommandPlay:
+			err = sendPlay(w, m.StreamID, data)
+		case CommandDeleteStream:
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func sendPlay(w *chunk.Writer, streamID uint32, data []byte) error {
+	if err := w.WriteMessage(newUserControlMessage(UserControlStreamBegin, streamID)); err != nil {
+		return err
+	}
+	if err := writeTestCommand(w, streamID, newCommand(CommandOnStatus, 0, nil, testStatus(StatusLevelStatus, "NetStream.Play.Reset"))); err != nil {
+		return err
+	}
+	if err := writeTestCommand(w, streamID, newCommand(CommandOnStatus, 0, nil, testStatus(StatusLevelStatus, StatusCodePlayStart))); err != nil {
+		return err
+	}
+
+	// |RtmpSampleAccess should be skipped
+	var access bytes.Buffer
+	for _, v := range []amf0.ValueType{amf0.NewString("|RtmpSampleAccess"), amf0.NewBoolean(true), amf0.NewBoolean(true)} {
+		if _, err := v.Encode(&access); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteMessage(&chunk.RTMPMessage{ChunkStreamID: chunkStreamIDData, TypeID: chunk.MessageTypeIDData, StreamID: streamID, Payload: access.Bytes()}); err != nil {
+		return err
+	}
+
+	// tags
+	type rawTag struct {
+		header tag.Header
+		data   []byte // whole tag
+	}
+	var tags []rawTag
+	for offset := int64(flv.HeaderSize + 4); offset < int64(len(data)); {
+		header := tag.Header{}
+		if err := header.Parse(bytes.NewReader(data[offset:])); err != nil {
+			return err
+		}
+		size := tag.HeaderSize + int64(header.DataSize)
+		tags = append(tags, rawTag{header: header, data: data[offset : offset+size]})
+		offset += size + 4
+	}
+
+	const aggregated = 5
+	for _, rt := range tags[:len(tags)-aggregated] {
+		m := &chunk.RTMPMessage{
+			ChunkStreamID: chunkStreamIDAudio,
+			Timestamp:     uint32(rt.header.TimestampCalculated),
+			TypeID:        rt.header.TagType,
+			StreamID:      streamID,
+			Payload:       rt.data[tag.HeaderSize:],
+		}
+		if m.TypeID == tag.TypeVideo {
+			m.ChunkStreamID = chunkStreamIDVideo
+		}
+		if err := w.WriteMessage(m); err != nil {
+			return err
+		}
+	}
+	aggregate := &chunk.RTMPMessage{
+		ChunkStreamID: chunkStreamIDVideo,
+		Timestamp:     uint32(tags[len(tags)-aggregated].header.TimestampCalculated),
+		TypeID:        chunk.MessageTypeIDAggregate,
+		StreamID:      streamID,
+	}
+	for _, rt := range tags[len(tags)-aggregated:] {
+		aggregate.Payload = append(aggregate.Payload, rt.data...)
+		backPointer := make([]byte, 4)
+		binary.BigEndian.PutUint32(backPointer, uint32(len(rt.data)))
+		aggregate.Payload = append(aggregate.Payload, backPointer...)
+	}
+	if err := w.WriteMessage(aggregate); err != nil {
+		return err
+	}
+
+	if err := w.WriteMessage(newUserControlMessage(UserControlStreamEOF, streamID)); err != nil {
+		return err
+	}
+	return writeTestCommand(w, streamID, newCommand(CommandOnStatus, 0, nil, testStatus(StatusLevelStatus, StatusCodePlayStop)))
+}
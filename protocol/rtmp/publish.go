@@ -0,0 +1,165 @@
+package rtmp
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/container/flv/tag/script"
+	"github.com/wangyoucao577/medialib/protocol/rtmp/chunk"
+	"github.com/wangyoucao577/medialib/util/amf/amf0"
+)
+
+// flashVer sent by connect command
+const flashVer = "FMLE/3.0 (compatible; medialib)"
+
+// publishType of publish command, i.e., live, record or append
+const publishType = "live"
+
+// connectApp sets outgoing chunk size, then connects to the application.
+func (h *Handler) connectApp() error {
+	if err := h.writeMessage(newProtocolControlMessage(chunk.MessageTypeIDSetPacketSize, outgoingChunkSize)); err != nil {
+		return err
+	}
+
+	transactionID := h.nextTransactionID()
+	object := amf0.NewObject(
+		amf0.NewObjectProperty("app", amf0.NewString(h.app)),
+		amf0.NewObjectProperty("type", amf0.NewString("nonprivate")),
+		amf0.NewObjectProperty("flashVer", amf0.NewString(flashVer)),
+		amf0.NewObjectProperty("tcUrl", amf0.NewString(h.tcURL)),
+	)
+	if err := h.writeCommand(controlStreamID, newCommand(CommandConnect, transactionID, &object)); err != nil {
+		return err
+	}
+
+	c, err := h.waitResult(transactionID)
+	if err != nil {
+		return fmt.Errorf("connect %s failed, err %v", h.tcURL, err)
+	}
+	_, code := c.StatusCode()
+	glog.Infof("connect %s, %s", h.tcURL, code)
+	return nil
+}
+
+// Publish creates a stream and publishes it by the stream name in URL, then tags can be sent by WriteTag.
+func (h *Handler) Publish(timeout time.Duration) error {
+	if h.conn == nil {
+		return fmt.Errorf("not connected")
+	}
+	if len(h.streamName) == 0 {
+		return fmt.Errorf("empty stream name in url %s", h.rawURL)
+	}
+
+	if err := h.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	stream := amf0.NewString(h.streamName)
+	if err := h.writeCommand(controlStreamID, newCommand(CommandReleaseStream, h.nextTransactionID(), nil, stream)); err != nil {
+		return err
+	}
+	if err := h.writeCommand(controlStreamID, newCommand(CommandFCPublish, h.nextTransactionID(), nil, stream)); err != nil {
+		return err
+	}
+	transactionID := h.nextTransactionID()
+	if err := h.writeCommand(controlStreamID, newCommand(CommandCreateStream, transactionID, nil)); err != nil {
+		return err
+	}
+	c, err := h.waitResult(transactionID)
+	if err != nil {
+		return fmt.Errorf("create stream failed, err %v", err)
+	}
+	streamID, err := c.Argument(0).AsNumber()
+	if err != nil {
+		return fmt.Errorf("invalid stream id of created stream, err %v", err)
+	}
+	h.streamID = uint32(streamID)
+
+	if err := h.writeCommand(h.streamID, newCommand(CommandPublish, 0, nil, stream, amf0.NewString(publishType))); err != nil {
+		return err
+	}
+	if c, err = h.waitCommand(func(c *Command) bool { return c.Name == CommandOnStatus }); err != nil {
+		return err
+	}
+	level, code := c.StatusCode()
+	if level == StatusLevelError || code != StatusCodePublishStart {
+		return fmt.Errorf("publish %s failed, level %s code %s", h.streamName, level, code)
+	}
+	h.publishing = true
+	glog.Infof("publish %s on message stream %d, %s", h.streamName, h.streamID, code)
+
+	return h.conn.SetDeadline(time.Time{})
+}
+
+// WriteTag sends raw data of FLV tag, i.e., DataSize bytes after tag header, to the published stream as audio, video or data message.
+// `onMetaData` will be sent with `@setDataFrame` so that the server keeps it for players.
+func (h *Handler) WriteTag(header tag.Header, data []byte) error {
+	if !h.publishing {
+		return fmt.Errorf("not publishing")
+	}
+	if header.Filter == 1 {
+		return fmt.Errorf("encrypted tag type %d(%s) is not supported", header.TagType, tag.TypeDescription(int(header.TagType)))
+	}
+
+	m := &chunk.RTMPMessage{Timestamp: uint32(header.TimestampCalculated), StreamID: h.streamID, Payload: data}
+	switch header.TagType {
+	case tag.TypeAudio:
+		m.ChunkStreamID, m.TypeID = chunkStreamIDAudio, chunk.MessageTypeIDAudioPacket
+	case tag.TypeVideo:
+		m.ChunkStreamID, m.TypeID = chunkStreamIDVideo, chunk.MessageTypeIDVideoPacket
+	case tag.TypeSriptData:
+		m.ChunkStreamID, m.TypeID = chunkStreamIDData, chunk.MessageTypeIDData
+		if payload, ok := metadataPayload(data); ok {
+			var buf bytes.Buffer
+			if _, err := amf0.NewString(SetDataFrame).Encode(&buf); err != nil {
+				return err
+			}
+			m.Payload = append(buf.Bytes(), payload...)
+		}
+	default:
+		return fmt.Errorf("invalid tag type %d", header.TagType)
+	}
+
+	return h.writeMessage(m)
+}
+
+// closeStream stops publishing or playing and deletes the stream, errors are ignored since the connection will be closed.
+func (h *Handler) closeStream() {
+	if err := h.conn.SetWriteDeadline(time.Now().Add(time.Second)); err != nil {
+		glog.Warning(err)
+	}
+
+	if h.publishing {
+		if err := h.writeCommand(controlStreamID, newCommand(CommandFCUnpublish, h.nextTransactionID(), nil, amf0.NewString(h.streamName))); err != nil {
+			glog.Warning(err)
+			return
+		}
+	}
+	if err := h.writeCommand(controlStreamID, newCommand(CommandDeleteStream, h.nextTransactionID(), nil, amf0.NewNumber(float64(h.streamID)))); err != nil {
+		glog.Warning(err)
+	}
+	h.publishing, h.playing, h.streamID = false, false, 0
+}
+
+// metadataPayload returns payload starts from `onMetaData`, `@setDataFrame` will be removed if exist.
+func metadataPayload(payload []byte) ([]byte, bool) {
+	for {
+		r := bytes.NewReader(payload)
+		name := amf0.ValueType{}
+		if _, err := name.Decode(r); err != nil {
+			return nil, false
+		}
+		str, _ := name.AsString()
+		switch str {
+		case script.OnMetaData:
+			return payload, true
+		case SetDataFrame:
+			payload = payload[len(payload)-r.Len():]
+		default:
+			return nil, false
+		}
+	}
+}
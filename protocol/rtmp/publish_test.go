@@ -0,0 +1,258 @@
+package rtmp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/wangyoucao577/medialib/container/flv"
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/protocol/rtmp/chunk"
+	"github.com/wangyoucao577/medialib/util"
+	"github.com/wangyoucao577/medialib/util/amf/amf0"
+)
+
+func TestPublish(t *testing.T) {
+	flvFile := "../../assets/sintel_trailer-720p-firstgopfmp4.flv"
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	type served struct {
+		commands []*Command
+		messages []*chunk.RTMPMessage
+		err      error
+	}
+	result := make(chan served, 1)
+	go func() {
+		var s served
+		s.commands, s.messages, s.err = servePublish(ln)
+		result <- s
+	}()
+
+	h, err := NewHandler("rtmp://" + ln.Addr().String() + "/live/test?key=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.app != "live" || h.streamName != "test?key=1" || h.tcURL != "rtmp://"+ln.Addr().String()+"/live" {
+		t.Errorf("unexpected app %s stream %s tcUrl %s", h.app, h.streamName, h.tcURL)
+	}
+	if err := h.Connect(5 * time.Second); err != nil {
+		t.Fatalf("connect expect nil but got %v", err)
+	}
+	if err := h.Publish(5 * time.Second); err != nil {
+		t.Fatalf("publish expect nil but got %v", err)
+	}
+	var tags []tag.Tag
+	var offsets []int64
+	fr := flv.NewReader(mustOpen(t, flvFile))
+	for {
+		ft, err := fr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.WriteTag(ft.GetTagHeader(), fr.TagData()); err != nil {
+			t.Fatalf("write tag expect nil but got %v", err)
+		}
+		tags = append(tags, ft)
+		offsets = append(offsets, fr.TagOffset())
+	}
+	// raw data will be forwarded as is even if it can't be parsed, e.g., MP3
+	mp3 := []byte{0x2f, 0xff, 0xfb, 0x90, 0x64, 0x00}
+	mp3Header := tag.NewHeader(tag.TypeAudio, uint32(tags[len(tags)-1].GetTagHeader().TimestampCalculated))
+	mp3Header.DataSize = uint32(len(mp3))
+	if err := h.WriteTag(mp3Header, mp3); err != nil {
+		t.Fatalf("write mp3 tag expect nil but got %v", err)
+	}
+	h.Close()
+
+	s := <-result
+	if s.err != nil {
+		t.Fatalf("serve expect nil but got %v", s.err)
+	}
+
+	var names []string
+	for _, c := range s.commands {
+		names = append(names, c.Name)
+	}
+	expectNames := []string{CommandConnect, CommandReleaseStream, CommandFCPublish, CommandCreateStream, CommandPublish, CommandFCUnpublish, CommandDeleteStream}
+	if len(names) != len(expectNames) {
+		t.Fatalf("expect commands %v but got %v", expectNames, names)
+	}
+	for i := range names {
+		if names[i] != expectNames[i] {
+			t.Errorf("expect commands %v but got %v", expectNames, names)
+			break
+		}
+	}
+	if app, ok := lookupProperty(s.commands[0].Object, "app"); !ok {
+		t.Errorf("connect expect app but got %+v", s.commands[0].Object)
+	} else if str, _ := app.AsString(); str != "live" {
+		t.Errorf("connect expect app live but got %s", str)
+	}
+	if str, _ := s.commands[4].Argument(0).AsString(); str != "test?key=1" {
+		t.Errorf("publish expect stream name test?key=1 but got %s", str)
+	}
+
+	data, err := os.ReadFile(flvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.messages) != len(tags)+1 {
+		t.Fatalf("expect %d messages but got %d", len(tags)+1, len(s.messages))
+	}
+	if m := s.messages[len(tags)]; m.TypeID != tag.TypeAudio || !bytes.Equal(m.Payload, mp3) {
+		t.Errorf("mp3 message expect type %d payload %x but got type %d payload %x", tag.TypeAudio, mp3, m.TypeID, m.Payload)
+	}
+	for i, m := range s.messages[:len(tags)] {
+		header := tags[i].GetTagHeader()
+		if m.TypeID != header.TagType || m.Timestamp != uint32(header.TimestampCalculated) || m.StreamID != 1 {
+			t.Errorf("message %d expect type %d timestamp %d stream 1 but got type %d timestamp %d stream %d",
+				i, header.TagType, header.TimestampCalculated, m.TypeID, m.Timestamp, m.StreamID)
+		}
+
+		body := data[offsets[i]+tag.HeaderSize : offsets[i]+tags[i].Size()]
+		payload := m.Payload
+		if header.TagType == tag.TypeSriptData {
+			v := amf0.ValueType{}
+			r := bytes.NewReader(payload)
+			if _, err := v.Decode(r); err != nil {
+				t.Fatal(err)
+			}
+			if str, _ := v.AsString(); str != SetDataFrame {
+				t.Errorf("message %d expect %s but got %s", i, SetDataFrame, str)
+			}
+			payload = payload[len(payload)-r.Len():]
+		}
+		if !bytes.Equal(payload, body) {
+			t.Errorf("message %d payload mismatch with tag body", i)
+		}
+	}
+}
+
+func mustOpen(t *testing.T, path string) *os.File {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// acceptTestConn accepts a connection and handshakes as server.
+func acceptTestConn(ln net.Listener) (net.Conn, *chunk.Reader, *chunk.Writer, error) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+
+	c0c1 := make([]byte, 1537)
+	if err := util.ReadOrError(conn, c0c1); err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+	s0s1s2 := make([]byte, 1+1536+1536)
+	s0s1s2[0] = c0c1[0]
+	if _, err := rand.Read(s0s1s2[9:1537]); err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+	copy(s0s1s2[1537:], c0c1[1:])
+	if _, err := conn.Write(s0s1s2); err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+	if err := util.ReadOrError(conn, make([]byte, 1536)); err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+
+	return conn, chunk.NewReader(conn), chunk.NewWriter(conn), nil
+}
+
+func writeTestCommand(w *chunk.Writer, streamID uint32, c *Command) error {
+	payload, err := c.Encode()
+	if err != nil {
+		return err
+	}
+	return w.WriteMessage(&chunk.RTMPMessage{ChunkStreamID: chunkStreamIDCommand, TypeID: chunk.MessageTypeIDCommand, StreamID: streamID, Payload: payload})
+}
+
+func testStatus(level, code string) amf0.ValueType {
+	return amf0.NewObject(
+		amf0.NewObjectProperty("level", amf0.NewString(level)),
+		amf0.NewObjectProperty("code", amf0.NewString(code)),
+	)
+}
+
+// respondConnect responds connect command with window and chunk size settings.
+func respondConnect(w *chunk.Writer, c *Command) error {
+	for _, m := range []*chunk.RTMPMessage{
+		newProtocolControlMessage(chunk.MessageTypeIDServerBandwidth, defaultWindowAckSize),
+		newSetPeerBandwidthMessage(defaultWindowAckSize, PeerBandwidthLimitDynamic),
+		newProtocolControlMessage(chunk.MessageTypeIDSetPacketSize, outgoingChunkSize),
+	} {
+		if err := w.WriteMessage(m); err != nil {
+			return err
+		}
+	}
+	return writeTestCommand(w, controlStreamID, newCommand(CommandResult, c.TransactionID, nil, testStatus(StatusLevelStatus, "NetConnection.Connect.Success")))
+}
+
+// servePublish accepts a connection and responds commands of publishing, commands and media messages will be returned once deleteStream received.
+func servePublish(ln net.Listener) ([]*Command, []*chunk.RTMPMessage, error) {
+	conn, r, w, err := acceptTestConn(ln)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+
+	var commands []*Command
+	var messages []*chunk.RTMPMessage
+	for {
+		m, err := r.ReadMessage()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch m.TypeID {
+		case chunk.MessageTypeIDAudioPacket, chunk.MessageTypeIDVideoPacket, chunk.MessageTypeIDData:
+			messages = append(messages, m)
+			continue
+		case chunk.MessageTypeIDCommand:
+		default:
+			continue
+		}
+
+		c, err := ParseCommand(m.Payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		commands = append(commands, c)
+		switch c.Name {
+		case CommandConnect:
+			err = respondConnect(w, c)
+		case CommandCreateStream:
+			err = writeTestCommand(w, controlStreamID, newCommand(CommandResult, c.TransactionID, nil, amf0.NewNumber(1)))
+		case CommandPublish:
+			err = writeTestCommand(w, m.StreamID, newCommand(CommandOnStatus, 0, nil, testStatus(StatusLevelStatus, StatusCodePublishStart)))
+		case CommandDeleteStream:
+			return commands, messages, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
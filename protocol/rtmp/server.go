@@ -0,0 +1,413 @@
+package rtmp
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/wangyoucao577/medialib/protocol"
+	"github.com/wangyoucao577/medialib/protocol/rtmp/chunk"
+	"github.com/wangyoucao577/medialib/util/amf/amf0"
+)
+
+// command and status codes for server side
+const (
+	CommandCloseStream = "closeStream"
+
+	StatusCodeConnectSuccess     = "NetConnection.Connect.Success"
+	StatusCodeConnectRejected    = "NetConnection.Connect.Rejected"
+	StatusCodePublishBadName     = "NetStream.Publish.BadName"
+	StatusCodeUnpublish          = "NetStream.Unpublish.Success"
+	StatusCodePlayReset          = "NetStream.Play.Reset"
+	StatusCodePlayFailed         = "NetStream.Play.Failed"
+	StatusCodePlayStreamNotFound = "NetStream.Play.StreamNotFound"
+)
+
+// default values of server
+const (
+	serverHandsharkTimeout = 10 * time.Second
+	serverFMSVersion       = "FMS/3,0,1,123"
+	serverCapabilities     = 31
+)
+
+// Server represents a minimal RTMP server for ingest and relay,
+// i.e., accepts published streams and fans out them to players with GOP cache.
+type Server struct {
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[*serverConn]struct{}
+	streams   map[string]*liveStream // keyed by app/stream
+	closed    bool
+
+	wg sync.WaitGroup // serving connections
+}
+
+// NewServer creates RTMP Server.
+func NewServer() *Server {
+	return &Server{
+		listeners: map[net.Listener]struct{}{},
+		conns:     map[*serverConn]struct{}{},
+		streams:   map[string]*liveStream{},
+	}
+}
+
+// ListenAndServe listens on the TCP address then serves connections, port 1935 will be used if absent.
+func (s *Server) ListenAndServe(addr string) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, strconv.Itoa(protocol.PortRTMP))
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on the listener and serves each of them in a new goroutine.
+// It always closes the listener, and returns nil once the server closed.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ln.Close()
+		return fmt.Errorf("server closed")
+	}
+	s.listeners[ln] = struct{}{}
+	s.mu.Unlock()
+	glog.Infof("serve rtmp on %s", ln.Addr())
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, ln)
+		s.mu.Unlock()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		c := &serverConn{server: s}
+		c.conn = conn
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[c] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go c.serve()
+	}
+}
+
+// Close closes all listeners and connections, then waits until connections finished.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for ln := range s.listeners {
+		if err := ln.Close(); err != nil {
+			glog.Warning(err)
+		}
+	}
+	for c := range s.conns {
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+// serverConn represents a connection accepted by Server, which may publish or play a stream.
+type serverConn struct {
+	session
+	server *Server
+
+	remoteAddr   string
+	app          string // application of connect command
+	lastStreamID uint32 // latest message stream id created by createStream
+
+	publishing      *liveStream
+	publishStreamID uint32
+	playing         *liveStream
+	playStreamID    uint32
+}
+
+// serve handshakes and handles messages until the connection closed.
+func (c *serverConn) serve() {
+	defer c.close()
+
+	c.remoteAddr = c.conn.RemoteAddr().String()
+	glog.V(1).Infof("accept %s", c.remoteAddr)
+	if err := c.conn.SetDeadline(time.Now().Add(serverHandsharkTimeout)); err != nil {
+		glog.Warning(err)
+		return
+	}
+	if err := serverHandshark(c.conn); err != nil {
+		glog.Warningf("handshark with %s failed, err %v", c.remoteAddr, err)
+		return
+	}
+	if err := c.conn.SetDeadline(time.Time{}); err != nil {
+		glog.Warning(err)
+		return
+	}
+	c.reader = chunk.NewReader(c.conn)
+	c.writer = chunk.NewWriter(c.conn)
+
+	for {
+		m, err := c.readMessage()
+		if err != nil {
+			if err != io.EOF {
+				glog.V(1).Infof("read from %s failed, err %v", c.remoteAddr, err)
+			}
+			return
+		}
+		if err := c.handleMessage(m); err != nil {
+			glog.Warningf("handle message from %s failed, err %v", c.remoteAddr, err)
+			return
+		}
+	}
+}
+
+// close stops publishing or playing, then closes the connection.
+func (c *serverConn) close() {
+	if err := c.conn.SetWriteDeadline(time.Now().Add(time.Second)); err != nil {
+		glog.V(1).Info(err)
+	}
+	c.unpublish()
+	c.stopPlay()
+	c.conn.Close()
+
+	s := c.server
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
+	s.wg.Done()
+	glog.V(1).Infof("close %s", c.remoteAddr)
+}
+
+// handleMessage relays media messages of the publishing stream, or handles commands.
+func (c *serverConn) handleMessage(m *chunk.RTMPMessage) error {
+	switch m.TypeID {
+	case chunk.MessageTypeIDAudioPacket, chunk.MessageTypeIDVideoPacket, chunk.MessageTypeIDData:
+		if c.publishing != nil && m.StreamID == c.publishStreamID {
+			c.server.relay(c.publishing, m)
+		}
+	case chunk.MessageTypeIDAggregate:
+		if c.publishing == nil || m.StreamID != c.publishStreamID {
+			return nil
+		}
+		messages, err := splitAggregate(m)
+		if err != nil {
+			return err
+		}
+		for _, sub := range messages {
+			c.server.relay(c.publishing, sub)
+		}
+	case chunk.MessageTypeIDCommand:
+		command, err := ParseCommand(m.Payload)
+		if err != nil {
+			return err
+		}
+		return c.handleCommand(m.StreamID, command)
+	default:
+		glog.V(2).Infof("ignore message type %d(%s) from %s", m.TypeID, chunk.MessageTypeIDDescription(int(m.TypeID)), c.remoteAddr)
+	}
+	return nil
+}
+
+// handleCommand responds commands of connecting, publishing and playing.
+func (c *serverConn) handleCommand(streamID uint32, command *Command) error {
+	glog.V(1).Infof("recv command %s transaction id %v on message stream %d from %s", command.Name, command.TransactionID, streamID, c.remoteAddr)
+
+	switch command.Name {
+	case CommandConnect:
+		return c.handleConnect(command)
+	case CommandFCUnpublish:
+		c.unpublish() // responds onStatus only, the client may close once FCUnpublish sent
+	case CommandReleaseStream, CommandFCPublish:
+		if command.TransactionID == 0 {
+			return nil
+		}
+		return c.writeCommand(controlStreamID, newCommand(CommandResult, command.TransactionID, nil, amf0.NewUndefined()))
+	case CommandCreateStream:
+		c.lastStreamID++
+		return c.writeCommand(controlStreamID, newCommand(CommandResult, command.TransactionID, nil, amf0.NewNumber(float64(c.lastStreamID))))
+	case CommandPublish:
+		return c.handlePublish(streamID, command)
+	case CommandPlay:
+		return c.handlePlay(streamID, command)
+	case CommandDeleteStream, CommandCloseStream:
+		if command.Name == CommandDeleteStream {
+			id, err := command.Argument(0).AsNumber()
+			if err != nil {
+				return fmt.Errorf("invalid stream id of %s, err %v", command.Name, err)
+			}
+			streamID = uint32(id)
+		}
+		if c.publishing != nil && streamID == c.publishStreamID {
+			c.unpublish()
+		}
+		if c.playing != nil && streamID == c.playStreamID {
+			c.stopPlay()
+		}
+	default:
+		glog.V(1).Infof("ignore command %s from %s", command.Name, c.remoteAddr)
+	}
+	return nil
+}
+
+// handleConnect sets window and chunk size, then accepts the application.
+func (c *serverConn) handleConnect(command *Command) error {
+	if p, ok := lookupProperty(command.Object, "app"); ok {
+		c.app, _ = p.AsString()
+	}
+	c.app = strings.Trim(c.app, "/")
+	if len(c.app) == 0 {
+		glog.Warningf("reject connect from %s without app", c.remoteAddr)
+		return c.writeCommand(controlStreamID, newCommand(CommandError, command.TransactionID, nil, serverStatus(StatusLevelError, StatusCodeConnectRejected, "empty app")))
+	}
+
+	for _, m := range []*chunk.RTMPMessage{
+		newProtocolControlMessage(chunk.MessageTypeIDServerBandwidth, defaultWindowAckSize),
+		newSetPeerBandwidthMessage(defaultWindowAckSize, PeerBandwidthLimitDynamic),
+		newProtocolControlMessage(chunk.MessageTypeIDSetPacketSize, outgoingChunkSize),
+	} {
+		if err := c.writeMessage(m); err != nil {
+			return err
+		}
+	}
+
+	properties := amf0.NewObject(
+		amf0.NewObjectProperty("fmsVer", amf0.NewString(serverFMSVersion)),
+		amf0.NewObjectProperty("capabilities", amf0.NewNumber(serverCapabilities)),
+	)
+	info := serverStatus(StatusLevelStatus, StatusCodeConnectSuccess, "Connection succeeded.")
+	glog.Infof("%s connected to app %s", c.remoteAddr, c.app)
+	return c.writeCommand(controlStreamID, newCommand(CommandResult, command.TransactionID, &properties, info))
+}
+
+// handlePublish starts publishing if the stream hasn't been published by others.
+func (c *serverConn) handlePublish(streamID uint32, command *Command) error {
+	name, err := command.Argument(0).AsString()
+	if err != nil || len(name) == 0 {
+		return c.writeCommand(streamID, newCommand(CommandOnStatus, 0, nil, serverStatus(StatusLevelError, StatusCodePublishBadName, "empty stream name")))
+	}
+	if c.publishing != nil || c.playing != nil {
+		return c.writeCommand(streamID, newCommand(CommandOnStatus, 0, nil, serverStatus(StatusLevelError, StatusCodePublishBadName, "stream in use")))
+	}
+
+	key := c.streamKey(name)
+	ls, ok := c.server.startPublish(key, c)
+	if !ok {
+		glog.Warningf("reject publish %s from %s since already published", key, c.remoteAddr)
+		return c.writeCommand(streamID, newCommand(CommandOnStatus, 0, nil, serverStatus(StatusLevelError, StatusCodePublishBadName, key+" is already published")))
+	}
+	c.publishing, c.publishStreamID = ls, streamID
+	glog.Infof("%s publish %s on message stream %d", c.remoteAddr, key, streamID)
+
+	if err := c.writeMessage(newUserControlMessage(UserControlStreamBegin, streamID)); err != nil {
+		return err
+	}
+	return c.writeCommand(streamID, newCommand(CommandOnStatus, 0, nil, serverStatus(StatusLevelStatus, StatusCodePublishStart, key+" is now published")))
+}
+
+// handlePlay starts relaying the stream to this connection, cached messages will be sent at first.
+// The stream can be played before published, then messages arrive once publishing started.
+func (c *serverConn) handlePlay(streamID uint32, command *Command) error {
+	name, err := command.Argument(0).AsString()
+	if err != nil || len(name) == 0 {
+		return c.writeCommand(streamID, newCommand(CommandOnStatus, 0, nil, serverStatus(StatusLevelError, StatusCodePlayStreamNotFound, "empty stream name")))
+	}
+	if c.publishing != nil || c.playing != nil {
+		return c.writeCommand(streamID, newCommand(CommandOnStatus, 0, nil, serverStatus(StatusLevelError, StatusCodePlayFailed, "stream in use")))
+	}
+	key := c.streamKey(name)
+
+	if err := c.writeMessage(newUserControlMessage(UserControlStreamBegin, streamID)); err != nil {
+		return err
+	}
+	if err := c.writeCommand(streamID, newCommand(CommandOnStatus, 0, nil, serverStatus(StatusLevelStatus, StatusCodePlayReset, "Playing and resetting "+key))); err != nil {
+		return err
+	}
+	if err := c.writeCommand(streamID, newCommand(CommandOnStatus, 0, nil, serverStatus(StatusLevelStatus, StatusCodePlayStart, "Started playing "+key))); err != nil {
+		return err
+	}
+
+	p := &player{conn: c, streamID: streamID, out: make(chan *chunk.RTMPMessage, playerQueueSize)}
+	go c.sendMessages(p.out)
+	c.playing, c.playStreamID = c.server.startPlay(key, p), streamID
+	glog.Infof("%s play %s on message stream %d", c.remoteAddr, key, streamID)
+	return nil
+}
+
+// sendMessages writes messages to the player until the queue closed.
+// The connection will be closed once write failed, and remain messages will be dropped.
+func (c *serverConn) sendMessages(out <-chan *chunk.RTMPMessage) {
+	var err error
+	for m := range out {
+		if err != nil {
+			continue
+		}
+		if err = c.writeMessage(m); err != nil {
+			glog.V(1).Infof("write to %s failed, err %v", c.remoteAddr, err)
+			c.conn.Close()
+		}
+	}
+}
+
+// unpublish stops publishing if in progress.
+func (c *serverConn) unpublish() {
+	if c.publishing == nil {
+		return
+	}
+	glog.Infof("%s unpublish %s", c.remoteAddr, c.publishing.key)
+	c.server.stopPublish(c.publishing, c)
+	if err := c.writeCommand(c.publishStreamID, newCommand(CommandOnStatus, 0, nil, serverStatus(StatusLevelStatus, StatusCodeUnpublish, c.publishing.key+" is now unpublished"))); err != nil {
+		glog.V(1).Infof("write to %s failed, err %v", c.remoteAddr, err)
+	}
+	c.publishing, c.publishStreamID = nil, 0
+}
+
+// stopPlay stops playing if in progress.
+func (c *serverConn) stopPlay() {
+	if c.playing == nil {
+		return
+	}
+	glog.Infof("%s stop playing %s", c.remoteAddr, c.playing.key)
+	c.server.stopPlay(c.playing, c)
+	c.playing, c.playStreamID = nil, 0
+}
+
+// streamKey returns app/stream as key of live streams, query of the stream name will be removed.
+func (c *serverConn) streamKey(name string) string {
+	if i := strings.Index(name, "?"); i >= 0 {
+		name = name[:i]
+	}
+	return c.app + "/" + name
+}
+
+// serverStatus creates info object of onStatus, _result or _error command with description.
+func serverStatus(level, code, description string) amf0.ValueType {
+	return amf0.NewObject(
+		amf0.NewObjectProperty("level", amf0.NewString(level)),
+		amf0.NewObjectProperty("code", amf0.NewString(code)),
+		amf0.NewObjectProperty("description", amf0.NewString(description)),
+	)
+}
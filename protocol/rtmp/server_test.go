@@ -0,0 +1,191 @@
+package rtmp
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/wangyoucao577/medialib/container/flv"
+	"github.com/wangyoucao577/medialib/container/flv/tag"
+	"github.com/wangyoucao577/medialib/container/flv/tag/video"
+)
+
+func TestServer(t *testing.T) {
+	flvFile := "../../assets/sintel_trailer-720p-firstgopfmp4.flv"
+	data, err := os.ReadFile(flvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer()
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ln)
+	}()
+	url := "rtmp://" + ln.Addr().String() + "/live/test"
+
+	// player before publishing receives all tags
+	type recorded struct {
+		data []byte
+		err  error
+	}
+	early := make(chan recorded, 1)
+	earlyPlayer := connectTestPlayer(t, url)
+	go func() {
+		var r recorded
+		r.data, r.err = recordTestPlayer(earlyPlayer)
+		early <- r
+	}()
+
+	publisher, err := NewHandler(url + "?key=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.Connect(5 * time.Second); err != nil {
+		t.Fatalf("connect expect nil but got %v", err)
+	}
+	if err := publisher.Publish(5 * time.Second); err != nil {
+		t.Fatalf("publish expect nil but got %v", err)
+	}
+	var last tag.Header
+	fr := flv.NewReader(mustOpen(t, flvFile))
+	for {
+		ft, err := fr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if err := publisher.WriteTag(ft.GetTagHeader(), fr.TagData()); err != nil {
+			t.Fatalf("write tag expect nil but got %v", err)
+		}
+		last = ft.GetTagHeader()
+	}
+
+	// the stream can't be published twice
+	second, err := NewHandler(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Connect(5 * time.Second); err != nil {
+		t.Fatalf("connect expect nil but got %v", err)
+	}
+	if err := second.Publish(5 * time.Second); err == nil {
+		t.Errorf("publish twice expect error but got nil")
+	}
+	second.Close()
+
+	// player after publishing receives metadata, sequence headers and the cached GOP
+	cached := waitTestCache(t, s, "live/test", last)
+	latePlayer := connectTestPlayer(t, url)
+	var lateTags []tag.Tag
+	for i := 0; i < cached; i++ {
+		ft, err := latePlayer.ReadTag()
+		if err != nil {
+			t.Fatalf("read tag expect nil but got %v", err)
+		}
+		lateTags = append(lateTags, ft)
+	}
+	if lateTags[0].GetTagHeader().TagType != tag.TypeSriptData {
+		t.Errorf("expect metadata at first but got tag type %d", lateTags[0].GetTagHeader().TagType)
+	}
+	var keyFrame bool
+	for _, ft := range lateTags[1:] {
+		vt, ok := ft.(*video.Tag)
+		if !ok || (vt.VideoTagHeader.AVCPacketType != nil && *vt.VideoTagHeader.AVCPacketType == video.AVCPacketTypeSequenceHeader) {
+			continue
+		}
+		keyFrame = vt.VideoTagHeader.FrameType == video.FrameTypeKey
+		break
+	}
+	if !keyFrame {
+		t.Errorf("expect the cached GOP starts from key frame")
+	}
+
+	publisher.Close()
+	if _, err := latePlayer.ReadTag(); err != io.EOF {
+		t.Errorf("expect io.EOF once unpublished but got %v", err)
+	}
+	latePlayer.Close()
+
+	r := <-early
+	earlyPlayer.Close()
+	if r.err != nil {
+		t.Fatalf("record expect nil but got %v", r.err)
+	}
+	if !bytes.Equal(r.data[flv.HeaderSize:], data[flv.HeaderSize:]) {
+		t.Errorf("relayed tags mismatch with %s", flvFile)
+	}
+
+	s.Close()
+	if err := <-served; err != nil {
+		t.Errorf("serve expect nil once closed but got %v", err)
+	}
+	if len(s.streams) != 0 || len(s.conns) != 0 {
+		t.Errorf("expect no streams and connections once closed but got %d streams %d connections", len(s.streams), len(s.conns))
+	}
+}
+
+func connectTestPlayer(t *testing.T, url string) *Handler {
+	h, err := NewHandler(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Connect(5 * time.Second); err != nil {
+		t.Fatalf("connect expect nil but got %v", err)
+	}
+	if err := h.Play(5 * time.Second); err != nil {
+		t.Fatalf("play expect nil but got %v", err)
+	}
+	return h
+}
+
+// recordTestPlayer reads tags until the stream stops and returns them as FLV.
+func recordTestPlayer(h *Handler) ([]byte, error) {
+	var buf bytes.Buffer
+	fw := flv.NewWriter(&buf)
+	if err := fw.WriteHeader(flv.NewHeader(true, true)); err != nil {
+		return nil, err
+	}
+	for {
+		ft, err := h.ReadTag()
+		if err == io.EOF {
+			return buf.Bytes(), nil
+		} else if err != nil {
+			return nil, err
+		}
+		if err := fw.WriteTag(ft); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// waitTestCache waits until the last published tag has been cached, then returns count of cached messages.
+func waitTestCache(t *testing.T, s *Server, key string, last tag.Header) int {
+	for i := 0; i < 100; i++ {
+		s.mu.Lock()
+		var cached int
+		var done bool
+		if ls, ok := s.streams[key]; ok {
+			cached = len(ls.cachedMessages())
+			if n := len(ls.gop); n > 0 {
+				m := ls.gop[n-1]
+				done = m.TypeID == last.TagType && m.Timestamp == uint32(last.TimestampCalculated) && len(m.Payload) == int(last.DataSize)
+			}
+		}
+		s.mu.Unlock()
+		if done {
+			return cached
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("wait %s cached timeout", key)
+	return 0
+}
@@ -3,14 +3,15 @@ package amf0
 import (
 	"encoding/binary"
 	"io"
+	"math"
 
 	"github.com/wangyoucao577/medialib/util"
 )
 
 // Date reprsents AMF0 date type.
 type Date struct {
-	Timestamp uint64 `json:"timestamp"`
-	TimeZone  int16  `json:"time_zone"`
+	Timestamp float64 `json:"timestamp"` // milliseconds since epoch in UTC
+	TimeZone  int16   `json:"time_zone"`
 }
 
 // Decode implements decoder.
@@ -21,7 +22,7 @@ func (d *Date) Decode(r io.Reader) (int, error) {
 	if err := util.ReadOrError(r, data); err != nil {
 		return parsedBytes, err
 	} else {
-		d.Timestamp = binary.BigEndian.Uint64(data)
+		d.Timestamp = math.Float64frombits(binary.BigEndian.Uint64(data))
 		parsedBytes += 8
 	}
 
@@ -34,3 +35,11 @@ func (d *Date) Decode(r io.Reader) (int, error) {
 
 	return parsedBytes, nil
 }
+
+// Encode implements encoder.
+func (d Date) Encode(w io.Writer) (int, error) {
+	data := make([]byte, 10)
+	binary.BigEndian.PutUint64(data, math.Float64bits(d.Timestamp))
+	binary.BigEndian.PutUint16(data[8:], uint16(d.TimeZone))
+	return w.Write(data)
+}
@@ -40,3 +40,23 @@ func (e *ECMAArrayPayload) Decode(r io.Reader) (int, error) {
 	}
 	return parsedBytes, nil
 }
+
+// Encode implements encoder, the Count will be written as it is since it's only a hint.
+func (e ECMAArrayPayload) Encode(w io.Writer) (int, error) {
+	var encodedBytes int
+
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, e.Count)
+	if bytes, err := w.Write(data); err != nil {
+		return encodedBytes, err
+	} else {
+		encodedBytes += bytes
+	}
+
+	if bytes, err := encodeObjectProperties(w, e.ObjectProperty); err != nil {
+		return encodedBytes, err
+	} else {
+		encodedBytes += bytes
+	}
+	return encodedBytes, nil
+}
@@ -5,3 +5,7 @@ import "io"
 type decoder interface {
 	Decode(io.Reader) (int, error)
 }
+
+type encoder interface {
+	Encode(io.Writer) (int, error)
+}
@@ -0,0 +1,53 @@
+package amf0
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/wangyoucao577/medialib/util"
+)
+
+// LongStringPayload represents AMF0 Long String type payload, which is also used by XML Document type.
+type LongStringPayload struct {
+	Length uint32 `json:"length"`
+	Str    string `json:"string"`
+}
+
+// Decode decodes AMF0 long string type payload.
+func (s *LongStringPayload) Decode(r io.Reader) (int, error) {
+
+	var parsedBytes int
+
+	data := make([]byte, 4)
+	if err := util.ReadOrError(r, data); err != nil {
+		return parsedBytes, err
+	} else {
+		s.Length = binary.BigEndian.Uint32(data)
+		parsedBytes += 4
+	}
+
+	data = make([]byte, s.Length)
+	if len(data) > 0 {
+		if err := util.ReadOrError(r, data); err != nil {
+			return parsedBytes, err
+		}
+	}
+	s.Str = string(data)
+	parsedBytes += int(s.Length)
+
+	return parsedBytes, nil
+}
+
+// Encode encodes AMF0 long string type payload, the length is calculated by the string.
+func (s LongStringPayload) Encode(w io.Writer) (int, error) {
+	if uint64(len(s.Str)) > math.MaxUint32 {
+		return 0, fmt.Errorf("long string length %d exceeds %d", len(s.Str), uint64(math.MaxUint32))
+	}
+
+	data := make([]byte, 4+len(s.Str))
+	binary.BigEndian.PutUint32(data, uint32(len(s.Str)))
+	copy(data[4:], s.Str)
+	return w.Write(data)
+}
@@ -51,3 +51,49 @@ func (o *ObjectPayload) Decode(r io.Reader) (int, error) {
 	}
 	return parsedBytes, nil
 }
+
+// Encode implements encoder.
+func (o ObjectProperty) Encode(w io.Writer) (int, error) {
+	var encodedBytes int
+
+	if bytes, err := o.String.Encode(w); err != nil {
+		return encodedBytes, err
+	} else {
+		encodedBytes += bytes
+	}
+
+	if bytes, err := o.ValueType.Encode(w); err != nil {
+		return encodedBytes, err
+	} else {
+		encodedBytes += bytes
+	}
+
+	return encodedBytes, nil
+}
+
+// Encode implements encoder, object-end-marker will be appended if it's not the last property.
+func (o ObjectPayload) Encode(w io.Writer) (int, error) {
+	return encodeObjectProperties(w, o.ObjectProperty)
+}
+
+func encodeObjectProperties(w io.Writer, properties []ObjectProperty) (int, error) {
+	var encodedBytes int
+
+	for _, op := range properties {
+		if bytes, err := op.Encode(w); err != nil {
+			return encodedBytes, err
+		} else {
+			encodedBytes += bytes
+		}
+	}
+
+	if len(properties) == 0 || properties[len(properties)-1].ValueType.TypeMarker != TypeMarkerObjectEnd {
+		if bytes, err := (ObjectProperty{ValueType: ValueType{TypeMarker: TypeMarkerObjectEnd}}).Encode(w); err != nil {
+			return encodedBytes, err
+		} else {
+			encodedBytes += bytes
+		}
+	}
+
+	return encodedBytes, nil
+}
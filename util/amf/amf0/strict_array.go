@@ -40,3 +40,25 @@ func (s *StrictArrayPayload) Decode(r io.Reader) (int, error) {
 	}
 	return parsedBytes, nil
 }
+
+// Encode implements encoder, the Count will be calculated by values.
+func (s StrictArrayPayload) Encode(w io.Writer) (int, error) {
+	var encodedBytes int
+
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, uint32(len(s.ValueType)))
+	if bytes, err := w.Write(data); err != nil {
+		return encodedBytes, err
+	} else {
+		encodedBytes += bytes
+	}
+
+	for _, v := range s.ValueType {
+		if bytes, err := v.Encode(w); err != nil {
+			return encodedBytes, err
+		} else {
+			encodedBytes += bytes
+		}
+	}
+	return encodedBytes, nil
+}
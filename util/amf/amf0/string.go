@@ -2,7 +2,9 @@ package amf0
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
+	"math"
 
 	"github.com/wangyoucao577/medialib/util"
 )
@@ -36,3 +38,15 @@ func (s *StringPayload) Decode(r io.Reader) (int, error) {
 
 	return parsedBytes, nil
 }
+
+// Encode encodes AMF0 string type payload, the length is calculated by the string.
+func (s StringPayload) Encode(w io.Writer) (int, error) {
+	if len(s.Str) > math.MaxUint16 {
+		return 0, fmt.Errorf("string length %d exceeds %d", len(s.Str), math.MaxUint16)
+	}
+
+	data := make([]byte, 2+len(s.Str))
+	binary.BigEndian.PutUint16(data, uint16(len(s.Str)))
+	copy(data[2:], s.Str)
+	return w.Write(data)
+}
@@ -20,6 +20,7 @@ const (
 	TypeMarkerRecordSet   = 0x0E // reserved, not supported
 	TypeMarkerXMLDocument = 0x0F
 	TypeMarkerTypedObject = 0x10
+	TypeMarkerAVMPlus     = 0x11 // switch to AMF3, the following value is encoded by AMF3
 )
 
 var typeMarkerDescriptions = map[int]string{
@@ -40,6 +41,7 @@ var typeMarkerDescriptions = map[int]string{
 	TypeMarkerRecordSet:   "recordset, reserved, not supported",
 	TypeMarkerXMLDocument: "xml-document",
 	TypeMarkerTypedObject: "typed-object",
+	TypeMarkerAVMPlus:     "avmplus-object",
 }
 
 // TypeMarkerDescription returns description of type marker.
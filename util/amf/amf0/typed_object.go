@@ -0,0 +1,41 @@
+package amf0
+
+import "io"
+
+// TypedObjectPayload represents AMF0 typed object payload, i.e., an object with registered class name.
+type TypedObjectPayload struct {
+	ClassName      StringPayload    `json:"class-name"`
+	ObjectProperty []ObjectProperty `json:"object-property"`
+}
+
+// Decode implements decoder.
+func (o *TypedObjectPayload) Decode(r io.Reader) (int, error) {
+	var parsedBytes int
+
+	if bytes, err := o.ClassName.Decode(r); err != nil {
+		return parsedBytes, err
+	} else {
+		parsedBytes += bytes
+	}
+
+	properties := ObjectPayload{}
+	bytes, err := properties.Decode(r)
+	parsedBytes += bytes
+	o.ObjectProperty = properties.ObjectProperty
+	return parsedBytes, err
+}
+
+// Encode implements encoder, object-end-marker will be appended if it's not the last property.
+func (o TypedObjectPayload) Encode(w io.Writer) (int, error) {
+	var encodedBytes int
+
+	if bytes, err := o.ClassName.Encode(w); err != nil {
+		return encodedBytes, err
+	} else {
+		encodedBytes += bytes
+	}
+
+	bytes, err := encodeObjectProperties(w, o.ObjectProperty)
+	encodedBytes += bytes
+	return encodedBytes, err
+}
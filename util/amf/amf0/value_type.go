@@ -8,6 +8,7 @@ import (
 	"math"
 
 	"github.com/wangyoucao577/medialib/util"
+	"github.com/wangyoucao577/medialib/util/amf/amf3"
 )
 
 // ValueType represents an AMF0 value-type.
@@ -87,6 +88,13 @@ func (v *ValueType) Decode(r io.Reader) (int, error) {
 		dec = &StrictArrayPayload{}
 	case TypeMarkerDate:
 		dec = &Date{}
+	case TypeMarkerLongString, TypeMarkerXMLDocument:
+		dec = &LongStringPayload{}
+	case TypeMarkerUnsupported: // nothing to do
+	case TypeMarkerTypedObject:
+		dec = &TypedObjectPayload{}
+	case TypeMarkerAVMPlus:
+		dec = &amf3.ValueType{}
 	default:
 		return parsedBytes, fmt.Errorf("AMF0 type %d(%s) unsupported", v.TypeMarker, TypeMarkerDescription(int(v.TypeMarker)))
 	}
@@ -102,3 +110,69 @@ func (v *ValueType) Decode(r io.Reader) (int, error) {
 
 	return parsedBytes, nil
 }
+
+// Encode encodes AMF0 value type to bytes.
+// The value should be same type as Decode generates, payload types can be either pointer or not.
+func (v ValueType) Encode(w io.Writer) (int, error) {
+	var encodedBytes int
+
+	if bytes, err := w.Write([]byte{v.TypeMarker}); err != nil {
+		return encodedBytes, err
+	} else {
+		encodedBytes += bytes
+	}
+
+	var data []byte
+	var enc encoder
+	switch v.TypeMarker {
+	case TypeMarkerNumber:
+		f, err := v.AsNumber()
+		if err != nil {
+			return encodedBytes, err
+		}
+		data = make([]byte, 8)
+		binary.BigEndian.PutUint64(data, math.Float64bits(f))
+	case TypeMarkerBoolean:
+		b, err := v.AsBoolean()
+		if err != nil {
+			return encodedBytes, err
+		}
+		data = []byte{0}
+		if b {
+			data[0] = 1
+		}
+	case TypeMarkerNull, TypeMarkerUndefined, TypeMarkerObjectEnd, TypeMarkerUnsupported: // nothing to do
+	case TypeMarkerReference:
+		r, err := v.AsReference()
+		if err != nil {
+			return encodedBytes, err
+		}
+		data = make([]byte, 2)
+		binary.BigEndian.PutUint16(data, r)
+	case TypeMarkerString, TypeMarkerObject, TypeMarkerECMAArray, TypeMarkerStrictArray, TypeMarkerDate,
+		TypeMarkerLongString, TypeMarkerXMLDocument, TypeMarkerTypedObject, TypeMarkerAVMPlus:
+		var ok bool
+		if enc, ok = v.Value.(encoder); !ok {
+			return encodedBytes, fmt.Errorf("AMF0 type %d(%s) invalid value %#v", v.TypeMarker, TypeMarkerDescription(int(v.TypeMarker)), v.Value)
+		}
+	default:
+		return encodedBytes, fmt.Errorf("AMF0 type %d(%s) unsupported", v.TypeMarker, TypeMarkerDescription(int(v.TypeMarker)))
+	}
+
+	if data != nil {
+		if bytes, err := w.Write(data); err != nil {
+			return encodedBytes, err
+		} else {
+			encodedBytes += bytes
+		}
+	}
+	if enc != nil {
+		if bytes, err := enc.Encode(w); err != nil {
+			return encodedBytes, err
+		} else {
+			encodedBytes += bytes
+		}
+	}
+
+	return encodedBytes, nil
+}
@@ -46,11 +46,13 @@ func (v ValueType) AsString() (string, error) {
 			v.TypeMarker, TypeMarkerDescription(int(v.TypeMarker)), TypeMarkerString, TypeMarkerDescription(TypeMarkerString))
 	}
 
-	s, ok := v.Value.(StringPayload)
-	if !ok {
-		return "", fmt.Errorf("value as string failed")
+	switch s := v.Value.(type) {
+	case *StringPayload: // decoded
+		return s.Str, nil
+	case StringPayload:
+		return s.Str, nil
 	}
-	return s.Str, nil
+	return "", fmt.Errorf("value as string failed")
 }
 
 // AsReference returns reference value if type matched.
@@ -69,3 +71,22 @@ func (v ValueType) AsReference() (uint16, error) {
 	}
 	return r, nil
 }
+
+// AsProperties returns properties if type is object or ECMA array, object-end-marker is included if exist.
+func (v ValueType) AsProperties() ([]ObjectProperty, error) {
+	if v.Value == nil {
+		return nil, fmt.Errorf("empty value")
+	}
+
+	switch p := v.Value.(type) {
+	case *ObjectPayload: // decoded
+		return p.ObjectProperty, nil
+	case ObjectPayload:
+		return p.ObjectProperty, nil
+	case *ECMAArrayPayload: // decoded
+		return p.ObjectProperty, nil
+	case ECMAArrayPayload:
+		return p.ObjectProperty, nil
+	}
+	return nil, fmt.Errorf("type 0x%x(%s) value as properties failed", v.TypeMarker, TypeMarkerDescription(int(v.TypeMarker)))
+}
@@ -0,0 +1,93 @@
+package amf0
+
+import "github.com/wangyoucao577/medialib/util/amf/amf3"
+
+// NewNumber creates AMF0 number value type.
+func NewNumber(f float64) ValueType {
+	return ValueType{TypeMarker: TypeMarkerNumber, Value: f}
+}
+
+// NewBoolean creates AMF0 boolean value type.
+func NewBoolean(b bool) ValueType {
+	return ValueType{TypeMarker: TypeMarkerBoolean, Value: b}
+}
+
+// NewString creates AMF0 string value type.
+func NewString(s string) ValueType {
+	return ValueType{TypeMarker: TypeMarkerString, Value: &StringPayload{Length: uint16(len(s)), Str: s}}
+}
+
+// NewNull creates AMF0 null value type.
+func NewNull() ValueType {
+	return ValueType{TypeMarker: TypeMarkerNull}
+}
+
+// NewUndefined creates AMF0 undefined value type.
+func NewUndefined() ValueType {
+	return ValueType{TypeMarker: TypeMarkerUndefined}
+}
+
+// NewReference creates AMF0 reference value type, which refers to a complex object by index.
+func NewReference(index uint16) ValueType {
+	return ValueType{TypeMarker: TypeMarkerReference, Value: index}
+}
+
+// NewDate creates AMF0 date value type, timestamp is milliseconds since epoch in UTC.
+func NewDate(timestamp float64, timeZone int16) ValueType {
+	return ValueType{TypeMarker: TypeMarkerDate, Value: &Date{Timestamp: timestamp, TimeZone: timeZone}}
+}
+
+// NewLongString creates AMF0 long string value type.
+func NewLongString(s string) ValueType {
+	return ValueType{TypeMarker: TypeMarkerLongString, Value: &LongStringPayload{Length: uint32(len(s)), Str: s}}
+}
+
+// NewUnsupported creates AMF0 unsupported value type.
+func NewUnsupported() ValueType {
+	return ValueType{TypeMarker: TypeMarkerUnsupported}
+}
+
+// NewXMLDocument creates AMF0 XML document value type.
+func NewXMLDocument(s string) ValueType {
+	return ValueType{TypeMarker: TypeMarkerXMLDocument, Value: &LongStringPayload{Length: uint32(len(s)), Str: s}}
+}
+
+// NewTypedObject creates AMF0 typed object value type, object-end-marker will be appended.
+func NewTypedObject(className string, properties ...ObjectProperty) ValueType {
+	return ValueType{TypeMarker: TypeMarkerTypedObject, Value: &TypedObjectPayload{
+		ClassName:      StringPayload{Length: uint16(len(className)), Str: className},
+		ObjectProperty: withObjectEnd(properties),
+	}}
+}
+
+// NewAVMPlus creates AMF0 avmplus-object value type, which switches to AMF3 for the value.
+func NewAVMPlus(v amf3.ValueType) ValueType {
+	return ValueType{TypeMarker: TypeMarkerAVMPlus, Value: &v}
+}
+
+// NewObjectProperty creates a property of AMF0 object or ECMA array.
+func NewObjectProperty(name string, v ValueType) ObjectProperty {
+	return ObjectProperty{String: StringPayload{Length: uint16(len(name)), Str: name}, ValueType: v}
+}
+
+// NewObject creates AMF0 object value type, object-end-marker will be appended.
+func NewObject(properties ...ObjectProperty) ValueType {
+	return ValueType{TypeMarker: TypeMarkerObject, Value: &ObjectPayload{ObjectProperty: withObjectEnd(properties)}}
+}
+
+// NewECMAArray creates AMF0 ECMA array value type, object-end-marker will be appended.
+func NewECMAArray(properties ...ObjectProperty) ValueType {
+	return ValueType{TypeMarker: TypeMarkerECMAArray, Value: &ECMAArrayPayload{Count: uint32(len(properties)), ObjectProperty: withObjectEnd(properties)}}
+}
+
+// NewStrictArray creates AMF0 strict array value type.
+func NewStrictArray(values ...ValueType) ValueType {
+	return ValueType{TypeMarker: TypeMarkerStrictArray, Value: &StrictArrayPayload{Count: uint32(len(values)), ValueType: values}}
+}
+
+// withObjectEnd returns a copy of properties with object-end-marker as the last one, same as decoded.
+func withObjectEnd(properties []ObjectProperty) []ObjectProperty {
+	ops := make([]ObjectProperty, 0, len(properties)+1)
+	ops = append(ops, properties...)
+	return append(ops, ObjectProperty{ValueType: ValueType{TypeMarker: TypeMarkerObjectEnd}})
+}
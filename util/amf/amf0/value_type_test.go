@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"reflect"
 	"testing"
+
+	"github.com/wangyoucao577/medialib/util/amf/amf3"
 )
 
 const floatEpsilon = 0.000001
@@ -69,3 +71,59 @@ func TestValueTypeDecode(t *testing.T) {
 
 	}
 }
+
+func TestValueTypeEncode(t *testing.T) {
+	var cases = []struct {
+		vt  ValueType
+		out []byte
+	}{
+		{NewNumber(1), []byte{0x00, 0x3F, 0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{NewBoolean(true), []byte{0x01, 0x01}},
+		{NewString("connect"), []byte{0x02, 0x00, 0x07, 0x63, 0x6F, 0x6E, 0x6E, 0x65, 0x63, 0x74}},
+		{NewNull(), []byte{0x05}},
+		{NewObject(NewObjectProperty("a", NewBoolean(false))), []byte{0x03, 0x00, 0x01, 0x61, 0x01, 0x00, 0x00, 0x00, 0x09}},
+		{NewECMAArray(NewObjectProperty("a", NewNull())), []byte{0x08, 0x00, 0x00, 0x00, 0x01, 0x00, 0x01, 0x61, 0x05, 0x00, 0x00, 0x09}},
+		{NewStrictArray(NewNull(), NewBoolean(true)), []byte{0x0A, 0x00, 0x00, 0x00, 0x02, 0x05, 0x01, 0x01}},
+		{NewUndefined(), []byte{0x06}},
+		{NewReference(1), []byte{0x07, 0x00, 0x01}},
+		{NewDate(1, -480), []byte{0x0B, 0x3F, 0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xFE, 0x20}},
+		{NewLongString("a"), []byte{0x0C, 0x00, 0x00, 0x00, 0x01, 0x61}},
+		{NewLongString(""), []byte{0x0C, 0x00, 0x00, 0x00, 0x00}},
+		{NewUnsupported(), []byte{0x0D}},
+		{NewXMLDocument("<a/>"), []byte{0x0F, 0x00, 0x00, 0x00, 0x04, 0x3C, 0x61, 0x2F, 0x3E}},
+		{NewTypedObject("c", NewObjectProperty("a", NewNumber(0))),
+			[]byte{0x10, 0x00, 0x01, 0x63, 0x00, 0x01, 0x61, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x09}},
+		{NewAVMPlus(amf3.NewAnonymousObject(amf3.NewProperty("a", amf3.NewInteger(1)))),
+			[]byte{0x11, 0x0A, 0x0B, 0x01, 0x03, 0x61, 0x04, 0x01, 0x01}},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		n, err := c.vt.Encode(&buf)
+		if err != nil {
+			t.Errorf("encode %v expect nil but got %v", c.vt, err)
+			continue
+		}
+		if n != len(c.out) || !bytes.Equal(buf.Bytes(), c.out) {
+			t.Errorf("encode %v expect %v but got %v(%d bytes)", c.vt, c.out, buf.Bytes(), n)
+		}
+
+		vt := ValueType{}
+		if _, err := vt.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Errorf("decode %v expect nil but got %v", buf.Bytes(), err)
+		}
+		if !reflect.DeepEqual(vt, c.vt) {
+			t.Errorf("decode %v expect %v but got %v", buf.Bytes(), c.vt, vt)
+		}
+	}
+
+	if _, err := (ValueType{TypeMarker: TypeMarkerString, Value: 1}).Encode(&bytes.Buffer{}); err == nil {
+		t.Errorf("encode invalid string value expect error but got nil")
+	}
+
+	for _, marker := range []uint8{TypeMarkerMoiveClip, TypeMarkerRecordSet} {
+		if _, err := (ValueType{TypeMarker: marker}).Encode(&bytes.Buffer{}); err == nil {
+			t.Errorf("encode reserved type %d expect error but got nil", marker)
+		}
+	}
+}
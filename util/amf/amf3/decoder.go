@@ -0,0 +1,373 @@
+package amf3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/wangyoucao577/medialib/util"
+)
+
+// Decoder decodes AMF3 values, reference tables of strings, objects and traits are shared by all values it decodes.
+type Decoder struct {
+	r io.Reader
+	n int // decoded bytes
+
+	strings []string
+	objects []interface{}
+	traits  []*Traits
+}
+
+// NewDecoder creates AMF3 Decoder.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode decodes the next value, it returns decoded bytes.
+func (d *Decoder) Decode(v *ValueType) (int, error) {
+	n := d.n
+	err := d.decodeValue(v)
+	return d.n - n, err
+}
+
+func (d *Decoder) read(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := util.ReadOrError(d.r, data); err != nil {
+		return err
+	}
+	d.n += len(data)
+	return nil
+}
+
+func (d *Decoder) readByte() (byte, error) {
+	data := make([]byte, 1)
+	err := d.read(data)
+	return data[0], err
+}
+
+func (d *Decoder) readU29() (uint32, error) {
+	var u uint32
+	for i := 0; i < 4; i++ {
+		b, err := d.readByte()
+		if err != nil {
+			return 0, err
+		}
+		if i == 3 { // all 8 bits of the 4th byte
+			return u<<8 | uint32(b), nil
+		}
+		u = u<<7 | uint32(b&0x7F)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	return u, nil
+}
+
+func (d *Decoder) readDouble() (float64, error) {
+	data := make([]byte, 8)
+	if err := d.read(data); err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+}
+
+// readString reads UTF-8-vr, empty string is never sent by reference.
+func (d *Decoder) readString() (string, error) {
+	u, err := d.readU29()
+	if err != nil {
+		return "", err
+	}
+	if u&1 == 0 {
+		index := int(u >> 1)
+		if index >= len(d.strings) {
+			return "", fmt.Errorf("string reference %d out of range %d", index, len(d.strings))
+		}
+		return d.strings[index], nil
+	}
+
+	data := make([]byte, u>>1)
+	if err := d.read(data); err != nil {
+		return "", err
+	}
+	s := string(data)
+	if len(s) > 0 {
+		d.strings = append(d.strings, s)
+	}
+	return s, nil
+}
+
+// readObjectHeader reads U29 of complex types, the referenced object will be returned if it's a reference,
+// otherwise the remaining bits(i.e., without the low bit) for the inline value.
+func (d *Decoder) readObjectHeader() (interface{}, uint32, error) {
+	u, err := d.readU29()
+	if err != nil {
+		return nil, 0, err
+	}
+	if u&1 == 0 {
+		index := int(u >> 1)
+		if index >= len(d.objects) {
+			return nil, 0, fmt.Errorf("object reference %d out of range %d", index, len(d.objects))
+		}
+		return d.objects[index], 0, nil
+	}
+	return nil, u >> 1, nil
+}
+
+func (d *Decoder) decodeValue(v *ValueType) error {
+	var err error
+	if v.TypeMarker, err = d.readByte(); err != nil {
+		return err
+	}
+	v.Value = nil
+
+	switch v.TypeMarker {
+	case TypeMarkerUndefined, TypeMarkerNull, TypeMarkerFalse, TypeMarkerTrue: // nothing to do
+	case TypeMarkerInteger:
+		u, err := d.readU29()
+		if err != nil {
+			return err
+		}
+		v.Value = u29ToInteger(u)
+	case TypeMarkerDouble:
+		v.Value, err = d.readDouble()
+	case TypeMarkerString:
+		v.Value, err = d.readString()
+	case TypeMarkerXMLDocument, TypeMarkerXML:
+		v.Value, err = d.decodeXML()
+	case TypeMarkerDate:
+		v.Value, err = d.decodeDate()
+	case TypeMarkerArray:
+		v.Value, err = d.decodeArray()
+	case TypeMarkerObject:
+		v.Value, err = d.decodeObject()
+	case TypeMarkerByteArray:
+		v.Value, err = d.decodeByteArray()
+	case TypeMarkerVectorInt, TypeMarkerVectorUint, TypeMarkerVectorDouble, TypeMarkerVectorObject:
+		v.Value, err = d.decodeVector(v.TypeMarker)
+	case TypeMarkerDictionary:
+		v.Value, err = d.decodeDictionary()
+	default:
+		return fmt.Errorf("AMF3 type %d(%s) unsupported", v.TypeMarker, TypeMarkerDescription(int(v.TypeMarker)))
+	}
+	return err
+}
+
+func (d *Decoder) decodeXML() (interface{}, error) {
+	ref, length, err := d.readObjectHeader()
+	if err != nil || ref != nil {
+		return ref, err
+	}
+
+	data := make([]byte, length)
+	if err := d.read(data); err != nil {
+		return nil, err
+	}
+	x := &XML{Str: string(data)}
+	d.objects = append(d.objects, x)
+	return x, nil
+}
+
+func (d *Decoder) decodeDate() (interface{}, error) {
+	ref, _, err := d.readObjectHeader()
+	if err != nil || ref != nil {
+		return ref, err
+	}
+
+	date := &Date{}
+	if date.Millis, err = d.readDouble(); err != nil {
+		return nil, err
+	}
+	d.objects = append(d.objects, date)
+	return date, nil
+}
+
+func (d *Decoder) decodeArray() (interface{}, error) {
+	ref, count, err := d.readObjectHeader()
+	if err != nil || ref != nil {
+		return ref, err
+	}
+
+	a := &Array{}
+	d.objects = append(d.objects, a) // before members since they may refer to it
+	if a.Associative, err = d.decodeProperties(); err != nil {
+		return nil, err
+	}
+	for i := 0; i < int(count); i++ {
+		v := ValueType{}
+		if err := d.decodeValue(&v); err != nil {
+			return nil, err
+		}
+		a.Dense = append(a.Dense, v)
+	}
+	return a, nil
+}
+
+// decodeProperties decodes name-value pairs until an empty name.
+func (d *Decoder) decodeProperties() ([]Property, error) {
+	var properties []Property
+	for {
+		name, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			return properties, nil
+		}
+
+		p := Property{Name: name}
+		if err := d.decodeValue(&p.Value); err != nil {
+			return nil, err
+		}
+		properties = append(properties, p)
+	}
+}
+
+func (d *Decoder) decodeObject() (interface{}, error) {
+	ref, u, err := d.readObjectHeader()
+	if err != nil || ref != nil {
+		return ref, err
+	}
+
+	var traits *Traits
+	if u&1 == 0 { // traits reference
+		index := int(u >> 1)
+		if index >= len(d.traits) {
+			return nil, fmt.Errorf("traits reference %d out of range %d", index, len(d.traits))
+		}
+		traits = d.traits[index]
+	} else {
+		traits = &Traits{Externalizable: u&2 != 0, Dynamic: u&4 != 0}
+		if traits.ClassName, err = d.readString(); err != nil {
+			return nil, err
+		}
+		if !traits.Externalizable {
+			for i := 0; i < int(u>>3); i++ {
+				member, err := d.readString()
+				if err != nil {
+					return nil, err
+				}
+				traits.Members = append(traits.Members, member)
+			}
+		}
+		d.traits = append(d.traits, traits)
+	}
+	if traits.Externalizable {
+		return nil, fmt.Errorf("externalizable object of class %q unsupported", traits.ClassName)
+	}
+
+	o := &Object{Traits: traits}
+	d.objects = append(d.objects, o) // before members since they may refer to it
+	for range traits.Members {
+		v := ValueType{}
+		if err := d.decodeValue(&v); err != nil {
+			return nil, err
+		}
+		o.Sealed = append(o.Sealed, v)
+	}
+	if traits.Dynamic {
+		if o.Dynamic, err = d.decodeProperties(); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+func (d *Decoder) decodeByteArray() (interface{}, error) {
+	ref, length, err := d.readObjectHeader()
+	if err != nil || ref != nil {
+		return ref, err
+	}
+
+	b := &ByteArray{Data: make([]byte, length)}
+	if err := d.read(b.Data); err != nil {
+		return nil, err
+	}
+	d.objects = append(d.objects, b)
+	return b, nil
+}
+
+func (d *Decoder) decodeVector(typeMarker uint8) (interface{}, error) {
+	ref, count, err := d.readObjectHeader()
+	if err != nil || ref != nil {
+		return ref, err
+	}
+	fixed, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 8)
+	switch typeMarker {
+	case TypeMarkerVectorInt:
+		vec := &VectorInt{Fixed: fixed != 0, Items: make([]int32, count)}
+		for i := range vec.Items {
+			if err := d.read(data[:4]); err != nil {
+				return nil, err
+			}
+			vec.Items[i] = int32(binary.BigEndian.Uint32(data))
+		}
+		d.objects = append(d.objects, vec)
+		return vec, nil
+	case TypeMarkerVectorUint:
+		vec := &VectorUint{Fixed: fixed != 0, Items: make([]uint32, count)}
+		for i := range vec.Items {
+			if err := d.read(data[:4]); err != nil {
+				return nil, err
+			}
+			vec.Items[i] = binary.BigEndian.Uint32(data)
+		}
+		d.objects = append(d.objects, vec)
+		return vec, nil
+	case TypeMarkerVectorDouble:
+		vec := &VectorDouble{Fixed: fixed != 0, Items: make([]float64, count)}
+		for i := range vec.Items {
+			if vec.Items[i], err = d.readDouble(); err != nil {
+				return nil, err
+			}
+		}
+		d.objects = append(d.objects, vec)
+		return vec, nil
+	}
+
+	vec := &VectorObject{Fixed: fixed != 0}
+	if vec.TypeName, err = d.readString(); err != nil {
+		return nil, err
+	}
+	d.objects = append(d.objects, vec) // before items since they may refer to it
+	for i := 0; i < int(count); i++ {
+		v := ValueType{}
+		if err := d.decodeValue(&v); err != nil {
+			return nil, err
+		}
+		vec.Items = append(vec.Items, v)
+	}
+	return vec, nil
+}
+
+func (d *Decoder) decodeDictionary() (interface{}, error) {
+	ref, count, err := d.readObjectHeader()
+	if err != nil || ref != nil {
+		return ref, err
+	}
+	weakKeys, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	dict := &Dictionary{WeakKeys: weakKeys != 0}
+	d.objects = append(d.objects, dict) // before entries since they may refer to it
+	for i := 0; i < int(count); i++ {
+		e := DictionaryEntry{}
+		if err := d.decodeValue(&e.Key); err != nil {
+			return nil, err
+		}
+		if err := d.decodeValue(&e.Value); err != nil {
+			return nil, err
+		}
+		dict.Entries = append(dict.Entries, e)
+	}
+	return dict, nil
+}
@@ -0,0 +1,372 @@
+package amf3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Encoder encodes AMF3 values, reference tables of strings, objects and traits are shared by all values it encodes.
+// Strings and traits are sent by reference if same ones have been sent, while objects are sent by reference
+// only if they're the same pointer.
+type Encoder struct {
+	w io.Writer
+	n int // encoded bytes
+
+	strings map[string]int
+	objects map[interface{}]int
+	traits  map[string]int
+}
+
+// NewEncoder creates AMF3 Encoder.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w:       w,
+		strings: map[string]int{},
+		objects: map[interface{}]int{},
+		traits:  map[string]int{},
+	}
+}
+
+// Encode encodes the value, it returns encoded bytes.
+func (e *Encoder) Encode(v ValueType) (int, error) {
+	n := e.n
+	err := e.encodeValue(v)
+	return e.n - n, err
+}
+
+func (e *Encoder) write(data []byte) error {
+	n, err := e.w.Write(data)
+	e.n += n
+	return err
+}
+
+func (e *Encoder) writeU29(u uint32) error {
+	data, err := encodeU29(u)
+	if err != nil {
+		return err
+	}
+	return e.write(data)
+}
+
+func (e *Encoder) writeDouble(f float64) error {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, math.Float64bits(f))
+	return e.write(data)
+}
+
+func (e *Encoder) writeBool(b bool) error {
+	if b {
+		return e.write([]byte{1})
+	}
+	return e.write([]byte{0})
+}
+
+// writeString writes UTF-8-vr, empty string is never sent by reference.
+func (e *Encoder) writeString(s string) error {
+	if index, ok := e.strings[s]; ok {
+		return e.writeU29(uint32(index) << 1)
+	}
+	if len(s) > MaxU29>>1 {
+		return fmt.Errorf("string length %d exceeds %d", len(s), MaxU29>>1)
+	}
+
+	if len(s) > 0 {
+		e.strings[s] = len(e.strings)
+	}
+	if err := e.writeU29(uint32(len(s))<<1 | 1); err != nil {
+		return err
+	}
+	return e.write([]byte(s))
+}
+
+// writeObjectHeader writes reference if the object has been sent, otherwise adds it to object table and
+// writes U29 of the inline value. It returns whether it's written as reference.
+func (e *Encoder) writeObjectHeader(o interface{}, u uint32) (bool, error) {
+	if index, ok := e.objects[o]; ok {
+		return true, e.writeU29(uint32(index) << 1)
+	}
+	e.objects[o] = len(e.objects)
+	return false, e.writeU29(u<<1 | 1)
+}
+
+func (e *Encoder) encodeValue(v ValueType) error {
+	if err := e.write([]byte{v.TypeMarker}); err != nil {
+		return err
+	}
+
+	switch v.TypeMarker {
+	case TypeMarkerUndefined, TypeMarkerNull, TypeMarkerFalse, TypeMarkerTrue: // nothing to do
+		return nil
+	case TypeMarkerInteger:
+		i, err := v.AsInteger()
+		if err != nil {
+			return err
+		}
+		u, err := integerToU29(i)
+		if err != nil {
+			return err
+		}
+		return e.writeU29(u)
+	case TypeMarkerDouble:
+		f, ok := v.Value.(float64)
+		if !ok {
+			break
+		}
+		return e.writeDouble(f)
+	case TypeMarkerString:
+		s, ok := v.Value.(string)
+		if !ok {
+			break
+		}
+		return e.writeString(s)
+	case TypeMarkerXMLDocument, TypeMarkerXML:
+		x, ok := v.Value.(*XML)
+		if !ok || x == nil {
+			break
+		}
+		return e.encodeBytes(x, []byte(x.Str))
+	case TypeMarkerDate:
+		date, ok := v.Value.(*Date)
+		if !ok || date == nil {
+			break
+		}
+		if ref, err := e.writeObjectHeader(date, 0); err != nil || ref {
+			return err
+		}
+		return e.writeDouble(date.Millis)
+	case TypeMarkerArray:
+		a, ok := v.Value.(*Array)
+		if !ok || a == nil {
+			break
+		}
+		return e.encodeArray(a)
+	case TypeMarkerObject:
+		o, ok := v.Value.(*Object)
+		if !ok || o == nil {
+			break
+		}
+		return e.encodeObject(o)
+	case TypeMarkerByteArray:
+		b, ok := v.Value.(*ByteArray)
+		if !ok || b == nil {
+			break
+		}
+		return e.encodeBytes(b, b.Data)
+	case TypeMarkerVectorInt, TypeMarkerVectorUint, TypeMarkerVectorDouble, TypeMarkerVectorObject:
+		if err := e.encodeVector(v); err != errInvalidValue {
+			return err
+		}
+	case TypeMarkerDictionary:
+		dict, ok := v.Value.(*Dictionary)
+		if !ok || dict == nil {
+			break
+		}
+		return e.encodeDictionary(dict)
+	default:
+		return fmt.Errorf("AMF3 type %d(%s) unsupported", v.TypeMarker, TypeMarkerDescription(int(v.TypeMarker)))
+	}
+
+	return fmt.Errorf("AMF3 type %d(%s) invalid value %#v", v.TypeMarker, TypeMarkerDescription(int(v.TypeMarker)), v.Value)
+}
+
+// encodeBytes encodes xml-doc, xml or byte-array.
+func (e *Encoder) encodeBytes(o interface{}, data []byte) error {
+	if len(data) > MaxU29>>1 {
+		return fmt.Errorf("length %d exceeds %d", len(data), MaxU29>>1)
+	}
+	if ref, err := e.writeObjectHeader(o, uint32(len(data))); err != nil || ref {
+		return err
+	}
+	return e.write(data)
+}
+
+func (e *Encoder) encodeArray(a *Array) error {
+	if ref, err := e.writeObjectHeader(a, uint32(len(a.Dense))); err != nil || ref {
+		return err
+	}
+
+	if err := e.encodeProperties(a.Associative); err != nil {
+		return err
+	}
+	for _, v := range a.Dense {
+		if err := e.encodeValue(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeProperties encodes name-value pairs and the empty name at the end.
+func (e *Encoder) encodeProperties(properties []Property) error {
+	for _, p := range properties {
+		if p.Name == "" {
+			return fmt.Errorf("empty property name")
+		}
+		if err := e.writeString(p.Name); err != nil {
+			return err
+		}
+		if err := e.encodeValue(p.Value); err != nil {
+			return err
+		}
+	}
+	return e.writeString("")
+}
+
+func (e *Encoder) encodeObject(o *Object) error {
+	traits := o.Traits
+	if traits == nil {
+		traits = &Traits{Dynamic: true} // anonymous object
+	}
+	if traits.Externalizable {
+		return fmt.Errorf("externalizable object of class %q unsupported", traits.ClassName)
+	}
+	if len(o.Sealed) != len(traits.Members) {
+		return fmt.Errorf("object of class %q expect %d sealed members but got %d", traits.ClassName, len(traits.Members), len(o.Sealed))
+	}
+	if !traits.Dynamic && len(o.Dynamic) > 0 {
+		return fmt.Errorf("object of class %q isn't dynamic but got %d dynamic members", traits.ClassName, len(o.Dynamic))
+	}
+
+	if _, ok := e.objects[o]; ok {
+		_, err := e.writeObjectHeader(o, 0)
+		return err
+	}
+	e.objects[o] = len(e.objects)
+
+	key := traitsKey(traits)
+	if index, ok := e.traits[key]; ok {
+		if err := e.writeU29(uint32(index)<<2 | 1); err != nil {
+			return err
+		}
+	} else {
+		e.traits[key] = len(e.traits)
+		u := uint32(len(traits.Members))<<4 | 0x3
+		if traits.Dynamic {
+			u |= 0x8
+		}
+		if err := e.writeU29(u); err != nil {
+			return err
+		}
+		if err := e.writeString(traits.ClassName); err != nil {
+			return err
+		}
+		for _, m := range traits.Members {
+			if err := e.writeString(m); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, v := range o.Sealed {
+		if err := e.encodeValue(v); err != nil {
+			return err
+		}
+	}
+	if traits.Dynamic {
+		return e.encodeProperties(o.Dynamic)
+	}
+	return nil
+}
+
+// traitsKey identifies traits for reference table, same class with same members are the same traits.
+func traitsKey(t *Traits) string {
+	return fmt.Sprintf("%s|%v|%v|%s", t.ClassName, t.Externalizable, t.Dynamic, strings.Join(t.Members, "|"))
+}
+
+var errInvalidValue = fmt.Errorf("invalid value")
+
+func (e *Encoder) encodeVector(v ValueType) error {
+	data := make([]byte, 8)
+	switch vec := v.Value.(type) {
+	case *VectorInt:
+		if vec == nil || v.TypeMarker != TypeMarkerVectorInt {
+			return errInvalidValue
+		}
+		if ref, err := e.writeObjectHeader(vec, uint32(len(vec.Items))); err != nil || ref {
+			return err
+		}
+		if err := e.writeBool(vec.Fixed); err != nil {
+			return err
+		}
+		for _, i := range vec.Items {
+			binary.BigEndian.PutUint32(data, uint32(i))
+			if err := e.write(data[:4]); err != nil {
+				return err
+			}
+		}
+	case *VectorUint:
+		if vec == nil || v.TypeMarker != TypeMarkerVectorUint {
+			return errInvalidValue
+		}
+		if ref, err := e.writeObjectHeader(vec, uint32(len(vec.Items))); err != nil || ref {
+			return err
+		}
+		if err := e.writeBool(vec.Fixed); err != nil {
+			return err
+		}
+		for _, u := range vec.Items {
+			binary.BigEndian.PutUint32(data, u)
+			if err := e.write(data[:4]); err != nil {
+				return err
+			}
+		}
+	case *VectorDouble:
+		if vec == nil || v.TypeMarker != TypeMarkerVectorDouble {
+			return errInvalidValue
+		}
+		if ref, err := e.writeObjectHeader(vec, uint32(len(vec.Items))); err != nil || ref {
+			return err
+		}
+		if err := e.writeBool(vec.Fixed); err != nil {
+			return err
+		}
+		for _, f := range vec.Items {
+			if err := e.writeDouble(f); err != nil {
+				return err
+			}
+		}
+	case *VectorObject:
+		if vec == nil || v.TypeMarker != TypeMarkerVectorObject {
+			return errInvalidValue
+		}
+		if ref, err := e.writeObjectHeader(vec, uint32(len(vec.Items))); err != nil || ref {
+			return err
+		}
+		if err := e.writeBool(vec.Fixed); err != nil {
+			return err
+		}
+		if err := e.writeString(vec.TypeName); err != nil {
+			return err
+		}
+		for _, item := range vec.Items {
+			if err := e.encodeValue(item); err != nil {
+				return err
+			}
+		}
+	default:
+		return errInvalidValue
+	}
+	return nil
+}
+
+func (e *Encoder) encodeDictionary(dict *Dictionary) error {
+	if ref, err := e.writeObjectHeader(dict, uint32(len(dict.Entries))); err != nil || ref {
+		return err
+	}
+	if err := e.writeBool(dict.WeakKeys); err != nil {
+		return err
+	}
+
+	for _, entry := range dict.Entries {
+		if err := e.encodeValue(entry.Key); err != nil {
+			return err
+		}
+		if err := e.encodeValue(entry.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
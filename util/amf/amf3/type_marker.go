@@ -0,0 +1,54 @@
+// Package amf3 implements AMF3 specification, including reference tables of strings, objects and traits.
+package amf3
+
+// type markers
+const (
+	TypeMarkerUndefined    = 0x00
+	TypeMarkerNull         = 0x01
+	TypeMarkerFalse        = 0x02
+	TypeMarkerTrue         = 0x03
+	TypeMarkerInteger      = 0x04
+	TypeMarkerDouble       = 0x05
+	TypeMarkerString       = 0x06
+	TypeMarkerXMLDocument  = 0x07
+	TypeMarkerDate         = 0x08
+	TypeMarkerArray        = 0x09
+	TypeMarkerObject       = 0x0A
+	TypeMarkerXML          = 0x0B
+	TypeMarkerByteArray    = 0x0C
+	TypeMarkerVectorInt    = 0x0D
+	TypeMarkerVectorUint   = 0x0E
+	TypeMarkerVectorDouble = 0x0F
+	TypeMarkerVectorObject = 0x10
+	TypeMarkerDictionary   = 0x11
+)
+
+var typeMarkerDescriptions = map[int]string{
+	TypeMarkerUndefined:    "undefined",
+	TypeMarkerNull:         "null",
+	TypeMarkerFalse:        "false",
+	TypeMarkerTrue:         "true",
+	TypeMarkerInteger:      "integer",
+	TypeMarkerDouble:       "double",
+	TypeMarkerString:       "string",
+	TypeMarkerXMLDocument:  "xml-doc",
+	TypeMarkerDate:         "date",
+	TypeMarkerArray:        "array",
+	TypeMarkerObject:       "object",
+	TypeMarkerXML:          "xml",
+	TypeMarkerByteArray:    "byte-array",
+	TypeMarkerVectorInt:    "vector-int",
+	TypeMarkerVectorUint:   "vector-uint",
+	TypeMarkerVectorDouble: "vector-double",
+	TypeMarkerVectorObject: "vector-object",
+	TypeMarkerDictionary:   "dictionary",
+}
+
+// TypeMarkerDescription returns description of type marker.
+func TypeMarkerDescription(t int) string {
+	d, ok := typeMarkerDescriptions[t]
+	if !ok {
+		return ""
+	}
+	return d
+}
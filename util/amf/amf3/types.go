@@ -0,0 +1,100 @@
+package amf3
+
+// Complex types below are stored in object reference table, they're always pointers in ValueType
+// so that the same one can be serialized as reference.
+
+// Traits represents traits of AMF3 object, which are shared by objects of the same class.
+type Traits struct {
+	ClassName      string   `json:"class_name"` // empty for anonymous object
+	Externalizable bool     `json:"externalizable"`
+	Dynamic        bool     `json:"dynamic"`
+	Members        []string `json:"members,omitempty"` // sealed member names
+}
+
+// Property represents a name-value pair, e.g., dynamic member of object or associative portion of array.
+type Property struct {
+	Name  string    `json:"name"`
+	Value ValueType `json:"value"`
+}
+
+// Object represents AMF3 object, externalizable object doesn't support since its payload is class specific.
+type Object struct {
+	Traits  *Traits     `json:"traits"`
+	Sealed  []ValueType `json:"sealed,omitempty"`  // values of sealed members in order of Traits.Members
+	Dynamic []Property  `json:"dynamic,omitempty"` // only if Traits.Dynamic
+}
+
+// Array represents AMF3 array, which has both associative and dense portion.
+type Array struct {
+	Associative []Property  `json:"associative,omitempty"`
+	Dense       []ValueType `json:"dense,omitempty"`
+}
+
+// Date represents AMF3 date.
+type Date struct {
+	Millis float64 `json:"millis"` // milliseconds since epoch in UTC
+}
+
+// XML represents AMF3 xml-doc or xml.
+type XML struct {
+	Str string `json:"string"`
+}
+
+// ByteArray represents AMF3 byte array.
+type ByteArray struct {
+	Data []byte `json:"data"`
+}
+
+// VectorInt represents AMF3 vector of int.
+type VectorInt struct {
+	Fixed bool    `json:"fixed"`
+	Items []int32 `json:"items"`
+}
+
+// VectorUint represents AMF3 vector of uint.
+type VectorUint struct {
+	Fixed bool     `json:"fixed"`
+	Items []uint32 `json:"items"`
+}
+
+// VectorDouble represents AMF3 vector of Number.
+type VectorDouble struct {
+	Fixed bool      `json:"fixed"`
+	Items []float64 `json:"items"`
+}
+
+// VectorObject represents AMF3 vector of objects.
+type VectorObject struct {
+	Fixed    bool        `json:"fixed"`
+	TypeName string      `json:"type_name"` // "*" for any type
+	Items    []ValueType `json:"items"`
+}
+
+// DictionaryEntry represents key-value pair of AMF3 dictionary.
+type DictionaryEntry struct {
+	Key   ValueType `json:"key"`
+	Value ValueType `json:"value"`
+}
+
+// Dictionary represents AMF3 dictionary.
+type Dictionary struct {
+	WeakKeys bool              `json:"weak_keys"`
+	Entries  []DictionaryEntry `json:"entries"`
+}
+
+// Property returns value of the sealed or dynamic member by name.
+func (o *Object) Property(name string) (ValueType, bool) {
+	if o.Traits != nil {
+		for i, m := range o.Traits.Members {
+			if m == name && i < len(o.Sealed) {
+				return o.Sealed[i], true
+			}
+		}
+	}
+	for _, p := range o.Dynamic {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return ValueType{}, false
+}
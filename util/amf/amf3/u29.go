@@ -0,0 +1,41 @@
+package amf3
+
+import "fmt"
+
+// Range of U29 and AMF3 integer type.
+const (
+	MaxU29     = 0x1FFFFFFF
+	MaxInteger = 1<<28 - 1 // larger integers should be serialized as double
+	MinInteger = -1 << 28
+)
+
+// encodeU29 encodes variable length unsigned 29-bit integer to 1~4 bytes.
+func encodeU29(u uint32) ([]byte, error) {
+	switch {
+	case u < 0x80:
+		return []byte{byte(u)}, nil
+	case u < 0x4000:
+		return []byte{byte(u>>7) | 0x80, byte(u) & 0x7F}, nil
+	case u < 0x200000:
+		return []byte{byte(u>>14) | 0x80, byte(u>>7) | 0x80, byte(u) & 0x7F}, nil
+	case u <= MaxU29:
+		return []byte{byte(u>>22) | 0x80, byte(u>>15) | 0x80, byte(u>>8) | 0x80, byte(u)}, nil
+	}
+	return nil, fmt.Errorf("U29 value 0x%x out of range", u)
+}
+
+// integerToU29 converts signed 29-bit integer to U29.
+func integerToU29(i int32) (uint32, error) {
+	if i < MinInteger || i > MaxInteger {
+		return 0, fmt.Errorf("integer %d out of range [%d,%d]", i, MinInteger, MaxInteger)
+	}
+	return uint32(i) & MaxU29, nil
+}
+
+// u29ToInteger converts U29 to signed 29-bit integer.
+func u29ToInteger(u uint32) int32 {
+	if u&0x10000000 != 0 { // sign bit
+		return int32(u) - 0x20000000
+	}
+	return int32(u)
+}
@@ -0,0 +1,43 @@
+package amf3
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// ValueType represents an AMF3 value-type.
+// Value is nil for undefined, null, false and true, int32 for integer, float64 for double, string for string,
+// and pointer of types for others, e.g., *Object, *Array, *XML.
+type ValueType struct {
+	TypeMarker uint8 `json:"type_marker"`
+
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v ValueType) MarshalJSON() ([]byte, error) {
+	var dj = struct {
+		TypeMarker            uint8  `json:"type_marker"`
+		TypeMarkerDescription string `json:"type_marker_description"`
+
+		Value interface{} `json:"value,omitempty"`
+	}{
+		TypeMarker:            v.TypeMarker,
+		TypeMarkerDescription: TypeMarkerDescription(int(v.TypeMarker)),
+
+		Value: v.Value,
+	}
+	return json.Marshal(dj)
+}
+
+// Decode decodes bytes to AMF3 value type with new reference tables.
+// Use Decoder to decode values that share reference tables.
+func (v *ValueType) Decode(r io.Reader) (int, error) {
+	return NewDecoder(r).Decode(v)
+}
+
+// Encode encodes AMF3 value type to bytes with new reference tables.
+// Use Encoder to encode values that share reference tables.
+func (v ValueType) Encode(w io.Writer) (int, error) {
+	return NewEncoder(w).Encode(v)
+}
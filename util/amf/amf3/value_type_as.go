@@ -0,0 +1,88 @@
+package amf3
+
+import "fmt"
+
+// AsBoolean returns boolean value if type is false or true.
+func (v ValueType) AsBoolean() (bool, error) {
+	switch v.TypeMarker {
+	case TypeMarkerFalse:
+		return false, nil
+	case TypeMarkerTrue:
+		return true, nil
+	}
+	return false, v.unmatched(TypeMarkerTrue)
+}
+
+// AsInteger returns integer value if type matched.
+func (v ValueType) AsInteger() (int32, error) {
+	if v.TypeMarker != TypeMarkerInteger {
+		return 0, v.unmatched(TypeMarkerInteger)
+	}
+
+	i, ok := v.Value.(int32)
+	if !ok {
+		return 0, fmt.Errorf("value as int32 failed")
+	}
+	return i, nil
+}
+
+// AsNumber returns number value if type is integer or double.
+func (v ValueType) AsNumber() (float64, error) {
+	if v.TypeMarker == TypeMarkerInteger {
+		i, err := v.AsInteger()
+		return float64(i), err
+	}
+	if v.TypeMarker != TypeMarkerDouble {
+		return 0, v.unmatched(TypeMarkerDouble)
+	}
+
+	f, ok := v.Value.(float64)
+	if !ok {
+		return 0, fmt.Errorf("value as float64 failed")
+	}
+	return f, nil
+}
+
+// AsString returns string value if type matched.
+func (v ValueType) AsString() (string, error) {
+	if v.TypeMarker != TypeMarkerString {
+		return "", v.unmatched(TypeMarkerString)
+	}
+
+	s, ok := v.Value.(string)
+	if !ok {
+		return "", fmt.Errorf("value as string failed")
+	}
+	return s, nil
+}
+
+// AsObject returns object value if type matched.
+func (v ValueType) AsObject() (*Object, error) {
+	if v.TypeMarker != TypeMarkerObject {
+		return nil, v.unmatched(TypeMarkerObject)
+	}
+
+	o, ok := v.Value.(*Object)
+	if !ok || o == nil {
+		return nil, fmt.Errorf("value as object failed")
+	}
+	return o, nil
+}
+
+// AsArray returns array value if type matched.
+func (v ValueType) AsArray() (*Array, error) {
+	if v.TypeMarker != TypeMarkerArray {
+		return nil, v.unmatched(TypeMarkerArray)
+	}
+
+	a, ok := v.Value.(*Array)
+	if !ok || a == nil {
+		return nil, fmt.Errorf("value as array failed")
+	}
+	return a, nil
+}
+
+func (v ValueType) unmatched(expect int) error {
+	return fmt.Errorf("type 0x%x(%s) unmatched with expect %d(%s)",
+		v.TypeMarker, TypeMarkerDescription(int(v.TypeMarker)), expect, TypeMarkerDescription(expect))
+}
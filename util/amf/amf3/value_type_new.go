@@ -0,0 +1,77 @@
+package amf3
+
+// NewUndefined creates AMF3 undefined value type.
+func NewUndefined() ValueType {
+	return ValueType{TypeMarker: TypeMarkerUndefined}
+}
+
+// NewNull creates AMF3 null value type.
+func NewNull() ValueType {
+	return ValueType{TypeMarker: TypeMarkerNull}
+}
+
+// NewBoolean creates AMF3 false or true value type.
+func NewBoolean(b bool) ValueType {
+	if b {
+		return ValueType{TypeMarker: TypeMarkerTrue}
+	}
+	return ValueType{TypeMarker: TypeMarkerFalse}
+}
+
+// NewInteger creates AMF3 integer value type, or double if it's out of 29-bit integer range.
+func NewInteger(i int32) ValueType {
+	if i < MinInteger || i > MaxInteger {
+		return NewDouble(float64(i))
+	}
+	return ValueType{TypeMarker: TypeMarkerInteger, Value: i}
+}
+
+// NewDouble creates AMF3 double value type.
+func NewDouble(f float64) ValueType {
+	return ValueType{TypeMarker: TypeMarkerDouble, Value: f}
+}
+
+// NewString creates AMF3 string value type.
+func NewString(s string) ValueType {
+	return ValueType{TypeMarker: TypeMarkerString, Value: s}
+}
+
+// NewXMLDocument creates AMF3 xml-doc value type.
+func NewXMLDocument(s string) ValueType {
+	return ValueType{TypeMarker: TypeMarkerXMLDocument, Value: &XML{Str: s}}
+}
+
+// NewXML creates AMF3 xml value type.
+func NewXML(s string) ValueType {
+	return ValueType{TypeMarker: TypeMarkerXML, Value: &XML{Str: s}}
+}
+
+// NewDate creates AMF3 date value type by milliseconds since epoch in UTC.
+func NewDate(millis float64) ValueType {
+	return ValueType{TypeMarker: TypeMarkerDate, Value: &Date{Millis: millis}}
+}
+
+// NewProperty creates a name-value pair for object or array.
+func NewProperty(name string, v ValueType) Property {
+	return Property{Name: name, Value: v}
+}
+
+// NewArray creates AMF3 array value type with dense values, set Associative for associative portion.
+func NewArray(values ...ValueType) ValueType {
+	return ValueType{TypeMarker: TypeMarkerArray, Value: &Array{Dense: values}}
+}
+
+// NewObject creates AMF3 object value type of traits, values of sealed members should be in order of traits members.
+func NewObject(traits *Traits, sealed ...ValueType) ValueType {
+	return ValueType{TypeMarker: TypeMarkerObject, Value: &Object{Traits: traits, Sealed: sealed}}
+}
+
+// NewAnonymousObject creates AMF3 dynamic anonymous object value type, e.g., for command objects.
+func NewAnonymousObject(properties ...Property) ValueType {
+	return ValueType{TypeMarker: TypeMarkerObject, Value: &Object{Traits: &Traits{Dynamic: true}, Dynamic: properties}}
+}
+
+// NewByteArray creates AMF3 byte-array value type.
+func NewByteArray(data []byte) ValueType {
+	return ValueType{TypeMarker: TypeMarkerByteArray, Value: &ByteArray{Data: data}}
+}
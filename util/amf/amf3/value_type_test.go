@@ -0,0 +1,149 @@
+package amf3
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestValueTypeRoundTrip(t *testing.T) {
+	traits := &Traits{ClassName: "Point", Members: []string{"x", "y"}}
+	object := &Object{Traits: &Traits{Dynamic: true}, Dynamic: []Property{NewProperty("a", NewInteger(1))}}
+
+	var cases = []struct {
+		name string
+		vt   ValueType
+		out  []byte
+	}{
+		{"undefined", NewUndefined(), []byte{0x00}},
+		{"null", NewNull(), []byte{0x01}},
+		{"false", NewBoolean(false), []byte{0x02}},
+		{"true", NewBoolean(true), []byte{0x03}},
+		{"integer 0", NewInteger(0), []byte{0x04, 0x00}},
+		{"integer 127", NewInteger(127), []byte{0x04, 0x7F}},
+		{"integer 128", NewInteger(128), []byte{0x04, 0x81, 0x00}},
+		{"integer 16384", NewInteger(16384), []byte{0x04, 0x81, 0x80, 0x00}},
+		{"integer 2097152", NewInteger(2097152), []byte{0x04, 0x80, 0xC0, 0x80, 0x00}},
+		{"integer max", NewInteger(MaxInteger), []byte{0x04, 0xBF, 0xFF, 0xFF, 0xFF}},
+		{"integer -1", NewInteger(-1), []byte{0x04, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{"integer min", NewInteger(MinInteger), []byte{0x04, 0xC0, 0x80, 0x80, 0x00}},
+		{"integer out of range as double", NewInteger(MaxInteger + 1), []byte{0x05, 0x41, 0xB0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"double", NewDouble(1), []byte{0x05, 0x3F, 0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"empty string", NewString(""), []byte{0x06, 0x01}},
+		{"string", NewString("hello"), []byte{0x06, 0x0B, 'h', 'e', 'l', 'l', 'o'}},
+		{"xml-doc", NewXMLDocument("<a/>"), []byte{0x07, 0x09, '<', 'a', '/', '>'}},
+		{"xml", NewXML("<a/>"), []byte{0x0B, 0x09, '<', 'a', '/', '>'}},
+		{"date", NewDate(1), []byte{0x08, 0x01, 0x3F, 0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"byte-array", NewByteArray([]byte{1, 2}), []byte{0x0C, 0x05, 0x01, 0x02}},
+		{"dense array", NewArray(NewInteger(1), NewNull()), []byte{0x09, 0x05, 0x01, 0x04, 0x01, 0x01}},
+		{"associative array",
+			ValueType{TypeMarker: TypeMarkerArray, Value: &Array{Associative: []Property{NewProperty("k", NewBoolean(true))}, Dense: []ValueType{NewNull()}}},
+			[]byte{0x09, 0x03, 0x03, 'k', 0x03, 0x01, 0x01}},
+		{"anonymous object", NewAnonymousObject(NewProperty("a", NewInteger(1))),
+			[]byte{0x0A, 0x0B, 0x01, 0x03, 'a', 0x04, 0x01, 0x01}},
+		{"typed object", NewObject(traits, NewInteger(1), NewInteger(2)),
+			[]byte{0x0A, 0x23, 0x0B, 'P', 'o', 'i', 'n', 't', 0x03, 'x', 0x03, 'y', 0x04, 0x01, 0x04, 0x02}},
+		{"vector-int", ValueType{TypeMarker: TypeMarkerVectorInt, Value: &VectorInt{Items: []int32{-1}}},
+			[]byte{0x0D, 0x03, 0x00, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{"vector-uint", ValueType{TypeMarker: TypeMarkerVectorUint, Value: &VectorUint{Fixed: true, Items: []uint32{1}}},
+			[]byte{0x0E, 0x03, 0x01, 0x00, 0x00, 0x00, 0x01}},
+		{"vector-double", ValueType{TypeMarker: TypeMarkerVectorDouble, Value: &VectorDouble{Items: []float64{1}}},
+			[]byte{0x0F, 0x03, 0x00, 0x3F, 0xF0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"vector-object", ValueType{TypeMarker: TypeMarkerVectorObject, Value: &VectorObject{TypeName: "*", Items: []ValueType{NewString("s")}}},
+			[]byte{0x10, 0x03, 0x00, 0x03, '*', 0x06, 0x03, 's'}},
+		{"dictionary", ValueType{TypeMarker: TypeMarkerDictionary, Value: &Dictionary{Entries: []DictionaryEntry{{NewString("k"), NewInteger(1)}}}},
+			[]byte{0x11, 0x03, 0x00, 0x06, 0x03, 'k', 0x04, 0x01}},
+
+		// reference tables
+		{"string reference", NewArray(NewString("a"), NewString("a"), NewString(""), NewString("")),
+			[]byte{0x09, 0x09, 0x01, 0x06, 0x03, 'a', 0x06, 0x00, 0x06, 0x01, 0x06, 0x01}},
+		{"string reference by property name", NewArray(NewAnonymousObject(NewProperty("a", NewString("a")))),
+			[]byte{0x09, 0x03, 0x01, 0x0A, 0x0B, 0x01, 0x03, 'a', 0x06, 0x00, 0x01}},
+		{"traits reference", NewArray(NewObject(traits, NewInteger(1), NewInteger(2)), NewObject(traits, NewInteger(3), NewInteger(4))),
+			[]byte{0x09, 0x05, 0x01,
+				0x0A, 0x23, 0x0B, 'P', 'o', 'i', 'n', 't', 0x03, 'x', 0x03, 'y', 0x04, 0x01, 0x04, 0x02,
+				0x0A, 0x01, 0x04, 0x03, 0x04, 0x04}},
+		{"object reference", NewArray(ValueType{TypeMarker: TypeMarkerObject, Value: object}, ValueType{TypeMarker: TypeMarkerObject, Value: object}),
+			[]byte{0x09, 0x05, 0x01, 0x0A, 0x0B, 0x01, 0x03, 'a', 0x04, 0x01, 0x01, 0x0A, 0x02}},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		n, err := c.vt.Encode(&buf)
+		if err != nil {
+			t.Errorf("%s encode expect nil but got %v", c.name, err)
+			continue
+		}
+		if n != len(c.out) || !bytes.Equal(buf.Bytes(), c.out) {
+			t.Errorf("%s encode expect %x but got %x(%d bytes)", c.name, c.out, buf.Bytes(), n)
+		}
+
+		vt := ValueType{}
+		if n, err := vt.Decode(bytes.NewReader(c.out)); err != nil || n != len(c.out) {
+			t.Errorf("%s decode %x expect %d bytes but got %d, err %v", c.name, c.out, len(c.out), n, err)
+			continue
+		}
+		if !reflect.DeepEqual(vt, c.vt) {
+			t.Errorf("%s decode %x expect %#v but got %#v", c.name, c.out, c.vt, vt)
+		}
+	}
+}
+
+func TestReferenceTables(t *testing.T) {
+	// tables are shared by values of the same Decoder/Encoder
+	values := []ValueType{NewString("a"), NewString("a"), NewAnonymousObject(), NewAnonymousObject()}
+	expect := []byte{0x06, 0x03, 'a', 0x06, 0x00, 0x0A, 0x0B, 0x01, 0x01, 0x0A, 0x01, 0x01}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, v := range values {
+		if _, err := enc.Encode(v); err != nil {
+			t.Fatalf("encode %v expect nil but got %v", v, err)
+		}
+	}
+	if !bytes.Equal(buf.Bytes(), expect) {
+		t.Errorf("encode expect %x but got %x", expect, buf.Bytes())
+	}
+
+	dec := NewDecoder(bytes.NewReader(expect))
+	var decoded []*Object
+	for i := range values {
+		v := ValueType{}
+		if _, err := dec.Decode(&v); err != nil {
+			t.Fatalf("decode value %d expect nil but got %v", i, err)
+		}
+		if s, err := v.AsString(); err == nil && s != "a" {
+			t.Errorf("decode value %d expect string a but got %q", i, s)
+		} else if o, err := v.AsObject(); err == nil {
+			decoded = append(decoded, o)
+		}
+	}
+	if len(decoded) != 2 || decoded[0].Traits != decoded[1].Traits {
+		t.Errorf("expect 2 objects share the same traits but got %v", decoded)
+	}
+
+	// self reference
+	a := &Array{}
+	a.Dense = []ValueType{{TypeMarker: TypeMarkerArray, Value: a}}
+	buf.Reset()
+	if _, err := (ValueType{TypeMarker: TypeMarkerArray, Value: a}).Encode(&buf); err != nil {
+		t.Fatalf("encode self reference expect nil but got %v", err)
+	}
+	if expect := []byte{0x09, 0x03, 0x01, 0x09, 0x00}; !bytes.Equal(buf.Bytes(), expect) {
+		t.Errorf("encode self reference expect %x but got %x", expect, buf.Bytes())
+	}
+	v := ValueType{}
+	if _, err := v.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("decode self reference expect nil but got %v", err)
+	}
+	if got, err := v.AsArray(); err != nil || len(got.Dense) != 1 || got.Dense[0].Value != got {
+		t.Errorf("decode self reference expect array refers to itself but got %v, err %v", got, err)
+	}
+
+	// invalid references
+	for _, in := range [][]byte{{0x06, 0x00}, {0x0A, 0x00}, {0x0A, 0x01}, {0x0A, 0x07, 0x01}} {
+		if _, err := v.Decode(bytes.NewReader(in)); err == nil {
+			t.Errorf("decode %x expect error but got nil", in)
+		}
+	}
+}
@@ -1,7 +1,14 @@
 // Package fixedpoint provides utils for fixed-point value calculation.
 package fixedpoint
 
+import "math"
+
 // From16x16 converts fixed-point 16.16 to normal decimal.
 func From16x16(v float64) float64 {
 	return v / 65536.0
 }
+
+// To16x16 converts normal decimal to fixed-point 16.16.
+func To16x16(v float64) float64 {
+	return math.Round(v * 65536.0)
+}
@@ -10,6 +10,9 @@ const (
 	FLV = "flv"
 
 	H264 = "h264"
+	H265 = "h265"
+
+	AAC = "aac"
 )
 
 // AsExtension returns extension representation of the format, e.g. return '.mp4' for format 'mp4'.
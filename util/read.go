@@ -13,6 +13,9 @@ const (
 func ReadOrError(r io.Reader, data []byte) error {
 
 	l := len(data)
+	if l == 0 { // nothing to read, e.g., empty body at the end of a bytes.Reader which returns io.EOF
+		return nil
+	}
 	readN := 0
 	for {
 		n, err := r.Read(data[readN:])
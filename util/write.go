@@ -0,0 +1,17 @@
+package util
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// WriteBigEndian writes values in big-endian order one by one, otherwise error.
+// Each value should be fixed-size value or slice of fixed-size values that supported by binary.Write.
+func WriteBigEndian(w io.Writer, values ...interface{}) error {
+	for _, v := range values {
+		if err := binary.Write(w, binary.BigEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -376,3 +376,34 @@ func (s *SequenceParameterSetData) Parse(r io.Reader, size int) (uint64, error)
 	}
 	return parsedBytes, nil
 }
+
+// Resolution calculates width and height of the decoded pictures with frame cropping applied,
+// see ISO/IEC-14496-10 7.4.2.1.1 for details.
+func (s *SequenceParameterSetData) Resolution() (width, height uint32) {
+	chromaArrayType := uint64(1) // chroma_format_idc inferred to be 1 if absent
+	if s.ChromaFormatIdc != nil {
+		chromaArrayType = s.ChromaFormatIdc.Value()
+	}
+	if s.SeparateColourPlaneFlag != nil && *s.SeparateColourPlaneFlag == 1 {
+		chromaArrayType = 0
+	}
+
+	frameHeightFactor := uint64(2 - s.FrameMbsOnlyFlag)
+	cropUnitX, cropUnitY := uint64(1), frameHeightFactor
+	switch chromaArrayType {
+	case 1: // 4:2:0
+		cropUnitX, cropUnitY = 2, 2*frameHeightFactor
+	case 2: // 4:2:2
+		cropUnitX = 2
+	}
+
+	w := (s.PicWidthInMbsMinus1.Value() + 1) * 16
+	h := frameHeightFactor * (s.PicHeightInMapUnitsMinus1.Value() + 1) * 16
+	if s.FrameCroppingFlag != 0 && s.FrameCropLeftOffset != nil && s.FrameCropRightOffset != nil &&
+		s.FrameCropTopOffset != nil && s.FrameCropBottomOffset != nil {
+		w -= cropUnitX * (s.FrameCropLeftOffset.Value() + s.FrameCropRightOffset.Value())
+		h -= cropUnitY * (s.FrameCropTopOffset.Value() + s.FrameCropBottomOffset.Value())
+	}
+
+	return uint32(w), uint32(h)
+}
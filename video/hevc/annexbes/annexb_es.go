@@ -0,0 +1,74 @@
+// Package annexbes represents Annex B defined HEVC Elementary byte stream,
+// which was defined in Rec. ITU-T H.265 Annex B.
+package annexbes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ghodss/yaml"
+	"github.com/wangyoucao577/medialib/video/hevc/nalu"
+)
+
+var startCode4Bytes = []byte{0x00, 0x00, 0x00, 0x01}
+
+// ElementaryStream represents HEVC Elementary Stream.
+type ElementaryStream struct {
+	NALU []nalu.NALUnit `json:"nalu"`
+}
+
+// JSON marshals elementary stream to JSON representation
+func (e *ElementaryStream) JSON() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// JSONIndent marshals elementary stream to JSON representation with customized indent.
+func (e *ElementaryStream) JSONIndent(prefix, indent string) ([]byte, error) {
+	return json.MarshalIndent(e, prefix, indent)
+}
+
+// YAML formats elementary stream to YAML representation.
+func (e *ElementaryStream) YAML() ([]byte, error) {
+	j, err := json.Marshal(e)
+	if err != nil {
+		return j, err
+	}
+	return yaml.JSONToYAML(j)
+}
+
+// CSV formats boxes to CSV representation, which isn't supported at the moment.
+func (e *ElementaryStream) CSV() ([]byte, error) {
+	return nil, fmt.Errorf("csv representation does not support yet")
+}
+
+// Dump dumps raw data into io.Writer.
+func (e *ElementaryStream) Dump(w io.Writer) (int, error) {
+	if len(e.NALU) == 0 {
+		return 0, fmt.Errorf("empty elementary stream")
+	}
+
+	var writedBytes int
+
+	for i := range e.NALU {
+		data := startCode4Bytes // Annex B start code
+		if n, err := w.Write(data); err != nil {
+			return writedBytes, err
+		} else if n != len(data) {
+			return writedBytes, fmt.Errorf("write bytes unmatch, expect(%d) != actual(%d)", len(data), n)
+		} else {
+			writedBytes += n
+		}
+
+		raw := e.NALU[i].Raw()
+		if n, err := w.Write(raw); err != nil {
+			return writedBytes, err
+		} else if n != len(raw) {
+			return writedBytes, fmt.Errorf("write bytes unmatch, expect(%d) != actual(%d)", len(raw), n)
+		} else {
+			writedBytes += n
+		}
+	}
+
+	return writedBytes, nil
+}
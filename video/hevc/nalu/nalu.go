@@ -0,0 +1,77 @@
+package nalu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/wangyoucao577/medialib/util"
+)
+
+// NALUnit represents HEVC NAL Unit that defined in Rec. ITU-T H.265 7.3.1.
+// Only NAL unit header will be parsed at the moment, the payload is kept as raw bytes.
+type NALUnit struct {
+	RawBytes []byte `json:"-"` // store raw bytes
+
+	ForbiddenZeroBit   uint8 `json:"forbidden_zero_bit"`    // 1 bit, shoule be 0 always
+	NALUnitType        uint8 `json:"nal_unit_type"`         // 6 bits
+	NuhLayerID         uint8 `json:"nuh_layer_id"`          // 6 bits
+	NuhTemporalIDPlus1 uint8 `json:"nuh_temporal_id_plus1"` // 3 bits
+}
+
+// MarshalJSON implements json.Marshaler.
+func (n *NALUnit) MarshalJSON() ([]byte, error) {
+	var nj = struct {
+		RawBytes []byte `json:"raw_bytes,omitempty"`
+
+		ForbiddenZeroBit       uint8  `json:"forbidden_zero_bit"`
+		NALUnitType            uint8  `json:"nal_unit_type"`
+		NALUnitTypeDescription string `json:"nal_unit_type_description"`
+		NuhLayerID             uint8  `json:"nuh_layer_id"`
+		NuhTemporalIDPlus1     uint8  `json:"nuh_temporal_id_plus1"`
+	}{
+		// RawBytes:               n.RawBytes, // set by type
+
+		ForbiddenZeroBit:       n.ForbiddenZeroBit,
+		NALUnitType:            n.NALUnitType,
+		NALUnitTypeDescription: TypeDescription(int(n.NALUnitType)),
+		NuhLayerID:             n.NuhLayerID,
+		NuhTemporalIDPlus1:     n.NuhTemporalIDPlus1,
+	}
+
+	switch n.NALUnitType {
+	case TypeVPS_NUT, TypeSPS_NUT, TypePPS_NUT, TypeAUD_NUT, TypePREFIX_SEI_NUT, TypeSUFFIX_SEI_NUT:
+		nj.RawBytes = n.RawBytes
+	}
+
+	return json.Marshal(nj)
+}
+
+// Parse parses bytes to HEVC NAL Unit, return parsed bytes or error.
+// The NAL Unit syntax defined in Rec. ITU-T H.265 7.3.1.
+func (n *NALUnit) Parse(r io.Reader, size int) (uint64, error) {
+	if size < 2 {
+		return 0, fmt.Errorf("nalu size %d too small", size)
+	}
+
+	n.RawBytes = make([]byte, size)
+	if err := util.ReadOrError(r, n.RawBytes); err != nil {
+		return 0, err
+	}
+
+	n.ForbiddenZeroBit = (n.RawBytes[0] >> 7) & 0x1
+	n.NALUnitType = (n.RawBytes[0] >> 1) & 0x3F
+	n.NuhLayerID = ((n.RawBytes[0] & 0x1) << 5) | ((n.RawBytes[1] >> 3) & 0x1F)
+	n.NuhTemporalIDPlus1 = n.RawBytes[1] & 0x7
+
+	if n.ForbiddenZeroBit != 0 {
+		return uint64(size), fmt.Errorf("nalu forbidden_zero_bit should be 0")
+	}
+
+	return uint64(size), nil
+}
+
+// Raw translates to raw bytes data.
+func (n *NALUnit) Raw() []byte {
+	return n.RawBytes
+}